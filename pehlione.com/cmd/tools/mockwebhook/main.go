@@ -21,6 +21,8 @@ type webhookPayload struct {
 	Data struct {
 		PaymentRef  string `json:"payment_ref"`
 		RefundRef   string `json:"refund_ref"`
+		DisputeRef  string `json:"dispute_ref,omitempty"`
+		Reason      string `json:"reason,omitempty"`
 		AmountCents int    `json:"amount_cents"`
 		Currency    string `json:"currency"`
 	} `json:"data"`
@@ -30,9 +32,11 @@ func main() {
 	url := flag.String("url", "http://localhost:8080/webhooks/mock", "Webhook URL")
 	secret := flag.String("secret", os.Getenv("MOCK_WEBHOOK_SECRET"), "Webhook secret")
 	eventID := flag.String("event-id", "evt_"+randomHex(8), "Event ID")
-	eventType := flag.String("type", "payment.succeeded", "Event type (payment.succeeded, payment.failed, refund.succeeded, refund.failed)")
+	eventType := flag.String("type", "payment.succeeded", "Event type (payment.authorized, payment.captured, payment.succeeded, payment.failed, refund.succeeded, refund.failed, dispute.opened, dispute.won, dispute.lost)")
 	paymentRef := flag.String("payment-ref", "pay_"+randomHex(8), "Payment ref (for payment events)")
 	refundRef := flag.String("refund-ref", "", "Refund ref (for refund events)")
+	disputeRef := flag.String("dispute-ref", "", "Dispute ref (for dispute events)")
+	reason := flag.String("reason", "", "Dispute reason")
 	amount := flag.Int("amount", 5000, "Amount in cents")
 	currency := flag.String("currency", "EUR", "Currency")
 	dryRun := flag.Bool("dry-run", false, "Only print signature header, don't send")
@@ -51,6 +55,8 @@ func main() {
 	}
 	payload.Data.PaymentRef = *paymentRef
 	payload.Data.RefundRef = *refundRef
+	payload.Data.DisputeRef = *disputeRef
+	payload.Data.Reason = *reason
 	payload.Data.AmountCents = *amount
 	payload.Data.Currency = *currency
 
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/modules/returns"
+)
+
+const usage = `usage: webhookevents <command> [flags]
+
+commands:
+  list   [-state failed|pending|processed] [-type T] [-provider P] [-limit N]
+  show   <id>
+  replay <id>
+`
+
+func main() {
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	dsn := os.Getenv("DB_DSN")
+	if dsn == "" {
+		log.Fatal("DB_DSN environment variable is required")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+
+	// replay'de iade e-postası ve iade talebi, webhook'taki gibi işlenir
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	emailSvc := email.NewService(db)
+	svc := payments.NewWebhookService(db)
+	svc.SetEmailService(emailSvc, baseURL)
+	svc.SetRefundListener(returns.NewService(db, nil, nil, emailSvc, baseURL))
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ExitOnError)
+		state := fs.String("state", payments.EventStateFailed, "failed|pending|processed (empty = all)")
+		typ := fs.String("type", "", "Event type")
+		provider := fs.String("provider", "", "Provider name")
+		limit := fs.Int("limit", 50, "Max rows")
+		_ = fs.Parse(os.Args[2:])
+
+		events, err := svc.ListEvents(ctx, payments.EventFilter{State: *state, Type: *typ, Provider: *provider, Limit: *limit})
+		if err != nil {
+			log.Fatalf("list: %v", err)
+		}
+		for _, pe := range events {
+			msg := ""
+			if pe.ProcessError != nil {
+				msg = *pe.ProcessError
+			}
+			fmt.Printf("%s  %s  %-8s %-20s %-10s attempts=%d  %s  %s\n",
+				pe.ID, pe.ReceivedAt.Format("2006-01-02 15:04:05"), pe.Provider, pe.EventType, pe.State(), pe.Attempts, pe.EventID, msg)
+		}
+		fmt.Printf("%d event(s)\n", len(events))
+
+	case "show":
+		pe, err := svc.GetEvent(ctx, arg(2))
+		if err != nil {
+			log.Fatalf("show: %v", err)
+		}
+		ev := pe.Event()
+		fmt.Printf("id:          %s\nprovider:    %s\nevent_id:    %s\ntype:        %s\nstate:       %s\nattempts:    %d\n",
+			pe.ID, pe.Provider, pe.EventID, pe.EventType, pe.State(), pe.Attempts)
+		fmt.Printf("payment_ref: %s\nrefund_ref:  %s\namount:      %d %s\n", ev.PaymentRef, ev.RefundRef, ev.AmountCents, ev.Currency)
+		if ev.CheckoutRef != "" {
+			fmt.Printf("checkout:    %s\n", ev.CheckoutRef)
+		}
+		if pe.ProcessError != nil {
+			fmt.Printf("error:       %s\n", *pe.ProcessError)
+		}
+		fmt.Printf("payload:\n%s\n", string(pe.PayloadJSON))
+
+	case "replay":
+		id := arg(2)
+		if err := svc.Replay(ctx, id); err != nil {
+			log.Fatalf("replay %s: %v", id, err)
+		}
+		fmt.Printf("✓ event %s processed\n", id)
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func arg(i int) string {
+	if len(os.Args) <= i || os.Args[i] == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	return os.Args[i]
+}
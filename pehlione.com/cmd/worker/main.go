@@ -16,6 +16,9 @@ import (
 	"pehlione.com/app/internal/config"
 	"pehlione.com/app/internal/modules/email"
 	"pehlione.com/app/internal/modules/fx"
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/modules/returns"
 	"pehlione.com/app/internal/modules/shipping"
 	"pehlione.com/app/internal/sms"
 )
@@ -41,7 +44,7 @@ func main() {
 	fxRepo := fx.NewRepo(db)
 	fxSvc := fx.NewService(fxRepo, cfg.Currency.BaseCurrency)
 	ctx := context.Background()
-	errCh := make(chan error, 4)
+	errCh := make(chan error, 6)
 	started := 0
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
@@ -149,6 +152,34 @@ func main() {
 		}
 	}
 
+	// unpaid orders: release expired stock reservations and auto-cancel
+	orderSvc := orders.NewService(db, nil)
+	orderSvc.SetReservationTTL(time.Duration(cfg.Inventory.ReservationTTLMinutes) * time.Minute)
+	reservationWorker := orders.NewReservationWorker(orderSvc, time.Duration(cfg.Inventory.ReleaseIntervalSeconds)*time.Second)
+	started++
+	log.Println("reservation worker starting")
+	go func() {
+		errCh <- reservationWorker.Run(ctx)
+	}()
+
+	// payments/refunds stuck without a webhook: ask the provider
+	webhookSvc := payments.NewWebhookService(db)
+	webhookSvc.SetLogger(logger)
+	webhookSvc.SetEmailService(emailSvc, cfg.AppBaseURL)
+	webhookSvc.SetRefundListener(returns.NewService(db, nil, nil, emailSvc, cfg.AppBaseURL))
+	registry := paymentRegistry(cfg.Payment)
+	reconciler := payments.NewReconciler(db, registry, webhookSvc,
+		time.Duration(cfg.Payment.ReconcileStaleMinutes)*time.Minute,
+		time.Duration(cfg.Payment.ReconcileExpireHours)*time.Hour)
+	// iptal edilmiş siparişe sonradan gelen ödemeler iade edilir
+	reconciler.SetRefundService(payments.NewRefundService(db, registry, emailSvc, cfg.AppBaseURL))
+	reconcileWorker := payments.NewReconcileWorker(reconciler, time.Duration(cfg.Payment.ReconcileIntervalSeconds)*time.Second)
+	started++
+	log.Println("payment reconcile worker starting")
+	go func() {
+		errCh <- reconcileWorker.Run(ctx)
+	}()
+
 	if started == 0 {
 		log.Println("worker: no workers enabled, exiting")
 		return
@@ -159,3 +190,19 @@ func main() {
 		log.Fatalf("worker stopped: %v", err)
 	}
 }
+
+func paymentRegistry(cfg config.PaymentConfig) *payments.Registry {
+	providers, err := payments.NewRegistryFromConfig(payments.RegistryConfig{
+		Providers:            cfg.Providers,
+		Routes:               cfg.Routes,
+		APIKey:               cfg.APIKey,
+		WebhookSecret:        cfg.WebhookSecret,
+		APIBaseURL:           cfg.APIBaseURL,
+		MockWebhookSecret:    cfg.MockWebhookSecret,
+		MockWebhookTolerance: cfg.MockWebhookTolerance,
+	})
+	if err != nil {
+		log.Fatalf("worker: payment providers: %v", err)
+	}
+	return providers
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -30,13 +31,23 @@ type EmailConfig struct {
 }
 
 type PaymentConfig struct {
-	Provider             string
+	Provider             string   // default provider (first of Providers)
+	Providers            []string // PAYMENT_PROVIDERS: all enabled providers
+	Routes               string   // PAYMENT_ROUTES: "card:EUR=stripe,paypal:*=mock"
 	APIKey               string
 	PublicKey            string
 	WebhookSecret        string
+	APIBaseURL           string // PAYMENT_API_BASE_URL: Stripe-compatible API root
 	LiveMode             bool
 	MockWebhookSecret    string
 	MockWebhookTolerance int
+	CaptureMode          string // automatic|manual
+	MockHostedPage       bool   // mock: redirect to a local hosted payment page
+
+	// reconciliation of payments/refunds whose webhook never arrived
+	ReconcileStaleMinutes    int
+	ReconcileExpireHours     int
+	ReconcileIntervalSeconds int
 }
 
 type FXConfig struct {
@@ -65,6 +76,10 @@ type AppConfig struct {
 	Shipping   ShippingConfig
 	SMS        SMSConfig
 	Currency   CurrencyConfig
+	Tax        TaxConfig
+	Inventory  InventoryConfig
+	Orders     OrdersConfig
+	Seller     SellerConfig
 }
 
 func Load() (AppConfig, error) {
@@ -82,6 +97,10 @@ func Load() (AppConfig, error) {
 	cfg.Shipping = loadShippingConfig()
 	cfg.SMS = loadSMSConfig()
 	cfg.Currency = loadCurrencyConfig()
+	cfg.Tax = loadTaxConfig()
+	cfg.Inventory = loadInventoryConfig()
+	cfg.Orders = loadOrdersConfig()
+	cfg.Seller = loadSellerConfig()
 
 	if err := validateConfig(&cfg); err != nil {
 		return AppConfig{}, err
@@ -118,21 +137,40 @@ func loadEmailConfig() EmailConfig {
 }
 
 func loadPaymentConfig() PaymentConfig {
+	def := strings.ToLower(strings.TrimSpace(getEnv("PAYMENT_PROVIDER", "mock")))
+	providers := []string{def}
+	for _, p := range strings.Split(os.Getenv("PAYMENT_PROVIDERS"), ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "" && !slices.Contains(providers, p) {
+			providers = append(providers, p)
+		}
+	}
+
 	return PaymentConfig{
-		Provider:             strings.ToLower(strings.TrimSpace(getEnv("PAYMENT_PROVIDER", "mock"))),
+		Provider:             def,
+		Providers:            providers,
+		Routes:               strings.TrimSpace(os.Getenv("PAYMENT_ROUTES")),
 		APIKey:               strings.TrimSpace(os.Getenv("PAYMENT_API_KEY")),
 		PublicKey:            strings.TrimSpace(os.Getenv("PAYMENT_PUBLIC_KEY")),
 		WebhookSecret:        strings.TrimSpace(os.Getenv("PAYMENT_WEBHOOK_SECRET")),
+		APIBaseURL:           strings.TrimSpace(getEnv("PAYMENT_API_BASE_URL", "https://api.stripe.com")),
 		LiveMode:             parseBool(getEnv("PAYMENT_LIVE_MODE", "false"), false),
 		MockWebhookSecret:    strings.TrimSpace(getEnv("MOCK_WEBHOOK_SECRET", "dev_secret_change_me")),
 		MockWebhookTolerance: parseInt(getEnv("MOCK_WEBHOOK_TOLERANCE_SECONDS", "300"), 300),
+		CaptureMode:          strings.ToLower(strings.TrimSpace(getEnv("PAYMENT_CAPTURE_MODE", "manual"))),
+		MockHostedPage:       parseBool(getEnv("MOCK_PAYMENT_HOSTED_PAGE", "true"), true),
+
+		ReconcileStaleMinutes:    parseInt(getEnv("PAYMENT_RECONCILE_STALE_MINUTES", "15"), 15),
+		ReconcileExpireHours:     parseInt(getEnv("PAYMENT_RECONCILE_EXPIRE_HOURS", "24"), 24),
+		ReconcileIntervalSeconds: parseInt(getEnv("PAYMENT_RECONCILE_INTERVAL_SECONDS", "300"), 300),
 	}
 }
 
 type ShippingConfig struct {
-	Enabled     bool
-	Provider    string
-	MockBaseURL string
+	Enabled        bool
+	Provider       string
+	MockBaseURL    string
+	DefaultCountry string
 }
 
 type SMSConfig struct {
@@ -140,11 +178,81 @@ type SMSConfig struct {
 	Provider string
 }
 
+type TaxConfig struct {
+	Enabled bool
+	// catalog prices are gross (VAT included) when true
+	PricesIncludeTax bool
+}
+
+func loadTaxConfig() TaxConfig {
+	return TaxConfig{
+		Enabled:          parseBool(getEnv("TAX_ENABLED", "true"), true),
+		PricesIncludeTax: parseBool(getEnv("TAX_PRICES_INCLUDE_TAX", "true"), true),
+	}
+}
+
+type InventoryConfig struct {
+	// unpaid orders hold stock for this long before the worker releases it
+	ReservationTTLMinutes int
+	// how often the worker looks for expired reservations
+	ReleaseIntervalSeconds int
+}
+
+func loadInventoryConfig() InventoryConfig {
+	return InventoryConfig{
+		ReservationTTLMinutes:  parseInt(getEnv("INVENTORY_RESERVATION_TTL_MINUTES", "30"), 30),
+		ReleaseIntervalSeconds: parseInt(getEnv("INVENTORY_RELEASE_INTERVAL_SECONDS", "60"), 60),
+	}
+}
+
+type OrdersConfig struct {
+	// guest order links (signed access tokens) stay valid for this long
+	GuestLinkTTLHours int
+}
+
+func loadOrdersConfig() OrdersConfig {
+	return OrdersConfig{
+		GuestLinkTTLHours: parseInt(getEnv("GUEST_ORDER_LINK_TTL_HOURS", "720"), 720),
+	}
+}
+
+// SellerConfig is the invoicing company printed on e-invoices.
+type SellerConfig struct {
+	Name       string
+	Street     string
+	District   string
+	City       string
+	PostalCode string
+	Country    string
+	TaxID      string // VKN
+	TaxOffice  string // vergi dairesi
+	VATID      string // EU VAT ID (boşsa ülke kodu + VKN)
+	Email      string
+	Phone      string
+}
+
+func loadSellerConfig() SellerConfig {
+	return SellerConfig{
+		Name:       strings.TrimSpace(getEnv("SELLER_NAME", "Pehlione")),
+		Street:     strings.TrimSpace(os.Getenv("SELLER_STREET")),
+		District:   strings.TrimSpace(os.Getenv("SELLER_DISTRICT")),
+		City:       strings.TrimSpace(os.Getenv("SELLER_CITY")),
+		PostalCode: strings.TrimSpace(os.Getenv("SELLER_POSTAL_CODE")),
+		Country:    strings.ToUpper(strings.TrimSpace(getEnv("SELLER_COUNTRY", "TR"))),
+		TaxID:      strings.TrimSpace(os.Getenv("SELLER_TAX_ID")),
+		TaxOffice:  strings.TrimSpace(os.Getenv("SELLER_TAX_OFFICE")),
+		VATID:      strings.TrimSpace(os.Getenv("SELLER_VAT_ID")),
+		Email:      strings.TrimSpace(os.Getenv("SELLER_EMAIL")),
+		Phone:      strings.TrimSpace(os.Getenv("SELLER_PHONE")),
+	}
+}
+
 func loadShippingConfig() ShippingConfig {
 	return ShippingConfig{
-		Enabled:     parseBool(getEnv("SHIPPING_ENABLED", "true"), true),
-		Provider:    strings.ToLower(strings.TrimSpace(getEnv("SHIPPING_PROVIDER", "mock"))),
-		MockBaseURL: strings.TrimSpace(os.Getenv("SHIPPING_MOCK_BASE_URL")),
+		Enabled:        parseBool(getEnv("SHIPPING_ENABLED", "true"), true),
+		Provider:       strings.ToLower(strings.TrimSpace(getEnv("SHIPPING_PROVIDER", "mock"))),
+		MockBaseURL:    strings.TrimSpace(os.Getenv("SHIPPING_MOCK_BASE_URL")),
+		DefaultCountry: strings.ToUpper(strings.TrimSpace(getEnv("SHIPPING_DEFAULT_COUNTRY", "TR"))),
 	}
 }
 
@@ -199,9 +307,14 @@ func validateConfig(cfg *AppConfig) error {
 	if cfg.Payment.MockWebhookTolerance <= 0 {
 		cfg.Payment.MockWebhookTolerance = 300
 	}
+	switch cfg.Payment.CaptureMode {
+	case "automatic", "manual":
+	default:
+		return fmt.Errorf("PAYMENT_CAPTURE_MODE must be automatic or manual")
+	}
 
 	if cfg.Env == "production" {
-		if cfg.Payment.Provider == "mock" {
+		if slices.Contains(cfg.Payment.Providers, "mock") {
 			return fmt.Errorf("mock payment provider is not allowed in production")
 		}
 		if !cfg.Payment.LiveMode {
@@ -220,6 +333,10 @@ func validateConfig(cfg *AppConfig) error {
 		}
 	}
 
+	if cfg.Orders.GuestLinkTTLHours <= 0 {
+		return fmt.Errorf("GUEST_ORDER_LINK_TTL_HOURS must be greater than zero")
+	}
+
 	if cfg.Currency.BaseCurrency == "" {
 		return fmt.Errorf("CURRENCY_BASE is required")
 	}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_GuestOrderLinkTTL(t *testing.T) {
+	t.Setenv("DB_DSN", "user:pass@tcp(localhost:3306)/app")
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("EMAIL_SEND_ENABLED", "false")
+
+	t.Setenv("GUEST_ORDER_LINK_TTL_HOURS", "")
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 720, cfg.Orders.GuestLinkTTLHours, "varsayılan 30 gün")
+
+	t.Setenv("GUEST_ORDER_LINK_TTL_HOURS", "48")
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, 48, cfg.Orders.GuestLinkTTLHours)
+
+	for _, v := range []string{"0", "-5"} {
+		t.Setenv("GUEST_ORDER_LINK_TTL_HOURS", v)
+		_, err = Load()
+		require.EqualError(t, err, "GUEST_ORDER_LINK_TTL_HOURS must be greater than zero", v)
+	}
+}
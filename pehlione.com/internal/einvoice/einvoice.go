@@ -0,0 +1,473 @@
+// Package einvoice builds UBL 2.1 e-invoices from paid orders: an EN 16931
+// (EU) profile and the Turkish UBL-TR e-Arşiv profile. The XML is unsigned;
+// signing and reporting to GİB is up to the integrator that receives it.
+package einvoice
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+// ErrNotInvoiced: fatura numarası yalnızca ödenmiş siparişlerde var.
+var ErrNotInvoiced = errors.New("order has no invoice number yet")
+
+type Profile string
+
+const (
+	ProfileEN16931 Profile = "en16931"
+	ProfileUBLTR   Profile = "ubl-tr"
+)
+
+// ParseProfile accepts the ?profile= values of the download link.
+func ParseProfile(s string) (Profile, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "en16931", "en", "eu":
+		return ProfileEN16931, true
+	case "ubl-tr", "tr":
+		return ProfileUBLTR, true
+	}
+	return "", false
+}
+
+// ProfileFor picks the profile for a buyer country: UBL-TR for Turkey, EN 16931
+// everywhere else.
+func ProfileFor(country string) Profile {
+	if strings.EqualFold(strings.TrimSpace(country), "TR") {
+		return ProfileUBLTR
+	}
+	return ProfileEN16931
+}
+
+// Seller is the invoicing company (SELLER_* env).
+type Seller struct {
+	Name       string
+	Street     string
+	District   string // ilçe (UBL-TR CitySubdivisionName)
+	City       string
+	PostalCode string
+	Country    string // ISO 3166-1 alpha-2
+	TaxID      string // VKN
+	TaxOffice  string // vergi dairesi
+	VATID      string // EU VAT identifier; boşsa Country+TaxID
+	Email      string
+	Phone      string
+}
+
+func (s Seller) vatID() string {
+	if s.VATID != "" {
+		return s.VATID
+	}
+	if s.TaxID == "" {
+		return ""
+	}
+	return strings.ToUpper(s.Country) + s.TaxID
+}
+
+// Input is an invoiced order as the e-invoice shows it.
+type Input struct {
+	Order      orders.Order
+	Items      []orders.OrderItem
+	BuyerEmail string
+}
+
+// Country is the buyer country used by ProfileFor (billing, else shipping).
+func (in Input) Country() string {
+	return in.buyerAddress().Country
+}
+
+// Filename is the download name of the XML.
+func (in Input) Filename(p Profile) string {
+	name := "pehlione-invoice-" + ptrVal(in.Order.InvoiceNumber)
+	if p == ProfileUBLTR {
+		name += "-ubl-tr"
+	}
+	return name + ".xml"
+}
+
+type buyerAddress struct {
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Address1   string `json:"address1"`
+	Address2   string `json:"address2"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone"`
+}
+
+func (in Input) buyerAddress() buyerAddress {
+	raw := in.Order.BillingAddressJSON
+	if len(raw) == 0 {
+		raw = in.Order.ShippingAddressJSON
+	}
+	var a buyerAddress
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &a)
+	}
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	return a
+}
+
+// Build renders the order as a UBL 2.1 Invoice in the given profile.
+func Build(seller Seller, in Input, p Profile) ([]byte, error) {
+	o := in.Order
+	if o.InvoiceNumber == nil || *o.InvoiceNumber == "" {
+		return nil, ErrNotInvoiced
+	}
+	if len(in.Items) == 0 {
+		return nil, errors.New("einvoice: order has no items")
+	}
+
+	issued := o.CreatedAt
+	if o.InvoicedAt != nil {
+		issued = *o.InvoicedAt
+	}
+	issued = issued.Local()
+
+	doc := ublInvoice{
+		Xmlns:                nsInvoice,
+		XmlnsCAC:             nsCAC,
+		XmlnsCBC:             nsCBC,
+		UBLVersionID:         "2.1",
+		ID:                   *o.InvoiceNumber,
+		IssueDate:            issued.Format("2006-01-02"),
+		DocumentCurrencyCode: o.Currency,
+		OrderReference: &orderReference{
+			ID:        o.Number(),
+			IssueDate: o.CreatedAt.Local().Format("2006-01-02"),
+		},
+		PaymentMeans: paymentMeansFor(ptrVal(o.PaymentMethod)),
+	}
+
+	t := computeTotals(o, in.Items)
+	switch p {
+	case ProfileUBLTR:
+		buildTR(&doc, seller, in, t, issued)
+	default:
+		buildEN(&doc, seller, in, t)
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.Write(out)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func buildEN(doc *ublInvoice, s Seller, in Input, t totals) {
+	cur := in.Order.Currency
+	a := in.buyerAddress()
+
+	doc.CustomizationID = "urn:cen.eu:en16931:2017"
+	doc.ProfileID = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+	doc.InvoiceTypeCode = "380" // commercial invoice
+
+	sp := party{
+		EndpointID:    emailEndpoint(s.Email),
+		Name:          nameOf(s.Name),
+		PostalAddress: &address{StreetName: s.Street, CityName: s.City, PostalZone: s.PostalCode, Country: country{IdentificationCode: strings.ToUpper(s.Country)}},
+		LegalEntity:   &legalEntity{RegistrationName: s.Name, CompanyID: s.TaxID},
+		Contact:       contactOf(s.Phone, s.Email),
+	}
+	if vat := s.vatID(); vat != "" {
+		sp.TaxScheme = &partyTaxScheme{CompanyID: vat, TaxScheme: taxScheme{ID: "VAT"}}
+	}
+	doc.Supplier = partyWrapper{Party: sp}
+
+	name := buyerName(a, in.BuyerEmail)
+	doc.Customer = partyWrapper{Party: party{
+		EndpointID:    emailEndpoint(in.BuyerEmail),
+		Name:          nameOf(name),
+		PostalAddress: &address{StreetName: a.Address1, AdditionalStreetName: a.Address2, CityName: a.City, PostalZone: a.PostalCode, Country: country{IdentificationCode: a.Country}},
+		LegalEntity:   &legalEntity{RegistrationName: name},
+		Contact:       contactOf(a.Phone, in.BuyerEmail),
+	}}
+
+	if t.shipping > 0 {
+		doc.AllowanceCharges = append(doc.AllowanceCharges, allowanceCharge{
+			ChargeIndicator: true,
+			ReasonCode:      "FC", // freight service
+			Reason:          "Shipping",
+			Amount:          money(t.shipping, cur),
+			TaxCategory:     enCategory(0),
+		})
+	}
+
+	doc.TaxTotal = taxTotal{TaxAmount: money(t.tax, cur)}
+	for _, g := range t.groups {
+		doc.TaxTotal.Subtotals = append(doc.TaxTotal.Subtotals, taxSubtotal{
+			TaxableAmount: money(g.taxable, cur),
+			TaxAmount:     money(g.tax, cur),
+			TaxCategory:   *enCategory(g.rateBps),
+		})
+	}
+
+	// fatura ödemede kesilir: tutarın tamamı ödenmiş, ödenecek 0
+	doc.LegalMonetaryTotal = t.monetary(cur)
+	doc.LegalMonetaryTotal.PrepaidAmount = ptrAmount(money(in.Order.TotalCents, cur))
+	doc.LegalMonetaryTotal.PayableAmount = money(0, cur)
+
+	for i, l := range t.lines {
+		il := baseLine(i, l, cur)
+		il.Item.ClassifiedTaxCategory = enCategory(l.rateBps)
+		doc.Lines = append(doc.Lines, il)
+	}
+}
+
+func buildTR(doc *ublInvoice, s Seller, in Input, t totals, issued time.Time) {
+	cur := in.Order.Currency
+	a := in.buyerAddress()
+	copyInd := false
+
+	doc.CustomizationID = "TR1.2"
+	doc.ProfileID = "EARSIVFATURA" // son tüketici satışı
+	doc.ID = gibNumber(doc.ID)
+	doc.CopyIndicator = &copyInd
+	doc.UUID = invoiceUUID(*in.Order.InvoiceNumber)
+	doc.IssueTime = issued.Format("15:04:05")
+	doc.InvoiceTypeCode = "SATIS"
+	doc.Notes = []string{"Sipariş No: " + in.Order.Number()}
+	doc.LineCountNumeric = len(t.lines)
+
+	sellerAddr := &address{
+		StreetName:          s.Street,
+		CitySubdivisionName: s.District,
+		CityName:            s.City,
+		PostalZone:          s.PostalCode,
+		Country:             country{Name: countryName(s.Country)},
+	}
+	vkn := partyID{ID: identifier{Value: s.TaxID, SchemeID: "VKN"}}
+	doc.Signature = &signature{
+		ID:         identifier{Value: s.TaxID, SchemeID: "VKN_TCKN"},
+		Signatory:  party{Identifications: []partyID{vkn}, PostalAddress: sellerAddr},
+		Attachment: signatureAttachment{URI: "#Signature_" + doc.ID},
+	}
+	doc.Supplier = partyWrapper{Party: party{
+		Identifications: []partyID{vkn},
+		Name:            nameOf(s.Name),
+		PostalAddress:   sellerAddr,
+		TaxScheme:       &partyTaxScheme{TaxScheme: taxScheme{Name: s.TaxOffice}},
+		Contact:         contactOf(s.Phone, s.Email),
+	}}
+
+	// alıcı kimliği toplanmıyor: GİB'in nihai tüketici için tanımlı TCKN'si
+	first, last := strings.TrimSpace(a.FirstName), strings.TrimSpace(a.LastName)
+	if first == "" && last == "" {
+		first = buyerName(a, in.BuyerEmail)
+	}
+	if last == "" {
+		last = "-"
+	}
+	district := a.City // ilçe adresten ayrı alınmıyor
+	doc.Customer = partyWrapper{Party: party{
+		Identifications: []partyID{{ID: identifier{Value: "11111111111", SchemeID: "TCKN"}}},
+		PostalAddress: &address{
+			StreetName:           a.Address1,
+			AdditionalStreetName: a.Address2,
+			CitySubdivisionName:  district,
+			CityName:             a.City,
+			PostalZone:           a.PostalCode,
+			Country:              country{Name: countryName(a.Country)},
+		},
+		Contact: contactOf(a.Phone, in.BuyerEmail),
+		Person:  &person{FirstName: first, FamilyName: last},
+	}}
+
+	if t.shipping > 0 {
+		doc.AllowanceCharges = append(doc.AllowanceCharges, allowanceCharge{
+			ChargeIndicator: true,
+			Reason:          "Kargo",
+			Amount:          money(t.shipping, cur),
+		})
+	}
+
+	doc.TaxTotal = taxTotal{TaxAmount: money(t.tax, cur)}
+	for i, g := range t.groups {
+		doc.TaxTotal.Subtotals = append(doc.TaxTotal.Subtotals, trSubtotal(g, i+1, cur))
+	}
+
+	doc.LegalMonetaryTotal = t.monetary(cur)
+
+	for i, l := range t.lines {
+		il := baseLine(i, l, cur)
+		il.TaxTotal = &taxTotal{
+			TaxAmount: money(l.tax, cur),
+			Subtotals: []taxSubtotal{trSubtotal(taxGroup{rateBps: l.rateBps, taxable: l.net, tax: l.tax}, 1, cur)},
+		}
+		doc.Lines = append(doc.Lines, il)
+	}
+}
+
+func baseLine(i int, l line, cur string) invoiceLine {
+	il := invoiceLine{
+		ID:                  fmt.Sprintf("%d", i+1),
+		InvoicedQuantity:    quantity{Value: fmt.Sprintf("%d", l.item.Quantity), UnitCode: "C62"},
+		LineExtensionAmount: money(l.net, cur),
+		Item:                item{Name: l.item.ProductName},
+		Price:               price{PriceAmount: amount{Value: unitPrice(l.base, l.item.Quantity), Currency: cur}},
+	}
+	if l.item.SKU != "" {
+		il.Item.SellersItemID = &itemID{ID: l.item.SKU}
+	}
+	if l.discount > 0 {
+		il.AllowanceCharges = []allowanceCharge{{
+			ChargeIndicator: false,
+			ReasonCode:      "95", // discount
+			Reason:          "Discount",
+			Amount:          money(l.discount, cur),
+			BaseAmount:      ptrAmount(money(l.base, cur)),
+		}}
+	}
+	return il
+}
+
+func enCategory(rateBps int) *taxCategory {
+	c := &taxCategory{ID: "S", Percent: percent(rateBps), TaxScheme: taxScheme{ID: "VAT"}}
+	if rateBps == 0 {
+		c.ID = "Z"
+	}
+	return c
+}
+
+func trSubtotal(g taxGroup, seq int, cur string) taxSubtotal {
+	c := taxCategory{TaxScheme: taxScheme{Name: "KDV", TaxTypeCode: "0015"}}
+	if g.rateBps == 0 {
+		c.ExemptionReasonCode = "351"
+		c.ExemptionReason = "KDV - İstisna Olmayan Diğer"
+	}
+	return taxSubtotal{
+		TaxableAmount:  money(g.taxable, cur),
+		TaxAmount:      money(g.tax, cur),
+		CalculationSeq: seq,
+		Percent:        percent(g.rateBps),
+		TaxCategory:    c,
+	}
+}
+
+func paymentMeansFor(method string) *paymentMeans {
+	switch method {
+	case "card":
+		return &paymentMeans{Code: "48"} // bank card
+	case "paypal", "klarna":
+		return &paymentMeans{Code: "68"} // online payment service
+	}
+	return nil
+}
+
+func emailEndpoint(email string) *identifier {
+	if email = strings.TrimSpace(email); email == "" {
+		return nil
+	}
+	return &identifier{Value: email, SchemeID: "EM"}
+}
+
+func nameOf(name string) *partyName {
+	if name = strings.TrimSpace(name); name == "" {
+		return nil
+	}
+	return &partyName{Name: name}
+}
+
+func contactOf(phone, email string) *contact {
+	phone, email = strings.TrimSpace(phone), strings.TrimSpace(email)
+	if phone == "" && email == "" {
+		return nil
+	}
+	return &contact{Telephone: phone, ElectronicMail: email}
+}
+
+func buyerName(a buyerAddress, email string) string {
+	if n := strings.TrimSpace(a.FirstName + " " + a.LastName); n != "" {
+		return n
+	}
+	if email != "" {
+		return email
+	}
+	return "Customer"
+}
+
+func countryName(code string) string {
+	switch strings.ToUpper(code) {
+	case "TR", "":
+		return "Türkiye"
+	}
+	return strings.ToUpper(code)
+}
+
+// gibNumber maps INV-2026-000123 to the 16 character GİB format
+// (3 letter prefix, year, 9 digit sequence): INV2026000000123.
+func gibNumber(n string) string {
+	parts := strings.Split(n, "-")
+	if len(parts) != 3 || len(parts[0]) != 3 || len(parts[1]) != 4 {
+		return n
+	}
+	var seq int
+	if _, err := fmt.Sscanf(parts[2], "%d", &seq); err != nil {
+		return n
+	}
+	return fmt.Sprintf("%s%s%09d", strings.ToUpper(parts[0]), parts[1], seq)
+}
+
+// invoiceUUID is the ETTN; derived from the invoice number so a re-download
+// carries the same one.
+func invoiceUUID(invoiceNumber string) string {
+	return strings.ToUpper(uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:pehlione:invoice:"+invoiceNumber)).String())
+}
+
+func money(cents int, cur string) amount {
+	return amount{Value: decimal(cents, 2), Currency: cur}
+}
+
+func ptrAmount(a amount) *amount { return &a }
+
+// percent renders basis points as a percentage (2000 -> 20, 850 -> 8.5).
+func percent(bps int) string {
+	return strings.TrimSuffix(strings.TrimRight(decimal(bps, 2), "0"), ".")
+}
+
+// unitPrice is the net unit price before discount with four decimals.
+func unitPrice(lineCents, qty int) string {
+	if qty <= 0 {
+		qty = 1
+	}
+	return decimal(roundDiv(lineCents*100, qty), 4)
+}
+
+func decimal(v, places int) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	div := 1
+	for range places {
+		div *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, v/div, places, v%div)
+}
+
+func roundDiv(a, b int) int {
+	if a < 0 {
+		return -roundDiv(-a, b)
+	}
+	return (a + b/2) / b
+}
+
+func ptrVal(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
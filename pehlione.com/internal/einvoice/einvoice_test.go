@@ -0,0 +1,173 @@
+package einvoice
+
+import (
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+var testSeller = Seller{
+	Name:       "Pehlione Ticaret A.Ş.",
+	Street:     "Atatürk Cad. 1",
+	District:   "Kadıköy",
+	City:       "İstanbul",
+	PostalCode: "34710",
+	Country:    "TR",
+	TaxID:      "1234567890",
+	TaxOffice:  "Kadıköy",
+	Email:      "fatura@pehlione.com",
+}
+
+// 2 x 60,00 (%20) + 1 x 55,00 (%10), KDV dahil; 15,00 indirim, 30,00 kargo.
+func testInput(country string) Input {
+	inv := "INV-2026-000042"
+	num := "PH-2026-000101"
+	method := "card"
+	at := time.Date(2026, 3, 5, 14, 30, 0, 0, time.Local)
+	o := orders.Order{
+		ID:                 "8f0c3a1e-0000-4000-8000-000000000001",
+		OrderNumber:        &num,
+		InvoiceNumber:      &inv,
+		InvoicedAt:         &at,
+		Currency:           "TRY",
+		SubtotalCents:      17500,
+		DiscountCents:      1500,
+		ShippingCents:      3000,
+		TotalCents:         19000,
+		GiftCardCents:      5000,
+		PricesIncludeTax:   true,
+		PaymentMethod:      &method,
+		BillingAddressJSON: []byte(`{"first_name":"Ayşe","last_name":"Yılmaz","address1":"Bağdat Cad. 5","city":"İstanbul","postal_code":"34728","country":"` + country + `","phone":"+905551112233"}`),
+		CreatedAt:          at.Add(-time.Hour),
+	}
+	// indirim 12000/5500 oranında: 1029 + 471; KDV indirimli tutardan
+	items := []orders.OrderItem{
+		{ProductName: "Kupa", SKU: "MUG-1", Quantity: 2, UnitPriceCents: 6000, LineTotalCents: 12000, TaxRateBps: 2000, TaxCents: 1829, Currency: "TRY"},
+		{ProductName: "Defter", SKU: "NB-1", Quantity: 1, UnitPriceCents: 5500, LineTotalCents: 5500, TaxRateBps: 1000, TaxCents: 457, Currency: "TRY"},
+	}
+	o.TaxCents = 1829 + 457
+	return Input{Order: o, Items: items, BuyerEmail: "ayse@example.com"}
+}
+
+type parsedInvoice struct {
+	ID        string `xml:"ID"`
+	UUID      string `xml:"UUID"`
+	ProfileID string `xml:"ProfileID"`
+	TaxTotal  struct {
+		TaxAmount string `xml:"TaxAmount"`
+		Subtotals []struct {
+			TaxableAmount string `xml:"TaxableAmount"`
+			TaxAmount     string `xml:"TaxAmount"`
+		} `xml:"TaxSubtotal"`
+	} `xml:"TaxTotal"`
+	Totals struct {
+		LineExtension string `xml:"LineExtensionAmount"`
+		TaxExclusive  string `xml:"TaxExclusiveAmount"`
+		TaxInclusive  string `xml:"TaxInclusiveAmount"`
+		Prepaid       string `xml:"PrepaidAmount"`
+		Payable       string `xml:"PayableAmount"`
+	} `xml:"LegalMonetaryTotal"`
+	Lines []struct {
+		LineExtension string `xml:"LineExtensionAmount"`
+		Allowance     string `xml:"AllowanceCharge>Amount"`
+	} `xml:"InvoiceLine"`
+}
+
+func TestBuild_EN16931(t *testing.T) {
+	in := testInput("DE")
+	require.Equal(t, ProfileEN16931, ProfileFor(in.Country()))
+
+	out, err := Build(testSeller, in, ProfileEN16931)
+	require.NoError(t, err)
+	validateXSD(t, out)
+
+	var doc parsedInvoice
+	require.NoError(t, xml.Unmarshal(out, &doc))
+	require.Equal(t, "INV-2026-000042", doc.ID)
+	require.Equal(t, "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0", doc.ProfileID)
+
+	// net satırlar: 12000-1029-1829 = 9142, 5500-471-457 = 4572
+	require.Equal(t, "91.42", doc.Lines[0].LineExtension)
+	require.Equal(t, "45.72", doc.Lines[1].LineExtension)
+	require.Equal(t, "8.58", doc.Lines[0].Allowance) // 100.00 net - 91.42
+
+	require.Equal(t, "137.14", doc.Totals.LineExtension)
+	require.Equal(t, "167.14", doc.Totals.TaxExclusive)
+	require.Equal(t, "190.00", doc.Totals.TaxInclusive)
+	require.Equal(t, "190.00", doc.Totals.Prepaid)
+	require.Equal(t, "0.00", doc.Totals.Payable)
+
+	require.Equal(t, "22.86", doc.TaxTotal.TaxAmount)
+	require.Len(t, doc.TaxTotal.Subtotals, 3) // %20, %10, kargo %0
+	require.Equal(t, "30.00", doc.TaxTotal.Subtotals[2].TaxableAmount)
+}
+
+func TestBuild_UBLTR(t *testing.T) {
+	in := testInput("TR")
+	require.Equal(t, ProfileUBLTR, ProfileFor(in.Country()))
+
+	out, err := Build(testSeller, in, ProfileUBLTR)
+	require.NoError(t, err)
+	validateXSD(t, out)
+
+	var doc parsedInvoice
+	require.NoError(t, xml.Unmarshal(out, &doc))
+	require.Equal(t, "INV2026000000042", doc.ID)
+	require.Equal(t, "EARSIVFATURA", doc.ProfileID)
+	require.Regexp(t, `^[0-9A-F]{8}-[0-9A-F]{4}-5[0-9A-F]{3}-[0-9A-F]{4}-[0-9A-F]{12}$`, doc.UUID)
+	require.Equal(t, "190.00", doc.Totals.Payable)
+	require.Empty(t, doc.Totals.Prepaid)
+
+	// aynı fatura, aynı ETTN
+	again, err := Build(testSeller, in, ProfileUBLTR)
+	require.NoError(t, err)
+	require.Equal(t, out, again)
+}
+
+func TestBuild_NotInvoiced(t *testing.T) {
+	in := testInput("TR")
+	in.Order.InvoiceNumber = nil
+	_, err := Build(testSeller, in, ProfileUBLTR)
+	require.ErrorIs(t, err, ErrNotInvoiced)
+}
+
+// validateXSD checks the document against the UBL 2.1 schemas with xmllint,
+// as its own subtest so a missing xmllint skips only the schema check.
+// testdata/xsd is a reduced copy of the OASIS set; EINVOICE_XSD_DIR points
+// the check at the official os-UBL-2.1/xsd (or GİB UBL-TR xsdrt) directory.
+// With CI set both are required, so the check can't pass without running.
+func validateXSD(t *testing.T, doc []byte) {
+	t.Helper()
+	t.Run("xsd", func(t *testing.T) {
+		ci := os.Getenv("CI") != ""
+		dir := os.Getenv("EINVOICE_XSD_DIR")
+		if dir == "" {
+			if ci {
+				t.Fatal("EINVOICE_XSD_DIR must point at the official UBL 2.1 schemas in CI")
+			}
+			dir = filepath.Join("testdata", "xsd")
+		}
+		xmllint, err := exec.LookPath("xmllint")
+		if err != nil {
+			if ci {
+				t.Fatal("xmllint is required for XSD validation in CI")
+			}
+			t.Skip("xmllint not found; XSD validation skipped (install libxml2-utils)")
+		}
+		schema := filepath.Join(dir, "maindoc", "UBL-Invoice-2.1.xsd")
+		_, err = os.Stat(schema)
+		require.NoError(t, err, "schema not found")
+
+		path := filepath.Join(t.TempDir(), "invoice.xml")
+		require.NoError(t, os.WriteFile(path, doc, 0o600))
+		out, err := exec.Command(xmllint, "--noout", "--nonet", "--schema", schema, path).CombinedOutput()
+		require.NoError(t, err, "xsd validation failed:\n%s\n%s", out, doc)
+	})
+}
@@ -0,0 +1,109 @@
+package einvoice
+
+import (
+	"sort"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+// line is an order item in UBL terms: amounts are net (VAT excluded), the
+// order discount is a line allowance.
+type line struct {
+	item     orders.OrderItem
+	rateBps  int
+	base     int // indirimden önce net
+	discount int
+	net      int // LineExtensionAmount
+	tax      int
+}
+
+type taxGroup struct {
+	rateBps int
+	taxable int
+	tax     int
+}
+
+type totals struct {
+	lines    []line
+	groups   []taxGroup // oran büyükten küçüğe
+	lineExt  int
+	shipping int
+	tax      int
+	total    int // order.TotalCents
+}
+
+// computeTotals splits the order into net lines and VAT groups. Line taxes are
+// the stored ones (computed after the discount), so the VAT matches the PDF
+// invoice. Shipping carries no VAT in this shop.
+func computeTotals(o orders.Order, items []orders.OrderItem) totals {
+	discounts := orders.LineDiscounts(o, items)
+
+	t := totals{shipping: o.ShippingCents, total: o.TotalCents}
+	byRate := map[int]*taxGroup{}
+	group := func(bps int) *taxGroup {
+		g, ok := byRate[bps]
+		if !ok {
+			g = &taxGroup{rateBps: bps}
+			byRate[bps] = g
+		}
+		return g
+	}
+
+	for i, it := range items {
+		l := line{item: it, rateBps: it.TaxRateBps, tax: it.TaxCents}
+		disc := discounts[i]
+		afterDiscount := it.LineTotalCents - disc
+		if o.PricesIncludeTax {
+			l.net = afterDiscount - it.TaxCents
+			l.base = l.net
+			if disc > 0 {
+				l.base = roundDiv(it.LineTotalCents*10000, 10000+it.TaxRateBps)
+			}
+		} else {
+			l.net = afterDiscount
+			l.base = it.LineTotalCents
+		}
+		// brüt fiyattan geri hesapta kuruş sapması: indirim negatif olamaz
+		if l.base < l.net {
+			l.base = l.net
+		}
+		l.discount = l.base - l.net
+
+		t.lines = append(t.lines, l)
+		t.lineExt += l.net
+		t.tax += l.tax
+		g := group(l.rateBps)
+		g.taxable += l.net
+		g.tax += l.tax
+	}
+	if t.shipping > 0 {
+		group(0).taxable += t.shipping
+	}
+
+	for _, g := range byRate {
+		t.groups = append(t.groups, *g)
+	}
+	sort.Slice(t.groups, func(i, j int) bool { return t.groups[i].rateBps > t.groups[j].rateBps })
+	return t
+}
+
+// monetary fills LegalMonetaryTotal; a cent left over from currency
+// conversion of the stored line amounts goes to PayableRoundingAmount.
+func (t totals) monetary(cur string) monetaryTotal {
+	exclusive := t.lineExt + t.shipping
+	inclusive := exclusive + t.tax
+	m := monetaryTotal{
+		LineExtensionAmount:  money(t.lineExt, cur),
+		TaxExclusiveAmount:   money(exclusive, cur),
+		TaxInclusiveAmount:   money(inclusive, cur),
+		AllowanceTotalAmount: ptrAmount(money(0, cur)),
+		PayableAmount:        money(t.total, cur),
+	}
+	if t.shipping > 0 {
+		m.ChargeTotalAmount = ptrAmount(money(t.shipping, cur))
+	}
+	if diff := t.total - inclusive; diff != 0 {
+		m.PayableRoundingAmount = ptrAmount(money(diff, cur))
+	}
+	return m
+}
@@ -0,0 +1,203 @@
+package einvoice
+
+import "encoding/xml"
+
+// UBL 2.1 Invoice, limited to the elements this shop fills. Field order is
+// the schema's sequence order; encoding/xml writes fields as declared.
+
+const (
+	nsInvoice = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	nsCAC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	nsCBC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+type ublInvoice struct {
+	XMLName  xml.Name `xml:"Invoice"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	XmlnsCAC string   `xml:"xmlns:cac,attr"`
+	XmlnsCBC string   `xml:"xmlns:cbc,attr"`
+
+	UBLVersionID         string            `xml:"cbc:UBLVersionID"`
+	CustomizationID      string            `xml:"cbc:CustomizationID"`
+	ProfileID            string            `xml:"cbc:ProfileID"`
+	ID                   string            `xml:"cbc:ID"`
+	CopyIndicator        *bool             `xml:"cbc:CopyIndicator"`
+	UUID                 string            `xml:"cbc:UUID,omitempty"`
+	IssueDate            string            `xml:"cbc:IssueDate"`
+	IssueTime            string            `xml:"cbc:IssueTime,omitempty"`
+	InvoiceTypeCode      string            `xml:"cbc:InvoiceTypeCode"`
+	Notes                []string          `xml:"cbc:Note"`
+	DocumentCurrencyCode string            `xml:"cbc:DocumentCurrencyCode"`
+	LineCountNumeric     int               `xml:"cbc:LineCountNumeric,omitempty"`
+	OrderReference       *orderReference   `xml:"cac:OrderReference"`
+	Signature            *signature        `xml:"cac:Signature"`
+	Supplier             partyWrapper      `xml:"cac:AccountingSupplierParty"`
+	Customer             partyWrapper      `xml:"cac:AccountingCustomerParty"`
+	PaymentMeans         *paymentMeans     `xml:"cac:PaymentMeans"`
+	AllowanceCharges     []allowanceCharge `xml:"cac:AllowanceCharge"`
+	TaxTotal             taxTotal          `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal   monetaryTotal     `xml:"cac:LegalMonetaryTotal"`
+	Lines                []invoiceLine     `xml:"cac:InvoiceLine"`
+}
+
+type identifier struct {
+	Value    string `xml:",chardata"`
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+}
+
+type amount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"currencyID,attr"`
+}
+
+type quantity struct {
+	Value    string `xml:",chardata"`
+	UnitCode string `xml:"unitCode,attr"`
+}
+
+type orderReference struct {
+	ID        string `xml:"cbc:ID"`
+	IssueDate string `xml:"cbc:IssueDate,omitempty"`
+}
+
+type signature struct {
+	ID         identifier          `xml:"cbc:ID"`
+	Signatory  party               `xml:"cac:SignatoryParty"`
+	Attachment signatureAttachment `xml:"cac:DigitalSignatureAttachment"`
+}
+
+type signatureAttachment struct {
+	URI string `xml:"cac:ExternalReference>cbc:URI"`
+}
+
+type partyWrapper struct {
+	Party party `xml:"cac:Party"`
+}
+
+type party struct {
+	EndpointID      *identifier     `xml:"cbc:EndpointID"`
+	Identifications []partyID       `xml:"cac:PartyIdentification"`
+	Name            *partyName      `xml:"cac:PartyName"`
+	PostalAddress   *address        `xml:"cac:PostalAddress"`
+	TaxScheme       *partyTaxScheme `xml:"cac:PartyTaxScheme"`
+	LegalEntity     *legalEntity    `xml:"cac:PartyLegalEntity"`
+	Contact         *contact        `xml:"cac:Contact"`
+	Person          *person         `xml:"cac:Person"`
+}
+
+type partyName struct {
+	Name string `xml:"cbc:Name"`
+}
+
+type partyID struct {
+	ID identifier `xml:"cbc:ID"`
+}
+
+type address struct {
+	StreetName           string  `xml:"cbc:StreetName,omitempty"`
+	AdditionalStreetName string  `xml:"cbc:AdditionalStreetName,omitempty"`
+	CitySubdivisionName  string  `xml:"cbc:CitySubdivisionName,omitempty"`
+	CityName             string  `xml:"cbc:CityName,omitempty"`
+	PostalZone           string  `xml:"cbc:PostalZone,omitempty"`
+	Country              country `xml:"cac:Country"`
+}
+
+type country struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode,omitempty"`
+	Name               string `xml:"cbc:Name,omitempty"`
+}
+
+type partyTaxScheme struct {
+	CompanyID string    `xml:"cbc:CompanyID,omitempty"`
+	TaxScheme taxScheme `xml:"cac:TaxScheme"`
+}
+
+type taxScheme struct {
+	ID          string `xml:"cbc:ID,omitempty"`
+	Name        string `xml:"cbc:Name,omitempty"`
+	TaxTypeCode string `xml:"cbc:TaxTypeCode,omitempty"`
+}
+
+type legalEntity struct {
+	RegistrationName string `xml:"cbc:RegistrationName"`
+	CompanyID        string `xml:"cbc:CompanyID,omitempty"`
+}
+
+type contact struct {
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+type person struct {
+	FirstName  string `xml:"cbc:FirstName"`
+	FamilyName string `xml:"cbc:FamilyName"`
+}
+
+type paymentMeans struct {
+	Code string `xml:"cbc:PaymentMeansCode"`
+}
+
+type allowanceCharge struct {
+	ChargeIndicator bool         `xml:"cbc:ChargeIndicator"`
+	ReasonCode      string       `xml:"cbc:AllowanceChargeReasonCode,omitempty"`
+	Reason          string       `xml:"cbc:AllowanceChargeReason,omitempty"`
+	Amount          amount       `xml:"cbc:Amount"`
+	BaseAmount      *amount      `xml:"cbc:BaseAmount"`
+	TaxCategory     *taxCategory `xml:"cac:TaxCategory"`
+}
+
+type taxTotal struct {
+	TaxAmount amount        `xml:"cbc:TaxAmount"`
+	Subtotals []taxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type taxSubtotal struct {
+	TaxableAmount  amount      `xml:"cbc:TaxableAmount"`
+	TaxAmount      amount      `xml:"cbc:TaxAmount"`
+	CalculationSeq int         `xml:"cbc:CalculationSequenceNumeric,omitempty"`
+	Percent        string      `xml:"cbc:Percent,omitempty"`
+	TaxCategory    taxCategory `xml:"cac:TaxCategory"`
+}
+
+type taxCategory struct {
+	ID                  string    `xml:"cbc:ID,omitempty"`
+	Percent             string    `xml:"cbc:Percent,omitempty"`
+	ExemptionReasonCode string    `xml:"cbc:TaxExemptionReasonCode,omitempty"`
+	ExemptionReason     string    `xml:"cbc:TaxExemptionReason,omitempty"`
+	TaxScheme           taxScheme `xml:"cac:TaxScheme"`
+}
+
+type monetaryTotal struct {
+	LineExtensionAmount   amount  `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount    amount  `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount    amount  `xml:"cbc:TaxInclusiveAmount"`
+	AllowanceTotalAmount  *amount `xml:"cbc:AllowanceTotalAmount"`
+	ChargeTotalAmount     *amount `xml:"cbc:ChargeTotalAmount"`
+	PrepaidAmount         *amount `xml:"cbc:PrepaidAmount"`
+	PayableRoundingAmount *amount `xml:"cbc:PayableRoundingAmount"`
+	PayableAmount         amount  `xml:"cbc:PayableAmount"`
+}
+
+type invoiceLine struct {
+	ID                  string            `xml:"cbc:ID"`
+	InvoicedQuantity    quantity          `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount amount            `xml:"cbc:LineExtensionAmount"`
+	AllowanceCharges    []allowanceCharge `xml:"cac:AllowanceCharge"`
+	TaxTotal            *taxTotal         `xml:"cac:TaxTotal"`
+	Item                item              `xml:"cac:Item"`
+	Price               price             `xml:"cac:Price"`
+}
+
+type item struct {
+	Name                  string       `xml:"cbc:Name"`
+	SellersItemID         *itemID      `xml:"cac:SellersItemIdentification"`
+	ClassifiedTaxCategory *taxCategory `xml:"cac:ClassifiedTaxCategory"`
+}
+
+type itemID struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type price struct {
+	PriceAmount amount `xml:"cbc:PriceAmount"`
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/tax"
 	"pehlione.com/app/pkg/view"
 )
 
@@ -11,9 +12,15 @@ import (
 func BuildOrderPayload(baseURL string, order orders.Order, items []orders.OrderItem, statusLabel string, reason string) map[string]any {
 	data := map[string]any{
 		"OrderID":     order.ID,
+		"OrderNumber": order.Number(),
 		"OrderURL":    strings.TrimRight(baseURL, "/") + "/orders/" + order.ID,
 		"StatusLabel": statusLabel,
 		"Total":       view.MoneyFromCents(order.TotalCents, order.Currency),
+		"TaxLabel":    view.TaxLabel(order.PricesIncludeTax),
+		"TaxTotal":    view.MoneyFromCents(order.TaxCents, order.Currency),
+	}
+	if order.DiscountCents > 0 {
+		data["Discount"] = view.MoneyFromCents(order.DiscountCents, order.Currency)
 	}
 	if reason != "" {
 		data["Reason"] = reason
@@ -23,9 +30,11 @@ func BuildOrderPayload(baseURL string, order orders.Order, items []orders.OrderI
 		out := make([]map[string]any, 0, len(items))
 		for _, it := range items {
 			out = append(out, map[string]any{
-				"Name":  it.ProductName,
-				"Qty":   it.Quantity,
-				"Price": view.MoneyFromCents(it.LineTotalCents, it.Currency),
+				"Name":    it.ProductName,
+				"Qty":     it.Quantity,
+				"Price":   view.MoneyFromCents(it.LineTotalCents, it.Currency),
+				"TaxRate": tax.FormatRate(it.TaxRateBps),
+				"Tax":     view.MoneyFromCents(it.TaxCents, it.Currency),
 			})
 		}
 		data["Items"] = out
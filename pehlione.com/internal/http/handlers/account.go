@@ -12,6 +12,7 @@ import (
 	"pehlione.com/app/internal/http/flash"
 	"pehlione.com/app/internal/http/middleware"
 	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/addresses"
 	"pehlione.com/app/internal/modules/auth"
 	"pehlione.com/app/internal/modules/orders"
 	"pehlione.com/app/internal/modules/products"
@@ -28,11 +29,12 @@ type AccountHandler struct {
 	wishlist          *wishlist.Service
 	products          products.Repository
 	ordersRepo        *orders.Repo
+	addresses         *addresses.Service
 	passwordChangeSvc *users.PasswordChangeService
 }
 
-func NewAccountHandler(authRepo *auth.Repo, flashCodec *flash.Codec, wsvc *wishlist.Service, prodRepo products.Repository, ordersRepo *orders.Repo) *AccountHandler {
-	return &AccountHandler{authRepo: authRepo, Flash: flashCodec, wishlist: wsvc, products: prodRepo, ordersRepo: ordersRepo}
+func NewAccountHandler(authRepo *auth.Repo, flashCodec *flash.Codec, wsvc *wishlist.Service, prodRepo products.Repository, ordersRepo *orders.Repo, addrSvc *addresses.Service) *AccountHandler {
+	return &AccountHandler{authRepo: authRepo, Flash: flashCodec, wishlist: wsvc, products: prodRepo, ordersRepo: ordersRepo, addresses: addrSvc}
 }
 
 func (h *AccountHandler) SetPasswordChangeService(svc *users.PasswordChangeService) {
@@ -124,14 +126,9 @@ func (h *AccountHandler) Get(c *gin.Context) {
 		})
 		if err == nil {
 			for _, item := range result.Items {
-				orderNum := item.Order.ID
-				if len(orderNum) > 8 {
-					orderNum = orderNum[:8]
-				}
-
 				ordersList = append(ordersList, view.AccountOrderListItem{
 					ID:         item.Order.ID,
-					Number:     orderNum,
+					Number:     item.Order.Number(),
 					CreatedAt:  item.Order.CreatedAt,
 					Status:     item.Order.Status,
 					TotalCents: int64(item.Order.TotalCents),
@@ -143,6 +140,12 @@ func (h *AccountHandler) Get(c *gin.Context) {
 		}
 	}
 
+	// profilde adres defterindeki varsayılan adres gösterilir
+	var defaultAddress string
+	if a, err := h.addresses.Default(c.Request.Context(), user.ID); err == nil {
+		defaultAddress = a.OneLine()
+	}
+
 	render.Component(c, http.StatusOK, pages.Account(
 		middleware.GetFlash(c),
 		middleware.GetCSRFToken(c),
@@ -150,7 +153,7 @@ func (h *AccountHandler) Get(c *gin.Context) {
 		user.EmailVerifiedAt,
 		user.FirstName,
 		user.LastName,
-		user.Address,
+		defaultAddress,
 		paginatedWishlist,
 		page,
 		totalPages,
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/http/validation"
+	"pehlione.com/app/internal/modules/addresses"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type AccountAddressesHandler struct {
+	Addresses *addresses.Service
+	Flash     *flash.Codec
+}
+
+func NewAccountAddressesHandler(svc *addresses.Service, fl *flash.Codec) *AccountAddressesHandler {
+	return &AccountAddressesHandler{Addresses: svc, Flash: fl}
+}
+
+type addressInput struct {
+	Label      string `form:"label" binding:"omitempty,max=64"`
+	FirstName  string `form:"first_name" binding:"required,min=2,max=100"`
+	LastName   string `form:"last_name" binding:"required,min=2,max=100"`
+	Address1   string `form:"address1" binding:"required,min=5,max=255"`
+	Address2   string `form:"address2" binding:"omitempty,max=255"`
+	City       string `form:"city" binding:"required,min=2,max=100"`
+	PostalCode string `form:"postal_code" binding:"required,min=2,max=32"`
+	Country    string `form:"country" binding:"required,len=2"`
+	Phone      string `form:"phone" binding:"omitempty,max=32"`
+	IsDefault  bool   `form:"is_default"`
+}
+
+func (in addressInput) toInput() addresses.Input {
+	return addresses.Input{
+		Label:       in.Label,
+		FirstName:   in.FirstName,
+		LastName:    in.LastName,
+		Address1:    in.Address1,
+		Address2:    in.Address2,
+		City:        in.City,
+		PostalCode:  in.PostalCode,
+		Country:     in.Country,
+		Phone:       in.Phone,
+		MakeDefault: in.IsDefault,
+	}
+}
+
+func (in addressInput) form(id string) view.AddressForm {
+	return view.AddressForm{
+		ID:         id,
+		Label:      in.Label,
+		FirstName:  in.FirstName,
+		LastName:   in.LastName,
+		Address1:   in.Address1,
+		Address2:   in.Address2,
+		City:       in.City,
+		PostalCode: in.PostalCode,
+		Country:    strings.ToUpper(in.Country),
+		Phone:      in.Phone,
+		IsDefault:  in.IsDefault,
+	}
+}
+
+// List shows the customer's address book.
+func (h *AccountAddressesHandler) List(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	list, err := h.Addresses.List(c.Request.Context(), user.ID)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AccountAddressesPage{Full: len(list) >= addresses.MaxPerUser}
+	for _, a := range list {
+		lines := []string{a.Address1}
+		if a.Address2 != "" {
+			lines = append(lines, a.Address2)
+		}
+		lines = append(lines, strings.TrimSpace(a.PostalCode+" "+a.City), a.Country)
+		vm.Items = append(vm.Items, view.AccountAddress{
+			ID:        a.ID,
+			Label:     a.Label,
+			Name:      strings.TrimSpace(a.FirstName + " " + a.LastName),
+			Lines:     lines,
+			Phone:     a.Phone,
+			IsDefault: a.IsDefault,
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AccountAddresses(middleware.GetFlash(c), middleware.GetCSRFToken(c), vm))
+}
+
+func (h *AccountAddressesHandler) New(c *gin.Context) {
+	if _, ok := middleware.CurrentUser(c); !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	h.renderForm(c, http.StatusOK, view.AddressForm{}, nil)
+}
+
+func (h *AccountAddressesHandler) Create(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var in addressInput
+	if err := c.ShouldBind(&in); err != nil {
+		h.renderForm(c, http.StatusBadRequest, in.form(""), validation.FromBindError(err, &in))
+		return
+	}
+
+	if _, err := h.Addresses.Create(c.Request.Context(), user.ID, in.toInput()); err != nil {
+		if errors.Is(err, addresses.ErrLimitReached) {
+			render.RedirectWithFlash(c, h.Flash, "/account/addresses", view.FlashError, "Adres defteriniz dolu. Yeni adres eklemek için bir adresi silin.")
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+	render.RedirectWithFlash(c, h.Flash, "/account/addresses", view.FlashSuccess, "Adres kaydedildi.")
+}
+
+func (h *AccountAddressesHandler) Edit(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	a, err := h.Addresses.Get(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.renderForm(c, http.StatusOK, view.AddressForm{
+		ID:         a.ID,
+		Label:      a.Label,
+		FirstName:  a.FirstName,
+		LastName:   a.LastName,
+		Address1:   a.Address1,
+		Address2:   a.Address2,
+		City:       a.City,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+		IsDefault:  a.IsDefault,
+	}, nil)
+}
+
+func (h *AccountAddressesHandler) Update(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	id := c.Param("id")
+
+	var in addressInput
+	if err := c.ShouldBind(&in); err != nil {
+		h.renderForm(c, http.StatusBadRequest, in.form(id), validation.FromBindError(err, &in))
+		return
+	}
+
+	if err := h.Addresses.Update(c.Request.Context(), user.ID, id, in.toInput()); err != nil {
+		h.fail(c, err)
+		return
+	}
+	render.RedirectWithFlash(c, h.Flash, "/account/addresses", view.FlashSuccess, "Adres güncellendi.")
+}
+
+func (h *AccountAddressesHandler) Delete(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	if err := h.Addresses.Delete(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+	render.RedirectWithFlash(c, h.Flash, "/account/addresses", view.FlashSuccess, "Adres silindi.")
+}
+
+func (h *AccountAddressesHandler) SetDefault(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	if err := h.Addresses.SetDefault(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+	render.RedirectWithFlash(c, h.Flash, "/account/addresses", view.FlashSuccess, "Varsayılan adres güncellendi.")
+}
+
+func (h *AccountAddressesHandler) renderForm(c *gin.Context, status int, form view.AddressForm, errs validation.FieldErrors) {
+	render.Component(c, status, pages.AccountAddressForm(middleware.GetFlash(c), middleware.GetCSRFToken(c), form, errs))
+}
+
+func (h *AccountAddressesHandler) fail(c *gin.Context, err error) {
+	if errors.Is(err, addresses.ErrNotFound) {
+		middleware.Fail(c, apperr.NotFoundErr("Adres bulunamadı."))
+		return
+	}
+	middleware.Fail(c, apperr.Wrap(err))
+}
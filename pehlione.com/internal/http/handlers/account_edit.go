@@ -35,7 +35,6 @@ func (h *AccountEditHandler) Get(c *gin.Context) {
 		user.FirstName,
 		user.LastName,
 		user.PhoneE164,
-		user.Address,
 	))
 }
 
@@ -63,7 +62,6 @@ func (h *AccountEditHandler) Post(c *gin.Context) {
 	firstName := c.PostForm("first_name")
 	lastName := c.PostForm("last_name")
 	phone := c.PostForm("phone")
-	address := c.PostForm("address")
 
 	// Validate email
 	if email == "" {
@@ -92,12 +90,6 @@ func (h *AccountEditHandler) Post(c *gin.Context) {
 		user.PhoneE164 = nil
 	}
 
-	if address != "" {
-		user.Address = &address
-	} else {
-		user.Address = nil
-	}
-
 	if err := h.authRepo.UpdateUser(c.Request.Context(), user); err != nil {
 		render.RedirectWithFlash(c, h.Flash, "/account/edit", view.FlashError, "Profil güncellenemedi")
 		return
@@ -41,20 +41,16 @@ func (h *AccountOrdersHandler) buildPage(c *gin.Context, user auth.User, page, p
 
 	items := make([]view.AccountOrderListItem, len(result.Items))
 	for i, item := range result.Items {
-		orderNum := item.Order.ID
-		if len(orderNum) > 8 {
-			orderNum = orderNum[:8]
-		}
-
 		items[i] = view.AccountOrderListItem{
 			ID:         item.Order.ID,
-			Number:     orderNum,
+			Number:     item.Order.Number(),
 			CreatedAt:  item.Order.CreatedAt,
 			Status:     item.Order.Status,
 			TotalCents: int64(item.Order.TotalCents),
 			Currency:   item.Order.Currency,
 			ItemCount:  item.Count,
 			PaidAt:     item.Order.PaidAt,
+			Returnable: item.Order.Status == "delivered" || item.Order.Status == "partially_refunded",
 		}
 	}
 
@@ -70,7 +66,7 @@ func (h *AccountOrdersHandler) buildPage(c *gin.Context, user auth.User, page, p
 		Page:           page,
 		PageSize:       pageSize,
 		FilterStatus:   status,
-		Statuses:       []string{"pending", "paid", "shipped", "delivered", "cancelled"},
+		Statuses:       []string{"pending", "authorized", "paid", "shipped", "delivered", "cancelled"},
 		IsPreviousPage: page > 1,
 		IsNextPage:     offset+pageSize < int(result.Total),
 		CSRFToken:      middleware.GetCSRFToken(c),
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/returns"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type AccountReturnsHandler struct {
+	Returns *returns.Service
+	Flash   *flash.Codec
+}
+
+func NewAccountReturnsHandler(svc *returns.Service, fl *flash.Codec) *AccountReturnsHandler {
+	return &AccountReturnsHandler{Returns: svc, Flash: fl}
+}
+
+// List shows the customer's return requests.
+func (h *AccountReturnsHandler) List(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	items, err := h.Returns.ListByUser(c.Request.Context(), user.ID, 50)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AccountReturnsPage{}
+	for _, r := range items {
+		ar := view.AccountReturn{
+			RMA:         "RMA-" + shortUpper(r.ID),
+			OrderID:     r.OrderID,
+			OrderNumber: shortUpper(r.OrderID),
+			Status:      r.Status,
+			StatusLabel: returns.StatusLabel(r.Status),
+			Refund:      view.MoneyFromCents(r.RefundCents, r.Currency),
+			CreatedAt:   r.CreatedAt.Format("02.01.2006"),
+			LabelURL:    derefStr(r.LabelURL),
+			TrackingNo:  derefStr(r.LabelTrackingNo),
+			Note:        derefStr(r.AdminNote),
+		}
+		for _, it := range r.Items {
+			ar.Lines = append(ar.Lines, view.AccountReturnItem{Name: it.ProductName, Qty: it.Qty})
+		}
+		vm.Items = append(vm.Items, ar)
+	}
+
+	render.Component(c, http.StatusOK, pages.AccountReturns(middleware.GetFlash(c), vm))
+}
+
+// New renders the return form for a delivered order.
+func (h *AccountReturnsHandler) New(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	h.renderForm(c, user.ID, c.Param("id"), http.StatusOK, "", "")
+}
+
+func (h *AccountReturnsHandler) Create(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	orderID := c.Param("id")
+	reason := strings.TrimSpace(c.PostForm("reason"))
+
+	// qty[<order_item_id>]=n
+	var lines []returns.LineInput
+	for itemID, raw := range c.PostFormMap("qty") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.renderForm(c, user.ID, orderID, http.StatusBadRequest, reason, "Geçersiz miktar.")
+			return
+		}
+		lines = append(lines, returns.LineInput{OrderItemID: itemID, Qty: n})
+	}
+
+	_, err := h.Returns.Create(c.Request.Context(), returns.CreateInput{
+		OrderID: orderID,
+		UserID:  user.ID,
+		Reason:  reason,
+		Lines:   lines,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("Sipariş bulunamadı."))
+			return
+		}
+		if msg := returnErrMessage(err); msg != "" {
+			h.renderForm(c, user.ID, orderID, http.StatusBadRequest, reason, msg)
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, "/account/returns", view.FlashSuccess, "İade talebiniz alındı.")
+}
+
+func (h *AccountReturnsHandler) renderForm(c *gin.Context, userID, orderID string, status int, reason, msg string) {
+	_, eligible, err := h.Returns.Eligible(c.Request.Context(), orderID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("Sipariş bulunamadı."))
+			return
+		}
+		if errors.Is(err, returns.ErrNotReturnable) {
+			render.RedirectWithFlash(c, h.Flash, "/account/orders", view.FlashError, "Bu sipariş iade için uygun değil.")
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AccountReturnForm{
+		OrderID:     orderID,
+		OrderNumber: shortUpper(orderID),
+		CSRFToken:   middleware.GetCSRFToken(c),
+		Reason:      reason,
+		Error:       msg,
+	}
+	for _, e := range eligible {
+		vm.Items = append(vm.Items, view.AccountReturnLine{
+			OrderItemID: e.Item.ID,
+			Name:        e.Item.ProductName,
+			SKU:         e.Item.SKU,
+			Unit:        view.MoneyFromCents(e.Item.UnitPriceCents, e.Item.Currency),
+			Max:         e.Max,
+		})
+	}
+
+	render.Component(c, status, pages.AccountReturnForm(middleware.GetFlash(c), vm))
+}
+
+func returnErrMessage(err error) string {
+	switch {
+	case errors.Is(err, returns.ErrReasonRequired):
+		return "Lütfen iade nedenini yazın."
+	case errors.Is(err, returns.ErrNoItems):
+		return "İade edilecek en az bir ürün seçin."
+	case errors.Is(err, returns.ErrInvalidQty):
+		return "Seçilen miktar iade edilebilir miktarı aşıyor."
+	case errors.Is(err, returns.ErrNotReturnable):
+		return "Bu sipariş iade için uygun değil."
+	default:
+		return ""
+	}
+}
+
+func shortUpper(id string) string {
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return strings.ToUpper(id)
+}
+
+func derefStr(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
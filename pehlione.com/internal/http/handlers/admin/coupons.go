@@ -0,0 +1,335 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/http/validation"
+	"pehlione.com/app/internal/modules/promotions"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+const couponTimeLayout = "2006-01-02T15:04"
+
+type CouponsHandler struct {
+	DB    *gorm.DB
+	Flash *flash.Codec
+}
+
+func NewCouponsHandler(db *gorm.DB, fl *flash.Codec) *CouponsHandler {
+	return &CouponsHandler{DB: db, Flash: fl}
+}
+
+type couponInput struct {
+	Code             string `form:"code" binding:"required,min=3,max=64"`
+	Description      string `form:"description" binding:"omitempty,max=255"`
+	Kind             string `form:"kind" binding:"required,oneof=percent fixed"`
+	PercentOff       int    `form:"percent_off" binding:"gte=0,lte=100"`
+	AmountOffCents   int    `form:"amount_off_cents" binding:"gte=0"`
+	Currency         string `form:"currency" binding:"omitempty,len=3"`
+	MinSubtotalCents int    `form:"min_subtotal_cents" binding:"gte=0"`
+	StartsAt         string `form:"starts_at"`
+	EndsAt           string `form:"ends_at"`
+	MaxRedemptions   int    `form:"max_redemptions" binding:"gte=0"`
+	MaxPerCustomer   int    `form:"max_per_customer" binding:"gte=0"`
+	ProductIDs       string `form:"product_ids" binding:"omitempty,max=5000"`
+	CategorySlugs    string `form:"category_slugs" binding:"omitempty,max=2000"`
+	Status           string `form:"status" binding:"required,oneof=active inactive"`
+}
+
+func (h *CouponsHandler) List(c *gin.Context) {
+	items, err := promotions.NewRepo(h.DB).List(c.Request.Context())
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	out := make([]view.AdminCouponListItem, 0, len(items))
+	for _, p := range items {
+		out = append(out, view.AdminCouponListItem{
+			ID:          p.ID,
+			Code:        p.Code,
+			Kind:        p.Kind,
+			Value:       couponValueLabel(p),
+			Window:      couponWindowLabel(p),
+			Usage:       couponUsageLabel(p),
+			Status:      p.Status,
+			Description: ptrStr(p.Description),
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminCouponsList(middleware.GetFlash(c), out))
+}
+
+func (h *CouponsHandler) New(c *gin.Context) {
+	render.Component(c, http.StatusOK, pages.AdminCouponForm(
+		middleware.GetFlash(c),
+		middleware.GetCSRFToken(c),
+		view.AdminCoupon{Kind: promotions.KindPercent, Status: promotions.StatusActive},
+		nil,
+		false,
+	))
+}
+
+func (h *CouponsHandler) Create(c *gin.Context) {
+	var in couponInput
+	if err := c.ShouldBind(&in); err != nil {
+		h.renderForm(c, http.StatusBadRequest, couponVMFromInput("", in), validation.FromBindError(err, &in), false)
+		return
+	}
+
+	p, errs := promotionFromInput(in)
+	if len(errs) > 0 {
+		h.renderForm(c, http.StatusBadRequest, couponVMFromInput("", in), errs, false)
+		return
+	}
+
+	now := time.Now()
+	p.ID = uuid.NewString()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+
+	if err := promotions.NewRepo(h.DB).Create(c.Request.Context(), &p); err != nil {
+		if promotions.IsDuplicateKey(err) {
+			h.renderForm(c, http.StatusConflict, couponVMFromInput("", in), validation.FieldErrors{"code": "Bu kod zaten kullanılıyor."}, false)
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, "/admin/coupons", view.FlashSuccess, "Kupon oluşturuldu.")
+}
+
+func (h *CouponsHandler) Edit(c *gin.Context) {
+	p, err := promotions.NewRepo(h.DB).Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("Kupon bulunamadı."))
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	h.renderForm(c, http.StatusOK, couponVM(p), nil, true)
+}
+
+func (h *CouponsHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	repo := promotions.NewRepo(h.DB)
+
+	existing, err := repo.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("Kupon bulunamadı."))
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	var in couponInput
+	if err := c.ShouldBind(&in); err != nil {
+		vm := couponVMFromInput(id, in)
+		vm.RedeemedCount = existing.RedeemedCount
+		h.renderForm(c, http.StatusBadRequest, vm, validation.FromBindError(err, &in), true)
+		return
+	}
+
+	p, errs := promotionFromInput(in)
+	if len(errs) > 0 {
+		vm := couponVMFromInput(id, in)
+		vm.RedeemedCount = existing.RedeemedCount
+		h.renderForm(c, http.StatusBadRequest, vm, errs, true)
+		return
+	}
+	p.ID = id
+	p.UpdatedAt = time.Now()
+
+	if err := repo.Update(c.Request.Context(), p); err != nil {
+		if promotions.IsDuplicateKey(err) {
+			vm := couponVMFromInput(id, in)
+			vm.RedeemedCount = existing.RedeemedCount
+			h.renderForm(c, http.StatusConflict, vm, validation.FieldErrors{"code": "Bu kod zaten kullanılıyor."}, true)
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, "/admin/coupons/"+id+"/edit", view.FlashSuccess, "Kupon güncellendi.")
+}
+
+func (h *CouponsHandler) renderForm(c *gin.Context, status int, vm view.AdminCoupon, errs validation.FieldErrors, isEdit bool) {
+	render.Component(c, status, pages.AdminCouponForm(
+		middleware.GetFlash(c),
+		middleware.GetCSRFToken(c),
+		vm,
+		errs,
+		isEdit,
+	))
+}
+
+// --- helpers ---
+
+func promotionFromInput(in couponInput) (promotions.Promotion, validation.FieldErrors) {
+	errs := validation.FieldErrors{}
+
+	p := promotions.Promotion{
+		Code:              promotions.NormalizeCode(in.Code),
+		Kind:              in.Kind,
+		MinSubtotalCents:  in.MinSubtotalCents,
+		MaxRedemptions:    in.MaxRedemptions,
+		MaxPerCustomer:    in.MaxPerCustomer,
+		ProductIDsJSON:    promotions.EncodeList(splitCSV(in.ProductIDs, false)),
+		CategorySlugsJSON: promotions.EncodeList(splitCSV(in.CategorySlugs, true)),
+		Status:            in.Status,
+	}
+	if d := strings.TrimSpace(in.Description); d != "" {
+		p.Description = &d
+	}
+
+	switch in.Kind {
+	case promotions.KindPercent:
+		if in.PercentOff < 1 {
+			errs["percent_off"] = "Yüzde 1 ile 100 arasında olmalıdır."
+		}
+		p.PercentOff = in.PercentOff
+	case promotions.KindFixed:
+		if in.AmountOffCents < 1 {
+			errs["amount_off_cents"] = "Tutar sıfırdan büyük olmalıdır."
+		}
+		p.AmountOffCents = in.AmountOffCents
+		if cur := strings.ToUpper(strings.TrimSpace(in.Currency)); cur != "" {
+			p.Currency = &cur
+		}
+	}
+
+	var err error
+	if p.StartsAt, err = parseCouponTime(in.StartsAt); err != nil {
+		errs["starts_at"] = "Geçersiz tarih."
+	}
+	if p.EndsAt, err = parseCouponTime(in.EndsAt); err != nil {
+		errs["ends_at"] = "Geçersiz tarih."
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		errs["ends_at"] = "Bitiş tarihi başlangıçtan sonra olmalıdır."
+	}
+
+	return p, errs
+}
+
+func parseCouponTime(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(couponTimeLayout, s, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func splitCSV(s string, lower bool) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if lower {
+			part = strings.ToLower(part)
+		}
+		out = append(out, part)
+	}
+	return out
+}
+
+func couponVM(p promotions.Promotion) view.AdminCoupon {
+	vm := view.AdminCoupon{
+		ID:               p.ID,
+		Code:             p.Code,
+		Description:      ptrStr(p.Description),
+		Kind:             p.Kind,
+		Currency:         ptrStr(p.Currency),
+		MinSubtotalCents: strconv.Itoa(p.MinSubtotalCents),
+		MaxRedemptions:   strconv.Itoa(p.MaxRedemptions),
+		MaxPerCustomer:   strconv.Itoa(p.MaxPerCustomer),
+		ProductIDs:       strings.Join(p.ProductIDs(), ", "),
+		CategorySlugs:    strings.Join(p.CategorySlugs(), ", "),
+		Status:           p.Status,
+		RedeemedCount:    p.RedeemedCount,
+	}
+	if p.Kind == promotions.KindPercent {
+		vm.PercentOff = strconv.Itoa(p.PercentOff)
+	} else {
+		vm.AmountOffCents = strconv.Itoa(p.AmountOffCents)
+	}
+	if p.StartsAt != nil {
+		vm.StartsAt = p.StartsAt.Local().Format(couponTimeLayout)
+	}
+	if p.EndsAt != nil {
+		vm.EndsAt = p.EndsAt.Local().Format(couponTimeLayout)
+	}
+	return vm
+}
+
+func couponVMFromInput(id string, in couponInput) view.AdminCoupon {
+	return view.AdminCoupon{
+		ID:               id,
+		Code:             in.Code,
+		Description:      in.Description,
+		Kind:             in.Kind,
+		PercentOff:       strconv.Itoa(in.PercentOff),
+		AmountOffCents:   strconv.Itoa(in.AmountOffCents),
+		Currency:         in.Currency,
+		MinSubtotalCents: strconv.Itoa(in.MinSubtotalCents),
+		StartsAt:         in.StartsAt,
+		EndsAt:           in.EndsAt,
+		MaxRedemptions:   strconv.Itoa(in.MaxRedemptions),
+		MaxPerCustomer:   strconv.Itoa(in.MaxPerCustomer),
+		ProductIDs:       in.ProductIDs,
+		CategorySlugs:    in.CategorySlugs,
+		Status:           in.Status,
+	}
+}
+
+func couponValueLabel(p promotions.Promotion) string {
+	if p.Kind == promotions.KindPercent {
+		return fmt.Sprintf("%%%d", p.PercentOff)
+	}
+	return view.MoneyFromCents(p.AmountOffCents, ptrStr(p.Currency))
+}
+
+func couponWindowLabel(p promotions.Promotion) string {
+	from, to := "—", "—"
+	if p.StartsAt != nil {
+		from = p.StartsAt.Local().Format("2006-01-02 15:04")
+	}
+	if p.EndsAt != nil {
+		to = p.EndsAt.Local().Format("2006-01-02 15:04")
+	}
+	return from + " → " + to
+}
+
+func couponUsageLabel(p promotions.Promotion) string {
+	if p.MaxRedemptions > 0 {
+		return fmt.Sprintf("%d / %d", p.RedeemedCount, p.MaxRedemptions)
+	}
+	return fmt.Sprintf("%d / ∞", p.RedeemedCount)
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/giftcards"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type GiftCardsHandler struct {
+	Svc *giftcards.Service
+}
+
+func NewGiftCardsHandler(svc *giftcards.Service) *GiftCardsHandler {
+	return &GiftCardsHandler{Svc: svc}
+}
+
+// Lookup shows a gift card / store credit balance and its ledger by ?code=.
+func (h *GiftCardsHandler) Lookup(c *gin.Context) {
+	vm := view.AdminGiftCard{Query: strings.TrimSpace(c.Query("code"))}
+	if vm.Query == "" {
+		render.Component(c, http.StatusOK, pages.AdminGiftCard(middleware.GetFlash(c), vm))
+		return
+	}
+
+	card, err := h.Svc.Lookup(c.Request.Context(), vm.Query)
+	if errors.Is(err, giftcards.ErrCardNotFound) {
+		vm.NotFound = true
+		render.Component(c, http.StatusOK, pages.AdminGiftCard(middleware.GetFlash(c), vm))
+		return
+	}
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+	txs, err := h.Svc.Transactions(c.Request.Context(), card.ID)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm.ID = card.ID
+	vm.Code = card.Code
+	vm.Kind = card.Kind
+	vm.Status = card.Status
+	vm.Initial = view.MoneyFromCents(card.InitialCents, card.Currency)
+	vm.Balance = view.MoneyFromCents(card.BalanceCents, card.Currency)
+	vm.Recipient = ptrStr(card.RecipientEmail)
+	vm.UserID = ptrStr(card.UserID)
+	vm.SourceOrderID = ptrStr(card.SourceOrderID)
+	vm.CreatedAt = card.CreatedAt.Local().Format("2006-01-02 15:04")
+	for _, t := range txs {
+		amount := view.MoneyFromCents(t.AmountCents, t.Currency)
+		if t.AmountCents > 0 {
+			amount = "+" + amount
+		}
+		vm.Transactions = append(vm.Transactions, view.AdminGiftCardTx{
+			At:           t.CreatedAt.Local().Format("2006-01-02 15:04"),
+			Kind:         t.Kind,
+			Amount:       amount,
+			BalanceAfter: view.MoneyFromCents(t.BalanceAfterCents, t.Currency),
+			OrderID:      ptrStr(t.OrderID),
+			Ref:          t.RefType + ":" + t.RefID,
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminGiftCard(middleware.GetFlash(c), vm))
+}
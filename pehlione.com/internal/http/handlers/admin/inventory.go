@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/inventory"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type InventoryHandler struct {
+	DB    *gorm.DB
+	Flash *flash.Codec
+}
+
+func NewInventoryHandler(db *gorm.DB, fl *flash.Codec) *InventoryHandler {
+	return &InventoryHandler{DB: db, Flash: fl}
+}
+
+// Movements lists the stock ledger, filtered by ?variant_id= when given.
+func (h *InventoryHandler) Movements(c *gin.Context) {
+	variantID := strings.TrimSpace(c.Query("variant_id"))
+
+	rows, err := inventory.NewRepo(h.DB).ListMovements(c.Request.Context(), variantID, 200)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AdminStockMovements{VariantID: variantID}
+	for _, r := range rows {
+		if variantID != "" && vm.SKU == "" {
+			vm.SKU = r.SKU
+			vm.ProductID = r.ProductID
+			vm.ProductName = r.ProductName
+		}
+		vm.Items = append(vm.Items, view.AdminStockMovement{
+			At:          r.CreatedAt.Local().Format("2006-01-02 15:04"),
+			VariantID:   r.VariantID,
+			SKU:         r.SKU,
+			ProductName: r.ProductName,
+			Delta:       fmt.Sprintf("%+d", r.Delta),
+			StockAfter:  r.StockAfter,
+			Reason:      r.Reason,
+			OrderID:     ptrStr(r.OrderID),
+			Ref:         movementRefLabel(r),
+			Actor:       ptrStr(r.ActorEmail),
+			Note:        ptrStr(r.Note),
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminStockMovements(middleware.GetFlash(c), vm))
+}
+
+func movementRefLabel(r inventory.MovementRow) string {
+	t, id := ptrStr(r.RefType), ptrStr(r.RefID)
+	if t == "" {
+		return id
+	}
+	if id == "" {
+		return t
+	}
+	return t + ":" + id
+}
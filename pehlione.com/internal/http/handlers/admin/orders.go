@@ -14,9 +14,12 @@ import (
 	"pehlione.com/app/internal/http/flash"
 	"pehlione.com/app/internal/http/middleware"
 	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/giftcards"
+	"pehlione.com/app/internal/modules/inventory"
 	"pehlione.com/app/internal/modules/orders"
 	"pehlione.com/app/internal/modules/payments"
 	"pehlione.com/app/internal/modules/shipping"
+	"pehlione.com/app/internal/pdf"
 	"pehlione.com/app/internal/shared/apperr"
 	"pehlione.com/app/pkg/view"
 	"pehlione.com/app/templates/pages"
@@ -27,10 +30,11 @@ type OrdersHandler struct {
 	Flash       *flash.Codec
 	RefundSvc   *payments.RefundService
 	ShippingSvc *shipping.Service
+	PaySvc      *payments.Service
 }
 
-func NewOrdersHandler(db *gorm.DB, fl *flash.Codec, refundSvc *payments.RefundService, shipSvc *shipping.Service) *OrdersHandler {
-	return &OrdersHandler{DB: db, Flash: fl, RefundSvc: refundSvc, ShippingSvc: shipSvc}
+func NewOrdersHandler(db *gorm.DB, fl *flash.Codec, refundSvc *payments.RefundService, shipSvc *shipping.Service, paySvc *payments.Service) *OrdersHandler {
+	return &OrdersHandler{DB: db, Flash: fl, RefundSvc: refundSvc, ShippingSvc: shipSvc, PaySvc: paySvc}
 }
 
 func (h *OrdersHandler) List(c *gin.Context) {
@@ -52,11 +56,14 @@ func (h *OrdersHandler) List(c *gin.Context) {
 	for _, o := range res.Items {
 		items = append(items, view.AdminOrderListItem{
 			ID:         o.ID,
+			Number:     o.Number(),
+			Invoice:    ptrStr(o.InvoiceNumber),
 			Status:     o.Status,
 			Total:      view.MoneyFromCents(o.TotalCents, o.Currency),
 			CreatedAt:  o.CreatedAt.Format("2006-01-02 15:04"),
 			UserID:     ptrStr(o.UserID),
 			GuestEmail: ptrStr(o.GuestEmail),
+			Dispute:    ptrStr(o.DisputeStatus),
 		})
 	}
 
@@ -69,6 +76,9 @@ func (h *OrdersHandler) List(c *gin.Context) {
 			Status:     status,
 			Page:       page,
 			TotalPages: totalPages,
+
+			CSRFToken:         middleware.GetCSRFToken(c),
+			ShippingAvailable: h.ShippingSvc != nil,
 		},
 	))
 }
@@ -85,17 +95,28 @@ func (h *OrdersHandler) Detail(c *gin.Context) {
 
 	vm := view.AdminOrderDetail{
 		ID:         o.ID,
+		Number:     o.Number(),
+		Invoice:    ptrStr(o.InvoiceNumber),
 		Status:     o.Status,
+		Dispute:    ptrStr(o.DisputeStatus),
 		Currency:   o.Currency,
 		Subtotal:   view.MoneyFromCents(o.SubtotalCents, o.Currency),
 		Shipping:   view.MoneyFromCents(o.ShippingCents, o.Currency),
 		Tax:        view.MoneyFromCents(o.TaxCents, o.Currency),
 		Discount:   view.MoneyFromCents(o.DiscountCents, o.Currency),
+		PromoCode:  ptrStr(o.PromoCode),
 		Total:      view.MoneyFromCents(o.TotalCents, o.Currency),
 		UserID:     ptrStr(o.UserID),
 		GuestEmail: ptrStr(o.GuestEmail),
 		CreatedAt:  o.CreatedAt.Format("2006-01-02 15:04"),
 	}
+	if o.InvoicedAt != nil {
+		vm.InvoicedAt = o.InvoicedAt.Format("2006-01-02 15:04")
+	}
+	if o.GiftCardCents > 0 {
+		vm.GiftCard = view.MoneyFromCents(o.GiftCardCents, o.Currency)
+		vm.AmountDue = view.MoneyFromCents(o.AmountDueCents(), o.Currency)
+	}
 
 	for _, it := range items {
 		vm.Items = append(vm.Items, view.AdminOrderItem{
@@ -155,6 +176,43 @@ func (h *OrdersHandler) Detail(c *gin.Context) {
 	}
 	vm.ShippingAvailable = h.ShippingSvc != nil
 
+	if refunds, lines, err := payments.ListRefunds(c.Request.Context(), h.DB, id); err == nil {
+		for _, r := range refunds {
+			ar := view.AdminOrderRefund{
+				ID:     r.ID,
+				Status: r.Status,
+				Amount: view.MoneyFromCents(r.AmountCents, r.Currency),
+				Reason: ptrStr(r.Reason),
+				At:     r.CreatedAt.Format("2006-01-02 15:04"),
+
+				CreditNote: ptrStr(r.CreditNoteNumber),
+			}
+			for _, l := range lines[r.ID] {
+				ar.Lines = append(ar.Lines, view.AdminOrderRefundLine{
+					Kind:   l.Kind,
+					Label:  l.Label,
+					Qty:    l.Qty,
+					Amount: view.MoneyFromCents(l.AmountCents, l.Currency),
+					Tax:    view.MoneyFromCents(l.TaxCents, l.Currency),
+				})
+			}
+			vm.Refunds = append(vm.Refunds, ar)
+		}
+	}
+
+	if disputes, err := payments.ListDisputes(c.Request.Context(), h.DB, id); err == nil {
+		for _, d := range disputes {
+			vm.Disputes = append(vm.Disputes, view.AdminOrderDispute{
+				ID:       d.ID,
+				Status:   d.Status,
+				Amount:   view.MoneyFromCents(d.AmountCents, d.Currency),
+				Reason:   ptrStr(d.Reason),
+				OpenedAt: d.OpenedAt.Format("2006-01-02 15:04"),
+				ClosedAt: formatTimePtr(d.ClosedAt),
+			})
+		}
+	}
+
 	render.Component(c, http.StatusOK, pages.AdminOrderDetail(
 		middleware.GetFlash(c),
 		middleware.GetCSRFToken(c),
@@ -162,6 +220,73 @@ func (h *OrdersHandler) Detail(c *gin.Context) {
 	))
 }
 
+// PackingSlip downloads the price-free packing slip of an order.
+func (h *OrdersHandler) PackingSlip(c *gin.Context) {
+	o, items, err := orders.NewRepo(h.DB).GetWithItems(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(apperr.NotFoundErr("Order bulunamadı."))
+		return
+	}
+
+	data := pdf.PackingSlipData{Order: o, Items: items}
+	out, err := pdf.GeneratePackingSlip(data)
+	if err != nil {
+		c.Error(apperr.Wrap(err))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+data.Filename()+`"`)
+	c.Data(http.StatusOK, "application/pdf", out)
+}
+
+// PickList downloads one pick list for the selected (?id=...) paid orders,
+// SKUs summed per stock location.
+func (h *OrdersHandler) PickList(c *gin.Context) {
+	ctx := c.Request.Context()
+	list, items, err := orders.NewRepo(h.DB).AdminPaidWithItems(ctx, c.QueryArray("id"))
+	if err != nil {
+		c.Error(apperr.Wrap(err))
+		return
+	}
+	if len(list) == 0 {
+		render.RedirectWithFlash(c, h.Flash, "/admin/orders?status=paid", view.FlashError, "Toplama listesi için ödenmiş sipariş seçin.")
+		return
+	}
+
+	numbers := make(map[string]string, len(list))
+	data := pdf.PickListData{GeneratedAt: time.Now()}
+	for _, o := range list {
+		numbers[o.ID] = o.Number()
+		data.Orders = append(data.Orders, o.Number())
+	}
+	picks := make([]inventory.PickItem, 0, len(items))
+	itemIDs := make([]string, 0, len(items))
+	for _, it := range items {
+		picks = append(picks, inventory.PickItem{
+			ID:          it.ID,
+			OrderNumber: numbers[it.OrderID],
+			SKU:         it.SKU,
+			ProductName: it.ProductName,
+			Options:     string(it.OptionsJSON),
+			Qty:         it.Quantity,
+		})
+		itemIDs = append(itemIDs, it.ID)
+	}
+	locs, err := inventory.ItemLocationsInTx(ctx, h.DB, itemIDs)
+	if err != nil {
+		c.Error(apperr.Wrap(err))
+		return
+	}
+	data.Lines = inventory.BuildPickList(picks, locs)
+
+	out, err := pdf.GeneratePickList(data)
+	if err != nil {
+		c.Error(apperr.Wrap(err))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+data.Filename()+`"`)
+	c.Data(http.StatusOK, "application/pdf", out)
+}
+
 func (h *OrdersHandler) Action(c *gin.Context) {
 	id := c.Param("id")
 	action := c.Param("action") // ship|deliver|cancel|refund
@@ -181,17 +306,32 @@ func (h *OrdersHandler) Action(c *gin.Context) {
 
 	// Handle other actions via state machine
 	svc := orders.NewAdminService(h.DB)
+	if h.PaySvc != nil {
+		svc.SetPaymentGateway(h.PaySvc)
+	}
 	err := svc.Transition(c.Request.Context(), orders.TransitionInput{
 		OrderID:     id,
 		ActorUserID: u.ID,
 		Action:      action,
 		Note:        note,
+		Restock:     c.PostForm("restock") == "1",
 	})
 	if err != nil {
 		if errors.Is(err, orders.ErrInvalidTransition) {
 			c.Error(apperr.Wrap(apperr.InvalidErr("Geçersiz status geçişi.", nil)))
 			return
 		}
+		switch {
+		case errors.Is(err, orders.ErrCapturePending):
+			render.RedirectWithFlash(c, h.Flash, "/admin/orders/"+id, view.FlashWarning, "Tahsilat sağlayıcıda işleniyor; onaylandığında tekrar kargolayın.")
+			return
+		case errors.Is(err, payments.ErrCaptureFailed), errors.Is(err, payments.ErrNotCapturable):
+			render.RedirectWithFlash(c, h.Flash, "/admin/orders/"+id, view.FlashError, "Ödeme tahsil edilemedi, sipariş kargolanmadı.")
+			return
+		case errors.Is(err, payments.ErrVoidFailed):
+			render.RedirectWithFlash(c, h.Flash, "/admin/orders/"+id, view.FlashError, "Ödeme yetkilendirmesi iptal edilemedi, sipariş iptal edilmedi.")
+			return
+		}
 		c.Error(apperr.Wrap(err))
 		return
 	}
@@ -237,13 +377,7 @@ func randHex(nBytes int) string {
 }
 
 func (h *OrdersHandler) RefundForm(c *gin.Context) {
-	id := c.Param("id")
-	vm := pages.AdminOrderRefundVM{
-		OrderID:   id,
-		CSRFToken: middleware.GetCSRFToken(c),
-		Error:     "",
-	}
-	render.Component(c, http.StatusOK, pages.AdminOrderRefund(vm))
+	h.renderRefundForm(c, c.Param("id"), http.StatusOK, "")
 }
 
 func (h *OrdersHandler) Refund(c *gin.Context) {
@@ -251,34 +385,110 @@ func (h *OrdersHandler) Refund(c *gin.Context) {
 
 	u, ok := middleware.CurrentUser(c)
 	if !ok {
-		c.Error(apperr.ForbiddenErr("Giri�Y gerekli."))
+		c.Error(apperr.ForbiddenErr("Giriş gerekli."))
 		return
 	}
 
 	note := strings.TrimSpace(c.PostForm("note"))
 	idem := randHex(16)
 
-	res, err := h.RefundSvc.RefundOrder(c.Request.Context(), payments.RefundOrderInput{
+	in := payments.RefundOrderInput{
 		OrderID:        id,
 		ActorUserID:    u.ID,
 		IdempotencyKey: idem,
-		AmountCents:    0,
 		Reason:         note,
-	})
-	if err != nil {
-		vm := pages.AdminOrderRefundVM{
-			OrderID:   id,
-			CSRFToken: middleware.GetCSRFToken(c),
-			Error:     err.Error(),
+		Restock:        c.PostForm("restock") == "1",
+		ToStoreCredit:  c.PostForm("store_credit") == "1",
+	}
+
+	// qty[<order_item_id>]=n
+	for itemID, raw := range c.PostFormMap("qty") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || raw == "0" {
+			continue
 		}
-		render.Component(c, http.StatusBadRequest, pages.AdminOrderRefund(vm))
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.renderRefundForm(c, id, http.StatusBadRequest, "Geçersiz miktar.")
+			return
+		}
+		in.Lines = append(in.Lines, payments.RefundLineInput{OrderItemID: itemID, Qty: n})
+	}
+	for name, dst := range map[string]*int{
+		"amount_cents":     &in.AmountCents,
+		"shipping_cents":   &in.ShippingCents,
+		"adjustment_cents": &in.AdjustmentCents,
+	} {
+		raw := strings.TrimSpace(c.PostForm(name))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.renderRefundForm(c, id, http.StatusBadRequest, "Geçersiz tutar.")
+			return
+		}
+		*dst = n
+	}
+
+	if _, err := h.RefundSvc.RefundOrder(c.Request.Context(), in); err != nil {
+		h.renderRefundForm(c, id, http.StatusBadRequest, friendlyRefundErr(err))
 		return
 	}
 
-	_ = res
 	c.Redirect(http.StatusSeeOther, "/admin/orders/"+id+"?refunded=1")
 }
 
+func (h *OrdersHandler) renderRefundForm(c *gin.Context, id string, status int, msg string) {
+	vm := pages.AdminOrderRefundVM{
+		OrderID:   id,
+		CSRFToken: middleware.GetCSRFToken(c),
+		Error:     msg,
+	}
+	if rf, err := h.RefundSvc.Refundable(c.Request.Context(), id); err == nil {
+		cur := rf.Order.Currency
+		vm.Currency = cur
+		vm.Remaining = view.MoneyFromCents(rf.RemainingCents, cur)
+		vm.ShippingLeft = view.MoneyFromCents(rf.ShippingLeftCents, cur)
+		for _, it := range rf.Items {
+			vm.Items = append(vm.Items, view.AdminRefundItem{
+				OrderItemID: it.Item.ID,
+				Name:        it.Item.ProductName,
+				SKU:         it.Item.SKU,
+				Unit:        view.MoneyFromCents(it.Item.UnitPriceCents, it.Item.Currency),
+				Max:         it.Max,
+			})
+		}
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Error(apperr.NotFoundErr("Order bulunamadı."))
+		return
+	}
+	render.Component(c, status, pages.AdminOrderRefund(vm))
+}
+
+func friendlyRefundErr(err error) string {
+	switch {
+	case errors.Is(err, payments.ErrInvalidRefundLine):
+		return "Geçersiz iade kalemi: miktar veya kargo tutarı iade edilebilir değeri aşıyor."
+	case errors.Is(err, payments.ErrRefundExceedsRemaining):
+		return "İade tutarı kalan iade edilebilir tutarı aşıyor."
+	case errors.Is(err, payments.ErrNotRefundable):
+		return "Sipariş iade için uygun durumda değil."
+	case errors.Is(err, payments.ErrNoSucceededPayment):
+		return "Siparişe ait başarılı ödeme bulunamadı."
+	case errors.Is(err, payments.ErrPaymentDisputed):
+		return "Ödemeye açık veya kaybedilmiş bir itiraz (chargeback) var; itiraz edilen tutar iade edilemez."
+	case errors.Is(err, payments.ErrExceedsCardRefundable):
+		return "İade tutarı karttan tahsil edilen tutarı aşıyor. Hediye kartı payını store credit olarak iade edin."
+	case errors.Is(err, payments.ErrGiftCardRefund):
+		return "İade tutarı satın alınan hediye kartlarının değerine uzanıyor; hediye kartı kalemlerini seçerek iade edin."
+	case errors.Is(err, giftcards.ErrCardSpent):
+		return "Satın alınan hediye kartı kullanılmış; bu kart iade edilemez."
+	default:
+		return err.Error()
+	}
+}
+
 func (h *OrdersHandler) CreateShipmentLabel(c *gin.Context) {
 	if h.ShippingSvc == nil {
 		c.Error(apperr.Wrap(errors.New("kargo entegrasyonu devre dışı")))
@@ -0,0 +1,178 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/modules/shipping"
+	"pehlione.com/app/internal/pdf"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+// Batch runs one action on the orders selected on /admin/orders:
+// ship|cancel|label go order by order and end on a per-order result page,
+// packing_slips downloads one merged PDF. Every order gets its own audit event.
+func (h *OrdersHandler) Batch(c *gin.Context) {
+	u, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.Error(apperr.ForbiddenErr("Giriş gerekli."))
+		return
+	}
+	ids := c.PostFormArray("id")
+	if len(ids) == 0 {
+		render.RedirectWithFlash(c, h.Flash, "/admin/orders", view.FlashError, "Sipariş seçilmedi.")
+		return
+	}
+	action := c.PostForm("action")
+	if action == "packing_slips" {
+		h.batchPackingSlips(c, u.ID, ids)
+		return
+	}
+	if c.PostForm("confirm") != "1" {
+		render.RedirectWithFlash(c, h.Flash, "/admin/orders", view.FlashWarning, "Onay gerekli.")
+		return
+	}
+
+	note := strings.TrimSpace(c.PostForm("note"))
+	if note == "" {
+		note = "toplu işlem"
+	}
+
+	var run func(ctx context.Context, o orders.Order) error
+	switch action {
+	case "ship", "cancel":
+		svc := orders.NewAdminService(h.DB)
+		if h.PaySvc != nil {
+			svc.SetPaymentGateway(h.PaySvc)
+		}
+		run = func(ctx context.Context, o orders.Order) error {
+			return svc.Transition(ctx, orders.TransitionInput{
+				OrderID:     o.ID,
+				ActorUserID: u.ID,
+				Action:      action,
+				Note:        note,
+			})
+		}
+	case "label":
+		if h.ShippingSvc == nil {
+			render.RedirectWithFlash(c, h.Flash, "/admin/orders", view.FlashError, "Kargo entegrasyonu devre dışı.")
+			return
+		}
+		carrier := strings.TrimSpace(c.PostForm("carrier"))
+		service := strings.TrimSpace(c.PostForm("service"))
+		run = func(ctx context.Context, o orders.Order) error {
+			_, err := h.ShippingSvc.QueueShipment(ctx, shipping.QueueShipmentInput{
+				OrderID:     o.ID,
+				ActorUserID: u.ID,
+				Carrier:     carrier,
+				Service:     service,
+				Note:        note,
+			})
+			return err
+		}
+	default:
+		render.RedirectWithFlash(c, h.Flash, "/admin/orders", view.FlashError, "Geçersiz toplu işlem.")
+		return
+	}
+
+	results, err := orders.NewRepo(h.DB).RunBatch(c.Request.Context(), ids, run)
+	if err != nil {
+		if errors.Is(err, orders.ErrBatchTooLarge) {
+			render.RedirectWithFlash(c, h.Flash, "/admin/orders", view.FlashError, "Tek seferde en fazla 100 sipariş seçilebilir.")
+			return
+		}
+		c.Error(apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AdminOrderBatchPage{Action: action}
+	for _, r := range results {
+		row := view.AdminOrderBatchResult{ID: r.OrderID, Number: r.Number, OK: r.Err == nil, Message: "Tamam"}
+		switch {
+		case r.Err == nil:
+			vm.Succeeded++
+		case errors.Is(r.Err, orders.ErrOrderNotFound):
+			vm.Failed++
+			row.Message = "Sipariş bulunamadı."
+		case action == "label":
+			vm.Failed++
+			row.Message = friendlyShipmentErr(r.Err)
+		default:
+			vm.Failed++
+			row.Message = friendlyTransitionErr(r.Err)
+		}
+		vm.Results = append(vm.Results, row)
+	}
+	render.Component(c, http.StatusOK, pages.AdminOrdersBatch(middleware.GetFlash(c), vm))
+}
+
+// batchPackingSlips prints the selected orders into one PDF. Orders that
+// can't be loaded are left out; once the PDF is generated each printed one
+// gets a packing_slip event.
+func (h *OrdersHandler) batchPackingSlips(c *gin.Context, actorID string, ids []string) {
+	ctx := c.Request.Context()
+	repo := orders.NewRepo(h.DB)
+	var slips []pdf.PackingSlipData
+	_, err := repo.RunBatch(ctx, ids, func(ctx context.Context, o orders.Order) error {
+		_, items, err := repo.GetWithItems(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		slips = append(slips, pdf.PackingSlipData{Order: o, Items: items})
+		return nil
+	})
+	if errors.Is(err, orders.ErrBatchTooLarge) {
+		render.RedirectWithFlash(c, h.Flash, "/admin/orders", view.FlashError, "Tek seferde en fazla 100 sipariş seçilebilir.")
+		return
+	}
+	if err != nil {
+		c.Error(apperr.Wrap(err))
+		return
+	}
+	if len(slips) == 0 {
+		render.RedirectWithFlash(c, h.Flash, "/admin/orders", view.FlashError, "Seçilen siparişler bulunamadı.")
+		return
+	}
+
+	out, err := pdf.GeneratePackingSlips(slips)
+	if err != nil {
+		c.Error(apperr.Wrap(err))
+		return
+	}
+	// PDF üretilemezse yazdırılmış sayılmaz; olaylar ancak şimdi kaydedilir
+	for _, s := range slips {
+		if err := repo.RecordEvent(ctx, s.Order, actorID, "packing_slip", "toplu yazdırma"); err != nil {
+			c.Error(apperr.Wrap(err))
+			return
+		}
+	}
+	filename := "pehlione-packing-slips-" + time.Now().Format("20060102-1504") + ".pdf"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/pdf", out)
+}
+
+func friendlyTransitionErr(err error) string {
+	switch {
+	case errors.Is(err, orders.ErrInvalidTransition):
+		return "Geçersiz status geçişi."
+	case errors.Is(err, orders.ErrCapturePending):
+		return "Tahsilat sağlayıcıda işleniyor; onaylandığında tekrar kargolayın."
+	case errors.Is(err, payments.ErrCaptureFailed), errors.Is(err, payments.ErrNotCapturable):
+		return "Ödeme tahsil edilemedi, sipariş kargolanmadı."
+	case errors.Is(err, payments.ErrVoidFailed):
+		return "Ödeme yetkilendirmesi iptal edilemedi, sipariş iptal edilmedi."
+	default:
+		return "İşlem başarısız: " + err.Error()
+	}
+}
@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/modules/shipping"
+)
+
+// voidFailProvider rejects voids, like a provider that is down.
+type voidFailProvider struct{ payments.MockProvider }
+
+func (voidFailProvider) VoidPayment(ctx context.Context, req payments.VoidRequest) (payments.VoidResponse, error) {
+	return payments.VoidResponse{}, errors.New("provider down")
+}
+
+func setupBatchHandler(t *testing.T) (*OrdersHandler, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	for _, q := range []string{
+		`CREATE TABLE orders (
+			id TEXT PRIMARY KEY, user_id TEXT, guest_email TEXT, status TEXT NOT NULL, currency TEXT NOT NULL,
+			total_cents INTEGER NOT NULL DEFAULT 0, order_number TEXT, shipping_address_json TEXT, paid_at DATETIME,
+			created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE order_items (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, product_name TEXT NOT NULL, sku TEXT NOT NULL,
+			quantity INTEGER NOT NULL, created_at DATETIME)`,
+		`CREATE TABLE order_events (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, actor_user_id TEXT NOT NULL, action TEXT NOT NULL,
+			from_status TEXT NOT NULL, to_status TEXT NOT NULL, note TEXT, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE payments (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, provider TEXT NOT NULL, provider_ref TEXT, status TEXT NOT NULL,
+			amount_cents INTEGER NOT NULL, currency TEXT NOT NULL, idempotency_key TEXT NOT NULL, error_message TEXT,
+			redirect_url TEXT, return_token TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`INSERT INTO orders (id, status, currency, order_number, created_at) VALUES
+			('o1', 'paid', 'EUR', 'PH-2026-000001', CURRENT_TIMESTAMP),
+			('o2', 'created', 'EUR', 'PH-2026-000002', CURRENT_TIMESTAMP),
+			('o3', 'authorized', 'EUR', 'PH-2026-000003', CURRENT_TIMESTAMP)`,
+		`INSERT INTO order_items (id, order_id, variant_id, product_name, sku, quantity, created_at)
+			VALUES ('i1', 'o1', 'v1', 'Shirt', 'SH-1', 2, CURRENT_TIMESTAMP)`,
+		`INSERT INTO payments (id, order_id, provider, provider_ref, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+			VALUES ('p3', 'o3', 'mock', 'ref3', 'authorized', 5000, 'EUR', 'k3', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+	paySvc := payments.NewService(db, payments.NewRegistry(voidFailProvider{payments.NewMockProvider("", 0)}))
+	h := NewOrdersHandler(db, flash.NewCodec([]byte("test-secret"), "flash", false), nil, nil, paySvc)
+	return h, db
+}
+
+// postBatch posts the batch form as admin u1.
+func postBatch(h *OrdersHandler, form url.Values) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/orders/batch", func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Set("user_role", "admin")
+	}, h.Batch)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/orders/batch", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// flashOf decodes the flash message a redirect set.
+func flashOf(t *testing.T, h *OrdersHandler, w *httptest.ResponseRecorder) string {
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "/admin/orders", w.Header().Get("Location"))
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == h.Flash.CookieName {
+			f, err := h.Flash.Decode(ck.Value)
+			require.NoError(t, err)
+			return f.Message
+		}
+	}
+	t.Fatal("no flash cookie")
+	return ""
+}
+
+func events(t *testing.T, db *gorm.DB, action string) []string {
+	var ids []string
+	require.NoError(t, db.Table("order_events").Where("action = ?", action).Order("order_id ASC").Pluck("order_id", &ids).Error)
+	return ids
+}
+
+func TestBatch_Ship(t *testing.T) {
+	h, db := setupBatchHandler(t)
+
+	w := postBatch(h, url.Values{"id": {"o1", "o2", "o1", "missing"}, "action": {"ship"}})
+	assert.Equal(t, "Onay gerekli.", flashOf(t, h, w))
+	assert.Empty(t, events(t, db, "ship"))
+
+	w = postBatch(h, url.Values{"id": {"o1", "o2", "o1", "missing"}, "action": {"ship"}, "confirm": {"1"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "1 başarılı, 2 başarısız")
+	assert.Contains(t, body, "Geçersiz status geçişi.")
+	assert.Contains(t, body, "Sipariş bulunamadı.")
+
+	var status string
+	require.NoError(t, db.Table("orders").Select("status").Where("id = ?", "o1").Scan(&status).Error)
+	assert.Equal(t, "shipped", status)
+	assert.Equal(t, []string{"o1"}, events(t, db, "ship"))
+}
+
+func TestBatch_CancelMapsPaymentErrors(t *testing.T) {
+	h, db := setupBatchHandler(t)
+
+	w := postBatch(h, url.Values{"id": {"o1", "o3"}, "action": {"cancel"}, "confirm": {"1"}, "note": {"stok yok"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "0 başarılı, 2 başarısız")
+	assert.Contains(t, body, "Geçersiz status geçişi.")
+	assert.Contains(t, body, "Ödeme yetkilendirmesi iptal edilemedi, sipariş iptal edilmedi.")
+	assert.Empty(t, events(t, db, "cancel"))
+}
+
+func TestBatch_Label(t *testing.T) {
+	h, _ := setupBatchHandler(t)
+
+	w := postBatch(h, url.Values{"id": {"o1"}, "action": {"label"}, "confirm": {"1"}})
+	assert.Equal(t, "Kargo entegrasyonu devre dışı.", flashOf(t, h, w))
+
+	h.ShippingSvc = shipping.NewService(h.DB, nil, nil, "")
+	w = postBatch(h, url.Values{"id": {"o1"}, "action": {"label"}, "confirm": {"1"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Kargo firması zorunlu.")
+
+	w = postBatch(h, url.Values{"id": {"o1"}, "action": {"label"}, "confirm": {"1"}, "carrier": {"yurtici"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Kargo sağlayıcısına ulaşılamadı.")
+}
+
+func TestBatch_Rejected(t *testing.T) {
+	h, _ := setupBatchHandler(t)
+
+	assert.Equal(t, "Sipariş seçilmedi.", flashOf(t, h, postBatch(h, url.Values{"action": {"ship"}})))
+	assert.Equal(t, "Geçersiz toplu işlem.", flashOf(t, h, postBatch(h, url.Values{"id": {"o1"}, "action": {"deliver"}, "confirm": {"1"}})))
+
+	many := url.Values{"action": {"ship"}, "confirm": {"1"}}
+	for i := 0; i < 101; i++ {
+		many.Add("id", fmt.Sprintf("o-%d", i))
+	}
+	assert.Equal(t, "Tek seferde en fazla 100 sipariş seçilebilir.", flashOf(t, h, postBatch(h, many)))
+}
+
+func TestBatch_PackingSlips(t *testing.T) {
+	h, db := setupBatchHandler(t)
+
+	w := postBatch(h, url.Values{"id": {"missing"}, "action": {"packing_slips"}})
+	assert.Equal(t, "Seçilen siparişler bulunamadı.", flashOf(t, h, w))
+	assert.Empty(t, events(t, db, "packing_slip"))
+
+	w = postBatch(h, url.Values{"id": {"o1", "missing", "o2"}, "action": {"packing_slips"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "%PDF"))
+	assert.Equal(t, []string{"o1", "o2"}, events(t, db, "packing_slip"))
+}
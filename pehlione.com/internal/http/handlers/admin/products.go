@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"os"
@@ -14,7 +15,9 @@ import (
 	"pehlione.com/app/internal/http/middleware"
 	"pehlione.com/app/internal/http/render"
 	"pehlione.com/app/internal/http/validation"
+	"pehlione.com/app/internal/modules/inventory"
 	"pehlione.com/app/internal/modules/products"
+	"pehlione.com/app/internal/modules/tax"
 	"pehlione.com/app/internal/shared/apperr"
 	"pehlione.com/app/internal/shared/slug"
 	"pehlione.com/app/internal/storage"
@@ -70,7 +73,7 @@ func (h *ProductsHandler) New(c *gin.Context) {
 	render.Component(c, http.StatusOK, pages.AdminProductForm(
 		middleware.GetFlash(c),
 		middleware.GetCSRFToken(c),
-		view.AdminProduct{Status: "active"},
+		view.AdminProduct{Status: "active", TaxClass: tax.ClassStandard, Kind: products.KindStandard},
 		nil,
 		"",
 		false,
@@ -82,6 +85,8 @@ type productInput struct {
 	Slug        string `form:"slug" binding:"omitempty,min=2,max=255"`
 	Description string `form:"description" binding:"omitempty,max=5000"`
 	Status      string `form:"status" binding:"required,oneof=active hidden"`
+	TaxClass    string `form:"tax_class" binding:"omitempty,oneof=standard reduced zero"`
+	Kind        string `form:"kind" binding:"omitempty,oneof=standard gift_card"`
 }
 
 func (h *ProductsHandler) Create(c *gin.Context) {
@@ -91,7 +96,7 @@ func (h *ProductsHandler) Create(c *gin.Context) {
 		render.Component(c, http.StatusBadRequest, pages.AdminProductForm(
 			middleware.GetFlash(c),
 			middleware.GetCSRFToken(c),
-			view.AdminProduct{Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status},
+			view.AdminProduct{Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status, TaxClass: in.TaxClass, Kind: in.Kind},
 			errs,
 			"",
 			false,
@@ -104,13 +109,13 @@ func (h *ProductsHandler) Create(c *gin.Context) {
 	}
 
 	repo := products.NewRepo(h.DB)
-	p, err := repo.CreateProduct(c.Request.Context(), in.Name, in.Slug, in.Description, in.Status)
+	p, err := repo.CreateProduct(c.Request.Context(), in.Name, in.Slug, in.Description, in.Status, tax.NormalizeClass(in.TaxClass), in.Kind)
 	if err != nil {
 		if products.IsDuplicateKey(err) {
 			render.Component(c, http.StatusConflict, pages.AdminProductForm(
 				middleware.GetFlash(c),
 				middleware.GetCSRFToken(c),
-				view.AdminProduct{Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status},
+				view.AdminProduct{Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status, TaxClass: in.TaxClass, Kind: in.Kind},
 				validation.FieldErrors{"slug": "Bu slug zaten kullanılıyor."},
 				"",
 				false,
@@ -140,6 +145,10 @@ func (h *ProductsHandler) Edit(c *gin.Context) {
 	}
 
 	vm := toAdminProductVM(p)
+	if err := attachStockLevels(c.Request.Context(), h.DB, &vm); err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
 	render.Component(c, http.StatusOK, pages.AdminProductForm(
 		middleware.GetFlash(c),
 		middleware.GetCSRFToken(c),
@@ -159,7 +168,7 @@ func (h *ProductsHandler) Update(c *gin.Context) {
 		render.Component(c, http.StatusBadRequest, pages.AdminProductForm(
 			middleware.GetFlash(c),
 			middleware.GetCSRFToken(c),
-			view.AdminProduct{ID: id, Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status},
+			view.AdminProduct{ID: id, Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status, TaxClass: in.TaxClass, Kind: in.Kind},
 			errs,
 			"",
 			true,
@@ -172,12 +181,12 @@ func (h *ProductsHandler) Update(c *gin.Context) {
 	}
 
 	repo := products.NewRepo(h.DB)
-	if err := repo.UpdateProduct(c.Request.Context(), id, in.Name, in.Slug, in.Description, in.Status); err != nil {
+	if err := repo.UpdateProduct(c.Request.Context(), id, in.Name, in.Slug, in.Description, in.Status, tax.NormalizeClass(in.TaxClass), in.Kind); err != nil {
 		if products.IsDuplicateKey(err) {
 			render.Component(c, http.StatusConflict, pages.AdminProductForm(
 				middleware.GetFlash(c),
 				middleware.GetCSRFToken(c),
-				view.AdminProduct{ID: id, Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status},
+				view.AdminProduct{ID: id, Name: in.Name, Slug: in.Slug, Description: in.Description, Status: in.Status, TaxClass: in.TaxClass, Kind: in.Kind},
 				validation.FieldErrors{"slug": "Bu slug zaten kullanılıyor."},
 				"",
 				true,
@@ -208,6 +217,8 @@ type variantInput struct {
 	PriceCents int    `form:"price_cents" binding:"required,min=0"`
 	Currency   string `form:"currency" binding:"required,len=3"`
 	Stock      int    `form:"stock" binding:"required,min=0"`
+	Weight     int    `form:"weight_grams" binding:"min=0"`
+	LowStock   int    `form:"low_stock_threshold" binding:"min=0"`
 	Options    string `form:"options_json" binding:"omitempty"`
 }
 
@@ -229,8 +240,13 @@ func (h *ProductsHandler) AddVariant(c *gin.Context) {
 		return
 	}
 
+	actorID := ""
+	if u, ok := middleware.CurrentUser(c); ok {
+		actorID = u.ID
+	}
+
 	repo := products.NewRepo(h.DB)
-	_, err := repo.AddVariant(c.Request.Context(), id, in.SKU, []byte(opts), in.PriceCents, strings.ToUpper(in.Currency), in.Stock)
+	_, err := repo.AddVariant(c.Request.Context(), id, in.SKU, []byte(opts), in.PriceCents, strings.ToUpper(in.Currency), in.Stock, in.Weight, in.LowStock, actorID)
 	if err != nil {
 		if products.IsDuplicateKey(err) {
 			render.RedirectWithFlash(c, h.Flash, "/admin/products/"+id+"/edit", view.FlashError, "SKU zaten kullanılıyor.")
@@ -315,7 +331,8 @@ func (h *ProductsHandler) UpdateVariant(c *gin.Context) {
 	type inT struct {
 		PriceCents int    `form:"price_cents" binding:"required,min=0"`
 		Currency   string `form:"currency" binding:"required,len=3"`
-		Stock      int    `form:"stock" binding:"required,min=0"`
+		Weight     int    `form:"weight_grams" binding:"min=0"`
+		LowStock   int    `form:"low_stock_threshold" binding:"min=0"`
 		Options    string `form:"options_json" binding:"omitempty"`
 	}
 	var in inT
@@ -324,6 +341,17 @@ func (h *ProductsHandler) UpdateVariant(c *gin.Context) {
 		return
 	}
 
+	// stock[<location_id>]=qty
+	levels := make(map[string]int)
+	for locID, raw := range c.PostFormMap("stock") {
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || n < 0 {
+			render.RedirectWithFlash(c, h.Flash, "/admin/products/"+pid+"/edit", view.FlashError, "Lokasyon stoğu 0 veya daha büyük bir sayı olmalı.")
+			return
+		}
+		levels[locID] = n
+	}
+
 	opts := strings.TrimSpace(in.Options)
 	if opts == "" {
 		opts = "{}"
@@ -333,6 +361,11 @@ func (h *ProductsHandler) UpdateVariant(c *gin.Context) {
 		return
 	}
 
+	actorID := ""
+	if u, ok := middleware.CurrentUser(c); ok {
+		actorID = u.ID
+	}
+
 	repo := products.NewRepo(h.DB)
 	if err := repo.UpdateVariant(
 		c.Request.Context(),
@@ -340,8 +373,11 @@ func (h *ProductsHandler) UpdateVariant(c *gin.Context) {
 		vid,
 		in.PriceCents,
 		strings.ToUpper(in.Currency),
-		in.Stock,
+		in.Weight,
+		in.LowStock,
 		[]byte(opts),
+		levels,
+		actorID,
 	); err != nil {
 		middleware.Fail(c, apperr.Wrap(err))
 		return
@@ -433,6 +469,34 @@ func (h *ProductsHandler) UploadImage(c *gin.Context) {
 }
 
 // ---------- helpers ----------
+
+// attachStockLevels fills per-location quantities for every variant; locations
+// without a stock_levels row are shown as 0 so they can be stocked from the form.
+func attachStockLevels(ctx context.Context, db *gorm.DB, vm *view.AdminProduct) error {
+	locs, err := inventory.ListLocations(ctx, db)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(vm.Variants))
+	for _, v := range vm.Variants {
+		ids = append(ids, v.ID)
+	}
+	levels, err := inventory.LevelsForVariants(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range vm.Variants {
+		for _, l := range locs {
+			vm.Variants[i].Levels = append(vm.Variants[i].Levels, view.AdminStockLevel{
+				LocationID: l.ID,
+				Code:       l.Code,
+				Name:       l.Name,
+				Qty:        levels[vm.Variants[i].ID][l.ID],
+			})
+		}
+	}
+	return nil
+}
 func toAdminProductVM(p products.Product) view.AdminProduct {
 	vm := view.AdminProduct{
 		ID:          p.ID,
@@ -440,6 +504,8 @@ func toAdminProductVM(p products.Product) view.AdminProduct {
 		Slug:        p.Slug,
 		Description: p.Description,
 		Status:      p.Status,
+		TaxClass:    p.TaxClass,
+		Kind:        p.Kind,
 	}
 	for _, v := range p.Variants {
 		vm.Variants = append(vm.Variants, view.AdminVariant{
@@ -448,6 +514,8 @@ func toAdminProductVM(p products.Product) view.AdminProduct {
 			PriceCents: v.PriceCents,
 			Currency:   v.Currency,
 			Stock:      v.Stock,
+			Weight:     v.WeightGrams,
+			LowStock:   v.LowStockThreshold,
 			Options:    string(v.Options),
 		})
 	}
@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type ProviderEventsHandler struct {
+	Flash     *flash.Codec
+	Webhooks  *payments.WebhookService
+	Providers []string
+}
+
+func NewProviderEventsHandler(fl *flash.Codec, webhooks *payments.WebhookService, providers []string) *ProviderEventsHandler {
+	return &ProviderEventsHandler{Flash: fl, Webhooks: webhooks, Providers: providers}
+}
+
+func (h *ProviderEventsHandler) List(c *gin.Context) {
+	f := payments.EventFilter{
+		State:    strings.TrimSpace(c.Query("state")),
+		Type:     strings.TrimSpace(c.Query("type")),
+		Provider: strings.TrimSpace(c.Query("provider")),
+	}
+
+	items, err := h.Webhooks.ListEvents(c.Request.Context(), f)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+	types, err := h.Webhooks.EventTypes(c.Request.Context())
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AdminProviderEventsList{
+		State:     f.State,
+		Type:      f.Type,
+		Provider:  f.Provider,
+		States:    []string{payments.EventStateFailed, payments.EventStatePending, payments.EventStateProcessed},
+		Types:     types,
+		Providers: h.Providers,
+	}
+	for _, pe := range items {
+		vm.Items = append(vm.Items, view.AdminProviderEventItem{
+			ID:         pe.ID,
+			Provider:   pe.Provider,
+			EventID:    pe.EventID,
+			Type:       pe.EventType,
+			State:      pe.State(),
+			Attempts:   pe.Attempts,
+			Error:      ptrStr(pe.ProcessError),
+			ReceivedAt: pe.ReceivedAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminProviderEventsList(middleware.GetFlash(c), vm))
+}
+
+func (h *ProviderEventsHandler) Detail(c *gin.Context) {
+	pe, err := h.Webhooks.GetEvent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("Olay bulunamadı."))
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	payload := string(pe.PayloadJSON)
+	var buf bytes.Buffer
+	if json.Indent(&buf, pe.PayloadJSON, "", "  ") == nil {
+		payload = buf.String()
+	}
+
+	vm := view.AdminProviderEventDetail{
+		ID:          pe.ID,
+		Provider:    pe.Provider,
+		EventID:     pe.EventID,
+		Type:        pe.EventType,
+		State:       pe.State(),
+		Attempts:    pe.Attempts,
+		Error:       ptrStr(pe.ProcessError),
+		PaymentRef:  ptrStr(pe.PaymentRef),
+		RefundRef:   ptrStr(pe.RefundRef),
+		ReceivedAt:  pe.ReceivedAt.Format("2006-01-02 15:04:05"),
+		ProcessedAt: formatTimePtr(pe.ProcessedAt),
+		Payload:     payload,
+	}
+	if pe.AmountCents != 0 {
+		vm.Amount = view.MoneyFromCents(pe.AmountCents, ptrStr(pe.Currency))
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminProviderEventDetail(
+		middleware.GetFlash(c),
+		middleware.GetCSRFToken(c),
+		vm,
+	))
+}
+
+// Replay re-applies a failed or pending event.
+func (h *ProviderEventsHandler) Replay(c *gin.Context) {
+	id := c.Param("id")
+	back := "/admin/webhooks/" + id
+
+	err := h.Webhooks.Replay(c.Request.Context(), id)
+	switch {
+	case err == nil:
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashSuccess, "Olay yeniden işlendi.")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		middleware.Fail(c, apperr.NotFoundErr("Olay bulunamadı."))
+	case errors.Is(err, payments.ErrEventAlreadyProcessed):
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashInfo, "Olay zaten işlenmiş.")
+	default:
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashError, "Olay işlenemedi: "+err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/reports"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type ReportsHandler struct {
+	Reports *reports.Service
+}
+
+func NewReportsHandler(svc *reports.Service) *ReportsHandler {
+	return &ReportsHandler{Reports: svc}
+}
+
+func (h *ReportsHandler) Sales(c *gin.Context) {
+	p, from, to := reportParams(c)
+	rows, totals, err := h.Reports.Sales(c.Request.Context(), p)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AdminReportsPage{
+		Period:  p.Period,
+		From:    from,
+		To:      to,
+		Periods: []string{reports.PeriodDay, reports.PeriodWeek, reports.PeriodMonth},
+		Totals: view.AdminSalesTotals{
+			Gross:       view.MoneyFromCents(totals.BaseGrossCents, totals.BaseCurrency),
+			Refunds:     view.MoneyFromCents(totals.BaseRefundsCents, totals.BaseCurrency),
+			Chargebacks: view.MoneyFromCents(totals.BaseChargebackCents, totals.BaseCurrency),
+			Net:         view.MoneyFromCents(totals.BaseNetCents, totals.BaseCurrency),
+			GiftCards:   view.MoneyFromCents(totals.BaseGiftCardCents, totals.BaseCurrency),
+		},
+	}
+	for _, r := range rows {
+		vm.Rows = append(vm.Rows, view.AdminSalesRow{
+			Bucket:          r.Bucket,
+			Currency:        r.Currency,
+			Orders:          r.Orders,
+			Gross:           view.MoneyFromCents(r.GrossCents, r.Currency),
+			Refunds:         view.MoneyFromCents(r.RefundsCents, r.Currency),
+			Chargebacks:     view.MoneyFromCents(r.ChargebackCents, r.Currency),
+			Net:             view.MoneyFromCents(r.NetCents, r.Currency),
+			GiftCards:       view.MoneyFromCents(r.GiftCardCents, r.Currency),
+			BaseGross:       view.MoneyFromCents(r.BaseGrossCents, r.BaseCurrency),
+			BaseRefunds:     view.MoneyFromCents(r.BaseRefundsCents, r.BaseCurrency),
+			BaseChargebacks: view.MoneyFromCents(r.BaseChargebackCents, r.BaseCurrency),
+			BaseNet:         view.MoneyFromCents(r.BaseNetCents, r.BaseCurrency),
+			BaseGiftCards:   view.MoneyFromCents(r.BaseGiftCardCents, r.BaseCurrency),
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminReports(middleware.GetFlash(c), vm))
+}
+
+func (h *ReportsHandler) SalesCSV(c *gin.Context) {
+	p, from, to := reportParams(c)
+	rows, _, err := h.Reports.Sales(c.Request.Context(), p)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	filename := fmt.Sprintf("sales_%s_%s_%s.csv", p.Period, from, to)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if err := reports.WriteSalesCSV(c.Writer, p.Period, rows); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+func (h *ReportsHandler) Reconciliation(c *gin.Context) {
+	items, err := h.Reports.Reconcile(c.Request.Context(), 200)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	var vm view.AdminReconciliationPage
+	for _, m := range items {
+		vm.Items = append(vm.Items, view.AdminReconciliationItem{
+			OrderID:   m.OrderID,
+			Status:    m.Status,
+			Expected:  view.MoneyFromCents(m.ExpectedCents, m.Currency),
+			Ledger:    view.MoneyFromCents(m.LedgerCents, m.Currency),
+			Diff:      view.MoneyFromCents(m.DiffCents, m.Currency),
+			Disputed:  m.Disputed,
+			CreatedAt: m.CreatedAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminReconciliation(middleware.GetFlash(c), vm))
+}
+
+// reportParams reads period and the inclusive from/to dates (default: last
+// 30 days).
+func reportParams(c *gin.Context) (reports.Params, string, string) {
+	const layout = "2006-01-02"
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	to, err := time.ParseInLocation(layout, strings.TrimSpace(c.Query("to")), time.Local)
+	if err != nil {
+		to = today
+	}
+	from, err := time.ParseInLocation(layout, strings.TrimSpace(c.Query("from")), time.Local)
+	if err != nil || from.After(to) {
+		from = to.AddDate(0, 0, -29)
+	}
+
+	return reports.Params{
+		Period: reports.ParsePeriod(c.Query("period")),
+		From:   from,
+		To:     to.AddDate(0, 0, 1),
+	}, from.Format(layout), to.Format(layout)
+}
@@ -0,0 +1,201 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/modules/returns"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type ReturnsHandler struct {
+	DB      *gorm.DB
+	Flash   *flash.Codec
+	Returns *returns.Service
+}
+
+func NewReturnsHandler(db *gorm.DB, fl *flash.Codec, svc *returns.Service) *ReturnsHandler {
+	return &ReturnsHandler{DB: db, Flash: fl, Returns: svc}
+}
+
+func (h *ReturnsHandler) List(c *gin.Context) {
+	status := strings.TrimSpace(c.Query("status"))
+
+	items, err := h.Returns.AdminList(c.Request.Context(), status, 200)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AdminReturnsList{
+		Status: status,
+		Statuses: []string{
+			returns.StatusRequested, returns.StatusApproved, returns.StatusRejected,
+			returns.StatusReceived, returns.StatusRefunded,
+		},
+	}
+	for _, r := range items {
+		n := 0
+		for _, it := range r.Items {
+			n += it.Qty
+		}
+		vm.Items = append(vm.Items, view.AdminReturnListItem{
+			ID:        r.ID,
+			RMA:       rmaNumber(r.ID),
+			OrderID:   r.OrderID,
+			Status:    r.Status,
+			Refund:    view.MoneyFromCents(r.RefundCents, r.Currency),
+			ItemCount: n,
+			CreatedAt: r.CreatedAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminReturnsList(middleware.GetFlash(c), vm))
+}
+
+func (h *ReturnsHandler) Detail(c *gin.Context) {
+	r, err := h.Returns.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("İade bulunamadı."))
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	vm := view.AdminReturnDetail{
+		ID:              r.ID,
+		RMA:             rmaNumber(r.ID),
+		OrderID:         r.OrderID,
+		UserID:          r.UserID,
+		Status:          r.Status,
+		Reason:          r.Reason,
+		AdminNote:       ptrStr(r.AdminNote),
+		Refund:          view.MoneyFromCents(r.RefundCents, r.Currency),
+		RefundID:        ptrStr(r.RefundID),
+		LabelCarrier:    ptrStr(r.LabelCarrier),
+		LabelTrackingNo: ptrStr(r.LabelTrackingNo),
+		LabelURL:        ptrStr(r.LabelURL),
+		CreatedAt:       r.CreatedAt.Format("2006-01-02 15:04"),
+		ApprovedAt:      formatTimePtr(r.ApprovedAt),
+		ReceivedAt:      formatTimePtr(r.ReceivedAt),
+		LabelsAvailable: h.Returns.LabelsAvailable(),
+	}
+	for _, it := range r.Items {
+		vm.Items = append(vm.Items, view.AdminReturnItem{
+			ProductName: it.ProductName,
+			SKU:         it.SKU,
+			Qty:         it.Qty,
+			Refund:      view.MoneyFromCents(it.RefundCents, r.Currency),
+		})
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminReturnDetail(
+		middleware.GetFlash(c),
+		middleware.GetCSRFToken(c),
+		vm,
+	))
+}
+
+// Action handles approve|reject|receive.
+func (h *ReturnsHandler) Action(c *gin.Context) {
+	id := c.Param("id")
+	back := "/admin/returns/" + id
+
+	u, ok := middleware.CurrentUser(c)
+	if !ok {
+		middleware.Fail(c, apperr.ForbiddenErr("Giriş gerekli."))
+		return
+	}
+
+	in := returns.ActionInput{
+		ReturnID:    id,
+		ActorUserID: u.ID,
+		Note:        strings.TrimSpace(c.PostForm("note")),
+	}
+
+	var err error
+	var okMsg string
+	switch c.Param("action") {
+	case "approve":
+		err, okMsg = h.Returns.Approve(c.Request.Context(), in), "İade onaylandı."
+	case "reject":
+		err, okMsg = h.Returns.Reject(c.Request.Context(), in), "İade reddedildi."
+	case "receive":
+		_, err = h.Returns.Receive(c.Request.Context(), in)
+		okMsg = "Ürünler teslim alındı, stok ve ödeme iadesi işlendi."
+	default:
+		middleware.Fail(c, apperr.NotFoundErr("Geçersiz işlem."))
+		return
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("İade bulunamadı."))
+			return
+		}
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashError, friendlyReturnErr(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, back, view.FlashSuccess, okMsg)
+}
+
+func (h *ReturnsHandler) Label(c *gin.Context) {
+	id := c.Param("id")
+	back := "/admin/returns/" + id
+
+	u, ok := middleware.CurrentUser(c)
+	if !ok {
+		middleware.Fail(c, apperr.ForbiddenErr("Giriş gerekli."))
+		return
+	}
+
+	err := h.Returns.CreateLabel(c.Request.Context(), returns.LabelInput{
+		ReturnID:    id,
+		ActorUserID: u.ID,
+		Carrier:     c.PostForm("carrier"),
+	})
+	if err != nil {
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashError, friendlyReturnErr(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, back, view.FlashSuccess, "İade kargo etiketi oluşturuldu.")
+}
+
+func friendlyReturnErr(err error) string {
+	switch {
+	case errors.Is(err, returns.ErrInvalidState):
+		return "İade bu işlem için uygun durumda değil."
+	case errors.Is(err, returns.ErrCarrierRequired):
+		return "Kargo firması zorunlu."
+	case errors.Is(err, returns.ErrLabelUnavailable):
+		return "İade etiketi oluşturulamadı: kargo sağlayıcısına ulaşılamadı."
+	case errors.Is(err, returns.ErrRefundFailed):
+		return "Ürünler teslim alındı ancak ödeme iadesi başarısız. Tekrar deneyebilirsiniz."
+	case errors.Is(err, payments.ErrPaymentDisputed):
+		return "Ürünler teslim alındı ancak ödemeye itiraz (chargeback) açıldığı için iade yapılamadı."
+	case errors.Is(err, payments.ErrNotRefundable), errors.Is(err, payments.ErrNoSucceededPayment):
+		return "Ürünler teslim alındı ancak sipariş ödeme iadesine uygun değil."
+	default:
+		return "İade işlemi başarısız: " + err.Error()
+	}
+}
+
+func rmaNumber(id string) string {
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return "RMA-" + strings.ToUpper(id)
+}
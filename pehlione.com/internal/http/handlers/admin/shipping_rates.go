@@ -0,0 +1,341 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/http/validation"
+	"pehlione.com/app/internal/modules/promotions"
+	"pehlione.com/app/internal/modules/shipping"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+type ShippingRatesHandler struct {
+	DB    *gorm.DB
+	Flash *flash.Codec
+	Rates *shipping.RateService
+}
+
+func NewShippingRatesHandler(db *gorm.DB, fl *flash.Codec) *ShippingRatesHandler {
+	return &ShippingRatesHandler{DB: db, Flash: fl, Rates: shipping.NewRateService(db, "")}
+}
+
+type shippingZoneInput struct {
+	Name      string `form:"name" binding:"required,max=128"`
+	Countries string `form:"countries" binding:"required,max=2000"`
+	Priority  int    `form:"priority" binding:"gte=0"`
+	Status    string `form:"status" binding:"required,oneof=active inactive"`
+}
+
+type shippingMethodInput struct {
+	Code          string `form:"code" binding:"required,max=32"`
+	Name          string `form:"name" binding:"required,max=128"`
+	RateType      string `form:"rate_type" binding:"required,oneof=flat weight subtotal"`
+	FlatCents     int    `form:"flat_cents" binding:"gte=0"`
+	Tiers         string `form:"tiers" binding:"omitempty,max=2000"`
+	FreeOverCents int    `form:"free_over_cents" binding:"gte=0"`
+	Position      int    `form:"position"`
+	Status        string `form:"status" binding:"required,oneof=active inactive"`
+}
+
+func (h *ShippingRatesHandler) List(c *gin.Context) {
+	zones, err := h.Rates.ListZones(c.Request.Context())
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	out := make([]view.AdminShippingZoneListItem, 0, len(zones))
+	for _, z := range zones {
+		item := view.AdminShippingZoneListItem{
+			ID:        z.ID,
+			Name:      z.Name,
+			Countries: strings.Join(z.Countries(), ", "),
+			Priority:  z.Priority,
+			Status:    z.Status,
+		}
+		for _, m := range z.Methods {
+			item.Methods = append(item.Methods, m.Name)
+		}
+		out = append(out, item)
+	}
+
+	render.Component(c, http.StatusOK, pages.AdminShippingList(middleware.GetFlash(c), out))
+}
+
+func (h *ShippingRatesHandler) NewZone(c *gin.Context) {
+	h.renderZoneForm(c, http.StatusOK, view.AdminShippingZone{Priority: "10", Status: shipping.RateStatusActive}, nil, false)
+}
+
+func (h *ShippingRatesHandler) CreateZone(c *gin.Context) {
+	var in shippingZoneInput
+	if err := c.ShouldBind(&in); err != nil {
+		h.renderZoneForm(c, http.StatusBadRequest, zoneVMFromInput("", in), validation.FromBindError(err, &in), false)
+		return
+	}
+
+	z, errs := zoneFromInput(in)
+	if len(errs) > 0 {
+		h.renderZoneForm(c, http.StatusBadRequest, zoneVMFromInput("", in), errs, false)
+		return
+	}
+	z.ID = uuid.NewString()
+
+	if err := h.Rates.SaveZone(c.Request.Context(), &z); err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, "/admin/shipping/zones/"+z.ID+"/edit", view.FlashSuccess, "Kargo bölgesi oluşturuldu.")
+}
+
+func (h *ShippingRatesHandler) EditZone(c *gin.Context) {
+	z, ok := h.loadZone(c)
+	if !ok {
+		return
+	}
+	h.renderZoneForm(c, http.StatusOK, zoneVM(z), nil, true)
+}
+
+func (h *ShippingRatesHandler) UpdateZone(c *gin.Context) {
+	existing, ok := h.loadZone(c)
+	if !ok {
+		return
+	}
+
+	var in shippingZoneInput
+	if err := c.ShouldBind(&in); err != nil {
+		vm := zoneVMFromInput(existing.ID, in)
+		vm.Methods = zoneVM(existing).Methods
+		h.renderZoneForm(c, http.StatusBadRequest, vm, validation.FromBindError(err, &in), true)
+		return
+	}
+
+	z, errs := zoneFromInput(in)
+	if len(errs) > 0 {
+		vm := zoneVMFromInput(existing.ID, in)
+		vm.Methods = zoneVM(existing).Methods
+		h.renderZoneForm(c, http.StatusBadRequest, vm, errs, true)
+		return
+	}
+	z.ID = existing.ID
+	z.CreatedAt = existing.CreatedAt
+
+	if err := h.Rates.SaveZone(c.Request.Context(), &z); err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, "/admin/shipping/zones/"+z.ID+"/edit", view.FlashSuccess, "Kargo bölgesi güncellendi.")
+}
+
+func (h *ShippingRatesHandler) CreateMethod(c *gin.Context) {
+	z, ok := h.loadZone(c)
+	if !ok {
+		return
+	}
+	h.saveMethod(c, z, shipping.Method{ID: uuid.NewString(), ZoneID: z.ID}, "Kargo yöntemi eklendi.")
+}
+
+func (h *ShippingRatesHandler) UpdateMethod(c *gin.Context) {
+	z, ok := h.loadZone(c)
+	if !ok {
+		return
+	}
+	m, err := h.Rates.GetMethod(c.Request.Context(), z.ID, c.Param("mid"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("Kargo yöntemi bulunamadı."))
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+	h.saveMethod(c, z, m, "Kargo yöntemi güncellendi.")
+}
+
+func (h *ShippingRatesHandler) DeleteMethod(c *gin.Context) {
+	zoneID := c.Param("id")
+	if err := h.Rates.DeleteMethod(c.Request.Context(), zoneID, c.Param("mid")); err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+	render.RedirectWithFlash(c, h.Flash, "/admin/shipping/zones/"+zoneID+"/edit", view.FlashSuccess, "Kargo yöntemi silindi.")
+}
+
+func (h *ShippingRatesHandler) saveMethod(c *gin.Context, z shipping.Zone, m shipping.Method, okMsg string) {
+	back := "/admin/shipping/zones/" + z.ID + "/edit"
+
+	var in shippingMethodInput
+	if err := c.ShouldBind(&in); err != nil {
+		h.renderZoneForm(c, http.StatusBadRequest, zoneVM(z), validation.FieldErrors{"method": methodBindMessage(validation.FromBindError(err, &in))}, true)
+		return
+	}
+
+	tiers, err := parseTiers(in.Tiers)
+	if err != nil {
+		h.renderZoneForm(c, http.StatusBadRequest, zoneVM(z), validation.FieldErrors{"method": err.Error()}, true)
+		return
+	}
+	if in.RateType != shipping.RateFlat && len(tiers) == 0 {
+		h.renderZoneForm(c, http.StatusBadRequest, zoneVM(z), validation.FieldErrors{"method": "Kademeli ücretlendirme için en az bir kademe girilmelidir."}, true)
+		return
+	}
+
+	m.Code = strings.ToLower(strings.TrimSpace(in.Code))
+	m.Name = strings.TrimSpace(in.Name)
+	m.RateType = in.RateType
+	m.FlatCents = in.FlatCents
+	m.FreeOverCents = in.FreeOverCents
+	m.Position = in.Position
+	m.Status = in.Status
+	m.TiersJSON = nil
+	if len(tiers) > 0 {
+		b, _ := json.Marshal(tiers)
+		m.TiersJSON = datatypes.JSON(b)
+	}
+
+	if err := h.Rates.SaveMethod(c.Request.Context(), &m); err != nil {
+		if promotions.IsDuplicateKey(err) {
+			h.renderZoneForm(c, http.StatusConflict, zoneVM(z), validation.FieldErrors{"method": "Bu bölgede aynı kodlu bir yöntem zaten var."}, true)
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, back, view.FlashSuccess, okMsg)
+}
+
+func (h *ShippingRatesHandler) loadZone(c *gin.Context) (shipping.Zone, bool) {
+	z, err := h.Rates.GetZone(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("Kargo bölgesi bulunamadı."))
+			return shipping.Zone{}, false
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return shipping.Zone{}, false
+	}
+	return z, true
+}
+
+func (h *ShippingRatesHandler) renderZoneForm(c *gin.Context, status int, vm view.AdminShippingZone, errs validation.FieldErrors, isEdit bool) {
+	render.Component(c, status, pages.AdminShippingZoneForm(
+		middleware.GetFlash(c),
+		middleware.GetCSRFToken(c),
+		vm,
+		errs,
+		isEdit,
+	))
+}
+
+// --- helpers ---
+
+func zoneFromInput(in shippingZoneInput) (shipping.Zone, validation.FieldErrors) {
+	errs := validation.FieldErrors{}
+
+	countries := splitCSV(strings.ToUpper(in.Countries), false)
+	for _, cc := range countries {
+		if cc != shipping.ZoneWildcard && len(cc) != 2 {
+			errs["countries"] = fmt.Sprintf("Geçersiz ülke kodu: %s", cc)
+			break
+		}
+	}
+	if len(countries) == 0 {
+		errs["countries"] = "En az bir ülke girilmelidir."
+	}
+
+	b, _ := json.Marshal(countries)
+	return shipping.Zone{
+		Name:          strings.TrimSpace(in.Name),
+		CountriesJSON: datatypes.JSON(b),
+		Priority:      in.Priority,
+		Status:        in.Status,
+	}, errs
+}
+
+// parseTiers reads one "up_to:cents" pair per line.
+func parseTiers(s string) ([]shipping.Tier, error) {
+	var out []shipping.Tier
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Geçersiz kademe: %q (beklenen biçim up_to:cents)", line)
+		}
+		upTo, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		cents, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 != nil || err2 != nil || upTo < 0 || cents < 0 {
+			return nil, fmt.Errorf("Geçersiz kademe: %q (beklenen biçim up_to:cents)", line)
+		}
+		out = append(out, shipping.Tier{UpTo: upTo, Cents: cents})
+	}
+	return out, nil
+}
+
+func formatTiers(tiers []shipping.Tier) string {
+	lines := make([]string, 0, len(tiers))
+	for _, t := range tiers {
+		lines = append(lines, fmt.Sprintf("%d:%d", t.UpTo, t.Cents))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func methodBindMessage(errs validation.FieldErrors) string {
+	for field, msg := range errs {
+		return field + ": " + msg
+	}
+	return "Geçersiz kargo yöntemi."
+}
+
+func zoneVM(z shipping.Zone) view.AdminShippingZone {
+	vm := view.AdminShippingZone{
+		ID:        z.ID,
+		Name:      z.Name,
+		Countries: strings.Join(z.Countries(), ", "),
+		Priority:  strconv.Itoa(z.Priority),
+		Status:    z.Status,
+	}
+	for _, m := range z.Methods {
+		vm.Methods = append(vm.Methods, view.AdminShippingMethod{
+			ID:            m.ID,
+			Code:          m.Code,
+			Name:          m.Name,
+			RateType:      m.RateType,
+			FlatCents:     strconv.Itoa(m.FlatCents),
+			Tiers:         formatTiers(m.Tiers()),
+			FreeOverCents: strconv.Itoa(m.FreeOverCents),
+			Position:      strconv.Itoa(m.Position),
+			Status:        m.Status,
+		})
+	}
+	return vm
+}
+
+func zoneVMFromInput(id string, in shippingZoneInput) view.AdminShippingZone {
+	return view.AdminShippingZone{
+		ID:        id,
+		Name:      in.Name,
+		Countries: in.Countries,
+		Priority:  strconv.Itoa(in.Priority),
+		Status:    in.Status,
+	}
+}
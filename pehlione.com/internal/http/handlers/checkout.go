@@ -6,7 +6,9 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -18,11 +20,16 @@ import (
 	"pehlione.com/app/internal/http/middleware"
 	"pehlione.com/app/internal/http/render"
 	"pehlione.com/app/internal/http/validation"
+	"pehlione.com/app/internal/modules/addresses"
 	cartmod "pehlione.com/app/internal/modules/cart"
 	"pehlione.com/app/internal/modules/checkout"
 	"pehlione.com/app/internal/modules/currency"
 	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/giftcards"
 	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/promotions"
+	"pehlione.com/app/internal/modules/shipping"
+	"pehlione.com/app/internal/modules/tax"
 	"pehlione.com/app/internal/shared/apperr"
 	"pehlione.com/app/pkg/view"
 	"pehlione.com/app/templates/pages"
@@ -36,6 +43,10 @@ type CheckoutHandler struct {
 	OrderSv     *orders.Service
 	EmailSv     *emailmod.OutboxService
 	CurrencySvc *currency.Service
+	PromoSvc    *promotions.Service
+	RateSvc     *shipping.RateService
+	Addresses   *addresses.Service
+	GuestTokens *orders.GuestTokens
 	BaseURL     string
 }
 
@@ -48,10 +59,23 @@ func NewCheckoutHandler(db *gorm.DB, fl *flash.Codec, ck *cartcookie.Codec, cart
 		OrderSv:     osvc,
 		EmailSv:     emailSvc,
 		CurrencySvc: currSvc,
+		PromoSvc:    promotions.NewService(db),
+		RateSvc:     shipping.NewRateService(db, ""),
+		Addresses:   addresses.NewService(db),
 		BaseURL:     baseURL,
 	}
 }
 
+// SetGuestTokens enables signed order links for guest checkouts.
+func (h *CheckoutHandler) SetGuestTokens(g *orders.GuestTokens) {
+	h.GuestTokens = g
+}
+
+// SetRateService replaces the default shipping rate service (e.g. with a configured default country).
+func (h *CheckoutHandler) SetRateService(svc *shipping.RateService) {
+	h.RateSvc = svc
+}
+
 type checkoutInput struct {
 	Email string `form:"email" binding:"omitempty,email,max=255"`
 
@@ -64,8 +88,22 @@ type checkoutInput struct {
 	Country    string `form:"country" binding:"required,len=2"`
 	Phone      string `form:"phone" binding:"required,min=5,max=32"`
 
-	ShippingMethod string `form:"shipping_method" binding:"required,oneof=standard express"`
+	UseBillingAddress bool   `form:"use_billing_address"`
+	BillingFirstName  string `form:"billing_first_name" binding:"required_if=UseBillingAddress true,max=100"`
+	BillingLastName   string `form:"billing_last_name" binding:"required_if=UseBillingAddress true,max=100"`
+	BillingAddress1   string `form:"billing_address1" binding:"required_if=UseBillingAddress true,max=255"`
+	BillingAddress2   string `form:"billing_address2" binding:"omitempty,max=255"`
+	BillingCity       string `form:"billing_city" binding:"required_if=UseBillingAddress true,max=100"`
+	BillingPostalCode string `form:"billing_postal_code" binding:"required_if=UseBillingAddress true,max=32"`
+	BillingCountry    string `form:"billing_country" binding:"required_if=UseBillingAddress true,max=2"`
+	BillingPhone      string `form:"billing_phone" binding:"omitempty,max=32"`
+	SaveAddress       bool   `form:"save_address"`
+
+	ShippingMethod string `form:"shipping_method" binding:"required,max=32"`
 	PaymentMethod  string `form:"payment_method" binding:"required,oneof=card paypal klarna"`
+	PromoCode      string `form:"promo_code" binding:"omitempty,max=64"`
+	GiftCardCodes  string `form:"gift_card_codes" binding:"omitempty,max=255"`
+	GiftRecipient  string `form:"gift_recipient_email" binding:"omitempty,email,max=255"`
 	IdemKey        string `form:"idempotency_key" binding:"omitempty,max=64"`
 }
 
@@ -79,6 +117,7 @@ type addressJSON struct {
 	Country        string `json:"country"`
 	Phone          string `json:"phone"`
 	ShippingMethod string `json:"shipping_method,omitempty"`
+	ShippingName   string `json:"shipping_method_name,omitempty"`
 	PaymentMethod  string `json:"payment_method,omitempty"`
 }
 
@@ -98,8 +137,8 @@ func (h *CheckoutHandler) Get(c *gin.Context) {
 
 	idem := randHex(16)
 	form := view.CheckoutForm{
-		ShippingMethod: "standard",
-		IdemKey:        idem,
+		Country: strings.ToUpper(strings.TrimSpace(c.Query("country"))),
+		IdemKey: idem,
 	}
 	if authed {
 		form.Email = u.Email
@@ -110,16 +149,32 @@ func (h *CheckoutHandler) Get(c *gin.Context) {
 		if u.LastName != nil {
 			form.LastName = *u.LastName
 		}
-		if u.Address != nil {
-			form.Address1 = *u.Address
-		}
 		if u.PhoneE164 != nil {
 			form.Phone = *u.PhoneE164
 		}
+		// adres defterinden: ?address_id= seçimi, yoksa varsayılan adres
+		form.SavedAddresses = h.savedAddresses(c, u.ID)
+		if picked, ok := pickSavedAddress(form.SavedAddresses, c.Query("address_id")); ok {
+			applySavedAddress(&form, picked, form.Country)
+		}
 		log.Printf("Checkout GET: pre-filled form for user %s (email=%s)", u.ID, u.Email)
 	}
 
-	opts := shippingOptions(c.Request.Context(), h.CurrencySvc, currency)
+	quotes, err := h.shippingQuotes(c.Request.Context(), form.Country, summary)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+	if len(quotes) > 0 {
+		form.ShippingMethod = quotes[0].Code
+		h.applyShipping(c.Request.Context(), &summary, quotes[0])
+	}
+	if err := h.applyTaxPreview(c.Request.Context(), &summary, form.Country); err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	opts := h.shippingOptions(c.Request.Context(), quotes, currency)
 	payments := paymentOptions()
 	render.Component(c, http.StatusOK, pages.Checkout(
 		middleware.GetFlash(c),
@@ -160,11 +215,43 @@ func (h *CheckoutHandler) Post(c *gin.Context) {
 		return
 	}
 
-	shipCents := shippingCents(in.ShippingMethod)
-	summary.ShippingCents = shipCents
-	summary.Shipping = view.MoneyFromCents(shipCents, currency)
-	summary.TotalCents = summary.SubtotalCents + shipCents
-	summary.Total = view.MoneyFromCents(summary.TotalCents, currency)
+	customerEmail := strings.ToLower(strings.TrimSpace(in.Email))
+	if authed {
+		customerEmail = strings.ToLower(strings.TrimSpace(u.Email))
+	}
+	var promoUserID *string
+	if authed {
+		promoUserID = &u.ID
+	}
+	if strings.TrimSpace(in.PromoCode) != "" {
+		if err := h.applyPromoPreview(c.Request.Context(), &summary, in.PromoCode, promoUserID, customerEmail); err != nil {
+			if msg, ok := promoErrorMessage(err); ok {
+				h.renderCheckoutWithErrors(c, authed, summary, currency, validation.FieldErrors{"promo_code": msg}, "", in)
+				return
+			}
+			middleware.Fail(c, apperr.Wrap(err))
+			return
+		}
+	}
+
+	// kargo, indirim sonrası tutara göre fiyatlanır (ücretsiz kargo eşiği)
+	quote, err := h.RateSvc.QuoteMethod(c.Request.Context(), in.Country, in.ShippingMethod, rateCart(summary))
+	if err != nil {
+		if errors.Is(err, shipping.ErrMethodUnavailable) {
+			errs := validation.FieldErrors{"shipping_method": "Seçilen kargo yöntemi bu ülke için kullanılamıyor."}
+			h.renderCheckoutWithErrors(c, authed, summary, currency, errs, "", in)
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+	h.applyShipping(c.Request.Context(), &summary, quote)
+	shipCents := quote.Cents
+
+	if err := h.applyTaxPreview(c.Request.Context(), &summary, in.Country); err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
 
 	addr := addressJSON{
 		FirstName:      strings.TrimSpace(in.FirstName),
@@ -175,7 +262,8 @@ func (h *CheckoutHandler) Post(c *gin.Context) {
 		PostalCode:     strings.TrimSpace(in.PostalCode),
 		Country:        strings.ToUpper(strings.TrimSpace(in.Country)),
 		Phone:          strings.TrimSpace(in.Phone),
-		ShippingMethod: in.ShippingMethod,
+		ShippingMethod: quote.Code,
+		ShippingName:   quote.Name,
 		PaymentMethod:  in.PaymentMethod,
 	}
 	addrBytes, err := json.Marshal(addr)
@@ -184,6 +272,35 @@ func (h *CheckoutHandler) Post(c *gin.Context) {
 		return
 	}
 
+	// fatura adresi: ayrı girilmediyse teslimat adresiyle aynı
+	billing := addressJSON{
+		FirstName:  addr.FirstName,
+		LastName:   addr.LastName,
+		Address1:   addr.Address1,
+		Address2:   addr.Address2,
+		City:       addr.City,
+		PostalCode: addr.PostalCode,
+		Country:    addr.Country,
+		Phone:      addr.Phone,
+	}
+	if in.UseBillingAddress {
+		billing = addressJSON{
+			FirstName:  strings.TrimSpace(in.BillingFirstName),
+			LastName:   strings.TrimSpace(in.BillingLastName),
+			Address1:   strings.TrimSpace(in.BillingAddress1),
+			Address2:   strings.TrimSpace(in.BillingAddress2),
+			City:       strings.TrimSpace(in.BillingCity),
+			PostalCode: strings.TrimSpace(in.BillingPostalCode),
+			Country:    strings.ToUpper(strings.TrimSpace(in.BillingCountry)),
+			Phone:      strings.TrimSpace(in.BillingPhone),
+		}
+	}
+	billingBytes, err := json.Marshal(billing)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
 	var userID *string
 	var guestEmail *string
 	var cartID string
@@ -241,13 +358,19 @@ func (h *CheckoutHandler) Post(c *gin.Context) {
 		UserID:              userID,
 		GuestEmail:          guestEmail,
 		IdempotencyKey:      idemKey,
-		TaxCents:            0,
+		TaxCents:            summary.TaxCents, // tax servisi varsa yeniden hesaplanır
 		ShippingCents:       shipCents,
 		DiscountCents:       0,
+		PromoCode:           in.PromoCode,
+		CustomerEmail:       customerEmail,
+		TaxCountry:          addr.Country,
+		PaymentMethod:       in.PaymentMethod,
 		ShippingAddressJSON: addrBytes,
-		BillingAddressJSON:  nil,
+		BillingAddressJSON:  billingBytes,
 		DisplayCurrency:     currency,
 		ChargeCurrency:      chargeCurrency,
+		GiftCardCodes:       giftcards.ParseCodes(in.GiftCardCodes),
+		GiftRecipientEmail:  in.GiftRecipient,
 	})
 	if err != nil {
 		log.Printf("CreateFromCart failed with error type %T: %v", err, err)
@@ -272,6 +395,17 @@ func (h *CheckoutHandler) Post(c *gin.Context) {
 			render.RedirectWithFlash(c, h.Flash, "/cart", view.FlashError, "Para birimi uyuşmazlığı.")
 			return
 		}
+		if msg, ok := promoErrorMessage(err); ok {
+			log.Printf("Checkout failed: promo code rejected - %v", err)
+			clearPromoPreview(&summary)
+			h.renderCheckoutWithErrors(c, authed, summary, currency, validation.FieldErrors{"promo_code": msg}, "", in)
+			return
+		}
+		if msg, ok := giftCardErrorMessage(err); ok {
+			log.Printf("Checkout failed: gift card rejected - %v", err)
+			h.renderCheckoutWithErrors(c, authed, summary, currency, validation.FieldErrors{"gift_card_codes": msg}, "", in)
+			return
+		}
 		log.Printf("Checkout error (unhandled): %T - %v", err, err)
 		h.renderCheckoutWithErrors(c, authed, summary, currency, nil, "Checkout başarısız. Lütfen tekrar deneyin.", in)
 		return
@@ -281,6 +415,10 @@ func (h *CheckoutHandler) Post(c *gin.Context) {
 		h.CartCK.Clear(c)
 	}
 
+	if authed && in.SaveAddress {
+		h.rememberAddresses(c, u.ID, addr, billing, in.UseBillingAddress)
+	}
+
 	// Clear session cart cache (forces refresh on next request)
 	middleware.ClearSessionCartCache(c)
 
@@ -291,10 +429,15 @@ func (h *CheckoutHandler) Post(c *gin.Context) {
 		} else {
 			emailAddr = strings.TrimSpace(in.Email)
 		}
-		h.sendOrderConfirmation(c.Request.Context(), emailAddr, res.OrderID, in.PaymentMethod, in.ShippingMethod)
+		h.sendOrderConfirmation(c.Request.Context(), emailAddr, res.OrderID, in.PaymentMethod, in.ShippingMethod, !authed)
 	}
 
-	render.RedirectWithFlash(c, h.Flash, "/orders/"+res.OrderID, view.FlashSuccess, "Sipariş oluşturuldu.")
+	// misafir: sipariş sayfası erişim anahtarıyla açılır (cookie'ye yazılır)
+	orderPath := "/orders/" + res.OrderID
+	if !authed && h.GuestTokens != nil {
+		orderPath = h.GuestTokens.URL("", res.OrderID, time.Now())
+	}
+	render.RedirectWithFlash(c, h.Flash, orderPath, view.FlashSuccess, "Sipariş oluşturuldu. Sipariş no: "+res.OrderNumber)
 }
 
 // --- helpers ---
@@ -321,34 +464,20 @@ func (h *CheckoutHandler) buildCartSummary(c *gin.Context) (view.CheckoutSummary
 		return view.CheckoutSummary{}, 0, displayCurrency, err
 	}
 
-	ship := shippingCents("standard")
-	shipDisplay := ship
-	if h.CurrencySvc != nil {
-		if converted, _, convErr := h.CurrencySvc.ConvertDisplay(c.Request.Context(), ship, cartPage.Currency); convErr == nil {
-			shipDisplay = converted
-		}
-	}
-	displayTotal := cartPage.DisplayTotalCents + shipDisplay
-	totalBase := cartPage.BaseSubtotalCents + ship
-
-	return view.CheckoutSummary{
+	// shipping is applied by the caller once the destination is known
+	summary := view.CheckoutSummary{
 		Currency:             cartPage.Currency,
 		BaseCurrency:         cartPage.BaseCurrency,
 		Subtotal:             cartPage.Subtotal,
-		Shipping:             view.MoneyFromCents(shipDisplay, cartPage.Currency),
-		Total:                view.MoneyFromCents(displayTotal, cartPage.Currency),
+		Shipping:             view.MoneyFromCents(0, cartPage.Currency),
 		Items:                cartPage.Count,
 		Lines:                cartPage.Items,
 		SubtotalCents:        cartPage.BaseSubtotalCents,
-		ShippingCents:        ship,
-		TotalCents:           totalBase,
 		BaseSubtotalCents:    cartPage.BaseSubtotalCents,
-		BaseShippingCents:    ship,
-		BaseTotalCents:       totalBase,
 		DisplaySubtotalCents: cartPage.DisplaySubtotalCents,
-		DisplayShippingCents: shipDisplay,
-		DisplayTotalCents:    displayTotal,
-	}, cartPage.Count, cartPage.Currency, nil
+	}
+	recalcSummary(&summary)
+	return summary, cartPage.Count, cartPage.Currency, nil
 }
 
 func (h *CheckoutHandler) createTempCartFromCookie(c *gin.Context, cc *cartcookie.Cart) (*cartmod.Cart, error) {
@@ -398,10 +527,24 @@ func (h *CheckoutHandler) renderCheckoutWithErrors(c *gin.Context, authed bool,
 		Phone:          in.Phone,
 		ShippingMethod: in.ShippingMethod,
 		PaymentMethod:  in.PaymentMethod,
+		PromoCode:      in.PromoCode,
+		GiftCardCodes:  in.GiftCardCodes,
+		GiftRecipient:  in.GiftRecipient,
 		IdemKey:        in.IdemKey,
+
+		UseBillingAddress: in.UseBillingAddress,
+		BillingFirstName:  in.BillingFirstName,
+		BillingLastName:   in.BillingLastName,
+		BillingAddress1:   in.BillingAddress1,
+		BillingAddress2:   in.BillingAddress2,
+		BillingCity:       in.BillingCity,
+		BillingPostalCode: in.BillingPostalCode,
+		BillingCountry:    in.BillingCountry,
+		BillingPhone:      in.BillingPhone,
+		SaveAddress:       in.SaveAddress,
 	}
-	if form.ShippingMethod == "" {
-		form.ShippingMethod = "standard"
+	if u, ok := middleware.CurrentUser(c); ok && authed {
+		form.SavedAddresses = h.savedAddresses(c, u.ID)
 	}
 	if form.PaymentMethod == "" {
 		form.PaymentMethod = "card"
@@ -410,23 +553,31 @@ func (h *CheckoutHandler) renderCheckoutWithErrors(c *gin.Context, authed bool,
 		form.IdemKey = randHex(16)
 	}
 
-	ship := shippingCents(form.ShippingMethod)
-	shipDisplay := ship
-	if h.CurrencySvc != nil {
-		if converted, _, err := h.CurrencySvc.ConvertDisplay(c.Request.Context(), ship, currency); err == nil {
-			shipDisplay = converted
+	quotes, err := h.shippingQuotes(c.Request.Context(), in.Country, summary)
+	if err != nil {
+		log.Printf("checkout: shipping quotes failed: %v", err)
+	}
+	selected := -1
+	for i, q := range quotes {
+		if q.Code == form.ShippingMethod {
+			selected = i
+			break
 		}
 	}
-	summary.Shipping = view.MoneyFromCents(shipDisplay, currency)
-	summary.ShippingCents = ship
-	summary.BaseShippingCents = ship
-	summary.DisplayShippingCents = shipDisplay
-	summary.TotalCents = summary.SubtotalCents + ship
-	displayTotal := summary.DisplaySubtotalCents + shipDisplay
-	summary.DisplayTotalCents = displayTotal
-	summary.Total = view.MoneyFromCents(displayTotal, currency)
+	if selected < 0 && len(quotes) > 0 {
+		selected = 0
+		form.ShippingMethod = quotes[0].Code
+	}
+	if selected >= 0 {
+		h.applyShipping(c.Request.Context(), &summary, quotes[selected])
+	} else {
+		h.applyShipping(c.Request.Context(), &summary, shipping.RateQuote{})
+	}
+	if err := h.applyTaxPreview(c.Request.Context(), &summary, in.Country); err != nil {
+		log.Printf("checkout: tax preview failed: %v", err)
+	}
 
-	opts := shippingOptions(c.Request.Context(), h.CurrencySvc, currency)
+	opts := h.shippingOptions(c.Request.Context(), quotes, currency)
 	payments := paymentOptions()
 
 	render.Component(c, http.StatusBadRequest, pages.Checkout(
@@ -442,30 +593,180 @@ func (h *CheckoutHandler) renderCheckoutWithErrors(c *gin.Context, authed bool,
 	))
 }
 
-func shippingOptions(ctx context.Context, currSvc *currency.Service, currency string) []view.ShippingOption {
-	return []view.ShippingOption{
-		{Code: "standard", Label: "Standard (2-4 gün)", Price: formatShippingPrice(ctx, currSvc, shippingCents("standard"), currency)},
-		{Code: "express", Label: "Express (1-2 gün)", Price: formatShippingPrice(ctx, currSvc, shippingCents("express"), currency)},
+// applyPromoPreview validates the code against the current cart and folds the
+// discount into the summary. CreateFromCart re-validates it under lock.
+func (h *CheckoutHandler) applyPromoPreview(ctx context.Context, summary *view.CheckoutSummary, code string, userID *string, email string) error {
+	if h.PromoSvc == nil {
+		return nil
+	}
+	lines := make([]promotions.VariantLine, 0, len(summary.Lines))
+	for _, l := range summary.Lines {
+		lines = append(lines, promotions.VariantLine{VariantID: l.VariantID, LineCents: l.BaseLineTotalCents})
+	}
+	res, err := h.PromoSvc.Quote(ctx, code, userID, email, summary.BaseCurrency, lines)
+	if err != nil {
+		return err
+	}
+
+	display := res.DiscountCents
+	if h.CurrencySvc != nil {
+		if converted, _, convErr := h.CurrencySvc.ConvertDisplay(ctx, res.DiscountCents, summary.Currency); convErr == nil {
+			display = converted
+		}
 	}
+	summary.PromoCode = res.Promotion.Code
+	summary.DiscountCents = res.DiscountCents
+	summary.DisplayDiscountCents = display
+	summary.Discount = view.MoneyFromCents(display, summary.Currency)
+	recalcSummary(summary)
+	return nil
 }
 
-func formatShippingPrice(ctx context.Context, currSvc *currency.Service, base int, currency string) string {
-	amount := base
-	if currSvc != nil {
-		if converted, _, err := currSvc.ConvertDisplay(ctx, base, currency); err == nil {
-			amount = converted
+func clearPromoPreview(summary *view.CheckoutSummary) {
+	summary.PromoCode = ""
+	summary.Discount = ""
+	summary.DiscountCents = 0
+	summary.DisplayDiscountCents = 0
+	recalcSummary(summary)
+}
+
+// recalcSummary derives the base and display totals from subtotal, shipping,
+// discount and, with net prices, the VAT on top.
+func recalcSummary(summary *view.CheckoutSummary) {
+	summary.TotalCents = summary.SubtotalCents + summary.ShippingCents - summary.DiscountCents
+	summary.DisplayTotalCents = summary.DisplaySubtotalCents + summary.DisplayShippingCents - summary.DisplayDiscountCents
+	if !summary.PricesIncludeTax {
+		summary.TotalCents += summary.TaxCents
+		summary.DisplayTotalCents += summary.DisplayTaxCents
+	}
+	summary.BaseTotalCents = summary.TotalCents
+	summary.Total = view.MoneyFromCents(summary.DisplayTotalCents, summary.Currency)
+}
+
+// applyTaxPreview runs the tax engine on the cart for the shipping country,
+// after shipping and promo are applied, so the total shown is the total
+// CreateFromCart charges. Shipping is not taxed in this shop.
+func (h *CheckoutHandler) applyTaxPreview(ctx context.Context, summary *view.CheckoutSummary, country string) error {
+	if h.OrderSv == nil {
+		return nil
+	}
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "" && h.RateSvc != nil {
+		country = h.RateSvc.DefaultCountry()
+	}
+	lines := make([]orders.TaxPreviewLine, 0, len(summary.Lines))
+	for _, l := range summary.Lines {
+		lines = append(lines, orders.TaxPreviewLine{VariantID: l.VariantID, AmountCents: l.BaseLineTotalCents})
+	}
+	res, err := h.OrderSv.PreviewTax(ctx, country, lines, summary.DiscountCents)
+	if err != nil {
+		return err
+	}
+
+	byRate := map[int]int{}
+	for _, lt := range res.Lines {
+		if lt.TaxCents != 0 {
+			byRate[lt.RateBps] += lt.TaxCents
 		}
 	}
-	return view.MoneyFromCents(amount, currency)
+	rates := make([]int, 0, len(byRate))
+	for bps := range byRate {
+		rates = append(rates, bps)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rates)))
+
+	summary.PricesIncludeTax = res.PricesIncludeTax
+	summary.TaxCents = res.TaxCents
+	summary.DisplayTaxCents = h.displayCents(ctx, res.TaxCents, summary.Currency)
+	summary.Tax = view.MoneyFromCents(summary.DisplayTaxCents, summary.Currency)
+	summary.TaxLines = summary.TaxLines[:0]
+	for _, bps := range rates {
+		summary.TaxLines = append(summary.TaxLines, view.CheckoutTaxLine{
+			Label:  view.TaxLabel(res.PricesIncludeTax) + " " + tax.FormatRate(bps),
+			Amount: view.MoneyFromCents(h.displayCents(ctx, byRate[bps], summary.Currency), summary.Currency),
+		})
+	}
+	recalcSummary(summary)
+	return nil
+}
+
+func promoErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, promotions.ErrCodeNotFound), errors.Is(err, promotions.ErrCodeInactive):
+		return "Kupon kodu geçersiz.", true
+	case errors.Is(err, promotions.ErrCodeExpired):
+		return "Kupon kodunun geçerlilik süresi dolmuş.", true
+	case errors.Is(err, promotions.ErrUsageLimitReached):
+		return "Kupon kodu kullanım limitine ulaştı.", true
+	case errors.Is(err, promotions.ErrCustomerLimitReached):
+		return "Bu kupon kodunu daha önce kullandınız.", true
+	case errors.Is(err, promotions.ErrMinSubtotalNotMet):
+		return "Sepet tutarı bu kupon için yeterli değil.", true
+	case errors.Is(err, promotions.ErrNotApplicable):
+		return "Kupon kodu sepetinizdeki ürünler için geçerli değil.", true
+	}
+	return "", false
+}
+
+func giftCardErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, giftcards.ErrCardNotFound):
+		return "Hediye kartı kodu geçersiz.", true
+	case errors.Is(err, giftcards.ErrCardInactive):
+		return "Hediye kartı aktif değil.", true
+	case errors.Is(err, giftcards.ErrCardEmpty):
+		return "Hediye kartının bakiyesi yok.", true
+	case errors.Is(err, giftcards.ErrCurrencyMismatch):
+		return "Hediye kartı para birimi siparişle uyuşmuyor.", true
+	}
+	return "", false
 }
 
-func shippingCents(method string) int {
-	switch method {
-	case "express":
-		return 1500
-	default:
-		return 500
+func rateCart(summary view.CheckoutSummary) shipping.RateCart {
+	lines := make([]shipping.RateLine, 0, len(summary.Lines))
+	for _, l := range summary.Lines {
+		lines = append(lines, shipping.RateLine{VariantID: l.VariantID, Qty: l.Qty})
 	}
+	return shipping.RateCart{SubtotalCents: summary.BaseSubtotalCents, DiscountCents: summary.DiscountCents, Lines: lines}
+}
+
+func (h *CheckoutHandler) shippingQuotes(ctx context.Context, country string, summary view.CheckoutSummary) ([]shipping.RateQuote, error) {
+	if h.RateSvc == nil {
+		return nil, nil
+	}
+	return h.RateSvc.Quote(ctx, country, rateCart(summary))
+}
+
+// applyShipping stores the base price and its display conversion on the summary.
+func (h *CheckoutHandler) applyShipping(ctx context.Context, summary *view.CheckoutSummary, q shipping.RateQuote) {
+	display := h.displayCents(ctx, q.Cents, summary.Currency)
+	summary.ShippingCents = q.Cents
+	summary.BaseShippingCents = q.Cents
+	summary.DisplayShippingCents = display
+	summary.Shipping = view.MoneyFromCents(display, summary.Currency)
+	recalcSummary(summary)
+}
+
+func (h *CheckoutHandler) shippingOptions(ctx context.Context, quotes []shipping.RateQuote, currency string) []view.ShippingOption {
+	out := make([]view.ShippingOption, 0, len(quotes))
+	for _, q := range quotes {
+		price := view.MoneyFromCents(h.displayCents(ctx, q.Cents, currency), currency)
+		if q.Free {
+			price = "Ücretsiz"
+		}
+		out = append(out, view.ShippingOption{Code: q.Code, Label: q.Name, Price: price})
+	}
+	return out
+}
+
+func (h *CheckoutHandler) displayCents(ctx context.Context, base int, currency string) int {
+	if h.CurrencySvc == nil || base == 0 {
+		return base
+	}
+	if converted, _, err := h.CurrencySvc.ConvertDisplay(ctx, base, currency); err == nil {
+		return converted
+	}
+	return base
 }
 
 func paymentOptions() []view.PaymentOption {
@@ -476,7 +777,7 @@ func paymentOptions() []view.PaymentOption {
 	}
 }
 
-func (h *CheckoutHandler) sendOrderConfirmation(ctx context.Context, emailAddr string, orderID string, paymentMethod, shippingMethod string) {
+func (h *CheckoutHandler) sendOrderConfirmation(ctx context.Context, emailAddr string, orderID string, paymentMethod, shippingMethod string, guest bool) {
 	if h.EmailSv == nil {
 		return
 	}
@@ -505,6 +806,9 @@ func (h *CheckoutHandler) sendOrderConfirmation(ctx context.Context, emailAddr s
 
 	payload := emails.BuildOrderPayload(h.BaseURL, order, items, "Paid", "")
 	payload["PreviewText"] = "Payment received - here are your order details."
+	if guest && h.GuestTokens != nil {
+		payload["OrderURL"] = h.GuestTokens.URL(h.BaseURL, order.ID, time.Now())
+	}
 
 	if err := h.EmailSv.Enqueue(ctx, emailmod.Job{
 		To:       to,
@@ -514,3 +818,92 @@ func (h *CheckoutHandler) sendOrderConfirmation(ctx context.Context, emailAddr s
 		log.Printf("checkout: failed to enqueue confirmation email: %v", err)
 	}
 }
+
+// savedAddresses loads the customer's address book for the checkout picker.
+func (h *CheckoutHandler) savedAddresses(c *gin.Context, userID string) []view.SavedAddress {
+	list, err := h.Addresses.List(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("checkout: address book load failed: %v", err)
+		return nil
+	}
+	out := make([]view.SavedAddress, 0, len(list))
+	for _, a := range list {
+		out = append(out, view.SavedAddress{
+			ID:         a.ID,
+			Label:      a.Label,
+			Summary:    a.OneLine(),
+			IsDefault:  a.IsDefault,
+			FirstName:  a.FirstName,
+			LastName:   a.LastName,
+			Address1:   a.Address1,
+			Address2:   a.Address2,
+			City:       a.City,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+			Phone:      a.Phone,
+		})
+	}
+	return out
+}
+
+// pickSavedAddress returns the requested address, or the default one.
+func pickSavedAddress(list []view.SavedAddress, id string) (view.SavedAddress, bool) {
+	for _, a := range list {
+		if id != "" && a.ID == id {
+			return a, true
+		}
+	}
+	for _, a := range list {
+		if a.IsDefault {
+			return a, true
+		}
+	}
+	return view.SavedAddress{}, false
+}
+
+// applySavedAddress prefills the shipping fields; an explicit ?country= wins.
+func applySavedAddress(form *view.CheckoutForm, a view.SavedAddress, country string) {
+	if a.FirstName != "" {
+		form.FirstName = a.FirstName
+	}
+	if a.LastName != "" {
+		form.LastName = a.LastName
+	}
+	form.Address1 = a.Address1
+	form.Address2 = a.Address2
+	form.City = a.City
+	form.PostalCode = a.PostalCode
+	if a.Phone != "" {
+		form.Phone = a.Phone
+	}
+	form.Country = country
+	if form.Country == "" {
+		form.Country = a.Country
+	}
+}
+
+// rememberAddresses stores the checkout addresses in the address book. A
+// failure here must not fail the already created order.
+func (h *CheckoutHandler) rememberAddresses(c *gin.Context, userID string, ship, billing addressJSON, separateBilling bool) {
+	toInput := func(a addressJSON) addresses.Input {
+		return addresses.Input{
+			FirstName:  a.FirstName,
+			LastName:   a.LastName,
+			Address1:   a.Address1,
+			Address2:   a.Address2,
+			City:       a.City,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+			Phone:      a.Phone,
+		}
+	}
+	if _, err := h.Addresses.Remember(c.Request.Context(), userID, toInput(ship)); err != nil {
+		log.Printf("checkout: save shipping address failed: %v", err)
+	}
+	if !separateBilling {
+		return
+	}
+	if _, err := h.Addresses.Remember(c.Request.Context(), userID, toInput(billing)); err != nil {
+		log.Printf("checkout: save billing address failed: %v", err)
+	}
+}
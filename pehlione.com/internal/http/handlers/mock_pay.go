@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/shared/apperr"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+// MockPayHandler simulates a provider-hosted payment page (3-D Secure) for
+// the mock provider, so the redirect flow works locally end to end.
+type MockPayHandler struct {
+	Provider payments.MockProvider
+	BaseURL  string
+}
+
+func NewMockPayHandler(p payments.MockProvider, baseURL string) *MockPayHandler {
+	return &MockPayHandler{Provider: p, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// GET /mock-pay/:ref
+func (h *MockPayHandler) Get(c *gin.Context) {
+	returnURL, cancelURL, ok := h.callbackURLs(c.Query("return_url"), c.Query("cancel_url"))
+	if !ok {
+		middleware.Fail(c, apperr.InvalidErr("Geçersiz dönüş adresi.", nil))
+		return
+	}
+
+	amount, _ := strconv.Atoi(c.Query("amount_cents"))
+	render.Component(c, http.StatusOK, pages.MockPay(middleware.GetCSRFToken(c), view.MockPayPage{
+		PaymentRef: c.Param("ref"),
+		Amount:     view.MoneyFromCents(amount, c.Query("currency")),
+		Capture:    c.Query("capture"),
+		ReturnURL:  returnURL,
+		CancelURL:  cancelURL,
+	}))
+}
+
+// POST /mock-pay/:ref  decision=approve|decline|cancel
+func (h *MockPayHandler) Post(c *gin.Context) {
+	returnURL, cancelURL, ok := h.callbackURLs(c.PostForm("return_url"), c.PostForm("cancel_url"))
+	if !ok {
+		middleware.Fail(c, apperr.InvalidErr("Geçersiz dönüş adresi.", nil))
+		return
+	}
+	ref := c.Param("ref")
+
+	switch c.PostForm("decision") {
+	case "approve":
+		status := payments.StatusSucceeded
+		if c.PostForm("capture") == payments.CaptureManual {
+			status = payments.StatusAuthorized
+		}
+		c.Redirect(http.StatusSeeOther, h.Provider.HostedResultURL(returnURL, ref, status))
+	case "decline":
+		c.Redirect(http.StatusSeeOther, h.Provider.HostedResultURL(returnURL, ref, payments.StatusFailed))
+	default:
+		c.Redirect(http.StatusSeeOther, cancelURL)
+	}
+}
+
+// callbackURLs only allows redirects back to this app (no open redirect).
+func (h *MockPayHandler) callbackURLs(returnURL, cancelURL string) (string, string, bool) {
+	prefix := h.BaseURL + "/"
+	if !strings.HasPrefix(returnURL, prefix) || !strings.HasPrefix(cancelURL, prefix) {
+		return "", "", false
+	}
+	return returnURL, cancelURL, true
+}
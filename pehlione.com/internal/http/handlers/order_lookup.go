@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"pehlione.com/app/internal/http/flash"
+	"pehlione.com/app/internal/http/middleware"
+	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/http/validation"
+	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/pkg/view"
+	"pehlione.com/app/templates/pages"
+)
+
+// OrderLookupHandler lets guests request a fresh access link for an order.
+type OrderLookupHandler struct {
+	Repo    *orders.Repo
+	Guest   *orders.GuestTokens
+	EmailSv *emailmod.OutboxService
+	Flash   *flash.Codec
+	BaseURL string
+}
+
+func NewOrderLookupHandler(repo *orders.Repo, guest *orders.GuestTokens, emailSvc *emailmod.OutboxService, fl *flash.Codec, baseURL string) *OrderLookupHandler {
+	return &OrderLookupHandler{Repo: repo, Guest: guest, EmailSv: emailSvc, Flash: fl, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+type orderLookupInput struct {
+	Email       string `form:"email" binding:"required,email,max=255"`
+	OrderNumber string `form:"order_number" binding:"required,min=8,max=64"`
+}
+
+func (h *OrderLookupHandler) Get(c *gin.Context) {
+	render.Component(c, http.StatusOK, pages.OrderLookup(middleware.GetFlash(c), middleware.GetCSRFToken(c), "", "", nil))
+}
+
+// Post emails a new link when email and order number match a guest order.
+// The answer is the same either way, so the form can't be used to probe
+// which addresses ordered.
+func (h *OrderLookupHandler) Post(c *gin.Context) {
+	var in orderLookupInput
+	if err := c.ShouldBind(&in); err != nil {
+		render.Component(c, http.StatusBadRequest, pages.OrderLookup(middleware.GetFlash(c), middleware.GetCSRFToken(c), in.Email, in.OrderNumber, validation.FromBindError(err, &in)))
+		return
+	}
+
+	o, err := h.Repo.FindGuestOrder(c.Request.Context(), in.Email, in.OrderNumber)
+	switch {
+	case err == nil:
+		h.sendAccessLink(c, o)
+	case errors.Is(err, orders.ErrOrderNotFound):
+		log.Printf("order lookup: no guest order for %q", in.OrderNumber)
+	default:
+		log.Printf("order lookup: %v", err)
+	}
+
+	render.RedirectWithFlash(c, h.Flash, "/orders/lookup", view.FlashInfo, "Bilgiler bir siparişle eşleşiyorsa, sipariş bağlantısı e-posta adresinize gönderildi.")
+}
+
+func (h *OrderLookupHandler) sendAccessLink(c *gin.Context, o orders.Order) {
+	if h.EmailSv == nil || o.GuestEmail == nil {
+		return
+	}
+	now := time.Now()
+	if err := h.EmailSv.Enqueue(c.Request.Context(), emailmod.Job{
+		To:       *o.GuestEmail,
+		Template: emailmod.TemplateOrderAccess,
+		Payload: map[string]any{
+			"OrderID":     o.ID,
+			"OrderNumber": o.Number(),
+			"OrderURL":    h.Guest.URL(h.BaseURL, o.ID, now),
+			"ExpiresAt":   now.Add(h.Guest.TTL()).Format("02.01.2006"),
+		},
+	}); err != nil {
+		log.Printf("order lookup: enqueue access email failed: %v", err)
+	}
+}
@@ -6,16 +6,19 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"pehlione.com/app/internal/einvoice"
 	"pehlione.com/app/internal/http/flash"
 	"pehlione.com/app/internal/http/middleware"
 	"pehlione.com/app/internal/http/render"
 	"pehlione.com/app/internal/modules/orders"
 	"pehlione.com/app/internal/modules/payments"
 	"pehlione.com/app/internal/modules/shipping"
+	"pehlione.com/app/internal/modules/tax"
 	"pehlione.com/app/internal/pdf"
 	"pehlione.com/app/internal/shared/apperr"
 	"pehlione.com/app/pkg/view"
@@ -23,13 +26,75 @@ import (
 )
 
 type OrdersHandler struct {
-	DB     *gorm.DB
-	Flash  *flash.Codec
-	PaySvc *payments.Service
+	DB      *gorm.DB
+	Flash   *flash.Codec
+	PaySvc  *payments.Service
+	Guest   *orders.GuestTokens
+	BaseURL string
+	Seller  einvoice.Seller
 }
 
-func NewOrdersHandler(db *gorm.DB, fl *flash.Codec, pay *payments.Service) *OrdersHandler {
-	return &OrdersHandler{DB: db, Flash: fl, PaySvc: pay}
+func NewOrdersHandler(db *gorm.DB, fl *flash.Codec, pay *payments.Service, guest *orders.GuestTokens, baseURL string) *OrdersHandler {
+	return &OrdersHandler{DB: db, Flash: fl, PaySvc: pay, Guest: guest, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// SetSeller sets the invoicing company printed on e-invoices.
+func (h *OrdersHandler) SetSeller(s einvoice.Seller) {
+	h.Seller = s
+}
+
+// guestOrderCookie keeps the guest access token for /orders/:id/* once the
+// emailed link was opened, so pay/return/invoice links work without it.
+const guestOrderCookie = "pehlione_order_access"
+
+// authorizeView lets the owner, admins and guests holding a valid access
+// token see an order. It writes the error response when access is denied.
+func (h *OrdersHandler) authorizeView(c *gin.Context, o orders.Order) bool {
+	if u, ok := middleware.CurrentUser(c); ok && (u.Role == "admin" || (o.UserID != nil && u.ID == *o.UserID)) {
+		return true
+	}
+	if o.UserID != nil {
+		middleware.Fail(c, apperr.ForbiddenErr("Bu siparişe erişim yok."))
+		return false
+	}
+	return h.guestAccess(c, o)
+}
+
+// authorizePay: account orders are paid by their owner only, guest orders
+// with an access token.
+func (h *OrdersHandler) authorizePay(c *gin.Context, o orders.Order) (actor *string, ok bool) {
+	if o.UserID == nil {
+		return nil, h.guestAccess(c, o)
+	}
+	u, authed := middleware.CurrentUser(c)
+	if !authed || u.ID != *o.UserID {
+		middleware.Fail(c, apperr.ForbiddenErr("Erişim yok."))
+		return nil, false
+	}
+	return &u.ID, true
+}
+
+func (h *OrdersHandler) guestAccess(c *gin.Context, o orders.Order) bool {
+	token := strings.TrimSpace(c.Query("access"))
+	fromLink := token != ""
+	if !fromLink {
+		token, _ = c.Cookie(guestOrderCookie)
+	}
+
+	if err := h.Guest.Verify(o.ID, token, time.Now()); err != nil {
+		msg := "Siparişinizi görüntülemek için e-posta adresiniz ve sipariş numaranızla yeni bir bağlantı isteyin."
+		if errors.Is(err, orders.ErrGuestTokenExpired) {
+			msg = "Sipariş bağlantınızın süresi dolmuş. Yeni bir bağlantı isteyin."
+		}
+		render.RedirectWithFlash(c, h.Flash, "/orders/lookup", view.FlashWarning, msg)
+		return false
+	}
+
+	if fromLink {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(guestOrderCookie, token, int(h.Guest.TTL().Seconds()), "/orders/"+o.ID, "", strings.HasPrefix(h.BaseURL, "https://"), true)
+	}
+	return true
 }
 
 func (h *OrdersHandler) Detail(c *gin.Context) {
@@ -40,17 +105,27 @@ func (h *OrdersHandler) Detail(c *gin.Context) {
 		middleware.Fail(c, apperr.NotFoundErr("Sipariş bulunamadı."))
 		return
 	}
+	if !h.authorizeView(c, o) {
+		return
+	}
 
 	vm := view.OrderDetail{
 		ID:       o.ID,
+		Number:   o.Number(),
+		Invoice:  strOrEmpty(o.InvoiceNumber),
 		Status:   o.Status,
 		Currency: o.Currency,
 		Subtotal: view.MoneyFromCents(o.SubtotalCents, o.Currency),
 		Shipping: view.MoneyFromCents(o.ShippingCents, o.Currency),
 		Tax:      view.MoneyFromCents(o.TaxCents, o.Currency),
+		TaxLabel: view.TaxLabel(o.PricesIncludeTax),
 		Discount: view.MoneyFromCents(o.DiscountCents, o.Currency),
 		Total:    view.MoneyFromCents(o.TotalCents, o.Currency),
 	}
+	if o.GiftCardCents > 0 {
+		vm.GiftCard = view.MoneyFromCents(o.GiftCardCents, o.Currency)
+		vm.AmountDue = view.MoneyFromCents(o.AmountDueCents(), o.Currency)
+	}
 
 	for _, it := range items {
 		vm.Items = append(vm.Items, view.OrderItem{
@@ -60,6 +135,8 @@ func (h *OrdersHandler) Detail(c *gin.Context) {
 			Qty:         it.Quantity,
 			PriceEach:   view.MoneyFromCents(it.UnitPriceCents, it.Currency),
 			LineTotal:   view.MoneyFromCents(it.LineTotalCents, it.Currency),
+			TaxRate:     tax.FormatRate(it.TaxRateBps),
+			Tax:         view.MoneyFromCents(it.TaxCents, it.Currency),
 		})
 	}
 
@@ -75,6 +152,27 @@ func (h *OrdersHandler) Detail(c *gin.Context) {
 		}
 	}
 
+	if p, err := h.PaySvc.LatestPayment(c.Request.Context(), id); err == nil && p != nil {
+		vm.PaymentState = paymentState(p.Status)
+	}
+
+	if refunds, _, err := payments.ListRefunds(c.Request.Context(), h.DB, id); err == nil {
+		for _, r := range refunds {
+			if r.CreditNoteNumber == nil {
+				continue
+			}
+			cn := view.OrderCreditNote{
+				RefundID: r.ID,
+				Number:   *r.CreditNoteNumber,
+				Amount:   view.MoneyFromCents(r.AmountCents, r.Currency),
+			}
+			if r.CreditNoteIssuedAt != nil {
+				cn.Date = r.CreditNoteIssuedAt.Format("02.01.2006")
+			}
+			vm.CreditNotes = append(vm.CreditNotes, cn)
+		}
+	}
+
 	render.Component(c, http.StatusOK, pages.OrderDetail(
 		middleware.GetFlash(c),
 		vm,
@@ -90,13 +188,8 @@ func (h *OrdersHandler) PayGet(c *gin.Context) {
 		return
 	}
 
-	// Eğer user order ise auth zorunlu
-	if o.UserID != nil {
-		u, ok := middleware.CurrentUser(c)
-		if !ok || u.ID != *o.UserID {
-			middleware.Fail(c, apperr.ForbiddenErr("Erişim yok."))
-			return
-		}
+	if _, ok := h.authorizePay(c, o); !ok {
+		return
 	}
 
 	if o.Status != "created" {
@@ -109,7 +202,7 @@ func (h *OrdersHandler) PayGet(c *gin.Context) {
 		middleware.GetFlash(c),
 		middleware.GetCSRFToken(c),
 		o.ID,
-		view.MoneyFromCents(o.TotalCents, o.Currency),
+		view.MoneyFromCents(o.AmountDueCents(), o.Currency),
 		idem,
 	))
 }
@@ -124,22 +217,17 @@ func (h *OrdersHandler) PayPost(c *gin.Context) {
 		return
 	}
 
-	var actor *string
-	if o.UserID != nil {
-		u, ok := middleware.CurrentUser(c)
-		if !ok || u.ID != *o.UserID {
-			middleware.Fail(c, apperr.ForbiddenErr("Erişim yok."))
-			return
-		}
-		actor = &u.ID
+	actor, ok := h.authorizePay(c, o)
+	if !ok {
+		return
 	}
 
 	res, err := h.PaySvc.PayOrder(c.Request.Context(), payments.PayOrderInput{
 		OrderID:        o.ID,
 		ActorUserID:    actor,
 		IdempotencyKey: idem,
-		ReturnURL:      "/orders/" + o.ID,
-		CancelURL:      "/orders/" + o.ID,
+		ReturnURL:      h.BaseURL + "/orders/" + o.ID + "/pay/return",
+		CancelURL:      h.BaseURL + "/orders/" + o.ID + "/pay/cancel",
 	})
 	if err != nil {
 		if errors.Is(err, payments.ErrOrderNotPayable) {
@@ -150,12 +238,82 @@ func (h *OrdersHandler) PayPost(c *gin.Context) {
 		return
 	}
 
-	if res.Status == payments.StatusSucceeded {
-		render.RedirectWithFlash(c, h.Flash, "/orders/"+o.ID, view.FlashSuccess, "Ödeme başarılı. Sipariş ödendi.")
+	if res.Status == payments.StatusRequiresRedirect {
+		// 3-D Secure / hosted page: tarayıcı sağlayıcıya gider
+		c.Redirect(http.StatusSeeOther, res.RedirectURL)
 		return
 	}
 
-	render.RedirectWithFlash(c, h.Flash, "/orders/"+o.ID, view.FlashError, "Ödeme başarısız.")
+	h.redirectWithPaymentStatus(c, o.ID, res.Status)
+}
+
+// PayReturn handles the provider ReturnURL callback.
+func (h *OrdersHandler) PayReturn(c *gin.Context) {
+	id := c.Param("id")
+
+	status, err := h.PaySvc.CompleteReturn(c.Request.Context(), payments.ReturnInput{
+		OrderID: id,
+		Token:   c.Query("token"),
+		Query:   c.Request.URL.Query(),
+	})
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidReturn) {
+			render.RedirectWithFlash(c, h.Flash, "/orders/"+id, view.FlashError, "Ödeme dönüşü doğrulanamadı.")
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	h.redirectWithPaymentStatus(c, id, status)
+}
+
+// PayCancel handles the provider CancelURL callback.
+func (h *OrdersHandler) PayCancel(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.PaySvc.CancelReturn(c.Request.Context(), payments.ReturnInput{
+		OrderID: id,
+		Token:   c.Query("token"),
+	})
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidReturn) {
+			render.RedirectWithFlash(c, h.Flash, "/orders/"+id, view.FlashError, "Ödeme dönüşü doğrulanamadı.")
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	render.RedirectWithFlash(c, h.Flash, "/orders/"+id, view.FlashWarning, "Ödeme iptal edildi. Tekrar deneyebilirsiniz.")
+}
+
+func (h *OrdersHandler) redirectWithPaymentStatus(c *gin.Context, orderID, status string) {
+	back := "/orders/" + orderID
+	switch status {
+	case payments.StatusSucceeded:
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashSuccess, "Ödeme başarılı. Sipariş ödendi.")
+	case payments.StatusAuthorized:
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashSuccess, "Ödeme onaylandı. Tutar siparişiniz kargoya verildiğinde tahsil edilecek.")
+	case payments.StatusInitiated:
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashInfo, "Ödemeniz işleniyor. Onaylandığında sipariş durumu güncellenecek.")
+	default:
+		render.RedirectWithFlash(c, h.Flash, back, view.FlashError, "Ödeme başarısız.")
+	}
+}
+
+// paymentState maps a payment status to what the order page shows.
+func paymentState(status string) string {
+	switch status {
+	case payments.StatusInitiated, payments.StatusRequiresRedirect:
+		return "pending"
+	case payments.StatusAuthorized, payments.StatusSucceeded:
+		return "confirmed"
+	case payments.StatusFailed, payments.StatusVoided:
+		return "failed"
+	default:
+		return ""
+	}
 }
 
 func (h *OrdersHandler) InvoicePDF(c *gin.Context) {
@@ -168,12 +326,8 @@ func (h *OrdersHandler) InvoicePDF(c *gin.Context) {
 		return
 	}
 
-	if o.UserID != nil {
-		u, ok := middleware.CurrentUser(c)
-		if !ok || (u.ID != *o.UserID && u.Role != "admin") {
-			middleware.Fail(c, apperr.ForbiddenErr("Bu siparişe erişim yok."))
-			return
-		}
+	if !h.authorizeView(c, o) {
+		return
 	}
 
 	addr := parseOrderAddress(o.ShippingAddressJSON)
@@ -181,7 +335,7 @@ func (h *OrdersHandler) InvoicePDF(c *gin.Context) {
 		Order:          o,
 		Items:          items,
 		ShippingLines:  formatOrderAddressLines(addr),
-		ShippingMethod: view.ShippingLabel(addr.ShippingMethod),
+		ShippingMethod: addr.shippingLabel(),
 		PaymentMethod:  view.PaymentMethodLabel(addr.PaymentMethod),
 	}
 
@@ -191,12 +345,140 @@ func (h *OrdersHandler) InvoicePDF(c *gin.Context) {
 		return
 	}
 
-	filename := fmt.Sprintf("pehlione-order-%s.pdf", o.ID)
+	filename := fmt.Sprintf("pehlione-order-%s.pdf", o.Number())
+	if o.InvoiceNumber != nil {
+		filename = fmt.Sprintf("pehlione-invoice-%s.pdf", *o.InvoiceNumber)
+	}
 	c.Header("Content-Type", "application/pdf")
 	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
 	c.Writer.Write(bytes)
 }
 
+// CreditNotePDF downloads the credit note of a refund; admins use the same link.
+func (h *OrdersHandler) CreditNotePDF(c *gin.Context) {
+	id := c.Param("id")
+
+	o, _, err := orders.NewRepo(h.DB).GetWithItems(c.Request.Context(), id)
+	if err != nil {
+		middleware.Fail(c, apperr.NotFoundErr("Sipariş bulunamadı."))
+		return
+	}
+	if !h.authorizeView(c, o) {
+		return
+	}
+
+	data, err := payments.CreditNote(c.Request.Context(), h.DB, o.ID, c.Param("refund_id"))
+	if err != nil {
+		if errors.Is(err, payments.ErrCreditNoteNotFound) {
+			middleware.Fail(c, apperr.NotFoundErr("İade dekontu bulunamadı."))
+			return
+		}
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	bytes, err := pdf.GenerateCreditNote(data)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", `attachment; filename="`+data.Filename()+`"`)
+	c.Writer.Write(bytes)
+}
+
+// EInvoiceXML downloads the UBL 2.1 e-invoice. The profile follows the buyer
+// country (UBL-TR for Turkey, EN 16931 otherwise) unless ?profile= is given.
+func (h *OrdersHandler) EInvoiceXML(c *gin.Context) {
+	in, ok := h.eInvoiceInput(c)
+	if !ok {
+		return
+	}
+	profile, ok := einvoice.ParseProfile(c.Query("profile"))
+	if !ok {
+		profile = einvoice.ProfileFor(in.Country())
+	}
+
+	out, err := einvoice.Build(h.Seller, in, profile)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+in.Filename(profile)+`"`)
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", out)
+}
+
+// InvoicePDFA downloads the PDF/A-3 invoice with the EN 16931 XML embedded,
+// the hybrid format EU buyers can book automatically.
+func (h *OrdersHandler) InvoicePDFA(c *gin.Context) {
+	in, ok := h.eInvoiceInput(c)
+	if !ok {
+		return
+	}
+	profile, ok := einvoice.ParseProfile(c.Query("profile"))
+	if !ok {
+		profile = einvoice.ProfileEN16931
+	}
+
+	xmlData, err := einvoice.Build(h.Seller, in, profile)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	addr := parseOrderAddress(in.Order.ShippingAddressJSON)
+	out, err := pdf.GenerateHybridInvoice(pdf.InvoiceData{
+		Order:          in.Order,
+		Items:          in.Items,
+		ShippingLines:  formatOrderAddressLines(addr),
+		ShippingMethod: addr.shippingLabel(),
+		PaymentMethod:  view.PaymentMethodLabel(addr.PaymentMethod),
+	}, in.Filename(profile), xmlData)
+	if err != nil {
+		middleware.Fail(c, apperr.Wrap(err))
+		return
+	}
+
+	filename := fmt.Sprintf("pehlione-invoice-%s-pdfa.pdf", *in.Order.InvoiceNumber)
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Writer.Write(out)
+}
+
+// eInvoiceInput loads an invoiced order the viewer may see.
+func (h *OrdersHandler) eInvoiceInput(c *gin.Context) (einvoice.Input, bool) {
+	ctx := c.Request.Context()
+	o, items, err := orders.NewRepo(h.DB).GetWithItems(ctx, c.Param("id"))
+	if err != nil {
+		middleware.Fail(c, apperr.NotFoundErr("Sipariş bulunamadı."))
+		return einvoice.Input{}, false
+	}
+	if !h.authorizeView(c, o) {
+		return einvoice.Input{}, false
+	}
+	if o.InvoiceNumber == nil || *o.InvoiceNumber == "" {
+		middleware.Fail(c, apperr.NotFoundErr("Bu sipariş için henüz fatura kesilmedi."))
+		return einvoice.Input{}, false
+	}
+	return einvoice.Input{Order: o, Items: items, BuyerEmail: h.orderEmail(c, o)}, true
+}
+
+func (h *OrdersHandler) orderEmail(c *gin.Context, o orders.Order) string {
+	if o.GuestEmail != nil && *o.GuestEmail != "" {
+		return *o.GuestEmail
+	}
+	if o.UserID == nil {
+		return ""
+	}
+	var email string
+	if err := h.DB.WithContext(c.Request.Context()).Table("users").Select("email").Where("id = ?", *o.UserID).Take(&email).Error; err != nil {
+		return ""
+	}
+	return email
+}
+
 type orderAddress struct {
 	FirstName      string `json:"first_name"`
 	LastName       string `json:"last_name"`
@@ -207,9 +489,18 @@ type orderAddress struct {
 	Country        string `json:"country"`
 	Phone          string `json:"phone"`
 	ShippingMethod string `json:"shipping_method"`
+	ShippingName   string `json:"shipping_method_name"`
 	PaymentMethod  string `json:"payment_method"`
 }
 
+// shippingLabel prefers the method name captured at checkout over the code.
+func (a orderAddress) shippingLabel() string {
+	if a.ShippingName != "" {
+		return a.ShippingName
+	}
+	return view.ShippingLabel(a.ShippingMethod)
+}
+
 func parseOrderAddress(data []byte) orderAddress {
 	var addr orderAddress
 	if len(data) == 0 {
@@ -12,32 +12,38 @@ import (
 
 type WebhookHandler struct {
 	Logger     *slog.Logger
-	Provider   payments.Provider
+	Providers  *payments.Registry
 	WebhookSvc *payments.WebhookService
 }
 
-func NewWebhookHandler(logger *slog.Logger, p payments.Provider, svc *payments.WebhookService) *WebhookHandler {
-	return &WebhookHandler{Logger: logger, Provider: p, WebhookSvc: svc}
+func NewWebhookHandler(logger *slog.Logger, providers *payments.Registry, svc *payments.WebhookService) *WebhookHandler {
+	return &WebhookHandler{Logger: logger, Providers: providers, WebhookSvc: svc}
 }
 
 // POST /webhooks/:provider
 // Body is raw JSON; signature header validated by provider adapter.
 func (h *WebhookHandler) Handle(c *gin.Context) {
+	provider, err := h.Providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "unknown provider"})
+		return
+	}
+
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid body"})
 		return
 	}
 
-	ev, err := h.Provider.VerifyAndParseWebhook(c.Request.Header, body)
+	ev, err := provider.VerifyAndParseWebhook(c.Request.Header, body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid signature or payload"})
 		return
 	}
 
-	if err := h.WebhookSvc.Handle(c.Request.Context(), h.Provider.Name(), ev, body); err != nil {
+	if err := h.WebhookSvc.Handle(c.Request.Context(), provider.Name(), ev, body); err != nil {
 		// 500 => provider retry etsin
-		h.Logger.Error("webhook apply failed", "event_id", ev.EventID, "type", ev.Type, "err", err)
+		h.Logger.Error("webhook apply failed", "provider", provider.Name(), "event_id", ev.EventID, "type", ev.Type, "err", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"ok": false})
 		return
 	}
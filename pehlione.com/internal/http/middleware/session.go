@@ -59,16 +59,14 @@ func SessionMiddleware(cfg SessionCfg) gin.HandlerFunc {
 		var firstName *string
 		var lastName *string
 		var phoneE164 *string
-		var address *string
 		var emailVerifiedAt *time.Time
-		row := cfg.DB.Table("users").Select("email", "role", "first_name", "last_name", "phone_e164", "address", "email_verified_at").Where("id = ?", sess.UserID).Row()
-		if err := row.Scan(&userEmail, &userRole, &firstName, &lastName, &phoneE164, &address, &emailVerifiedAt); err == nil {
+		row := cfg.DB.Table("users").Select("email", "role", "first_name", "last_name", "phone_e164", "email_verified_at").Where("id = ?", sess.UserID).Row()
+		if err := row.Scan(&userEmail, &userRole, &firstName, &lastName, &phoneE164, &emailVerifiedAt); err == nil {
 			c.Set("user_email", userEmail)
 			c.Set("user_role", userRole)
 			c.Set("user_first_name", firstName)
 			c.Set("user_last_name", lastName)
 			c.Set("user_phone", phoneE164)
-			c.Set("user_address", address)
 			c.Set("user_email_verified_at", emailVerifiedAt)
 		}
 
@@ -116,7 +114,6 @@ type ContextUser struct {
 	FirstName       *string
 	LastName        *string
 	PhoneE164       *string
-	Address         *string
 	EmailVerifiedAt *time.Time
 }
 
@@ -134,7 +131,7 @@ func CurrentUser(c *gin.Context) (ContextUser, bool) {
 	}
 
 	var emailStr, roleStr string
-	var firstName, lastName, phoneE164, address *string
+	var firstName, lastName, phoneE164 *string
 	var emailVerifiedAt *time.Time
 	if email, ok := c.Get("user_email"); ok && email != nil {
 		emailStr, _ = email.(string)
@@ -151,9 +148,6 @@ func CurrentUser(c *gin.Context) (ContextUser, bool) {
 	if phone, ok := c.Get("user_phone"); ok && phone != nil {
 		phoneE164, _ = phone.(*string)
 	}
-	if addr, ok := c.Get("user_address"); ok && addr != nil {
-		address, _ = addr.(*string)
-	}
 	if eva, ok := c.Get("user_email_verified_at"); ok && eva != nil {
 		emailVerifiedAt, _ = eva.(*time.Time)
 	}
@@ -165,7 +159,6 @@ func CurrentUser(c *gin.Context) (ContextUser, bool) {
 		FirstName:       firstName,
 		LastName:        lastName,
 		PhoneE164:       phoneE164,
-		Address:         address,
 		EmailVerifiedAt: emailVerifiedAt,
 	}, true
 }
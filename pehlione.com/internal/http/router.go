@@ -14,21 +14,27 @@ import (
 	"gorm.io/gorm"
 
 	"pehlione.com/app/internal/config"
+	"pehlione.com/app/internal/einvoice"
 	"pehlione.com/app/internal/http/cartcookie"
 	"pehlione.com/app/internal/http/flash"
 	"pehlione.com/app/internal/http/handlers"
 	adminHandlers "pehlione.com/app/internal/http/handlers/admin"
 	"pehlione.com/app/internal/http/middleware"
 	"pehlione.com/app/internal/http/render"
+	"pehlione.com/app/internal/modules/addresses"
 	"pehlione.com/app/internal/modules/auth"
 	"pehlione.com/app/internal/modules/cart"
 	"pehlione.com/app/internal/modules/currency"
 	"pehlione.com/app/internal/modules/email"
 	"pehlione.com/app/internal/modules/fx"
+	"pehlione.com/app/internal/modules/giftcards"
 	"pehlione.com/app/internal/modules/orders"
 	"pehlione.com/app/internal/modules/payments"
 	"pehlione.com/app/internal/modules/products"
+	"pehlione.com/app/internal/modules/reports"
+	"pehlione.com/app/internal/modules/returns"
 	"pehlione.com/app/internal/modules/shipping"
+	"pehlione.com/app/internal/modules/tax"
 	"pehlione.com/app/internal/modules/users"
 	"pehlione.com/app/internal/modules/wishlist"
 	"pehlione.com/app/internal/sms"
@@ -200,18 +206,32 @@ func NewRouter(logger *slog.Logger, db *gorm.DB, cfg config.AppConfig) *gin.Engi
 
 	admin.POST("/products/:id/variants", ph.AddVariant)
 
-	// Payment provider (used by both checkout and admin)
-	var provider payments.Provider
-	switch cfg.Payment.Provider {
-	case "", "mock":
-		provider = payments.NewMockProvider(cfg.Payment.MockWebhookSecret, cfg.Payment.MockWebhookTolerance)
-	default:
-		log.Fatalf("unsupported payment provider: %s", cfg.Payment.Provider)
+	// Payment providers (used by both checkout and admin); payments are
+	// routed by method + currency, refunds/webhooks by Payment.Provider
+	registryCfg := payments.RegistryConfig{
+		Providers:            cfg.Payment.Providers,
+		Routes:               cfg.Payment.Routes,
+		APIKey:               cfg.Payment.APIKey,
+		WebhookSecret:        cfg.Payment.WebhookSecret,
+		APIBaseURL:           cfg.Payment.APIBaseURL,
+		MockWebhookSecret:    cfg.Payment.MockWebhookSecret,
+		MockWebhookTolerance: cfg.Payment.MockWebhookTolerance,
+	}
+	if cfg.Payment.MockHostedPage {
+		registryCfg.MockHostedPageURL = strings.TrimRight(appBaseURL, "/") + "/mock-pay"
+	}
+	providers, err := payments.NewRegistryFromConfig(registryCfg)
+	if err != nil {
+		log.Fatalf("payment providers: %v", err)
+	}
+	var mockPayH *handlers.MockPayHandler
+	if p, err := providers.Get("mock"); err == nil && registryCfg.MockHostedPageURL != "" {
+		mockPayH = handlers.NewMockPayHandler(p.(payments.MockProvider), appBaseURL)
 	}
 
 	// Webhook service + handler
 	webhookSvc := payments.NewWebhookService(db)
-	webhookH := handlers.NewWebhookHandler(logger, provider, webhookSvc)
+	webhookH := handlers.NewWebhookHandler(logger, providers, webhookSvc)
 
 	admin.POST("/products/:id/variants/:vid/delete", ph.DeleteVariant)
 	admin.POST("/products/:id/variants/:vid", ph.UpdateVariant)
@@ -227,7 +247,8 @@ func NewRouter(logger *slog.Logger, db *gorm.DB, cfg config.AppConfig) *gin.Engi
 
 	authRepo := auth.NewRepo(db)
 	ordersRepo := orders.NewRepo(db)
-	accountH := handlers.NewAccountHandler(authRepo, flashCodec, wishlistSvc, productsRepo, ordersRepo)
+	addressSvc := addresses.NewService(db)
+	accountH := handlers.NewAccountHandler(authRepo, flashCodec, wishlistSvc, productsRepo, ordersRepo, addressSvc)
 	authOnly.GET("/account", accountH.Get)
 	authOnly.POST("/account/password", accountH.ChangePassword)
 
@@ -242,6 +263,15 @@ func NewRouter(logger *slog.Logger, db *gorm.DB, cfg config.AppConfig) *gin.Engi
 	account.Use(middleware.RequireAuth(flashCodec))
 	account.GET("/orders", accountOrdersH.List)
 
+	accountAddressesH := handlers.NewAccountAddressesHandler(addressSvc, flashCodec)
+	account.GET("/addresses", accountAddressesH.List)
+	account.GET("/addresses/new", accountAddressesH.New)
+	account.POST("/addresses", accountAddressesH.Create)
+	account.GET("/addresses/:id/edit", accountAddressesH.Edit)
+	account.POST("/addresses/:id", accountAddressesH.Update)
+	account.POST("/addresses/:id/delete", accountAddressesH.Delete)
+	account.POST("/addresses/:id/default", accountAddressesH.SetDefault)
+
 	smsRepo := sms.NewOutboxRepository(db)
 	smsH := handlers.NewSmsHandler(db, smsRepo, flashCodec, logger)
 
@@ -317,8 +347,8 @@ func NewRouter(logger *slog.Logger, db *gorm.DB, cfg config.AppConfig) *gin.Engi
 	}
 
 	var shippingSvc *shipping.Service
+	var shipProvider shipping.Provider
 	if cfg.Shipping.Enabled {
-		var shipProvider shipping.Provider
 		switch cfg.Shipping.Provider {
 		case "", "mock":
 			shipProvider = shipping.NewMockProvider(cfg.Shipping.MockBaseURL)
@@ -342,13 +372,65 @@ func NewRouter(logger *slog.Logger, db *gorm.DB, cfg config.AppConfig) *gin.Engi
 	// The web server should not run background workers to avoid redundant processing
 
 	// Admin Orders (depends on email/shipping services)
-	refundSvc := payments.NewRefundService(db, provider, emailSvc, appBaseURL)
+	refundSvc := payments.NewRefundService(db, providers, emailSvc, appBaseURL)
 	adminSmsH := adminHandlers.NewSmsHandler(db, flashCodec, logger)
 	admin.GET("/sms/failed", adminSmsH.ListFailed)
 
-	adminOrders := adminHandlers.NewOrdersHandler(db, flashCodec, refundSvc, shippingSvc)
+	adminCoupons := adminHandlers.NewCouponsHandler(db, flashCodec)
+	admin.GET("/coupons", adminCoupons.List)
+	admin.GET("/coupons/new", adminCoupons.New)
+	admin.POST("/coupons", adminCoupons.Create)
+	admin.GET("/coupons/:id/edit", adminCoupons.Edit)
+	admin.POST("/coupons/:id", adminCoupons.Update)
+
+	adminInventory := adminHandlers.NewInventoryHandler(db, flashCodec)
+	admin.GET("/inventory/movements", adminInventory.Movements)
+
+	adminGiftCards := adminHandlers.NewGiftCardsHandler(giftcards.NewService(db))
+	admin.GET("/giftcards", adminGiftCards.Lookup)
+
+	adminShipping := adminHandlers.NewShippingRatesHandler(db, flashCodec)
+	admin.GET("/shipping", adminShipping.List)
+	admin.GET("/shipping/zones/new", adminShipping.NewZone)
+	admin.POST("/shipping/zones", adminShipping.CreateZone)
+	admin.GET("/shipping/zones/:id/edit", adminShipping.EditZone)
+	admin.POST("/shipping/zones/:id", adminShipping.UpdateZone)
+	admin.POST("/shipping/zones/:id/methods", adminShipping.CreateMethod)
+	admin.POST("/shipping/zones/:id/methods/:mid", adminShipping.UpdateMethod)
+	admin.POST("/shipping/zones/:id/methods/:mid/delete", adminShipping.DeleteMethod)
+
+	returnsSvc := returns.NewService(db, refundSvc, shipProvider, emailSvc, appBaseURL)
+	webhookSvc.SetEmailService(emailSvc, appBaseURL)
+	webhookSvc.SetRefundListener(returnsSvc) // asenkron iade tamamlanınca iade talebi kapanır
+	adminReturns := adminHandlers.NewReturnsHandler(db, flashCodec, returnsSvc)
+	admin.GET("/returns", adminReturns.List)
+	admin.GET("/returns/:id", adminReturns.Detail)
+	admin.POST("/returns/:id/label", adminReturns.Label)
+	admin.POST("/returns/:id/:action", adminReturns.Action) // approve|reject|receive
+
+	adminReports := adminHandlers.NewReportsHandler(reports.NewService(db))
+	admin.GET("/reports", adminReports.Sales)
+	admin.GET("/reports/export.csv", adminReports.SalesCSV)
+	admin.GET("/reports/reconciliation", adminReports.Reconciliation)
+
+	adminEvents := adminHandlers.NewProviderEventsHandler(flashCodec, webhookSvc, cfg.Payment.Providers)
+	admin.GET("/webhooks", adminEvents.List)
+	admin.GET("/webhooks/:id", adminEvents.Detail)
+	admin.POST("/webhooks/:id/replay", adminEvents.Replay)
+
+	accountReturnsH := handlers.NewAccountReturnsHandler(returnsSvc, flashCodec)
+	account.GET("/returns", accountReturnsH.List)
+	account.GET("/orders/:id/return", accountReturnsH.New)
+	account.POST("/orders/:id/return", accountReturnsH.Create)
+
+	paySvc := payments.NewService(db, providers)
+	paySvc.SetCaptureMethod(cfg.Payment.CaptureMode)
+	adminOrders := adminHandlers.NewOrdersHandler(db, flashCodec, refundSvc, shippingSvc, paySvc)
 	admin.GET("/orders", adminOrders.List)
+	admin.GET("/orders/pick-list.pdf", adminOrders.PickList)
+	admin.POST("/orders/batch", adminOrders.Batch) // ship|cancel|label|packing_slips
 	admin.GET("/orders/:id", adminOrders.Detail)
+	admin.GET("/orders/:id/packing-slip.pdf", adminOrders.PackingSlip)
 	admin.GET("/orders/:id/refund", adminOrders.RefundForm)
 	admin.POST("/orders/:id/refund", adminOrders.Refund)
 	admin.POST("/orders/:id/shipments/label", adminOrders.CreateShipmentLabel)
@@ -357,26 +439,60 @@ func NewRouter(logger *slog.Logger, db *gorm.DB, cfg config.AppConfig) *gin.Engi
 
 	// Checkout & Orders
 	orderSvc := orders.NewService(db, currencySvc)
-	paySvc := payments.NewService(db, provider)
+	if cfg.Tax.Enabled {
+		orderSvc.SetTaxService(tax.NewService(db, cfg.Tax.PricesIncludeTax))
+	}
+	orderSvc.SetReservationTTL(time.Duration(cfg.Inventory.ReservationTTLMinutes) * time.Minute)
+	// misafir sipariş bağlantıları: imzalı, süreli erişim anahtarı
+	guestTokens := orders.NewGuestTokens(secret, time.Duration(cfg.Orders.GuestLinkTTLHours)*time.Hour)
 	checkoutH := handlers.NewCheckoutHandler(db, flashCodec, cartCK, cartSvc, orderSvc, emailSvc, currencySvc, appBaseURL)
-	ordersH := handlers.NewOrdersHandler(db, flashCodec, paySvc)
+	checkoutH.SetRateService(shipping.NewRateService(db, cfg.Shipping.DefaultCountry))
+	checkoutH.SetGuestTokens(guestTokens)
+	ordersH := handlers.NewOrdersHandler(db, flashCodec, paySvc, guestTokens, appBaseURL)
+	ordersH.SetSeller(einvoice.Seller{
+		Name:       cfg.Seller.Name,
+		Street:     cfg.Seller.Street,
+		District:   cfg.Seller.District,
+		City:       cfg.Seller.City,
+		PostalCode: cfg.Seller.PostalCode,
+		Country:    cfg.Seller.Country,
+		TaxID:      cfg.Seller.TaxID,
+		TaxOffice:  cfg.Seller.TaxOffice,
+		VATID:      cfg.Seller.VATID,
+		Email:      cfg.Seller.Email,
+		Phone:      cfg.Seller.Phone,
+	})
+	orderLookupH := handlers.NewOrderLookupHandler(ordersRepo, guestTokens, emailSvc, flashCodec, appBaseURL)
 	cartBadgeH := handlers.NewCartBadgeHandler(db)
 	cartAddH := handlers.NewCartAddHandler(db)
 
 	r.GET("/checkout", checkoutH.Get)
 	r.POST("/checkout", checkoutH.Post)
 
+	r.GET("/orders/lookup", orderLookupH.Get)
+	r.POST("/orders/lookup", orderLookupH.Post)
 	r.GET("/orders/:id", ordersH.Detail)
 	r.GET("/orders/:id/invoice.pdf", ordersH.InvoicePDF)
+	r.GET("/orders/:id/invoice.xml", ordersH.EInvoiceXML)
+	r.GET("/orders/:id/invoice-pdfa.pdf", ordersH.InvoicePDFA)
+	r.GET("/orders/:id/credit-notes/:refund_id", ordersH.CreditNotePDF)
 	r.GET("/orders/:id/pay", ordersH.PayGet)
 	r.POST("/orders/:id/pay", ordersH.PayPost)
+	r.GET("/orders/:id/pay/return", ordersH.PayReturn)
+	r.GET("/orders/:id/pay/cancel", ordersH.PayCancel)
+
+	// Mock provider hosted payment page (3-D Secure simulation)
+	if mockPayH != nil {
+		r.GET("/mock-pay/:ref", mockPayH.Get)
+		r.POST("/mock-pay/:ref", mockPayH.Post)
+	}
 
 	// HTMX cart endpoints
 	r.GET("/api/cart/badge", cartBadgeH.GetBadge)
 	r.POST("/api/cart/add", cartAddH.AddItem)
 
 	// Webhooks (not CSRF-protected; signature is security layer)
-	r.POST("/webhooks/mock", webhookH.Handle)
+	r.POST("/webhooks/:provider", webhookH.Handle)
 
 	return r
 }
@@ -59,7 +59,7 @@ func fieldKey(dst any, structField string) string {
 
 func messageForTag(tag, param string) string {
 	switch tag {
-	case "required":
+	case "required", "required_if":
 		return "Bu alan zorunludur."
 	case "email":
 		return "Geçerli bir e-posta giriniz."
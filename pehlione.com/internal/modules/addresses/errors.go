@@ -0,0 +1,8 @@
+package addresses
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("address not found")
+	ErrLimitReached = errors.New("address book is full")
+)
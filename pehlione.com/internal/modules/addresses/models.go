@@ -0,0 +1,58 @@
+package addresses
+
+import (
+	"strings"
+	"time"
+
+	"pehlione.com/app/internal/modules/shipping"
+)
+
+// Address is a saved shipping/billing address of a customer.
+type Address struct {
+	ID     string `gorm:"type:char(36);primaryKey"`
+	UserID string `gorm:"type:char(36);not null;index:ix_user_addresses_user"`
+
+	Label      string `gorm:"type:varchar(64);not null;default:''"` // "Ev", "İş" ...
+	FirstName  string `gorm:"type:varchar(100);not null"`
+	LastName   string `gorm:"type:varchar(100);not null"`
+	Address1   string `gorm:"type:varchar(255);not null"`
+	Address2   string `gorm:"type:varchar(255);not null;default:''"`
+	City       string `gorm:"type:varchar(100);not null"`
+	PostalCode string `gorm:"type:varchar(32);not null"`
+	Country    string `gorm:"type:char(2);not null"`
+	Phone      string `gorm:"type:varchar(32);not null;default:''"`
+
+	IsDefault bool `gorm:"not null;default:false"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Address) TableName() string { return "user_addresses" }
+
+// Shipping converts the saved address to the shipping provider shape.
+func (a Address) Shipping(email string) shipping.Address {
+	return shipping.Address{
+		FirstName:  a.FirstName,
+		LastName:   a.LastName,
+		Address1:   a.Address1,
+		Address2:   a.Address2,
+		City:       a.City,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+		Email:      email,
+	}
+}
+
+// OneLine formats the address for lists and pickers.
+func (a Address) OneLine() string {
+	parts := []string{strings.TrimSpace(a.FirstName + " " + a.LastName), a.Address1, a.Address2, strings.TrimSpace(a.PostalCode + " " + a.City), a.Country}
+	out := parts[:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,235 @@
+package addresses
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxPerUser limits the size of an address book.
+const MaxPerUser = 20
+
+// Input is the editable part of an address.
+type Input struct {
+	Label       string
+	FirstName   string
+	LastName    string
+	Address1    string
+	Address2    string
+	City        string
+	PostalCode  string
+	Country     string
+	Phone       string
+	MakeDefault bool
+}
+
+func (in Input) normalized() Input {
+	in.Label = strings.TrimSpace(in.Label)
+	in.FirstName = strings.TrimSpace(in.FirstName)
+	in.LastName = strings.TrimSpace(in.LastName)
+	in.Address1 = strings.TrimSpace(in.Address1)
+	in.Address2 = strings.TrimSpace(in.Address2)
+	in.City = strings.TrimSpace(in.City)
+	in.PostalCode = strings.TrimSpace(in.PostalCode)
+	in.Country = strings.ToUpper(strings.TrimSpace(in.Country))
+	in.Phone = strings.TrimSpace(in.Phone)
+	return in
+}
+
+func (in Input) fields() map[string]any {
+	return map[string]any{
+		"label":       in.Label,
+		"first_name":  in.FirstName,
+		"last_name":   in.LastName,
+		"address1":    in.Address1,
+		"address2":    in.Address2,
+		"city":        in.City,
+		"postal_code": in.PostalCode,
+		"country":     in.Country,
+		"phone":       in.Phone,
+	}
+}
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// List returns the user's addresses, default first.
+func (s *Service) List(ctx context.Context, userID string) ([]Address, error) {
+	var out []Address
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("is_default DESC, created_at ASC, id ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (s *Service) Get(ctx context.Context, userID, id string) (Address, error) {
+	return getInTx(ctx, s.db, userID, id)
+}
+
+// Default returns the default address; ErrNotFound when the book is empty.
+func (s *Service) Default(ctx context.Context, userID string) (Address, error) {
+	var a Address
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("is_default DESC, created_at ASC, id ASC").
+		First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Address{}, ErrNotFound
+	}
+	return a, err
+}
+
+// Create adds an address. The first address of a user becomes the default.
+func (s *Service) Create(ctx context.Context, userID string, in Input) (Address, error) {
+	in = in.normalized()
+	var a Address
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&Address{}).Where("user_id = ?", userID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n >= MaxPerUser {
+			return ErrLimitReached
+		}
+		if n == 0 {
+			in.MakeDefault = true
+		}
+		if in.MakeDefault {
+			if err := clearDefaultInTx(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		a = Address{
+			ID:         uuid.NewString(),
+			UserID:     userID,
+			Label:      in.Label,
+			FirstName:  in.FirstName,
+			LastName:   in.LastName,
+			Address1:   in.Address1,
+			Address2:   in.Address2,
+			City:       in.City,
+			PostalCode: in.PostalCode,
+			Country:    in.Country,
+			Phone:      in.Phone,
+			IsDefault:  in.MakeDefault,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		return tx.Create(&a).Error
+	})
+	return a, err
+}
+
+// Update edits an address of the user.
+func (s *Service) Update(ctx context.Context, userID, id string, in Input) error {
+	in = in.normalized()
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if in.MakeDefault {
+			if err := clearDefaultInTx(tx, userID); err != nil {
+				return err
+			}
+		}
+		upd := in.fields()
+		if in.MakeDefault {
+			upd["is_default"] = true
+		}
+		upd["updated_at"] = time.Now()
+		res := tx.Model(&Address{}).Where("id = ? AND user_id = ?", id, userID).Updates(upd)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// SetDefault makes the address the user's default.
+func (s *Service) SetDefault(ctx context.Context, userID, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := getInTx(ctx, tx, userID, id); err != nil {
+			return err
+		}
+		if err := clearDefaultInTx(tx, userID); err != nil {
+			return err
+		}
+		return tx.Model(&Address{}).Where("id = ?", id).
+			Updates(map[string]any{"is_default": true, "updated_at": time.Now()}).Error
+	})
+}
+
+// Delete removes an address; when it was the default the oldest remaining
+// address takes over.
+func (s *Service) Delete(ctx context.Context, userID, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		a, err := getInTx(ctx, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&Address{}, "id = ?", a.ID).Error; err != nil {
+			return err
+		}
+		if !a.IsDefault {
+			return nil
+		}
+		var next Address
+		err = tx.Where("user_id = ?", userID).Order("created_at ASC, id ASC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&Address{}).Where("id = ?", next.ID).Update("is_default", true).Error
+	})
+}
+
+// Remember saves an address entered at checkout unless the same address is
+// already in the book. A full book is not an error here.
+func (s *Service) Remember(ctx context.Context, userID string, in Input) (Address, error) {
+	in = in.normalized()
+	var a Address
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND first_name = ? AND last_name = ? AND address1 = ? AND address2 = ? AND city = ? AND postal_code = ? AND country = ?",
+			userID, in.FirstName, in.LastName, in.Address1, in.Address2, in.City, in.PostalCode, in.Country).
+		First(&a).Error
+	if err == nil {
+		return a, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return Address{}, err
+	}
+	a, err = s.Create(ctx, userID, in)
+	if errors.Is(err, ErrLimitReached) {
+		return Address{}, nil
+	}
+	return a, err
+}
+
+func getInTx(ctx context.Context, tx *gorm.DB, userID, id string) (Address, error) {
+	var a Address
+	err := tx.WithContext(ctx).First(&a, "id = ? AND user_id = ?", id, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Address{}, ErrNotFound
+	}
+	return a, err
+}
+
+func clearDefaultInTx(tx *gorm.DB, userID string) error {
+	return tx.Model(&Address{}).
+		Where("user_id = ? AND is_default = ?", userID, true).
+		Update("is_default", false).Error
+}
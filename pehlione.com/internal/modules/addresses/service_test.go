@@ -0,0 +1,74 @@
+package addresses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE user_addresses (
+		id TEXT PRIMARY KEY, user_id TEXT NOT NULL, label TEXT NOT NULL DEFAULT '',
+		first_name TEXT NOT NULL, last_name TEXT NOT NULL, address1 TEXT NOT NULL, address2 TEXT NOT NULL DEFAULT '',
+		city TEXT NOT NULL, postal_code TEXT NOT NULL, country TEXT NOT NULL, phone TEXT NOT NULL DEFAULT '',
+		is_default BOOLEAN NOT NULL DEFAULT 0, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	return db
+}
+
+func home(city string) Input {
+	return Input{FirstName: "Ada", LastName: "Yılmaz", Address1: "Bağdat Cd. 1", City: city, PostalCode: "34710", Country: "tr"}
+}
+
+func TestDefaultFollowsBook(t *testing.T) {
+	svc := NewService(setupTestDB(t))
+	ctx := context.Background()
+
+	first, err := svc.Create(ctx, "u1", home("İstanbul"))
+	require.NoError(t, err)
+	assert.True(t, first.IsDefault, "first address becomes default")
+	assert.Equal(t, "TR", first.Country)
+
+	second, err := svc.Create(ctx, "u1", home("Ankara"))
+	require.NoError(t, err)
+	assert.False(t, second.IsDefault)
+
+	require.NoError(t, svc.SetDefault(ctx, "u1", second.ID))
+	def, err := svc.Default(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, def.ID)
+
+	list, err := svc.List(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, second.ID, list[0].ID, "default first")
+	assert.False(t, list[1].IsDefault)
+
+	require.NoError(t, svc.Delete(ctx, "u1", second.ID))
+	def, err = svc.Default(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, def.ID)
+	assert.True(t, def.IsDefault, "remaining address takes over")
+
+	assert.ErrorIs(t, svc.Delete(ctx, "u2", first.ID), ErrNotFound, "other users can't touch the address")
+}
+
+func TestRememberSkipsDuplicates(t *testing.T) {
+	svc := NewService(setupTestDB(t))
+	ctx := context.Background()
+
+	a, err := svc.Remember(ctx, "u1", home("İstanbul"))
+	require.NoError(t, err)
+	b, err := svc.Remember(ctx, "u1", Input{FirstName: " Ada ", LastName: "Yılmaz", Address1: "Bağdat Cd. 1", City: "İstanbul", PostalCode: "34710", Country: "TR"})
+	require.NoError(t, err)
+	assert.Equal(t, a.ID, b.ID)
+
+	list, err := svc.List(ctx, "u1")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
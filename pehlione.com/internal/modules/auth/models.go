@@ -15,7 +15,6 @@ type User struct {
 	SMSOptOutAt     *time.Time `gorm:"type:datetime"`
 	FirstName       *string    `gorm:"type:varchar(255)"`
 	LastName        *string    `gorm:"type:varchar(255)"`
-	Address         *string    `gorm:"type:text"`
 	CreatedAt       time.Time  `gorm:"type:datetime(3);not null"`
 	UpdatedAt       time.Time  `gorm:"type:datetime(3);not null"`
 }
@@ -0,0 +1,227 @@
+package checkout
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/inventory"
+)
+
+// Reservation statuses.
+const (
+	ReservationActive    = "active"
+	ReservationConverted = "converted"
+	ReservationReleased  = "released"
+)
+
+// Reservation holds stock for an unpaid order until ExpiresAt. Stock is only
+// decremented when the reservation is converted (order paid).
+type Reservation struct {
+	ID          string     `gorm:"type:char(36);primaryKey"`
+	OrderID     string     `gorm:"type:char(36);not null;uniqueIndex:ux_stock_reservations_order_variant,priority:1"`
+	VariantID   string     `gorm:"type:char(36);not null;uniqueIndex:ux_stock_reservations_order_variant,priority:2"`
+	Qty         int        `gorm:"not null"`
+	Status      string     `gorm:"type:varchar(16);not null"`
+	ExpiresAt   time.Time  `gorm:"type:datetime(3);not null"`
+	ConvertedAt *time.Time `gorm:"type:datetime(3)"`
+	ReleasedAt  *time.Time `gorm:"type:datetime(3)"`
+	CreatedAt   time.Time  `gorm:"type:datetime(3);not null"`
+	UpdatedAt   time.Time  `gorm:"type:datetime(3);not null"`
+}
+
+func (Reservation) TableName() string { return "stock_reservations" }
+
+// ReserveStockInTx: stock - aktif rezervasyonlar >= qty kontrolü yapar ve
+// order için rezervasyon satırlarını yazar. Order aynı tx içinde önceden insert edilmiş olmalı.
+func ReserveStockInTx(ctx context.Context, tx *gorm.DB, orderID string, lines []StockLine, expiresAt time.Time) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	want := make(map[string]int, len(lines))
+	for _, ln := range lines {
+		q := ln.Qty
+		if q < 1 {
+			q = 1
+		}
+		want[ln.VariantID] += q
+	}
+
+	ids := make([]string, 0, len(want))
+	for id := range want {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	type VariantRow struct {
+		ID    string `gorm:"column:id"`
+		Stock int    `gorm:"column:stock"`
+	}
+	var rows []VariantRow
+
+	// SELECT ... FOR UPDATE: aynı varyantı rezerve eden tx'ler sıraya girer
+	if err := tx.WithContext(ctx).
+		Table("product_variants").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+
+	held, err := reservedQtyInTx(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	avail := make(map[string]int, len(rows))
+	for _, r := range rows {
+		avail[r.ID] = r.Stock - held[r.ID]
+	}
+
+	var oos []OutOfStockItem
+	for _, id := range ids {
+		req := want[id]
+		av, ok := avail[id]
+		if av < 0 {
+			av = 0
+		}
+		if !ok || av < req {
+			oos = append(oos, OutOfStockItem{VariantID: id, Requested: req, Available: av})
+		}
+	}
+	if len(oos) > 0 {
+		return &OutOfStockError{Items: oos}
+	}
+
+	now := time.Now()
+	res := make([]Reservation, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, Reservation{
+			ID:        uuid.NewString(),
+			OrderID:   orderID,
+			VariantID: id,
+			Qty:       want[id],
+			Status:    ReservationActive,
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return tx.WithContext(ctx).Create(&res).Error
+}
+
+// ConvertReservationsInTx turns the order's active reservations into a permanent
+// stock deduction. Safe to call repeatedly; converted rows are skipped.
+func ConvertReservationsInTx(ctx context.Context, tx *gorm.DB, orderID string) error {
+	var res []Reservation
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, ReservationActive).
+		Order("variant_id ASC").
+		Find(&res).Error; err != nil {
+		return err
+	}
+	if len(res) == 0 {
+		return nil
+	}
+
+	type itemRow struct {
+		ID        string `gorm:"column:id"`
+		VariantID string `gorm:"column:variant_id"`
+	}
+	var items []itemRow
+	if err := tx.WithContext(ctx).
+		Table("order_items").
+		Select("id, variant_id").
+		Where("order_id = ?", orderID).
+		Scan(&items).Error; err != nil {
+		return err
+	}
+	itemByVariant := make(map[string]string, len(items))
+	for _, it := range items {
+		itemByVariant[it.VariantID] = it.ID
+	}
+
+	now := time.Now()
+	for _, r := range res {
+		// rezervasyon toplam stoğu tuttuğu için lokasyon dağıtımı da karşılanır
+		allocs, err := inventory.DeductInTx(ctx, tx, r.VariantID, r.Qty, inventory.Movement{
+			Reason:    inventory.ReasonSale,
+			OrderID:   &r.OrderID,
+			RefType:   inventory.Ptr("reservation"),
+			RefID:     inventory.Ptr(r.ID),
+			CreatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		if itemID := itemByVariant[r.VariantID]; itemID != "" {
+			if err := inventory.SaveItemAllocationsInTx(ctx, tx, itemID, allocs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.WithContext(ctx).Model(&Reservation{}).
+		Where("order_id = ? AND status = ?", orderID, ReservationActive).
+		Updates(map[string]any{
+			"status":       ReservationConverted,
+			"converted_at": &now,
+			"updated_at":   now,
+		}).Error
+}
+
+// ReleaseReservationsInTx frees the order's active reservations without touching stock.
+func ReleaseReservationsInTx(ctx context.Context, tx *gorm.DB, orderID string) (int64, error) {
+	now := time.Now()
+	res := tx.WithContext(ctx).Model(&Reservation{}).
+		Where("order_id = ? AND status = ?", orderID, ReservationActive).
+		Updates(map[string]any{
+			"status":      ReservationReleased,
+			"released_at": &now,
+			"updated_at":  now,
+		})
+	return res.RowsAffected, res.Error
+}
+
+// ExpiredReservationOrders returns order ids that still hold an active reservation past its expiry.
+func ExpiredReservationOrders(ctx context.Context, db *gorm.DB, now time.Time, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var ids []string
+	err := db.WithContext(ctx).
+		Model(&Reservation{}).
+		Distinct("order_id").
+		Where("status = ? AND expires_at <= ?", ReservationActive, now).
+		Limit(limit).
+		Pluck("order_id", &ids).Error
+	return ids, err
+}
+
+func reservedQtyInTx(ctx context.Context, tx *gorm.DB, variantIDs []string) (map[string]int, error) {
+	type row struct {
+		VariantID string `gorm:"column:variant_id"`
+		Qty       int    `gorm:"column:qty"`
+	}
+	var rows []row
+	if err := tx.WithContext(ctx).
+		Model(&Reservation{}).
+		Select("variant_id, SUM(qty) AS qty").
+		Where("variant_id IN ? AND status = ?", variantIDs, ReservationActive).
+		Group("variant_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]int, len(rows))
+	for _, r := range rows {
+		out[r.VariantID] = r.Qty
+	}
+	return out, nil
+}
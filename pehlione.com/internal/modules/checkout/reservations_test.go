@@ -0,0 +1,161 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/inventory"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE products (id TEXT PRIMARY KEY, kind TEXT NOT NULL DEFAULT 'standard')`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE product_variants (id TEXT PRIMARY KEY, product_id TEXT, stock INTEGER NOT NULL DEFAULT 0, low_stock_threshold INTEGER NOT NULL DEFAULT 0)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE stock_reservations (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL,
+		qty INTEGER NOT NULL, status TEXT NOT NULL, expires_at DATETIME NOT NULL,
+		converted_at DATETIME, released_at DATETIME,
+		created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL,
+		UNIQUE (order_id, variant_id))`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE stock_movements (
+		id TEXT PRIMARY KEY, variant_id TEXT NOT NULL, location_id TEXT, delta INTEGER NOT NULL, stock_after INTEGER NOT NULL,
+		reason TEXT NOT NULL, order_id TEXT, ref_type TEXT, ref_id TEXT, actor_user_id TEXT, note TEXT,
+		created_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE order_items (id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, quantity INTEGER NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE stock_locations (
+		id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, name TEXT NOT NULL, priority INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL, created_at DATETIME, updated_at DATETIME)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE stock_levels (
+		location_id TEXT NOT NULL, variant_id TEXT NOT NULL, qty INTEGER NOT NULL DEFAULT 0, updated_at DATETIME NOT NULL,
+		PRIMARY KEY (location_id, variant_id))`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE order_item_allocations (
+		id TEXT PRIMARY KEY, order_item_id TEXT NOT NULL, location_id TEXT NOT NULL, qty INTEGER NOT NULL,
+		created_at DATETIME NOT NULL, UNIQUE (order_item_id, location_id))`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO stock_locations (id, code, name, priority, status) VALUES
+		('l1', 'wh1', 'Warehouse 1', 10, 'active'), ('l2', 'wh2', 'Warehouse 2', 20, 'active')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO products (id, kind) VALUES ('pr1', 'standard'), ('gc', 'gift_card')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO product_variants (id, product_id, stock) VALUES ('v1', 'pr1', 5), ('g1', 'gc', 0)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO stock_levels (location_id, variant_id, qty, updated_at) VALUES
+		('l1', 'v1', 2, CURRENT_TIMESTAMP), ('l2', 'v1', 3, CURRENT_TIMESTAMP)`).Error)
+	return db
+}
+
+func stockOf(t *testing.T, db *gorm.DB, id string) int {
+	var stock int
+	require.NoError(t, db.Table("product_variants").Select("stock").Where("id = ?", id).Scan(&stock).Error)
+	return stock
+}
+
+func levelOf(t *testing.T, db *gorm.DB, loc, id string) int {
+	var qty int
+	require.NoError(t, db.Table("stock_levels").Select("qty").Where("location_id = ? AND variant_id = ?", loc, id).Scan(&qty).Error)
+	return qty
+}
+
+func TestReservations_ReserveConvertRelease(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	exp := time.Now().Add(30 * time.Minute)
+
+	require.NoError(t, ReserveStockInTx(ctx, db, "o1", []StockLine{{VariantID: "v1", Qty: 3}}, exp))
+	assert.Equal(t, 5, stockOf(t, db, "v1"), "reservation must not touch stock")
+
+	// 5 in stock, 3 held: only 2 left for the next order
+	err := ReserveStockInTx(ctx, db, "o2", []StockLine{{VariantID: "v1", Qty: 3}}, exp)
+	var oos *OutOfStockError
+	require.True(t, errors.As(err, &oos))
+	assert.Equal(t, 2, oos.Items[0].Available)
+
+	require.NoError(t, ConvertReservationsInTx(ctx, db, "o1"))
+	assert.Equal(t, 2, stockOf(t, db, "v1"))
+	require.NoError(t, ConvertReservationsInTx(ctx, db, "o1"))
+	assert.Equal(t, 2, stockOf(t, db, "v1"), "conversion is idempotent")
+
+	require.NoError(t, ReserveStockInTx(ctx, db, "o3", []StockLine{{VariantID: "v1", Qty: 2}}, time.Now().Add(-time.Minute)))
+	ids, err := ExpiredReservationOrders(ctx, db, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"o3"}, ids)
+
+	n, err := ReleaseReservationsInTx(ctx, db, "o3")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	assert.Equal(t, 2, stockOf(t, db, "v1"))
+
+	// released stock is available again
+	require.NoError(t, ReserveStockInTx(ctx, db, "o4", []StockLine{{VariantID: "v1", Qty: 2}}, exp))
+}
+
+func TestRestockOrderInTx(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	exp := time.Now().Add(30 * time.Minute)
+
+	// paid order: reservation converted, stock 5 -> 2; wh1 (2) is emptied before wh2
+	require.NoError(t, db.Exec(`INSERT INTO order_items (id, order_id, variant_id, quantity) VALUES ('p1', 'paid', 'v1', 3)`).Error)
+	require.NoError(t, ReserveStockInTx(ctx, db, "paid", []StockLine{{VariantID: "v1", Qty: 3}}, exp))
+	require.NoError(t, ConvertReservationsInTx(ctx, db, "paid"))
+	assert.Equal(t, 2, stockOf(t, db, "v1"))
+	assert.Equal(t, 0, levelOf(t, db, "l1", "v1"))
+	assert.Equal(t, 2, levelOf(t, db, "l2", "v1"))
+
+	allocs, err := inventory.OrderAllocationsInTx(ctx, db, "paid")
+	require.NoError(t, err)
+	assert.Equal(t, []inventory.Allocation{{LocationID: "l1", Qty: 2}, {LocationID: "l2", Qty: 1}}, allocs["v1"])
+
+	n, err := RestockOrderInTx(ctx, db, RestockInput{OrderID: "paid", RefType: "order", RefID: "paid"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 5, stockOf(t, db, "v1"))
+	assert.Equal(t, 2, levelOf(t, db, "l1", "v1"), "restock returns to the picking locations")
+	assert.Equal(t, 3, levelOf(t, db, "l2", "v1"))
+
+	// second restock is a no-op
+	n, err = RestockOrderInTx(ctx, db, RestockInput{OrderID: "paid", RefType: "refund", RefID: "r1"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 5, stockOf(t, db, "v1"))
+
+	// unpaid order: only the reservation is released
+	require.NoError(t, ReserveStockInTx(ctx, db, "unpaid", []StockLine{{VariantID: "v1", Qty: 2}}, exp))
+	n, err = RestockOrderInTx(ctx, db, RestockInput{OrderID: "unpaid", RefType: "order", RefID: "unpaid"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 5, stockOf(t, db, "v1"))
+
+	// legacy order (stock deducted at creation, no reservations)
+	require.NoError(t, db.Exec(`INSERT INTO order_items (id, order_id, variant_id, quantity) VALUES ('i1', 'legacy', 'v1', 1)`).Error)
+	n, err = RestockOrderInTx(ctx, db, RestockInput{OrderID: "legacy", RefType: "order", RefID: "legacy"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 6, stockOf(t, db, "v1"))
+
+	// gift-card-only order: no reservations, and nothing to put back
+	require.NoError(t, db.Exec(`INSERT INTO order_items (id, order_id, variant_id, quantity) VALUES ('g1', 'giftcard', 'g1', 2)`).Error)
+	n, err = RestockOrderInTx(ctx, db, RestockInput{OrderID: "giftcard", RefType: "order", RefID: "giftcard"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 0, stockOf(t, db, "g1"))
+
+	// partial return: only the given quantity goes back, booked as "return"
+	require.NoError(t, db.Exec(`INSERT INTO order_items (id, order_id, variant_id, quantity) VALUES ('d1', 'delivered', 'v1', 2)`).Error)
+	require.NoError(t, ReserveStockInTx(ctx, db, "delivered", []StockLine{{VariantID: "v1", Qty: 2}}, exp))
+	require.NoError(t, ConvertReservationsInTx(ctx, db, "delivered"))
+	assert.Equal(t, 4, stockOf(t, db, "v1"))
+	n, err = RestockOrderInTx(ctx, db, RestockInput{OrderID: "delivered", RefType: "return", RefID: "ret1",
+		Reason: "return", Lines: []StockLine{{VariantID: "v1", Qty: 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 5, stockOf(t, db, "v1"))
+
+	var sum int
+	require.NoError(t, db.Table("stock_movements").Select("SUM(delta)").Scan(&sum).Error)
+	assert.Equal(t, 0, sum, "ledger reconciles with stock change 5 -> 5")
+}
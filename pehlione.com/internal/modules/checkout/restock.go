@@ -0,0 +1,170 @@
+package checkout
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/inventory"
+	"pehlione.com/app/internal/modules/products"
+)
+
+type RestockInput struct {
+	OrderID     string
+	ActorUserID string
+	RefType     string // order|refund|return
+	RefID       string
+	Note        string
+	Reason      string // boşsa inventory.ReasonRestock
+	// Lines: boş değilse yalnızca bu varyantlar, en fazla verilen miktarlar kadar
+	// geri eklenir (iade edilen kalemler). Boşsa siparişin tamamı.
+	Lines []StockLine
+}
+
+// RestockOrderInTx returns an order's stock inside the caller's tx:
+// active reservations are released and whatever was actually deducted
+// (converted reservations, or order_items for orders that predate
+// reservations) is put back, minus what an earlier restock already returned.
+// With Lines set only those quantities are put back (e.g. returned items).
+// Returns the total quantity put back.
+func RestockOrderInTx(ctx context.Context, tx *gorm.DB, in RestockInput) (int, error) {
+	var only map[string]int
+	if len(in.Lines) > 0 {
+		only = make(map[string]int, len(in.Lines))
+		for _, ln := range in.Lines {
+			only[ln.VariantID] += ln.Qty
+		}
+	} else if _, err := ReleaseReservationsInTx(ctx, tx, in.OrderID); err != nil {
+		return 0, err
+	}
+
+	reason := in.Reason
+	if reason == "" {
+		reason = inventory.ReasonRestock
+	}
+
+	sold, err := soldQtyInTx(ctx, tx, in.OrderID)
+	if err != nil {
+		return 0, err
+	}
+	restocked, err := inventory.OrderRestockedQtyInTx(ctx, tx, in.OrderID)
+	if err != nil {
+		return 0, err
+	}
+
+	allocs, err := inventory.OrderAllocationsInTx(ctx, tx, in.OrderID)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(sold))
+	for id := range sold {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	now := time.Now()
+	total := 0
+	for _, id := range ids {
+		done := 0
+		for _, q := range restocked[id] {
+			done += q
+		}
+		qty := sold[id] - done
+		if only != nil && qty > only[id] {
+			qty = only[id]
+		}
+		if qty <= 0 {
+			continue
+		}
+
+		// çekildiği depolara geri koy; kalan (lokasyonsuz eski siparişler) varsayılan depoya
+		for _, part := range restockTargets(allocs[id], restocked[id], qty) {
+			m := inventory.Movement{
+				VariantID:   id,
+				Delta:       part.Qty,
+				Reason:      reason,
+				OrderID:     &in.OrderID,
+				RefType:     inventory.Ptr(in.RefType),
+				RefID:       inventory.Ptr(in.RefID),
+				ActorUserID: inventory.Ptr(in.ActorUserID),
+				Note:        inventory.Ptr(in.Note),
+				CreatedAt:   now,
+			}
+			if part.LocationID != "" {
+				m.LocationID = &part.LocationID
+			}
+			if _, err := inventory.ApplyInTx(ctx, tx, m); err != nil {
+				return total, err
+			}
+			total += part.Qty
+		}
+	}
+	return total, nil
+}
+
+// restockTargets splits qty over the locations an item was allocated from,
+// skipping what was already put back there. Leftover gets LocationID "".
+func restockTargets(allocs []inventory.Allocation, restocked map[string]int, qty int) []inventory.Allocation {
+	var out []inventory.Allocation
+	for _, a := range allocs {
+		if qty == 0 {
+			break
+		}
+		room := a.Qty - restocked[a.LocationID]
+		if room <= 0 {
+			continue
+		}
+		if room > qty {
+			room = qty
+		}
+		out = append(out, inventory.Allocation{LocationID: a.LocationID, Qty: room})
+		qty -= room
+	}
+	if qty > 0 {
+		out = append(out, inventory.Allocation{Qty: qty})
+	}
+	return out
+}
+
+func soldQtyInTx(ctx context.Context, tx *gorm.DB, orderID string) (map[string]int, error) {
+	type row struct {
+		VariantID string `gorm:"column:variant_id"`
+		Qty       int    `gorm:"column:qty"`
+	}
+
+	var cnt int64
+	if err := tx.WithContext(ctx).Model(&Reservation{}).Where("order_id = ?", orderID).Count(&cnt).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	q := tx.WithContext(ctx)
+	if cnt > 0 {
+		q = q.Model(&Reservation{}).
+			Select("variant_id, SUM(qty) AS qty").
+			Where("order_id = ? AND status = ?", orderID, ReservationConverted).
+			Group("variant_id")
+	} else {
+		// rezervasyon öncesi siparişler: stok order oluşturulurken düşülmüştü.
+		// Hediye kartları hiç stoktan düşülmez; yalnızca hediye kartı içeren
+		// siparişlerin de rezervasyonu olmaz.
+		q = q.Table("order_items oi").
+			Select("oi.variant_id, SUM(oi.quantity) AS qty").
+			Joins("JOIN product_variants v ON v.id = oi.variant_id").
+			Joins("JOIN products p ON p.id = v.product_id").
+			Where("oi.order_id = ? AND p.kind <> ?", orderID, products.KindGiftCard).
+			Group("oi.variant_id")
+	}
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]int, len(rows))
+	for _, r := range rows {
+		out[r.VariantID] = r.Qty
+	}
+	return out, nil
+}
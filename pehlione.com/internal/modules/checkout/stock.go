@@ -9,6 +9,8 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/inventory"
 )
 
 type StockLine struct {
@@ -74,18 +76,14 @@ func DeductStockInTx(ctx context.Context, tx *gorm.DB, lines []StockLine) error
 		return &OutOfStockError{Items: oos}
 	}
 
-	// stock = stock - qty
+	// stock = stock - qty, lokasyonlara önceliğe göre dağıtılır
 	for _, id := range ids {
 		req := want[id]
-		res := tx.WithContext(ctx).
-			Table("product_variants").
-			Where("id = ?", id).
-			UpdateColumn("stock", gorm.Expr("stock - ?", req))
-		if res.Error != nil {
-			return res.Error
-		}
-		if res.RowsAffected != 1 {
-			return &OutOfStockError{Items: []OutOfStockItem{{VariantID: id, Requested: req, Available: 0}}}
+		if _, err := inventory.DeductInTx(ctx, tx, id, req, inventory.Movement{Reason: inventory.ReasonSale}); err != nil {
+			if errors.Is(err, inventory.ErrInsufficientStock) {
+				return &OutOfStockError{Items: []OutOfStockItem{{VariantID: id, Requested: req, Available: avail[id]}}}
+			}
+			return err
 		}
 	}
 
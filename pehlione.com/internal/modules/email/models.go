@@ -12,6 +12,7 @@ type OutboxEmail struct {
 	ToEmail      string         `gorm:"type:varchar(320);not null"`
 	Template     string         `gorm:"type:varchar(128);not null"`
 	Payload      datatypes.JSON `gorm:"type:json;not null"`
+	Attachments  datatypes.JSON `gorm:"type:json"`
 	Status       string         `gorm:"type:varchar(16);not null"`
 	AttemptCount int            `gorm:"not null;default:0"`
 	LastError    *string        `gorm:"type:text"`
@@ -33,10 +34,18 @@ const (
 )
 
 type Message struct {
-	To      string
-	Subject string
-	Text    string
-	HTML    string
+	To          string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent along with an email (e.g. a PDF document).
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
 }
 
 type Sender interface {
@@ -152,6 +152,16 @@ func defaultPreview(templateName string) string {
 		return "We processed your refund."
 	case TemplatePasswordReset:
 		return "Reset your password securely."
+	case TemplateLowStock:
+		return "A variant dropped below its low-stock threshold."
+	case TemplateReturnUpdate:
+		return "There is an update on your return."
+	case TemplateDisputeAlert:
+		return "A payment dispute needs attention."
+	case TemplateGiftCard:
+		return "Your gift card code is inside."
+	case TemplateOrderAccess:
+		return "Your secure link to view your order."
 	default:
 		return ""
 	}
@@ -159,6 +169,9 @@ func defaultPreview(templateName string) string {
 
 func (r *Renderer) subjectFor(templateName string, data map[string]any) string {
 	orderID := shortOrderID(data["OrderID"])
+	if n, _ := data["OrderNumber"].(string); n != "" {
+		orderID = n
+	}
 	switch templateName {
 	case TemplateVerifyEmail:
 		return "Verify your email"
@@ -184,6 +197,33 @@ func (r *Renderer) subjectFor(templateName string, data map[string]any) string {
 		return "Refund processed"
 	case TemplatePasswordReset:
 		return "Reset your password"
+	case TemplateLowStock:
+		if sku, _ := data["SKU"].(string); sku != "" {
+			return fmt.Sprintf("Low stock: %s", sku)
+		}
+		return "Low stock alert"
+	case TemplateReturnUpdate:
+		label, _ := data["StatusLabel"].(string)
+		if rma, _ := data["RMA"].(string); rma != "" && label != "" {
+			return fmt.Sprintf("%s: %s", rma, label)
+		}
+		return "Return update"
+	case TemplateDisputeAlert:
+		status, _ := data["Status"].(string)
+		if orderID != "" && status != "" {
+			return fmt.Sprintf("Dispute %s: order %s", status, orderID)
+		}
+		return "Payment dispute"
+	case TemplateGiftCard:
+		if storeCredit, _ := data["StoreCredit"].(bool); storeCredit {
+			return "Store credit added to your account"
+		}
+		return "You received a gift card"
+	case TemplateOrderAccess:
+		if orderID != "" {
+			return fmt.Sprintf("Your link to order %s", orderID)
+		}
+		return "Your order link"
 	default:
 		return "Notification"
 	}
@@ -13,9 +13,10 @@ import (
 )
 
 type Job struct {
-	To       string
-	Template string
-	Payload  map[string]any
+	To          string
+	Template    string
+	Payload     map[string]any
+	Attachments []Attachment
 }
 
 type OutboxService struct {
@@ -51,11 +52,18 @@ func (s *OutboxService) enqueue(ctx context.Context, db *gorm.DB, job Job) error
 	if err != nil {
 		return err
 	}
+	var files datatypes.JSON
+	if len(job.Attachments) > 0 {
+		if files, err = json.Marshal(job.Attachments); err != nil {
+			return err
+		}
+	}
 	now := time.Now()
 	e := OutboxEmail{
 		ToEmail:      to,
 		Template:     job.Template,
 		Payload:      datatypes.JSON(data),
+		Attachments:  files,
 		Status:       StatusPending,
 		AttemptCount: 0,
 		LastError:    nil,
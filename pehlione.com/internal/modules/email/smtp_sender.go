@@ -3,6 +3,7 @@ package email
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
 	"net"
 	"net/smtp"
@@ -34,7 +35,7 @@ func (s *SMTPSender) Send(ctx context.Context, m Message) error {
 		auth = smtp.PlainAuth("", s.cfg.User, s.cfg.Pass, s.cfg.Host)
 	}
 
-	body := buildMIME(s.cfg.From, m.To, m.Subject, m.Text, m.HTML)
+	body := buildMIME(s.cfg.From, m.To, m.Subject, m.Text, m.HTML, m.Attachments...)
 
 	// No TLS: plain connection (e.g., MailHog development)
 	if !s.cfg.UseTLS {
@@ -169,7 +170,7 @@ func (s *SMTPSender) Send(ctx context.Context, m Message) error {
 	return c.Quit()
 }
 
-func buildMIME(from, to, subject, text, html string) string {
+func buildMIME(from, to, subject, text, html string, attachments ...Attachment) string {
 	subject = strings.ReplaceAll(subject, "\n", " ")
 	subject = strings.ReplaceAll(subject, "\r", " ")
 
@@ -178,6 +179,11 @@ func buildMIME(from, to, subject, text, html string) string {
 	sb.WriteString("To: " + to + "\r\n")
 	sb.WriteString("Subject: " + subject + "\r\n")
 	sb.WriteString("MIME-Version: 1.0\r\n")
+	if len(attachments) > 0 {
+		// ekler varsa: mixed { alternative { text, html }, dosyalar... }
+		sb.WriteString("Content-Type: multipart/mixed; boundary=MIXED\r\n")
+		sb.WriteString("\r\n--MIXED\r\n")
+	}
 	sb.WriteString("Content-Type: multipart/alternative; boundary=BOUNDARY\r\n")
 	sb.WriteString("\r\n--BOUNDARY\r\n")
 	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
@@ -186,5 +192,27 @@ func buildMIME(from, to, subject, text, html string) string {
 	sb.WriteString("Content-Type: text/html; charset=utf-8\r\n\r\n")
 	sb.WriteString(html + "\r\n")
 	sb.WriteString("\r\n--BOUNDARY--\r\n")
+	if len(attachments) == 0 {
+		return sb.String()
+	}
+
+	for _, a := range attachments {
+		ct := a.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		name := strings.NewReplacer("\"", "", "\r", "", "\n", "").Replace(a.Filename)
+		sb.WriteString("\r\n--MIXED\r\n")
+		sb.WriteString("Content-Type: " + ct + "; name=\"" + name + "\"\r\n")
+		sb.WriteString("Content-Transfer-Encoding: base64\r\n")
+		sb.WriteString("Content-Disposition: attachment; filename=\"" + name + "\"\r\n\r\n")
+		enc := base64.StdEncoding.EncodeToString(a.Data)
+		for len(enc) > 76 {
+			sb.WriteString(enc[:76] + "\r\n")
+			enc = enc[76:]
+		}
+		sb.WriteString(enc + "\r\n")
+	}
+	sb.WriteString("\r\n--MIXED--\r\n")
 	return sb.String()
 }
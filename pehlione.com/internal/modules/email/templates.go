@@ -8,4 +8,9 @@ const (
 	TemplateOrderRefunded         = "order_refunded"
 	TemplatePasswordReset         = "password_reset"
 	TemplatePasswordChangeConfirm = "password_change_confirmation"
+	TemplateLowStock              = "low_stock"
+	TemplateReturnUpdate          = "return_update"
+	TemplateDisputeAlert          = "dispute_alert"
+	TemplateGiftCard              = "gift_card"
+	TemplateOrderAccess           = "order_access"
 )
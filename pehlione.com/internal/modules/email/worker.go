@@ -76,6 +76,17 @@ func (w *Worker) processOne(ctx context.Context, job OutboxEmail) {
 		return
 	}
 
+	var attachments []Attachment
+	if len(job.Attachments) > 0 {
+		if err := json.Unmarshal(job.Attachments, &attachments); err != nil {
+			log.Printf("Email worker %s: invalid attachments for %d: %v", w.workerID, job.ID, err)
+			if markErr := w.svc.MarkFailed(ctx, job.ID, truncateStr(err.Error(), 255)); markErr != nil {
+				log.Printf("Email worker %s: mark failed error for %d: %v", w.workerID, job.ID, markErr)
+			}
+			return
+		}
+	}
+
 	subject := "pehlione notification"
 	htmlBody := ""
 	textBody := ""
@@ -106,10 +117,11 @@ func (w *Worker) processOne(ctx context.Context, job OutboxEmail) {
 	attemptNum := job.AttemptCount + 1
 	log.Printf("Email worker %s: sending %d to %s (attempt %d)", w.workerID, job.ID, job.ToEmail, attemptNum)
 	sendErr := w.sender.Send(ctx, Message{
-		To:      job.ToEmail,
-		Subject: subject,
-		Text:    textBody,
-		HTML:    htmlBody,
+		To:          job.ToEmail,
+		Subject:     subject,
+		Text:        textBody,
+		HTML:        htmlBody,
+		Attachments: attachments,
 	})
 
 	if sendErr == nil {
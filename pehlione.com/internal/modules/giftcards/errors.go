@@ -0,0 +1,11 @@
+package giftcards
+
+import "errors"
+
+var (
+	ErrCardNotFound     = errors.New("gift card not found")
+	ErrCardInactive     = errors.New("gift card is not active")
+	ErrCardEmpty        = errors.New("gift card has no balance")
+	ErrCurrencyMismatch = errors.New("gift card currency does not match order")
+	ErrCardSpent        = errors.New("gift card has already been used")
+)
@@ -0,0 +1,65 @@
+package giftcards
+
+import "time"
+
+// Card kinds. Store credit is a gift card issued by a refund.
+const (
+	KindGiftCard    = "gift_card"
+	KindStoreCredit = "store_credit"
+)
+
+// Card statuses.
+const (
+	StatusActive   = "active"
+	StatusDisabled = "disabled"
+)
+
+// Transaction kinds.
+const (
+	TxIssue   = "issue"   // + satın alma ile oluşturuldu
+	TxRedeem  = "redeem"  // - siparişte kullanıldı
+	TxRelease = "release" // + ödenmeyen sipariş iptal edildi
+	TxCredit  = "credit"  // + iade store credit olarak yüklendi
+	TxVoid    = "void"    // - satın alınan kart iade edildi, kart kapatıldı
+	TxRestore = "restore" // + iadesi başarısız oldu, kart yeniden açıldı
+)
+
+type GiftCard struct {
+	ID   string `gorm:"type:char(36);primaryKey"`
+	Code string `gorm:"type:varchar(32);not null;uniqueIndex:ux_gift_cards_code"`
+
+	Kind         string `gorm:"type:varchar(16);not null"`
+	Status       string `gorm:"type:varchar(16);not null"`
+	Currency     string `gorm:"type:char(3);not null"`
+	InitialCents int    `gorm:"not null"`
+	BalanceCents int    `gorm:"not null"`
+
+	UserID            *string `gorm:"type:char(36)"`
+	RecipientEmail    *string `gorm:"type:varchar(255)"`
+	SourceOrderID     *string `gorm:"type:char(36);index:ix_gift_cards_source_order"`
+	SourceOrderItemID *string `gorm:"type:char(36)"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (GiftCard) TableName() string { return "gift_cards" }
+
+// Transaction is one balance movement; the rows of a card sum to its balance.
+type Transaction struct {
+	ID         string  `gorm:"type:char(36);primaryKey"`
+	GiftCardID string  `gorm:"type:char(36);not null"`
+	OrderID    *string `gorm:"type:char(36)"`
+
+	Kind              string `gorm:"type:varchar(16);not null"`
+	AmountCents       int    `gorm:"not null"` // +in, -out
+	BalanceAfterCents int    `gorm:"not null"`
+	Currency          string `gorm:"type:char(3);not null"`
+
+	RefType string `gorm:"type:varchar(16);not null"`
+	RefID   string `gorm:"type:char(36);not null"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Transaction) TableName() string { return "gift_card_transactions" }
@@ -0,0 +1,461 @@
+package giftcards
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// codeAlphabet: karıştırılabilen karakterler (0/O, 1/I) yok.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewCode returns a random code in XXXX-XXXX-XXXX form (~60 bits).
+func NewCode() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b[0:4]) + "-" + string(b[4:8]) + "-" + string(b[8:12])
+}
+
+// NormalizeCode upper-cases a customer supplied code and restores the dashes,
+// so "abcd efgh jkmn" and "ABCD-EFGH-JKMN" match.
+func NormalizeCode(code string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	s := sb.String()
+	if len(s) != 12 {
+		return s
+	}
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12]
+}
+
+// ParseCodes splits a comma/space separated field into unique normalized codes.
+func ParseCodes(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r'
+	})
+	var out []string
+	seen := map[string]bool{}
+	for _, f := range fields {
+		c := NormalizeCode(f)
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+type RedeemInput struct {
+	Codes       []string
+	OrderID     string
+	UserID      *string
+	Currency    string // sipariş (charge) para birimi
+	AmountCents int    // karşılanabilecek en fazla tutar
+}
+
+// RedeemInTx debits the cards in the given order until AmountCents is covered
+// and returns one redeem transaction per used card. Cards are locked, so two
+// checkouts can't spend the same balance.
+func RedeemInTx(ctx context.Context, tx *gorm.DB, in RedeemInput) ([]Transaction, error) {
+	codes := make([]string, 0, len(in.Codes))
+	for _, c := range in.Codes {
+		if c = NormalizeCode(c); c != "" {
+			codes = append(codes, c)
+		}
+	}
+	if len(codes) == 0 || in.AmountCents <= 0 {
+		return nil, nil
+	}
+
+	var cards []GiftCard
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code IN ?", codes).
+		Order("code ASC").
+		Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]GiftCard, len(cards))
+	for _, c := range cards {
+		byCode[c.Code] = c
+	}
+
+	now := time.Now()
+	remaining := in.AmountCents
+	var out []Transaction
+	for _, code := range codes {
+		c, ok := byCode[code]
+		if !ok {
+			return nil, ErrCardNotFound
+		}
+		// kullanıcıya ait store credit başkası tarafından kullanılamaz
+		if c.UserID != nil && (in.UserID == nil || *in.UserID != *c.UserID) {
+			return nil, ErrCardNotFound
+		}
+		if c.Status != StatusActive {
+			return nil, ErrCardInactive
+		}
+		if c.Currency != in.Currency {
+			return nil, ErrCurrencyMismatch
+		}
+		if c.BalanceCents <= 0 {
+			return nil, ErrCardEmpty
+		}
+		if remaining == 0 {
+			continue // sipariş zaten karşılandı; kart dokunulmadan kalır
+		}
+
+		amount := min(c.BalanceCents, remaining)
+		t, err := moveInTx(ctx, tx, c, TxRedeem, -amount, &in.OrderID, "order", in.OrderID, now)
+		if err != nil {
+			return nil, err
+		}
+		remaining -= amount
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// ReleaseOrderInTx gives the redeemed balance of a cancelled, unpaid order
+// back to its cards. Already released cards are skipped. Returns the total
+// released.
+func ReleaseOrderInTx(ctx context.Context, tx *gorm.DB, orderID string) (int, error) {
+	var rows []Transaction
+	if err := tx.WithContext(ctx).
+		Where("order_id = ? AND kind IN ?", orderID, []string{TxRedeem, TxRelease}).
+		Order("gift_card_id ASC").
+		Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	redeemed := map[string]int{}
+	for _, t := range rows {
+		if t.Kind == TxRedeem {
+			redeemed[t.GiftCardID] += -t.AmountCents
+		}
+	}
+	for _, t := range rows {
+		if t.Kind == TxRelease {
+			delete(redeemed, t.GiftCardID)
+		}
+	}
+	if len(redeemed) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, 0, len(redeemed))
+	for id := range redeemed {
+		ids = append(ids, id)
+	}
+	var cards []GiftCard
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Find(&cards).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	total := 0
+	for _, c := range cards {
+		amount := redeemed[c.ID]
+		if _, err := moveInTx(ctx, tx, c, TxRelease, amount, &orderID, "order", orderID, now); err != nil {
+			return 0, err
+		}
+		total += amount
+	}
+	return total, nil
+}
+
+// OrderRedemptionsInTx returns the redeem transactions of an order.
+func OrderRedemptionsInTx(ctx context.Context, tx *gorm.DB, orderID string) ([]Transaction, error) {
+	var out []Transaction
+	err := tx.WithContext(ctx).
+		Where("order_id = ? AND kind = ?", orderID, TxRedeem).
+		Order("created_at ASC, id ASC").
+		Find(&out).Error
+	return out, err
+}
+
+// VoidPurchasedInTx takes back count cards bought with an order item when the
+// item is refunded: their balance is booked out and they are disabled. Only
+// unspent cards can be taken back, ErrCardSpent otherwise. count < 0 takes
+// back every still active card of the item. An item without issued cards
+// (the order was never paid) is a no-op. Returns the total voided.
+func VoidPurchasedInTx(ctx context.Context, tx *gorm.DB, orderItemID string, count int, refType, refID string) (int, error) {
+	if count == 0 {
+		return 0, nil
+	}
+	var cards []GiftCard
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("source_order_item_id = ? AND kind = ?", orderItemID, KindGiftCard).
+		Order("created_at ASC, id ASC").
+		Find(&cards).Error; err != nil {
+		return 0, err
+	}
+	if len(cards) == 0 {
+		return 0, nil
+	}
+
+	var take []GiftCard
+	for _, c := range cards {
+		if c.Status != StatusActive {
+			continue // daha önce iade edildi
+		}
+		if c.BalanceCents == c.InitialCents {
+			take = append(take, c)
+		} else if count < 0 {
+			return 0, ErrCardSpent
+		}
+	}
+	if count > 0 {
+		if len(take) < count {
+			return 0, ErrCardSpent
+		}
+		take = take[:count]
+	}
+
+	now := time.Now()
+	total := 0
+	for _, c := range take {
+		if _, err := moveInTx(ctx, tx, c, TxVoid, -c.BalanceCents, c.SourceOrderID, refType, refID, now); err != nil {
+			return 0, err
+		}
+		if err := tx.WithContext(ctx).Model(&GiftCard{}).
+			Where("id = ?", c.ID).
+			Update("status", StatusDisabled).Error; err != nil {
+			return 0, err
+		}
+		total += c.BalanceCents
+	}
+	return total, nil
+}
+
+// RestoreInTx reopens the cards voided for a refund that failed and gives
+// them their balance back. Already restored cards are skipped.
+func RestoreInTx(ctx context.Context, tx *gorm.DB, refType, refID string) error {
+	var rows []Transaction
+	if err := tx.WithContext(ctx).
+		Where("ref_type = ? AND ref_id = ? AND kind IN ?", refType, refID, []string{TxVoid, TxRestore}).
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	voided := map[string]int{}
+	for _, t := range rows {
+		if t.Kind == TxVoid {
+			voided[t.GiftCardID] += -t.AmountCents
+		}
+	}
+	for _, t := range rows {
+		if t.Kind == TxRestore {
+			delete(voided, t.GiftCardID)
+		}
+	}
+	if len(voided) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(voided))
+	for id := range voided {
+		ids = append(ids, id)
+	}
+	var cards []GiftCard
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Find(&cards).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, c := range cards {
+		if _, err := moveInTx(ctx, tx, c, TxRestore, voided[c.ID], c.SourceOrderID, refType, refID, now); err != nil {
+			return err
+		}
+		if err := tx.WithContext(ctx).Model(&GiftCard{}).
+			Where("id = ?", c.ID).
+			Update("status", StatusActive).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type IssueInput struct {
+	Kind           string
+	Currency       string
+	AmountCents    int
+	UserID         *string
+	RecipientEmail string
+
+	SourceOrderID     *string
+	SourceOrderItemID *string
+
+	// ilk hareketin referansı (order_item veya refund)
+	RefType string
+	RefID   string
+}
+
+// IssueInTx creates a card with a fresh code and its opening transaction.
+func IssueInTx(ctx context.Context, tx *gorm.DB, in IssueInput) (GiftCard, error) {
+	if in.AmountCents <= 0 {
+		return GiftCard{}, errors.New("gift card amount must be positive")
+	}
+	kind := in.Kind
+	if kind == "" {
+		kind = KindGiftCard
+	}
+	var email *string
+	if e := strings.ToLower(strings.TrimSpace(in.RecipientEmail)); e != "" {
+		email = &e
+	}
+
+	now := time.Now()
+	c := GiftCard{
+		ID:                uuid.NewString(),
+		Code:              NewCode(),
+		Kind:              kind,
+		Status:            StatusActive,
+		Currency:          strings.ToUpper(in.Currency),
+		InitialCents:      in.AmountCents,
+		BalanceCents:      0,
+		UserID:            in.UserID,
+		RecipientEmail:    email,
+		SourceOrderID:     in.SourceOrderID,
+		SourceOrderItemID: in.SourceOrderItemID,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := tx.WithContext(ctx).Create(&c).Error; err != nil {
+		return GiftCard{}, err
+	}
+
+	txKind := TxIssue
+	if kind == KindStoreCredit {
+		txKind = TxCredit
+	}
+	t, err := moveInTx(ctx, tx, c, txKind, in.AmountCents, in.SourceOrderID, in.RefType, in.RefID, now)
+	if err != nil {
+		return GiftCard{}, err
+	}
+	c.BalanceCents = t.BalanceAfterCents
+	return c, nil
+}
+
+type CreditInput struct {
+	UserID      *string // kayıtlı müşteri: mevcut store credit kartına yüklenir
+	Email       string  // misafir: yeni kart, kod e-posta ile gönderilir
+	Currency    string
+	AmountCents int
+	OrderID     string
+	RefundID    string
+}
+
+// CreditStoreCreditInTx loads a refund onto the customer's store credit.
+// Registered customers keep one card per currency; guests get a new card.
+// created reports whether a new code was issued.
+func CreditStoreCreditInTx(ctx context.Context, tx *gorm.DB, in CreditInput) (card GiftCard, created bool, err error) {
+	currency := strings.ToUpper(in.Currency)
+	if in.UserID != nil {
+		err = tx.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND kind = ? AND currency = ? AND status = ?", *in.UserID, KindStoreCredit, currency, StatusActive).
+			Order("created_at ASC").
+			First(&card).Error
+		if err == nil {
+			t, err := moveInTx(ctx, tx, card, TxCredit, in.AmountCents, &in.OrderID, "refund", in.RefundID, time.Now())
+			if err != nil {
+				return GiftCard{}, false, err
+			}
+			card.BalanceCents = t.BalanceAfterCents
+			return card, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return GiftCard{}, false, err
+		}
+	}
+
+	card, err = IssueInTx(ctx, tx, IssueInput{
+		Kind:           KindStoreCredit,
+		Currency:       currency,
+		AmountCents:    in.AmountCents,
+		UserID:         in.UserID,
+		RecipientEmail: in.Email,
+		SourceOrderID:  &in.OrderID,
+		RefType:        "refund",
+		RefID:          in.RefundID,
+	})
+	return card, err == nil, err
+}
+
+// moveInTx applies a balance change to a locked card and records it.
+func moveInTx(ctx context.Context, tx *gorm.DB, c GiftCard, kind string, amount int, orderID *string, refType, refID string, now time.Time) (Transaction, error) {
+	balance := c.BalanceCents + amount
+	if balance < 0 {
+		return Transaction{}, ErrCardEmpty
+	}
+	if err := tx.WithContext(ctx).Model(&GiftCard{}).
+		Where("id = ?", c.ID).
+		Updates(map[string]any{"balance_cents": balance, "updated_at": now}).Error; err != nil {
+		return Transaction{}, err
+	}
+
+	t := Transaction{
+		ID:                uuid.NewString(),
+		GiftCardID:        c.ID,
+		OrderID:           orderID,
+		Kind:              kind,
+		AmountCents:       amount,
+		BalanceAfterCents: balance,
+		Currency:          c.Currency,
+		RefType:           refType,
+		RefID:             refID,
+		CreatedAt:         now,
+	}
+	return t, tx.WithContext(ctx).Create(&t).Error
+}
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Lookup returns a card by code (balance check).
+func (s *Service) Lookup(ctx context.Context, code string) (GiftCard, error) {
+	var c GiftCard
+	err := s.db.WithContext(ctx).First(&c, "code = ?", NormalizeCode(code)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return GiftCard{}, ErrCardNotFound
+	}
+	return c, err
+}
+
+// Transactions returns a card's ledger, oldest first.
+func (s *Service) Transactions(ctx context.Context, cardID string) ([]Transaction, error) {
+	var out []Transaction
+	err := s.db.WithContext(ctx).
+		Where("gift_card_id = ?", cardID).
+		Order("created_at ASC, id ASC").
+		Find(&out).Error
+	return out, err
+}
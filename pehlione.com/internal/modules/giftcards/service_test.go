@@ -0,0 +1,138 @@
+package giftcards
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE gift_cards (
+		id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, kind TEXT NOT NULL, status TEXT NOT NULL, currency TEXT NOT NULL,
+		initial_cents INTEGER NOT NULL, balance_cents INTEGER NOT NULL, user_id TEXT, recipient_email TEXT,
+		source_order_id TEXT, source_order_item_id TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE gift_card_transactions (
+		id TEXT PRIMARY KEY, gift_card_id TEXT NOT NULL, order_id TEXT, kind TEXT NOT NULL, amount_cents INTEGER NOT NULL,
+		balance_after_cents INTEGER NOT NULL, currency TEXT NOT NULL, ref_type TEXT NOT NULL, ref_id TEXT NOT NULL,
+		created_at DATETIME NOT NULL, UNIQUE (gift_card_id, kind, ref_type, ref_id))`).Error)
+	return db
+}
+
+func issue(t *testing.T, db *gorm.DB, amount int, currency string) GiftCard {
+	c, err := IssueInTx(context.Background(), db, IssueInput{Currency: currency, AmountCents: amount, RefType: "order_item", RefID: "it-" + currency})
+	require.NoError(t, err)
+	return c
+}
+
+func balance(t *testing.T, db *gorm.DB, id string) int {
+	var c GiftCard
+	require.NoError(t, db.First(&c, "id = ?", id).Error)
+	return c.BalanceCents
+}
+
+func TestCodes(t *testing.T) {
+	c := NewCode()
+	assert.Len(t, c, 14)
+	assert.Equal(t, c, NormalizeCode(c))
+	assert.Equal(t, "ABCD-EFGH-JKMN", NormalizeCode(" abcd efgh-jkmn "))
+	assert.Equal(t, []string{"ABCD-EFGH-JKMN", "WXYZ-2345-6789"}, ParseCodes("abcdefghjkmn, WXYZ-2345-6789;ABCD-EFGH-JKMN"))
+}
+
+func TestRedeem_SplitsAcrossCardsAndReleases(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	a := issue(t, db, 3000, "EUR")
+	b := issue(t, db, 5000, "EUR")
+
+	txs, err := RedeemInTx(ctx, db, RedeemInput{Codes: []string{a.Code, b.Code}, OrderID: "o1", Currency: "EUR", AmountCents: 4000})
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	assert.Equal(t, -3000, txs[0].AmountCents)
+	assert.Equal(t, -1000, txs[1].AmountCents)
+	assert.Equal(t, 0, balance(t, db, a.ID))
+	assert.Equal(t, 4000, balance(t, db, b.ID))
+
+	_, err = RedeemInTx(ctx, db, RedeemInput{Codes: []string{a.Code}, OrderID: "o2", Currency: "EUR", AmountCents: 100})
+	assert.ErrorIs(t, err, ErrCardEmpty)
+	_, err = RedeemInTx(ctx, db, RedeemInput{Codes: []string{b.Code}, OrderID: "o2", Currency: "USD", AmountCents: 100})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = RedeemInTx(ctx, db, RedeemInput{Codes: []string{"NOPE-NOPE-NOPE"}, OrderID: "o2", Currency: "EUR", AmountCents: 100})
+	assert.ErrorIs(t, err, ErrCardNotFound)
+
+	released, err := ReleaseOrderInTx(ctx, db, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, 4000, released)
+	assert.Equal(t, 3000, balance(t, db, a.ID))
+	assert.Equal(t, 5000, balance(t, db, b.ID))
+
+	released, err = ReleaseOrderInTx(ctx, db, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, released, "release is idempotent")
+}
+
+func TestStoreCredit_ToppedUpAndOwnerOnly(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	user, other := "u1", "u2"
+
+	card, created, err := CreditStoreCreditInTx(ctx, db, CreditInput{UserID: &user, Currency: "eur", AmountCents: 1500, OrderID: "o1", RefundID: "r1"})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, KindStoreCredit, card.Kind)
+
+	again, created, err := CreditStoreCreditInTx(ctx, db, CreditInput{UserID: &user, Currency: "EUR", AmountCents: 500, OrderID: "o2", RefundID: "r2"})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, card.ID, again.ID)
+	assert.Equal(t, 2000, again.BalanceCents)
+
+	_, err = RedeemInTx(ctx, db, RedeemInput{Codes: []string{card.Code}, OrderID: "o3", UserID: &other, Currency: "EUR", AmountCents: 100})
+	assert.ErrorIs(t, err, ErrCardNotFound, "store credit is bound to its owner")
+
+	txs, err := RedeemInTx(ctx, db, RedeemInput{Codes: []string{card.Code}, OrderID: "o3", UserID: &user, Currency: "EUR", AmountCents: 100})
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, 1900, balance(t, db, card.ID))
+}
+
+func TestVoidPurchased_OnlyUnspentCardsAndRestore(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	order, item := "o1", "oi1"
+	var cards []GiftCard
+	for range 2 {
+		c, err := IssueInTx(ctx, db, IssueInput{Currency: "EUR", AmountCents: 2500, SourceOrderID: &order, SourceOrderItemID: &item, RefType: "order_item", RefID: item})
+		require.NoError(t, err)
+		cards = append(cards, c)
+	}
+	_, err := RedeemInTx(ctx, db, RedeemInput{Codes: []string{cards[0].Code}, OrderID: "o2", Currency: "EUR", AmountCents: 100})
+	require.NoError(t, err)
+
+	_, err = VoidPurchasedInTx(ctx, db, item, 2, "refund", "r1")
+	assert.ErrorIs(t, err, ErrCardSpent, "one card was already used")
+	_, err = VoidPurchasedInTx(ctx, db, item, -1, "refund", "r1")
+	assert.ErrorIs(t, err, ErrCardSpent)
+
+	voided, err := VoidPurchasedInTx(ctx, db, item, 1, "refund", "r1")
+	require.NoError(t, err)
+	assert.Equal(t, 2500, voided, "the unspent card is taken back")
+	assert.Equal(t, 0, balance(t, db, cards[1].ID))
+	_, err = RedeemInTx(ctx, db, RedeemInput{Codes: []string{cards[1].Code}, OrderID: "o3", Currency: "EUR", AmountCents: 100})
+	assert.ErrorIs(t, err, ErrCardInactive)
+
+	require.NoError(t, RestoreInTx(ctx, db, "refund", "r1"))
+	require.NoError(t, RestoreInTx(ctx, db, "refund", "r1"), "restore is idempotent")
+	assert.Equal(t, 2500, balance(t, db, cards[1].ID))
+	_, err = RedeemInTx(ctx, db, RedeemInput{Codes: []string{cards[1].Code}, OrderID: "o3", Currency: "EUR", AmountCents: 100})
+	assert.NoError(t, err)
+
+	voided, err = VoidPurchasedInTx(ctx, db, "no-cards", -1, "refund", "r2")
+	require.NoError(t, err)
+	assert.Zero(t, voided)
+}
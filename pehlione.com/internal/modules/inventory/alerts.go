@@ -0,0 +1,54 @@
+package inventory
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	emailmod "pehlione.com/app/internal/modules/email"
+)
+
+// enqueueLowStockInTx queues a low-stock email to every admin in the same tx,
+// so the alert is only sent if the stock change commits.
+func enqueueLowStockInTx(ctx context.Context, tx *gorm.DB, variantID string, stock, threshold int) error {
+	var info struct {
+		SKU         string `gorm:"column:sku"`
+		ProductID   string `gorm:"column:product_id"`
+		ProductName string `gorm:"column:name"`
+	}
+	if err := tx.WithContext(ctx).
+		Table("product_variants v").
+		Select("v.sku, v.product_id, p.name").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Where("v.id = ?", variantID).
+		Scan(&info).Error; err != nil {
+		return err
+	}
+
+	var recipients []string
+	if err := tx.WithContext(ctx).
+		Table("users").
+		Where("role = ?", "admin").
+		Pluck("email", &recipients).Error; err != nil {
+		return err
+	}
+
+	outbox := emailmod.NewService(tx)
+	for _, to := range recipients {
+		if err := outbox.EnqueueTx(ctx, tx, emailmod.Job{
+			To:       to,
+			Template: emailmod.TemplateLowStock,
+			Payload: map[string]any{
+				"VariantID":   variantID,
+				"SKU":         info.SKU,
+				"ProductID":   info.ProductID,
+				"ProductName": info.ProductName,
+				"Stock":       stock,
+				"Threshold":   threshold,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Location statuses.
+const (
+	LocationActive   = "active"
+	LocationInactive = "inactive"
+)
+
+var ErrInsufficientStock = errors.New("insufficient stock across locations")
+
+// Location is a warehouse we ship from. Lower priority is allocated first.
+type Location struct {
+	ID        string    `gorm:"type:char(36);primaryKey"`
+	Code      string    `gorm:"type:varchar(32);not null;uniqueIndex:ux_stock_locations_code"`
+	Name      string    `gorm:"type:varchar(128);not null"`
+	Priority  int       `gorm:"not null;default:0"`
+	Status    string    `gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Location) TableName() string { return "stock_locations" }
+
+// Level is the on-hand quantity of a variant at a location.
+// product_variants.stock is kept equal to the sum of its levels.
+type Level struct {
+	LocationID string    `gorm:"type:char(36);primaryKey"`
+	VariantID  string    `gorm:"type:char(36);primaryKey"`
+	Qty        int       `gorm:"not null;default:0"`
+	UpdatedAt  time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Level) TableName() string { return "stock_levels" }
+
+// ItemAllocation records which location an order item ships from.
+type ItemAllocation struct {
+	ID          string    `gorm:"type:char(36);primaryKey"`
+	OrderItemID string    `gorm:"type:char(36);not null;uniqueIndex:ux_order_item_allocations_item_location,priority:1"`
+	LocationID  string    `gorm:"type:char(36);not null;uniqueIndex:ux_order_item_allocations_item_location,priority:2"`
+	Qty         int       `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (ItemAllocation) TableName() string { return "order_item_allocations" }
+
+// Allocation is a slice of a deduction taken from one location.
+type Allocation struct {
+	LocationID string
+	Qty        int
+}
+
+// ListLocations returns all locations, active ones first, by priority.
+func ListLocations(ctx context.Context, db *gorm.DB) ([]Location, error) {
+	var out []Location
+	err := db.WithContext(ctx).
+		Order("CASE WHEN status = 'active' THEN 0 ELSE 1 END, priority ASC, code ASC").
+		Find(&out).Error
+	return out, err
+}
+
+// DefaultLocationInTx is where stock without an explicit location is booked.
+// Returns "" when no location is configured.
+func DefaultLocationInTx(ctx context.Context, tx *gorm.DB) (string, error) {
+	locs, err := ListLocations(ctx, tx)
+	if err != nil || len(locs) == 0 {
+		return "", err
+	}
+	return locs[0].ID, nil
+}
+
+// Allocate splits qty across levels in the given (priority) order without
+// touching the database. ok is false when the levels can't cover qty.
+func Allocate(levels []Allocation, qty int) ([]Allocation, bool) {
+	var out []Allocation
+	left := qty
+	for _, l := range levels {
+		if left == 0 {
+			break
+		}
+		if l.Qty <= 0 {
+			continue
+		}
+		take := l.Qty
+		if take > left {
+			take = left
+		}
+		out = append(out, Allocation{LocationID: l.LocationID, Qty: take})
+		left -= take
+	}
+	return out, left == 0
+}
+
+// DeductInTx allocates qty of a variant across locations by priority,
+// deducts it and writes one ledger row per location. m is used as the
+// template for those rows (reason, order, ref, actor).
+func DeductInTx(ctx context.Context, tx *gorm.DB, variantID string, qty int, m Movement) ([]Allocation, error) {
+	type row struct {
+		LocationID string `gorm:"column:location_id"`
+		Qty        int    `gorm:"column:qty"`
+	}
+	var rows []row
+	if err := tx.WithContext(ctx).
+		Table("stock_levels sl").
+		Select("sl.location_id, sl.qty").
+		Joins("JOIN stock_locations l ON l.id = sl.location_id").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("sl.variant_id = ?", variantID).
+		Order("CASE WHEN l.status = 'active' THEN 0 ELSE 1 END, l.priority ASC, l.code ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var allocs []Allocation
+	if len(rows) == 0 {
+		// lokasyon tanımlı değil: yalnızca toplam stok
+		loc, err := DefaultLocationInTx(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		if loc != "" {
+			return nil, ErrInsufficientStock
+		}
+		allocs = []Allocation{{Qty: qty}}
+	} else {
+		levels := make([]Allocation, 0, len(rows))
+		for _, r := range rows {
+			levels = append(levels, Allocation{LocationID: r.LocationID, Qty: r.Qty})
+		}
+		var ok bool
+		if allocs, ok = Allocate(levels, qty); !ok {
+			return nil, ErrInsufficientStock
+		}
+	}
+
+	for _, a := range allocs {
+		mv := m
+		mv.ID = ""
+		mv.VariantID = variantID
+		mv.Delta = -a.Qty
+		mv.LocationID = Ptr(a.LocationID)
+		if _, err := ApplyInTx(ctx, tx, mv); err != nil {
+			return nil, err
+		}
+	}
+	return allocs, nil
+}
+
+// SaveItemAllocationsInTx stores where an order item was picked from.
+func SaveItemAllocationsInTx(ctx context.Context, tx *gorm.DB, orderItemID string, allocs []Allocation) error {
+	now := time.Now()
+	for _, a := range allocs {
+		if a.LocationID == "" {
+			continue
+		}
+		ia := ItemAllocation{ID: uuid.NewString(), OrderItemID: orderItemID, LocationID: a.LocationID, Qty: a.Qty, CreatedAt: now}
+		if err := tx.WithContext(ctx).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "order_item_id"}, {Name: "location_id"}},
+				DoUpdates: clause.Assignments(map[string]any{"qty": gorm.Expr("qty + ?", a.Qty)}),
+			}).
+			Create(&ia).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OrderAllocationsInTx returns per-variant allocations of an order, in location priority order.
+func OrderAllocationsInTx(ctx context.Context, tx *gorm.DB, orderID string) (map[string][]Allocation, error) {
+	type row struct {
+		VariantID  string `gorm:"column:variant_id"`
+		LocationID string `gorm:"column:location_id"`
+		Qty        int    `gorm:"column:qty"`
+		Priority   int    `gorm:"column:priority"`
+	}
+	var rows []row
+	if err := tx.WithContext(ctx).
+		Table("order_item_allocations a").
+		Select("oi.variant_id, a.location_id, a.qty, l.priority").
+		Joins("JOIN order_items oi ON oi.id = a.order_item_id").
+		Joins("JOIN stock_locations l ON l.id = a.location_id").
+		Where("oi.order_id = ?", orderID).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Priority < rows[j].Priority })
+
+	out := map[string][]Allocation{}
+	for _, r := range rows {
+		out[r.VariantID] = append(out[r.VariantID], Allocation{LocationID: r.LocationID, Qty: r.Qty})
+	}
+	return out, nil
+}
+
+// LevelsForVariants returns variant -> location -> qty.
+func LevelsForVariants(ctx context.Context, db *gorm.DB, variantIDs []string) (map[string]map[string]int, error) {
+	out := map[string]map[string]int{}
+	if len(variantIDs) == 0 {
+		return out, nil
+	}
+	var levels []Level
+	if err := db.WithContext(ctx).Where("variant_id IN ?", variantIDs).Find(&levels).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range levels {
+		if out[l.VariantID] == nil {
+			out[l.VariantID] = map[string]int{}
+		}
+		out[l.VariantID][l.LocationID] = l.Qty
+	}
+	return out, nil
+}
+
+// ItemLocation is a picked quantity of an order item at a named location.
+type ItemLocation struct {
+	OrderItemID string `gorm:"column:order_item_id"`
+	Code        string `gorm:"column:code"`
+	Name        string `gorm:"column:name"`
+	Priority    int    `gorm:"column:priority"`
+	Qty         int    `gorm:"column:qty"`
+}
+
+// ItemLocationsInTx returns order item -> allocations with location codes, in priority order.
+func ItemLocationsInTx(ctx context.Context, tx *gorm.DB, orderItemIDs []string) (map[string][]ItemLocation, error) {
+	out := map[string][]ItemLocation{}
+	if len(orderItemIDs) == 0 {
+		return out, nil
+	}
+	var rows []ItemLocation
+	if err := tx.WithContext(ctx).
+		Table("order_item_allocations a").
+		Select("a.order_item_id, l.code, l.name, l.priority, a.qty").
+		Joins("JOIN stock_locations l ON l.id = a.location_id").
+		Where("a.order_item_id IN ?", orderItemIDs).
+		Order("l.priority ASC, l.code ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.OrderItemID] = append(out[r.OrderItemID], r)
+	}
+	return out, nil
+}
@@ -0,0 +1,22 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocate(t *testing.T) {
+	levels := []Allocation{{LocationID: "wh1", Qty: 2}, {LocationID: "wh2", Qty: 0}, {LocationID: "wh3", Qty: 5}}
+
+	got, ok := Allocate(levels, 4)
+	assert.True(t, ok)
+	assert.Equal(t, []Allocation{{LocationID: "wh1", Qty: 2}, {LocationID: "wh3", Qty: 2}}, got)
+
+	got, ok = Allocate(levels, 1)
+	assert.True(t, ok)
+	assert.Equal(t, []Allocation{{LocationID: "wh1", Qty: 1}}, got)
+
+	_, ok = Allocate(levels, 8)
+	assert.False(t, ok)
+}
@@ -0,0 +1,149 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Movement reasons.
+const (
+	ReasonSale    = "sale"
+	ReasonRestock = "restock"
+	ReasonAdjust  = "adjust"
+	ReasonReturn  = "return"
+	ReasonImport  = "import"
+)
+
+// Movement is one row of the stock ledger: every change to
+// product_variants.stock is written here in the same transaction.
+type Movement struct {
+	ID          string  `gorm:"type:char(36);primaryKey"`
+	VariantID   string  `gorm:"type:char(36);not null;index:ix_stock_movements_variant_created,priority:1"`
+	LocationID  *string `gorm:"type:char(36)"`
+	Delta       int     `gorm:"not null"`
+	StockAfter  int     `gorm:"not null"`
+	Reason      string  `gorm:"type:varchar(16);not null"`
+	OrderID     *string `gorm:"type:char(36);index:ix_stock_movements_order"`
+	RefType     *string `gorm:"type:varchar(16)"`
+	RefID       *string `gorm:"type:varchar(64)"`
+	ActorUserID *string `gorm:"type:char(36)"`
+	Note        *string `gorm:"type:varchar(255)"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null;index:ix_stock_movements_variant_created,priority:2"`
+}
+
+func (Movement) TableName() string { return "stock_movements" }
+
+// ApplyInTx adds m.Delta to the variant stock (and to the location level,
+// the default location when m.LocationID is nil) and records the movement.
+// Caller is expected to hold the variant row lock when the delta is negative.
+func ApplyInTx(ctx context.Context, tx *gorm.DB, m Movement) (Movement, error) {
+	if m.LocationID == nil {
+		loc, err := DefaultLocationInTx(ctx, tx)
+		if err != nil {
+			return Movement{}, err
+		}
+		m.LocationID = Ptr(loc)
+	}
+	if m.LocationID != nil {
+		lv := Level{LocationID: *m.LocationID, VariantID: m.VariantID, Qty: m.Delta, UpdatedAt: time.Now()}
+		if err := tx.WithContext(ctx).
+			Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "location_id"}, {Name: "variant_id"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"qty":        gorm.Expr("qty + ?", m.Delta),
+					"updated_at": lv.UpdatedAt,
+				}),
+			}).
+			Create(&lv).Error; err != nil {
+			return Movement{}, err
+		}
+	}
+
+	if err := tx.WithContext(ctx).
+		Table("product_variants").
+		Where("id = ?", m.VariantID).
+		UpdateColumn("stock", gorm.Expr("stock + ?", m.Delta)).Error; err != nil {
+		return Movement{}, err
+	}
+
+	var v struct {
+		Stock             int `gorm:"column:stock"`
+		LowStockThreshold int `gorm:"column:low_stock_threshold"`
+	}
+	if err := tx.WithContext(ctx).
+		Table("product_variants").
+		Select("stock, low_stock_threshold").
+		Where("id = ?", m.VariantID).
+		Scan(&v).Error; err != nil {
+		return Movement{}, err
+	}
+	after := v.Stock
+
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	m.StockAfter = after
+	if err := tx.WithContext(ctx).Create(&m).Error; err != nil {
+		return Movement{}, err
+	}
+
+	if CrossedLowStock(after-m.Delta, after, v.LowStockThreshold) {
+		if err := enqueueLowStockInTx(ctx, tx, m.VariantID, after, v.LowStockThreshold); err != nil {
+			return Movement{}, err
+		}
+	}
+	return m, nil
+}
+
+// CrossedLowStock reports whether a change from before to after went down
+// through the threshold. A threshold of 0 disables alerting.
+func CrossedLowStock(before, after, threshold int) bool {
+	return threshold > 0 && before > threshold && after <= threshold
+}
+
+// OrderRestockedQtyInTx sums the quantities already put back for an order,
+// per variant and location ("" for movements without a location).
+func OrderRestockedQtyInTx(ctx context.Context, tx *gorm.DB, orderID string) (map[string]map[string]int, error) {
+	type row struct {
+		VariantID  string  `gorm:"column:variant_id"`
+		LocationID *string `gorm:"column:location_id"`
+		Qty        int     `gorm:"column:qty"`
+	}
+	var rows []row
+	if err := tx.WithContext(ctx).
+		Model(&Movement{}).
+		Select("variant_id, location_id, SUM(delta) AS qty").
+		Where("order_id = ? AND reason IN ?", orderID, []string{ReasonRestock, ReasonReturn}).
+		Group("variant_id, location_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := map[string]map[string]int{}
+	for _, r := range rows {
+		loc := ""
+		if r.LocationID != nil {
+			loc = *r.LocationID
+		}
+		if out[r.VariantID] == nil {
+			out[r.VariantID] = map[string]int{}
+		}
+		out[r.VariantID][loc] += r.Qty
+	}
+	return out, nil
+}
+
+// Ptr is a small helper for the optional string columns.
+func Ptr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
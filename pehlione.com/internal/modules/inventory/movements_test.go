@@ -0,0 +1,25 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrossedLowStock(t *testing.T) {
+	cases := []struct {
+		before, after, threshold int
+		want                     bool
+	}{
+		{10, 5, 5, true},
+		{10, 2, 5, true},
+		{6, 5, 5, true},
+		{5, 4, 5, false}, // already below, alerted before
+		{10, 6, 5, false},
+		{2, 8, 5, false},  // restock
+		{10, 0, 0, false}, // disabled
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, CrossedLowStock(tc.before, tc.after, tc.threshold), "%+v", tc)
+	}
+}
@@ -0,0 +1,90 @@
+package inventory
+
+import (
+	"slices"
+	"sort"
+)
+
+// PickItem is an order item waiting to be picked.
+type PickItem struct {
+	ID          string // order item id
+	OrderNumber string
+	SKU         string
+	ProductName string
+	Options     string
+	Qty         int
+}
+
+// PickLine is one SKU to pick at one location, summed over the batch.
+type PickLine struct {
+	LocationCode string // "": lokasyonu kayıtlı değil
+	LocationName string
+	SKU          string
+	ProductName  string
+	Options      string
+	Qty          int
+	Orders       []string // sipariş numaraları
+}
+
+// BuildPickList aggregates items per location and SKU in walking order:
+// locations by priority, then SKU. locs comes from ItemLocationsInTx;
+// quantities without an allocation (orders placed before locations existed)
+// are listed last under an empty location.
+func BuildPickList(items []PickItem, locs map[string][]ItemLocation) []PickLine {
+	type key struct{ loc, sku, name, options string }
+	lines := map[key]*PickLine{}
+	priority := map[string]int{}
+	add := func(it PickItem, code, name string, qty int) {
+		k := key{code, it.SKU, it.ProductName, it.Options}
+		l, ok := lines[k]
+		if !ok {
+			l = &PickLine{LocationCode: code, LocationName: name, SKU: it.SKU, ProductName: it.ProductName, Options: it.Options}
+			lines[k] = l
+		}
+		l.Qty += qty
+		l.Orders = append(l.Orders, it.OrderNumber)
+	}
+
+	for _, it := range items {
+		left := it.Qty
+		for _, l := range locs[it.ID] {
+			qty := min(l.Qty, left)
+			if qty <= 0 {
+				continue
+			}
+			priority[l.Code] = l.Priority
+			add(it, l.Code, l.Name, qty)
+			left -= qty
+		}
+		if left > 0 {
+			add(it, "", "", left)
+		}
+	}
+
+	out := make([]PickLine, 0, len(lines))
+	for _, l := range lines {
+		slices.Sort(l.Orders)
+		l.Orders = slices.Compact(l.Orders)
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.LocationCode == "") != (b.LocationCode == "") {
+			return b.LocationCode == ""
+		}
+		if a.LocationCode != b.LocationCode {
+			if priority[a.LocationCode] != priority[b.LocationCode] {
+				return priority[a.LocationCode] < priority[b.LocationCode]
+			}
+			return a.LocationCode < b.LocationCode
+		}
+		if a.SKU != b.SKU {
+			return a.SKU < b.SKU
+		}
+		if a.ProductName != b.ProductName {
+			return a.ProductName < b.ProductName
+		}
+		return a.Options < b.Options
+	})
+	return out
+}
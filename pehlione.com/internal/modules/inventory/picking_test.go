@@ -0,0 +1,33 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPickList(t *testing.T) {
+	items := []PickItem{
+		{ID: "i1", OrderNumber: "PH-2026-000002", SKU: "MUG-1", ProductName: "Kupa", Qty: 3},
+		{ID: "i2", OrderNumber: "PH-2026-000001", SKU: "MUG-1", ProductName: "Kupa", Qty: 1},
+		{ID: "i3", OrderNumber: "PH-2026-000001", SKU: "NB-1", ProductName: "Defter", Qty: 2},
+		{ID: "i4", OrderNumber: "PH-2026-000003", SKU: "AA-1", ProductName: "Kalem", Qty: 1},
+	}
+	wh1 := ItemLocation{Code: "wh1", Name: "Warehouse 1", Priority: 10}
+	wh2 := ItemLocation{Code: "wh2", Name: "Warehouse 2", Priority: 20}
+	at := func(l ItemLocation, qty int) ItemLocation { l.Qty = qty; return l }
+	locs := map[string][]ItemLocation{
+		"i1": {at(wh1, 2), at(wh2, 1)},
+		"i2": {at(wh1, 1)},
+		"i3": {at(wh2, 1)}, // 1 adet lokasyonsuz
+	}
+
+	got := BuildPickList(items, locs)
+	assert.Equal(t, []PickLine{
+		{LocationCode: "wh1", LocationName: "Warehouse 1", SKU: "MUG-1", ProductName: "Kupa", Qty: 3, Orders: []string{"PH-2026-000001", "PH-2026-000002"}},
+		{LocationCode: "wh2", LocationName: "Warehouse 2", SKU: "MUG-1", ProductName: "Kupa", Qty: 1, Orders: []string{"PH-2026-000002"}},
+		{LocationCode: "wh2", LocationName: "Warehouse 2", SKU: "NB-1", ProductName: "Defter", Qty: 1, Orders: []string{"PH-2026-000001"}},
+		{SKU: "AA-1", ProductName: "Kalem", Qty: 1, Orders: []string{"PH-2026-000003"}},
+		{SKU: "NB-1", ProductName: "Defter", Qty: 1, Orders: []string{"PH-2026-000001"}},
+	}, got)
+}
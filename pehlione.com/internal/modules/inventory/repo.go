@@ -0,0 +1,49 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MovementRow is a ledger row joined with its variant/product for admin listing.
+type MovementRow struct {
+	ID          string    `gorm:"column:id"`
+	VariantID   string    `gorm:"column:variant_id"`
+	SKU         string    `gorm:"column:sku"`
+	ProductID   string    `gorm:"column:product_id"`
+	ProductName string    `gorm:"column:product_name"`
+	Delta       int       `gorm:"column:delta"`
+	StockAfter  int       `gorm:"column:stock_after"`
+	Reason      string    `gorm:"column:reason"`
+	OrderID     *string   `gorm:"column:order_id"`
+	RefType     *string   `gorm:"column:ref_type"`
+	RefID       *string   `gorm:"column:ref_id"`
+	ActorEmail  *string   `gorm:"column:actor_email"`
+	Note        *string   `gorm:"column:note"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+}
+
+type Repo struct{ db *gorm.DB }
+
+func NewRepo(db *gorm.DB) *Repo { return &Repo{db: db} }
+
+// ListMovements returns the newest movements, optionally for a single variant.
+func (r *Repo) ListMovements(ctx context.Context, variantID string, limit int) ([]MovementRow, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+	q := r.db.WithContext(ctx).
+		Table("stock_movements m").
+		Select("m.id, m.variant_id, v.sku, v.product_id, p.name AS product_name, m.delta, m.stock_after, m.reason, m.order_id, m.ref_type, m.ref_id, u.email AS actor_email, m.note, m.created_at").
+		Joins("JOIN product_variants v ON v.id = m.variant_id").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Joins("LEFT JOIN users u ON u.id = m.actor_user_id")
+	if variantID != "" {
+		q = q.Where("m.variant_id = ?", variantID)
+	}
+	var rows []MovementRow
+	err := q.Order("m.created_at DESC").Limit(limit).Scan(&rows).Error
+	return rows, err
+}
@@ -8,24 +8,44 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/checkout"
+	"pehlione.com/app/internal/modules/giftcards"
+	"pehlione.com/app/internal/modules/promotions"
 )
 
 var (
 	ErrInvalidTransition = errors.New("invalid order status transition")
 	ErrNotActionable     = errors.New("order not actionable")
+	ErrCapturePending    = errors.New("payment capture pending")
 )
 
+// PaymentGateway captures or voids the authorized payment of an order.
+// Implemented by payments.Service (payments imports orders, not vice versa).
+type PaymentGateway interface {
+	CaptureOrder(ctx context.Context, orderID, actorUserID string) error
+	VoidOrder(ctx context.Context, orderID, actorUserID string) error
+}
+
 type AdminService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	payments PaymentGateway
 }
 
 func NewAdminService(db *gorm.DB) *AdminService { return &AdminService{db: db} }
 
+// SetPaymentGateway enables ship/cancel on authorized orders (capture/void).
+func (s *AdminService) SetPaymentGateway(g PaymentGateway) {
+	s.payments = g
+}
+
 type TransitionInput struct {
 	OrderID     string
 	ActorUserID string // admin user id
 	Action      string // ship|deliver|cancel|refund
 	Note        string
+	// Restock: refund geçişinde stok iade edilsin mi (cancel her zaman iade eder)
+	Restock bool
 }
 
 func (s *AdminService) Transition(ctx context.Context, in TransitionInput) error {
@@ -33,6 +53,11 @@ func (s *AdminService) Transition(ctx context.Context, in TransitionInput) error
 		return ErrNotActionable
 	}
 
+	// authorized ödeme: ship => capture, cancel => void (provider çağrısı tx dışında)
+	if err := s.settleAuthorization(ctx, in); err != nil {
+		return err
+	}
+
 	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var o Order
 
@@ -73,6 +98,29 @@ func (s *AdminService) Transition(ctx context.Context, in TransitionInput) error
 			notePtr = &n
 		}
 
+		// stock return in the same tx
+		if to == "cancelled" || (to == "refunded" && in.Restock) {
+			if _, err := checkout.RestockOrderInTx(ctx, tx, checkout.RestockInput{
+				OrderID:     o.ID,
+				ActorUserID: in.ActorUserID,
+				RefType:     "order",
+				RefID:       o.ID,
+				Note:        in.Action,
+			}); err != nil {
+				return err
+			}
+		}
+		// ödenmemiş siparişte kullanılan hediye kartı bakiyesi kartlara, promosyon
+		// kodu kullanımı koda döner
+		if to == "cancelled" {
+			if _, err := giftcards.ReleaseOrderInTx(ctx, tx, o.ID); err != nil {
+				return err
+			}
+			if _, err := promotions.ReleaseOrderInTx(ctx, tx, o.ID); err != nil {
+				return err
+			}
+		}
+
 		ev := OrderEvent{
 			ID:          uuid.NewString(),
 			OrderID:     o.ID,
@@ -87,10 +135,37 @@ func (s *AdminService) Transition(ctx context.Context, in TransitionInput) error
 	})
 }
 
+func (s *AdminService) settleAuthorization(ctx context.Context, in TransitionInput) error {
+	var o Order
+	if err := s.db.WithContext(ctx).Select("id", "status").First(&o, "id = ?", in.OrderID).Error; err != nil {
+		return err
+	}
+	if o.Status != "authorized" {
+		return nil
+	}
+
+	switch in.Action {
+	case "ship":
+		if s.payments == nil {
+			return ErrInvalidTransition
+		}
+		// capture başarılıysa sipariş paid olur, ardından shipped
+		return s.payments.CaptureOrder(ctx, o.ID, in.ActorUserID)
+	case "cancel":
+		if s.payments == nil {
+			return ErrInvalidTransition
+		}
+		return s.payments.VoidOrder(ctx, o.ID, in.ActorUserID)
+	default:
+		return nil
+	}
+}
+
 func nextStatus(from, action string) (string, error) {
 	switch action {
 	case "cancel":
-		if from == "created" {
+		// authorized: void settleAuthorization içinde yapıldı
+		if from == "created" || from == "authorized" {
 			return "cancelled", nil
 		}
 		return "", ErrInvalidTransition
@@ -0,0 +1,15 @@
+package orders
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminCancel_ReleasesPromotionCode(t *testing.T) {
+	db := setupCancelDB(t)
+
+	require.NoError(t, NewAdminService(db).Transition(context.Background(), TransitionInput{OrderID: "o1", ActorUserID: "admin", Action: "cancel"}))
+	assertCodeReleased(t, db)
+}
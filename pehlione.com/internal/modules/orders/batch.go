@@ -0,0 +1,79 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatchOrders caps one batch admin action.
+const MaxBatchOrders = 100
+
+var ErrBatchTooLarge = errors.New("too many orders in one batch")
+
+// BatchResult is the outcome of a batch admin action for one order.
+type BatchResult struct {
+	OrderID string
+	Number  string
+	Err     error
+}
+
+// RunBatch runs fn for each selected order in turn, in selection order. fn
+// owns its transaction, so every order succeeds or fails on its own; unknown
+// ids fail with ErrOrderNotFound.
+func (r *Repo) RunBatch(ctx context.Context, ids []string, fn func(ctx context.Context, o Order) error) ([]BatchResult, error) {
+	seen := make(map[string]bool, len(ids))
+	uniq := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id = stringsTrim(id); id != "" && !seen[id] {
+			seen[id] = true
+			uniq = append(uniq, id)
+		}
+	}
+	if len(uniq) > MaxBatchOrders {
+		return nil, ErrBatchTooLarge
+	}
+	if len(uniq) == 0 {
+		return nil, nil
+	}
+
+	var list []Order
+	if err := r.db.WithContext(ctx).Find(&list, "id IN ?", uniq).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Order, len(list))
+	for _, o := range list {
+		byID[o.ID] = o
+	}
+
+	out := make([]BatchResult, 0, len(uniq))
+	for _, id := range uniq {
+		o, ok := byID[id]
+		if !ok {
+			out = append(out, BatchResult{OrderID: id, Number: id, Err: ErrOrderNotFound})
+			continue
+		}
+		out = append(out, BatchResult{OrderID: o.ID, Number: o.Number(), Err: fn(ctx, o)})
+	}
+	return out, nil
+}
+
+// RecordEvent writes an audit event that leaves the status as it is, e.g. a
+// printed packing slip.
+func (r *Repo) RecordEvent(ctx context.Context, o Order, actorUserID, action, note string) error {
+	ev := OrderEvent{
+		ID:          uuid.NewString(),
+		OrderID:     o.ID,
+		ActorUserID: actorUserID,
+		Action:      action,
+		FromStatus:  o.Status,
+		ToStatus:    o.Status,
+		CreatedAt:   time.Now(),
+	}
+	if n := stringsTrim(note); n != "" {
+		ev.Note = &n
+	}
+	return r.db.WithContext(ctx).Create(&ev).Error
+}
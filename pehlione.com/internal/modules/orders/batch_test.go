@@ -0,0 +1,47 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRunBatch_PerOrderResults(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE orders (id TEXT PRIMARY KEY, order_number TEXT, status TEXT)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO orders (id, order_number, status) VALUES
+		('o1', 'PH-2026-000001', 'paid'), ('o2', 'PH-2026-000002', 'created')`).Error)
+
+	boom := errors.New("boom")
+	var ran []string
+	res, err := NewRepo(db).RunBatch(context.Background(), []string{"o2", "missing", "o1", " o2 ", ""},
+		func(_ context.Context, o Order) error {
+			ran = append(ran, o.ID)
+			if o.Status != "paid" {
+				return boom
+			}
+			return nil
+		})
+	require.NoError(t, err)
+
+	// seçim sırası korunur, tekrarlar tek sefer çalışır; bir hata diğerlerini durdurmaz
+	require.Equal(t, []string{"o2", "o1"}, ran)
+	require.Len(t, res, 3)
+	require.Equal(t, "PH-2026-000002", res[0].Number)
+	require.ErrorIs(t, res[0].Err, boom)
+	require.ErrorIs(t, res[1].Err, ErrOrderNotFound)
+	require.NoError(t, res[2].Err)
+
+	many := make([]string, MaxBatchOrders+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("id-%d", i)
+	}
+	_, err = NewRepo(db).RunBatch(context.Background(), many, nil)
+	require.ErrorIs(t, err, ErrBatchTooLarge)
+}
@@ -0,0 +1,60 @@
+package orders
+
+import (
+	"sort"
+
+	"pehlione.com/app/internal/modules/tax"
+)
+
+// LineDiscounts returns each item's share of the order discount in the
+// order (charge) currency, index-aligned with items. The shares are stored on
+// the items at checkout; for orders placed before that they are allocated
+// again the way CreateFromCart did, in variant id order, so the rounding
+// remainder lands on the same line.
+func LineDiscounts(o Order, items []OrderItem) []int {
+	out := make([]int, len(items))
+	stored := 0
+	for i, it := range items {
+		out[i] = it.DiscountCents
+		stored += it.DiscountCents
+	}
+	if stored > 0 || o.DiscountCents <= 0 {
+		return out
+	}
+
+	idx := make([]int, len(items))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return items[idx[a]].VariantID < items[idx[b]].VariantID })
+	amounts := make([]int, len(idx))
+	for k, i := range idx {
+		amounts[k] = items[i].LineTotalCents
+	}
+	net := tax.AllocateDiscount(amounts, o.DiscountCents)
+	for k, i := range idx {
+		out[i] = items[i].LineTotalCents - net[k]
+	}
+	return out
+}
+
+// allocateDiscount spreads the order discount over the lines like
+// tax.AllocateDiscount, leaving gift card lines out: cards are sold and
+// issued at face value.
+func allocateDiscount(amounts []int, giftCards []bool, discount int) []int {
+	out := make([]int, len(amounts))
+	copy(out, amounts)
+	idx := make([]int, 0, len(amounts))
+	sub := make([]int, 0, len(amounts))
+	for i, a := range amounts {
+		if i < len(giftCards) && giftCards[i] {
+			continue
+		}
+		idx = append(idx, i)
+		sub = append(sub, a)
+	}
+	for k, net := range tax.AllocateDiscount(sub, discount) {
+		out[idx[k]] = net
+	}
+	return out
+}
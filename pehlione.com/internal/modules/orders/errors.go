@@ -6,4 +6,7 @@ var (
 	ErrCartEmpty          = errors.New("cart is empty")
 	ErrCurrencyMismatch   = errors.New("currency mismatch in cart")
 	ErrProductUnavailable = errors.New("product unavailable")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrGuestTokenInvalid  = errors.New("guest order token is invalid")
+	ErrGuestTokenExpired  = errors.New("guest order token has expired")
 )
@@ -0,0 +1,63 @@
+package orders
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GuestTokens signs and verifies the links that give a guest access to one
+// order. Token format: <unix expiry>.<base64 hmac(order id, expiry)>; the
+// order ID itself travels in the URL path.
+type GuestTokens struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewGuestTokens(secret []byte, ttl time.Duration) *GuestTokens {
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	return &GuestTokens{secret: secret, ttl: ttl}
+}
+
+func (g *GuestTokens) TTL() time.Duration { return g.ttl }
+
+// Sign returns a token for orderID valid until now+TTL.
+func (g *GuestTokens) Sign(orderID string, now time.Time) string {
+	exp := strconv.FormatInt(now.Add(g.ttl).Unix(), 10)
+	return exp + "." + g.mac(orderID, exp)
+}
+
+// Verify checks a token against the order it is presented for.
+func (g *GuestTokens) Verify(orderID, token string, now time.Time) error {
+	exp, sig, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || orderID == "" {
+		return ErrGuestTokenInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(g.mac(orderID, exp))) {
+		return ErrGuestTokenInvalid
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrGuestTokenInvalid
+	}
+	if !now.Before(time.Unix(unix, 0)) {
+		return ErrGuestTokenExpired
+	}
+	return nil
+}
+
+// URL builds the absolute guest link of an order.
+func (g *GuestTokens) URL(baseURL, orderID string, now time.Time) string {
+	return strings.TrimRight(baseURL, "/") + "/orders/" + orderID + "?access=" + g.Sign(orderID, now)
+}
+
+func (g *GuestTokens) mac(orderID, exp string) string {
+	m := hmac.New(sha256.New, g.secret)
+	m.Write([]byte("guest-order:" + orderID + ":" + exp))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,27 @@
+package orders
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestTokens(t *testing.T) {
+	g := NewGuestTokens([]byte("secret"), time.Hour)
+	now := time.Unix(1_700_000_000, 0)
+	tok := g.Sign("order-1", now)
+
+	require.NoError(t, g.Verify("order-1", tok, now.Add(59*time.Minute)))
+	require.ErrorIs(t, g.Verify("order-1", tok, now.Add(time.Hour)), ErrGuestTokenExpired)
+	require.ErrorIs(t, g.Verify("order-2", tok, now), ErrGuestTokenInvalid)
+	require.ErrorIs(t, NewGuestTokens([]byte("other"), time.Hour).Verify("order-1", tok, now), ErrGuestTokenInvalid)
+	require.ErrorIs(t, g.Verify("order-1", "garbage", now), ErrGuestTokenInvalid)
+
+	// süre uzatılmış token imzayı bozar
+	exp, sig, _ := strings.Cut(tok, ".")
+	require.ErrorIs(t, g.Verify("order-1", exp+"9."+sig, now), ErrGuestTokenInvalid)
+
+	require.Equal(t, "https://shop.test/orders/order-1?access="+tok, g.URL("https://shop.test/", "order-1", now))
+}
@@ -1,6 +1,7 @@
 package orders
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/datatypes"
@@ -9,8 +10,15 @@ import (
 type Order struct {
 	ID string `gorm:"type:char(36);primaryKey"`
 
+	// müşteriye gösterilen numara (PH-2026-000123); fatura numarası ödemede atanır
+	OrderNumber   *string    `gorm:"type:varchar(32);uniqueIndex:ux_orders_order_number"`
+	InvoiceNumber *string    `gorm:"type:varchar(32);uniqueIndex:ux_orders_invoice_number"`
+	InvoicedAt    *time.Time `gorm:"type:datetime(3)"`
+
 	UserID     *string `gorm:"type:char(36);index:ix_orders_user_id_created_at,priority:1"`
 	GuestEmail *string `gorm:"type:varchar(255)"`
+	// satın alınan hediye kartlarının kodları bu adrese gider (boşsa müşteri)
+	GiftRecipientEmail *string `gorm:"type:varchar(255)"`
 
 	Status          string  `gorm:"type:varchar(32);not null"`
 	Currency        string  `gorm:"type:char(3);not null"`
@@ -24,12 +32,17 @@ type Order struct {
 	ShippingCents     int `gorm:"not null"`
 	DiscountCents     int `gorm:"not null"`
 	TotalCents        int `gorm:"not null"`
+	GiftCardCents     int `gorm:"not null;default:0"` // hediye kartı/store credit ile karşılanan; sağlayıcı TotalCents-GiftCardCents tahsil eder
 	BaseSubtotalCents int `gorm:"not null"`
 	BaseTaxCents      int `gorm:"not null"`
 	BaseShippingCents int `gorm:"not null"`
 	BaseDiscountCents int `gorm:"not null"`
 	BaseTotalCents    int `gorm:"not null"`
 
+	PromoCode        *string `gorm:"type:varchar(64)"`
+	PricesIncludeTax bool    `gorm:"not null;default:true"`
+	PaymentMethod    *string `gorm:"type:varchar(32)"` // card|paypal|klarna; provider routing
+
 	ShippingAddressJSON datatypes.JSON `gorm:"type:json"`
 	BillingAddressJSON  datatypes.JSON `gorm:"type:json"`
 
@@ -37,6 +50,7 @@ type Order struct {
 	PaidAt         *time.Time `gorm:"-"` // TODO: add to database via migration
 	RefundedCents  int        `gorm:"type:bigint;not null;default:0"`
 	RefundedAt     *time.Time `gorm:"type:datetime(3)"`
+	DisputeStatus  *string    `gorm:"type:varchar(16)"` // open|won|lost (chargeback)
 
 	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
 	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
@@ -44,6 +58,23 @@ type Order struct {
 
 func (Order) TableName() string { return "orders" }
 
+// Number is the order number shown to customers; orders created before
+// numbering existed fall back to the short ID.
+func (o Order) Number() string {
+	if o.OrderNumber != nil && *o.OrderNumber != "" {
+		return *o.OrderNumber
+	}
+	if len(o.ID) > 8 {
+		return strings.ToUpper(o.ID[:8])
+	}
+	return strings.ToUpper(o.ID)
+}
+
+// AmountDueCents is what the payment provider charges after gift cards.
+func (o Order) AmountDueCents() int {
+	return max(o.TotalCents-o.GiftCardCents, 0)
+}
+
 type OrderItem struct {
 	ID        string `gorm:"type:char(36);primaryKey"`
 	OrderID   string `gorm:"type:char(36);not null;index:ix_order_items_order_id"`
@@ -61,6 +92,16 @@ type OrderItem struct {
 	BaseUnitPriceCents int    `gorm:"not null"`
 	BaseLineTotalCents int    `gorm:"not null"`
 
+	// siparişin indirim payı (LineTotalCents'ten düşülür), checkout'ta dağıtıldığı gibi
+	DiscountCents     int `gorm:"not null;default:0"`
+	BaseDiscountCents int `gorm:"not null;default:0"`
+
+	// vergi dökümü: TaxCents sipariş para biriminde, BaseTaxCents base currency'de
+	TaxClass     string `gorm:"type:varchar(32);not null;default:standard"`
+	TaxRateBps   int    `gorm:"not null;default:0"`
+	TaxCents     int    `gorm:"not null;default:0"`
+	BaseTaxCents int    `gorm:"not null;default:0"`
+
 	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
 }
 
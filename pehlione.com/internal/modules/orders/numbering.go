@@ -0,0 +1,114 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	SeqOrder      = "order"
+	SeqInvoice    = "invoice"
+	SeqCreditNote = "credit_note"
+
+	OrderNumberPrefix   = "PH"
+	InvoiceNumberPrefix = "INV"
+	CreditNotePrefix    = "CN"
+)
+
+// NumberSequence is a per-year counter behind order and invoice numbers.
+type NumberSequence struct {
+	Name      string    `gorm:"type:varchar(32);primaryKey"`
+	Year      int       `gorm:"primaryKey;autoIncrement:false"`
+	LastValue int64     `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (NumberSequence) TableName() string { return "number_sequences" }
+
+// FormatNumber renders a sequence value as PREFIX-YYYY-000123.
+func FormatNumber(prefix string, year int, n int64) string {
+	return fmt.Sprintf("%s-%d-%06d", prefix, year, n)
+}
+
+// NextNumberInTx allocates the next value of a yearly sequence. The counter
+// row is bumped with a plain UPDATE, which keeps it locked until the
+// transaction ends: concurrent checkouts queue behind it and a rolled back
+// transaction returns its value, so the sequence has no gaps.
+func NextNumberInTx(ctx context.Context, tx *gorm.DB, name string, year int, now time.Time) (int64, error) {
+	db := tx.WithContext(ctx)
+	for range 2 {
+		res := db.Model(&NumberSequence{}).
+			Where("name = ? AND year = ?", name, year).
+			Updates(map[string]any{
+				"last_value": gorm.Expr("last_value + 1"),
+				"updated_at": now,
+			})
+		if res.Error != nil {
+			return 0, res.Error
+		}
+		if res.RowsAffected == 1 {
+			var n int64
+			err := db.Model(&NumberSequence{}).
+				Select("last_value").
+				Where("name = ? AND year = ?", name, year).
+				Row().Scan(&n)
+			return n, err
+		}
+
+		// yılın ilk numarası; aynı anda başka bir tx oluşturduysa UPDATE'e dön
+		res = db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&NumberSequence{Name: name, Year: year, LastValue: 1, UpdatedAt: now})
+		if res.Error != nil {
+			return 0, res.Error
+		}
+		if res.RowsAffected == 1 {
+			return 1, nil
+		}
+	}
+	return 0, errors.New("number sequence: could not allocate " + name)
+}
+
+// NextOrderNumberInTx returns a fresh order number for an order created at now.
+func NextOrderNumberInTx(ctx context.Context, tx *gorm.DB, now time.Time) (string, error) {
+	n, err := NextNumberInTx(ctx, tx, SeqOrder, now.Year(), now)
+	if err != nil {
+		return "", err
+	}
+	return FormatNumber(OrderNumberPrefix, now.Year(), n), nil
+}
+
+// AssignInvoiceNumberInTx gives a paid order its invoice number. Orders that
+// already have one keep it, so repeated payment events don't burn numbers.
+func AssignInvoiceNumberInTx(ctx context.Context, tx *gorm.DB, orderID string, now time.Time) (string, error) {
+	var o Order
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "invoice_number").
+		First(&o, "id = ?", orderID).Error; err != nil {
+		return "", err
+	}
+	if o.InvoiceNumber != nil && *o.InvoiceNumber != "" {
+		return *o.InvoiceNumber, nil
+	}
+
+	n, err := NextNumberInTx(ctx, tx, SeqInvoice, now.Year(), now)
+	if err != nil {
+		return "", err
+	}
+	number := FormatNumber(InvoiceNumberPrefix, now.Year(), n)
+	invoicedAt := now
+	if err := tx.WithContext(ctx).Model(&Order{}).
+		Where("id = ?", orderID).
+		Updates(map[string]any{
+			"invoice_number": number,
+			"invoiced_at":    &invoicedAt,
+		}).Error; err != nil {
+		return "", err
+	}
+	return number, nil
+}
@@ -0,0 +1,84 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupNumberingDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE number_sequences (
+		name TEXT NOT NULL, year INTEGER NOT NULL, last_value INTEGER NOT NULL, updated_at DATETIME NOT NULL,
+		PRIMARY KEY (name, year))`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE orders (
+		id TEXT PRIMARY KEY, order_number TEXT UNIQUE, invoice_number TEXT UNIQUE, invoiced_at DATETIME, updated_at DATETIME)`).Error)
+	return db
+}
+
+func TestNextOrderNumber_SequentialPerYear(t *testing.T) {
+	db := setupNumberingDB(t)
+	ctx := context.Background()
+	jan := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	n1, err := NextOrderNumberInTx(ctx, db, jan)
+	require.NoError(t, err)
+	n2, err := NextOrderNumberInTx(ctx, db, jan)
+	require.NoError(t, err)
+	require.Equal(t, "PH-2026-000001", n1)
+	require.Equal(t, "PH-2026-000002", n2)
+
+	// yeni yıl sıfırdan başlar, faturalar ayrı sayılır
+	n3, err := NextOrderNumberInTx(ctx, db, jan.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	require.Equal(t, "PH-2027-000001", n3)
+	inv, err := NextNumberInTx(ctx, db, SeqInvoice, 2026, jan)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, inv)
+}
+
+func TestNextOrderNumber_RollbackLeavesNoGap(t *testing.T) {
+	db := setupNumberingDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := NextOrderNumberInTx(ctx, db, now)
+	require.NoError(t, err)
+
+	boom := errors.New("checkout failed")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		n, err := NextOrderNumberInTx(ctx, tx, now)
+		require.NoError(t, err)
+		require.Equal(t, "PH-2026-000002", n)
+		return boom
+	})
+	require.ErrorIs(t, err, boom)
+
+	n, err := NextOrderNumberInTx(ctx, db, now)
+	require.NoError(t, err)
+	require.Equal(t, "PH-2026-000002", n)
+}
+
+func TestAssignInvoiceNumber_Idempotent(t *testing.T) {
+	db := setupNumberingDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Exec(`INSERT INTO orders (id) VALUES ('o1'), ('o2')`).Error)
+
+	a, err := AssignInvoiceNumberInTx(ctx, db, "o1", now)
+	require.NoError(t, err)
+	again, err := AssignInvoiceNumberInTx(ctx, db, "o1", now)
+	require.NoError(t, err)
+	b, err := AssignInvoiceNumberInTx(ctx, db, "o2", now)
+	require.NoError(t, err)
+
+	require.Equal(t, "INV-2026-000001", a)
+	require.Equal(t, a, again)
+	require.Equal(t, "INV-2026-000002", b)
+}
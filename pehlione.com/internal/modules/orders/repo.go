@@ -100,3 +100,32 @@ func (r *Repo) GetWithItems(ctx context.Context, id string) (Order, []OrderItem,
 	}
 	return o, items, nil
 }
+
+// FindGuestOrder resolves the order lookup form: the guest email must match
+// and ref is the order number, the full order ID or the short ID that older
+// orders show.
+func (r *Repo) FindGuestOrder(ctx context.Context, email, ref string) (Order, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	ref = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ref), "#"))
+	if email == "" || len(ref) < 8 {
+		return Order{}, ErrOrderNotFound
+	}
+
+	q := r.db.WithContext(ctx).Where("user_id IS NULL AND LOWER(guest_email) = ?", email)
+	if strings.HasPrefix(ref, strings.ToLower(OrderNumberPrefix)+"-") {
+		q = q.Where("order_number = ?", strings.ToUpper(ref))
+	} else if len(ref) == 36 {
+		q = q.Where("id = ?", ref)
+	} else {
+		q = q.Where("id LIKE ?", ref[:8]+"%")
+	}
+	var list []Order
+	if err := q.Order("created_at DESC").Limit(2).Find(&list).Error; err != nil {
+		return Order{}, err
+	}
+	// kısa numara birden fazla siparişe uyuyorsa tam ID istenir
+	if len(list) != 1 {
+		return Order{}, ErrOrderNotFound
+	}
+	return list[0], nil
+}
@@ -36,8 +36,8 @@ func (r *Repo) AdminList(ctx context.Context, in AdminListParams) (AdminListResu
 	}
 	if q != "" {
 		like := "%" + q + "%"
-		// order id or guest email (simple)
-		base = base.Where("(id LIKE ? OR guest_email LIKE ?)", like, like)
+		// order id/number, invoice number or guest email (simple)
+		base = base.Where("(id LIKE ? OR order_number LIKE ? OR invoice_number LIKE ? OR guest_email LIKE ?)", like, like, like, like)
 	}
 
 	var total int64
@@ -78,3 +78,33 @@ func (r *Repo) AdminListFinancial(ctx context.Context, orderID string) ([]Financ
 		Find(&out, "order_id = ?", orderID).Error
 	return out, err
 }
+
+// AdminPaidWithItems loads the paid orders among ids (oldest first) with their
+// items, for warehouse documents. Other statuses are skipped.
+func (r *Repo) AdminPaidWithItems(ctx context.Context, ids []string) ([]Order, []OrderItem, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+	var list []Order
+	if err := r.db.WithContext(ctx).
+		Where("id IN ? AND status = ?", ids, "paid").
+		Order("created_at ASC").
+		Find(&list).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil, nil
+	}
+	paid := make([]string, 0, len(list))
+	for _, o := range list {
+		paid = append(paid, o.ID)
+	}
+	var items []OrderItem
+	if err := r.db.WithContext(ctx).
+		Where("order_id IN ?", paid).
+		Order("created_at ASC").
+		Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	return list, items, nil
+}
@@ -0,0 +1,159 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/checkout"
+	"pehlione.com/app/internal/modules/giftcards"
+	"pehlione.com/app/internal/modules/promotions"
+)
+
+// SystemActorID is the seeded users row used as actor for automated order events.
+const SystemActorID = "00000000-0000-0000-0000-000000000000"
+
+// ExpireReservations cancels unpaid orders older than the reservation TTL,
+// releasing their stock, redeemed gift card balance and promotion codes. An
+// order without reservations (gift cards only) is found by its age; leftover
+// reservations past their expiry are released too. Returns the number of
+// orders cancelled.
+func (s *Service) ExpireReservations(ctx context.Context, now time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var ids []string
+	if err := s.db.WithContext(ctx).
+		Model(&Order{}).
+		Where("status = ? AND created_at <= ?", "created", now.Add(-s.reservationTTL)).
+		Order("created_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	held, err := checkout.ExpiredReservationOrders(ctx, s.db, now, limit)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, id := range held {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	cancelled := 0
+	for _, id := range ids {
+		ok, err := s.expireOrder(ctx, id)
+		if err != nil {
+			log.Printf("reservations: expire order %s failed: %v", id, err)
+			continue
+		}
+		if ok {
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
+
+func (s *Service) expireOrder(ctx context.Context, orderID string) (bool, error) {
+	cancelled := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var o Order
+		if err := tx.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&o, "id = ?", orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				_, err = checkout.ReleaseReservationsInTx(ctx, tx, orderID)
+			}
+			return err
+		}
+
+		// ödeme arada geldiyse dönüşüm payment tx'inde yapılmıştır; sadece askıda kalanları bırak
+		if _, err := checkout.ReleaseReservationsInTx(ctx, tx, orderID); err != nil {
+			return err
+		}
+		if o.Status != "created" {
+			return nil
+		}
+
+		now := time.Now()
+		res := tx.WithContext(ctx).Model(&Order{}).
+			Where("id = ? AND status = ?", o.ID, "created").
+			Updates(map[string]any{"status": "cancelled", "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return nil
+		}
+		// hediye kartı bakiyesi ve promosyon kodu kullanımı iade
+		if _, err := giftcards.ReleaseOrderInTx(ctx, tx, o.ID); err != nil {
+			return err
+		}
+		if _, err := promotions.ReleaseOrderInTx(ctx, tx, o.ID); err != nil {
+			return err
+		}
+
+		note := "reservation expired: unpaid order auto-cancelled"
+		ev := OrderEvent{
+			ID:          uuid.NewString(),
+			OrderID:     o.ID,
+			ActorUserID: SystemActorID,
+			Action:      "auto_cancel",
+			FromStatus:  o.Status,
+			ToStatus:    "cancelled",
+			Note:        &note,
+			CreatedAt:   now,
+		}
+		if err := tx.WithContext(ctx).Create(&ev).Error; err != nil {
+			return err
+		}
+		cancelled = true
+		return nil
+	})
+	return cancelled, err
+}
+
+// ReservationWorker periodically expires unpaid reservations.
+type ReservationWorker struct {
+	svc       *Service
+	interval  time.Duration
+	batchSize int
+}
+
+func NewReservationWorker(svc *Service, interval time.Duration) *ReservationWorker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &ReservationWorker{svc: svc, interval: interval, batchSize: 50}
+}
+
+func (w *ReservationWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			n, err := w.svc.ExpireReservations(ctx, time.Now(), w.batchSize)
+			if err != nil {
+				log.Printf("reservation worker tick error: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("reservation worker: cancelled %d unpaid orders", n)
+			}
+		}
+	}
+}
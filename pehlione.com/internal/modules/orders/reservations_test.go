@@ -0,0 +1,119 @@
+package orders
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/giftcards"
+)
+
+// setupCancelDB holds an unpaid order o1 that used the single-use code ONCE.
+func setupCancelDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	for _, q := range []string{
+		`CREATE TABLE orders (id TEXT PRIMARY KEY, status TEXT NOT NULL, currency TEXT NOT NULL, total_cents INTEGER NOT NULL,
+			paid_at DATETIME, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE order_events (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, actor_user_id TEXT NOT NULL, action TEXT NOT NULL,
+			from_status TEXT NOT NULL, to_status TEXT NOT NULL, note TEXT, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE products (id TEXT PRIMARY KEY, kind TEXT NOT NULL DEFAULT 'standard')`,
+		`CREATE TABLE product_variants (id TEXT PRIMARY KEY, product_id TEXT, stock INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE order_items (id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, quantity INTEGER NOT NULL)`,
+		`CREATE TABLE stock_reservations (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, qty INTEGER NOT NULL, status TEXT NOT NULL,
+			expires_at DATETIME NOT NULL, converted_at DATETIME, released_at DATETIME, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE stock_movements (
+			id TEXT PRIMARY KEY, variant_id TEXT NOT NULL, location_id TEXT, delta INTEGER NOT NULL, stock_after INTEGER NOT NULL,
+			reason TEXT NOT NULL, order_id TEXT, ref_type TEXT, ref_id TEXT, actor_user_id TEXT, note TEXT, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE stock_locations (
+			id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, name TEXT NOT NULL, priority INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE stock_levels (
+			location_id TEXT NOT NULL, variant_id TEXT NOT NULL, qty INTEGER NOT NULL DEFAULT 0, updated_at DATETIME NOT NULL,
+			PRIMARY KEY (location_id, variant_id))`,
+		`CREATE TABLE order_item_allocations (
+			id TEXT PRIMARY KEY, order_item_id TEXT NOT NULL, location_id TEXT NOT NULL, qty INTEGER NOT NULL, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE gift_card_transactions (
+			id TEXT PRIMARY KEY, gift_card_id TEXT NOT NULL, order_id TEXT, kind TEXT NOT NULL, amount_cents INTEGER NOT NULL,
+			balance_after_cents INTEGER NOT NULL, currency TEXT NOT NULL, ref_type TEXT NOT NULL, ref_id TEXT NOT NULL, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE promotions (id TEXT PRIMARY KEY, code TEXT NOT NULL, max_redemptions INTEGER NOT NULL DEFAULT 0,
+			redeemed_count INTEGER NOT NULL DEFAULT 0, updated_at DATETIME)`,
+		`CREATE TABLE promotion_redemptions (
+			id TEXT PRIMARY KEY, promotion_id TEXT NOT NULL, order_id TEXT NOT NULL, user_id TEXT, email TEXT, code TEXT NOT NULL,
+			discount_cents INTEGER NOT NULL, currency TEXT NOT NULL, base_discount_cents INTEGER NOT NULL, base_currency TEXT NOT NULL,
+			created_at DATETIME NOT NULL)`,
+
+		`INSERT INTO orders (id, status, currency, total_cents, created_at) VALUES ('o1', 'created', 'EUR', 4500, CURRENT_TIMESTAMP)`,
+		`INSERT INTO products (id) VALUES ('pr1')`,
+		`INSERT INTO product_variants (id, product_id, stock) VALUES ('v1', 'pr1', 5)`,
+		`INSERT INTO order_items (id, order_id, variant_id, quantity) VALUES ('oi1', 'o1', 'v1', 1)`,
+		`INSERT INTO stock_reservations (id, order_id, variant_id, qty, status, expires_at) VALUES ('sr1', 'o1', 'v1', 1, 'active', '2000-01-01 00:00:00')`,
+		`INSERT INTO promotions (id, code, max_redemptions, redeemed_count) VALUES ('pm1', 'ONCE', 1, 1)`,
+		`INSERT INTO promotion_redemptions (id, promotion_id, order_id, code, discount_cents, currency, base_discount_cents, base_currency, created_at)
+			VALUES ('rd1', 'pm1', 'o1', 'ONCE', 500, 'EUR', 500, 'EUR', CURRENT_TIMESTAMP)`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+	return db
+}
+
+// assertCodeReleased checks that o1's cancellation gave the code back.
+func assertCodeReleased(t *testing.T, db *gorm.DB) {
+	var status string
+	require.NoError(t, db.Table("orders").Select("status").Where("id = ?", "o1").Scan(&status).Error)
+	assert.Equal(t, "cancelled", status)
+	var used int
+	require.NoError(t, db.Table("promotions").Select("redeemed_count").Where("id = ?", "pm1").Scan(&used).Error)
+	assert.Equal(t, 0, used)
+	var rows int64
+	require.NoError(t, db.Table("promotion_redemptions").Where("order_id = ?", "o1").Count(&rows).Error)
+	assert.Zero(t, rows)
+}
+
+func TestExpireReservations_ReleasesPromotionCode(t *testing.T) {
+	db := setupCancelDB(t)
+	svc := NewService(db, nil)
+
+	n, err := svc.ExpireReservations(context.Background(), time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assertCodeReleased(t, db)
+}
+
+func TestExpireReservations_CancelsGiftCardOnlyOrder(t *testing.T) {
+	db := setupCancelDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`CREATE TABLE gift_cards (
+		id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, kind TEXT NOT NULL, status TEXT NOT NULL, currency TEXT NOT NULL,
+		initial_cents INTEGER NOT NULL, balance_cents INTEGER NOT NULL, user_id TEXT, recipient_email TEXT,
+		source_order_id TEXT, source_order_item_id TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	card, err := giftcards.IssueInTx(ctx, db, giftcards.IssueInput{Currency: "EUR", AmountCents: 5000, RefType: "order_item", RefID: "seed"})
+	require.NoError(t, err)
+
+	// stoğu olmayan (yalnızca hediye kartı) siparişin rezervasyonu yok; yaşına göre iptal edilir
+	old := time.Now().Add(-2 * DefaultReservationTTL)
+	require.NoError(t, db.Exec(`INSERT INTO orders (id, status, currency, total_cents, created_at) VALUES ('o2', 'created', 'EUR', 2500, ?), ('o3', 'created', 'EUR', 2500, ?)`,
+		old, time.Now()).Error)
+	_, err = giftcards.RedeemInTx(ctx, db, giftcards.RedeemInput{Codes: []string{card.Code}, OrderID: "o2", Currency: "EUR", AmountCents: 1000})
+	require.NoError(t, err)
+
+	n, err := NewService(db, nil).ExpireReservations(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "o1 by its reservation, o2 by its age")
+
+	var status string
+	require.NoError(t, db.Table("orders").Select("status").Where("id = ?", "o2").Scan(&status).Error)
+	assert.Equal(t, "cancelled", status)
+	require.NoError(t, db.Table("orders").Select("status").Where("id = ?", "o3").Scan(&status).Error)
+	assert.Equal(t, "created", status, "still within the TTL")
+	var balance int
+	require.NoError(t, db.Table("gift_cards").Select("balance_cents").Where("id = ?", card.ID).Scan(&balance).Error)
+	assert.Equal(t, 5000, balance, "redeemed balance is released")
+}
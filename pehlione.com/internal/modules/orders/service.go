@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"math"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -15,15 +16,110 @@ import (
 
 	"pehlione.com/app/internal/modules/checkout"
 	"pehlione.com/app/internal/modules/currency"
+	"pehlione.com/app/internal/modules/giftcards"
+	"pehlione.com/app/internal/modules/products"
+	"pehlione.com/app/internal/modules/promotions"
+	"pehlione.com/app/internal/modules/tax"
 )
 
 type Service struct {
-	db       *gorm.DB
-	currency *currency.Service
+	db         *gorm.DB
+	currency   *currency.Service
+	promotions *promotions.Service
+	tax        *tax.Service
+
+	reservationTTL time.Duration
 }
 
+// DefaultReservationTTL is how long an unpaid order holds its stock.
+const DefaultReservationTTL = 30 * time.Minute
+
 func NewService(db *gorm.DB, curr *currency.Service) *Service {
-	return &Service{db: db, currency: curr}
+	return &Service{db: db, currency: curr, promotions: promotions.NewService(db), reservationTTL: DefaultReservationTTL}
+}
+
+// SetReservationTTL overrides how long stock stays reserved for an unpaid order.
+func (s *Service) SetReservationTTL(d time.Duration) {
+	if d > 0 {
+		s.reservationTTL = d
+	}
+}
+
+// SetTaxService enables VAT calculation; without it TaxCents from the input is used as-is.
+func (s *Service) SetTaxService(t *tax.Service) {
+	s.tax = t
+}
+
+// lineTaxInTx spreads the order discount over the lines and taxes what is
+// left. Shipping is not taxed in this shop, so it is not a tax line. Gift
+// cards are vouchers: VAT is due when they are redeemed, not when sold, so
+// their lines are always in the zero class.
+func (s *Service) lineTaxInTx(ctx context.Context, tx *gorm.DB, country string, classes []string, amounts []int, giftCards []bool, discount int) (tax.Result, error) {
+	net := allocateDiscount(amounts, giftCards, discount)
+	lines := make([]tax.Line, len(amounts))
+	for i := range amounts {
+		class := classes[i]
+		if giftCards[i] {
+			class = tax.ClassZero
+		}
+		lines[i] = tax.Line{TaxClass: class, AmountCents: net[i]}
+	}
+	return s.tax.CalculateInTx(ctx, tx, country, lines)
+}
+
+// TaxPreviewLine is a cart line for the checkout preview, in base currency.
+type TaxPreviewLine struct {
+	VariantID   string
+	AmountCents int
+}
+
+// PreviewTax computes the VAT CreateFromCart will charge for the cart, so the
+// checkout total matches the order. Without a tax service it returns a zero
+// result.
+func (s *Service) PreviewTax(ctx context.Context, country string, lines []TaxPreviewLine, discount int) (tax.Result, error) {
+	if s.tax == nil || len(lines) == 0 {
+		return tax.Result{}, nil
+	}
+	// CreateFromCart ile aynı satır sırası: varyant başına toplam, id sıralı
+	// (indirim yuvarlaması son satıra kalır)
+	byVariant := map[string]int{}
+	ids := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if _, ok := byVariant[l.VariantID]; !ok {
+			ids = append(ids, l.VariantID)
+		}
+		byVariant[l.VariantID] += l.AmountCents
+	}
+	sort.Strings(ids)
+
+	type row struct {
+		ID       string `gorm:"column:id"`
+		TaxClass string `gorm:"column:tax_class"`
+		Kind     string `gorm:"column:kind"`
+	}
+	var rows []row
+	if err := s.db.WithContext(ctx).
+		Table("product_variants v").
+		Select("v.id, p.tax_class, p.kind").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Where("v.id IN ?", ids).
+		Scan(&rows).Error; err != nil {
+		return tax.Result{}, err
+	}
+	byID := make(map[string]row, len(rows))
+	for _, r := range rows {
+		byID[r.ID] = r
+	}
+
+	classes := make([]string, len(ids))
+	amounts := make([]int, len(ids))
+	giftCards := make([]bool, len(ids))
+	for i, id := range ids {
+		classes[i] = tax.NormalizeClass(byID[id].TaxClass)
+		amounts[i] = byVariant[id]
+		giftCards[i] = byID[id].Kind == products.KindGiftCard
+	}
+	return s.lineTaxInTx(ctx, s.db, country, classes, amounts, giftCards, discount)
 }
 
 type CreateFromCartInput struct {
@@ -36,22 +132,42 @@ type CreateFromCartInput struct {
 	IdempotencyKey *string
 
 	// basit totals (şimdilik dışarıdan veya hesaplayarak)
+	// TaxCents tax servisi set edilmişse yok sayılır
 	TaxCents      int
 	ShippingCents int
 	DiscountCents int
 
+	// promo kodu: tx içinde kilitlenip yeniden doğrulanır, DiscountCents'i ezer
+	PromoCode     string
+	CustomerEmail string
+
+	// KDV oranları bu ülkeye göre seçilir (shipping address country)
+	TaxCountry string
+
+	// ödeme sağlayıcısı yönlendirmesi için (card|paypal|klarna)
+	PaymentMethod string
+
+	// hediye kartı / store credit kodları: tx içinde kilitlenip sırayla kullanılır
+	GiftCardCodes []string
+	// sepetteki hediye kartı ürünlerinin kodlarının gideceği adres (opsiyonel)
+	GiftRecipientEmail string
+
 	ShippingAddressJSON []byte // optional
 	BillingAddressJSON  []byte // optional
 	DisplayCurrency     string
 	ChargeCurrency      string
 }
 
+// CreateFromCartResult amounts are in the order (charge) currency, as stored
+// on the order.
 type CreateFromCartResult struct {
 	OrderID       string
+	OrderNumber   string
 	Status        string
 	Currency      string
 	TotalCents    int
 	SubtotalCents int
+	GiftCardCents int
 	Idempotent    bool // existing order döndüyse true
 }
 
@@ -72,10 +188,12 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 			if err == nil {
 				out = CreateFromCartResult{
 					OrderID:       existing.ID,
+					OrderNumber:   existing.Number(),
 					Status:        existing.Status,
 					Currency:      existing.Currency,
 					TotalCents:    existing.TotalCents,
 					SubtotalCents: existing.SubtotalCents,
+					GiftCardCents: existing.GiftCardCents,
 					Idempotent:    true,
 				}
 				return nil
@@ -163,21 +281,21 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 			}
 		}
 
-		// 4) STOCK DEDUCT (FOR UPDATE + validate + update) — istenen sıradaki kritik adım
-		if err := checkout.DeductStockInTx(ctx, tx, lines); err != nil {
-			return err // OutOfStockError buradan geçer
-		}
+		// 4) stok burada düşülmez; order insert sonrası rezerve edilir (8b), ödeme ile kalıcılaşır
 
 		// 5) Product name snapshot
 		type ProdRow struct {
-			ID     string `gorm:"column:id"`
-			Name   string `gorm:"column:name"`
-			Status string `gorm:"column:status"`
+			ID           string  `gorm:"column:id"`
+			Name         string  `gorm:"column:name"`
+			Status       string  `gorm:"column:status"`
+			CategorySlug *string `gorm:"column:category_slug"`
+			TaxClass     string  `gorm:"column:tax_class"`
+			Kind         string  `gorm:"column:kind"`
 		}
 		var prs []ProdRow
 		if err := tx.WithContext(ctx).
 			Table("products").
-			Select("id, name, status").
+			Select("id, name, status, category_slug, tax_class, kind").
 			Where("id IN ?", productIDs).
 			Find(&prs).Error; err != nil {
 			return err
@@ -193,9 +311,15 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 			}
 		}
 
+		// hediye kartları dijital: stok rezervasyonu yok
+		lines = slices.DeleteFunc(lines, func(l checkout.StockLine) bool {
+			return pmap[vmap[l.VariantID].ProductID].Kind == products.KindGiftCard
+		})
+
 		// 6) totals + order_items prepare
 		subtotal := 0
 		oi := make([]OrderItem, 0, len(ids))
+		giftCards := make([]bool, 0, len(ids)) // oi ile aynı sıra
 
 		for _, vid := range ids {
 			v := vmap[vid]
@@ -204,6 +328,7 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 			subtotal += line
 
 			p := pmap[v.ProductID]
+			giftCards = append(giftCards, p.Kind == products.KindGiftCard)
 
 			oi = append(oi, OrderItem{
 				ID:                 uuid.NewString(),
@@ -219,23 +344,86 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 				BaseCurrency:       currency,
 				BaseUnitPriceCents: v.PriceCents,
 				BaseLineTotalCents: line,
+				TaxClass:           tax.NormalizeClass(p.TaxClass),
 				CreatedAt:          now,
 			})
 		}
 
-		total := subtotal + in.TaxCents + in.ShippingCents - in.DiscountCents
+		baseCurrency := s.normalizeBaseCurrency(currency)
+
+		// 6b) promo code (base currency üzerinden, kilitli)
+		discount := in.DiscountCents
+		var promo *promotions.ApplyResult
+		var promoCode *string
+		if code := promotions.NormalizeCode(in.PromoCode); code != "" {
+			promoLines := make([]promotions.Line, 0, len(ids))
+			for _, vid := range ids {
+				v := vmap[vid]
+				cat := ""
+				if p := pmap[v.ProductID]; p.CategorySlug != nil {
+					cat = *p.CategorySlug
+				}
+				promoLines = append(promoLines, promotions.Line{
+					ProductID:    v.ProductID,
+					CategorySlug: cat,
+					LineCents:    v.PriceCents * want[vid],
+					GiftCard:     pmap[v.ProductID].Kind == products.KindGiftCard,
+				})
+			}
+			res, err := s.promotions.ApplyInTx(ctx, tx, promotions.ApplyInput{
+				Code:         code,
+				UserID:       in.UserID,
+				Email:        in.CustomerEmail,
+				BaseCurrency: baseCurrency,
+				Lines:        promoLines,
+			})
+			if err != nil {
+				return err
+			}
+			promo = &res
+			promoCode = &res.Promotion.Code
+			discount = res.DiscountCents
+		}
+
+		// 6c) tax (indirim satırlara dağıtıldıktan sonra)
+		taxTotal := in.TaxCents
+		pricesIncludeTax := false
+		if s.tax != nil {
+			classes := make([]string, len(oi))
+			amounts := make([]int, len(oi))
+			for i := range oi {
+				classes[i] = oi[i].TaxClass
+				amounts[i] = oi[i].BaseLineTotalCents
+			}
+			res, err := s.lineTaxInTx(ctx, tx, in.TaxCountry, classes, amounts, giftCards, discount)
+			if err != nil {
+				return err
+			}
+			for i, lt := range res.Lines {
+				oi[i].TaxClass = lt.TaxClass
+				oi[i].TaxRateBps = lt.RateBps
+				oi[i].BaseTaxCents = lt.TaxCents
+				oi[i].TaxCents = lt.TaxCents
+			}
+			taxTotal = res.TaxCents
+			pricesIncludeTax = res.PricesIncludeTax
+		}
+
+		total := subtotal + in.ShippingCents - discount
+		if !pricesIncludeTax {
+			total += taxTotal
+		}
 		if total < 0 {
 			total = 0
 		}
 
-		baseCurrency := s.normalizeBaseCurrency(currency)
 		displayCurrency := s.normalizeDisplayCurrency(in.DisplayCurrency, baseCurrency)
 		chargeCurrency := s.normalizeChargeCurrency(in.ChargeCurrency, displayCurrency, baseCurrency)
 
 		chargeSubtotal := subtotal
 		chargeShipping := in.ShippingCents
-		chargeTax := in.TaxCents
-		chargeDiscount := in.DiscountCents
+		chargeTax := taxTotal
+		chargeDiscount := discount
 		chargeTotal := total
 		fxRate := 1.0
 		fxSource := "base"
@@ -248,12 +436,13 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 				}
 				chargeSubtotal = convertedSubtotal
 				chargeShipping = convertWithRate(in.ShippingCents, fxRate)
-				chargeTax = convertWithRate(in.TaxCents, fxRate)
-				chargeDiscount = convertWithRate(in.DiscountCents, fxRate)
+				chargeTax = convertWithRate(taxTotal, fxRate)
+				chargeDiscount = convertWithRate(discount, fxRate)
 				chargeTotal = convertWithRate(total, fxRate)
 				for i := range oi {
 					oi[i].UnitPriceCents = convertWithRate(oi[i].BaseUnitPriceCents, fxRate)
 					oi[i].LineTotalCents = convertWithRate(oi[i].BaseLineTotalCents, fxRate)
+					oi[i].TaxCents = convertWithRate(oi[i].BaseTaxCents, fxRate)
 				}
 			} else {
 				chargeCurrency = baseCurrency
@@ -264,18 +453,50 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 			oi[i].Currency = chargeCurrency
 		}
 
+		// indirimin kalem payları (vergideki dağıtımla aynı sıra, hediye kartları
+		// hariç); kalem bazlı iade ve e-fatura bunları kullanır
+		baseAmounts := make([]int, len(oi))
+		chargeAmounts := make([]int, len(oi))
+		for i := range oi {
+			baseAmounts[i] = oi[i].BaseLineTotalCents
+			chargeAmounts[i] = oi[i].LineTotalCents
+		}
+		baseNet := allocateDiscount(baseAmounts, giftCards, discount)
+		chargeNet := allocateDiscount(chargeAmounts, giftCards, chargeDiscount)
+		for i := range oi {
+			oi[i].BaseDiscountCents = oi[i].BaseLineTotalCents - baseNet[i]
+			oi[i].DiscountCents = oi[i].LineTotalCents - chargeNet[i]
+		}
+
 		var fxSourcePtr *string
 		if fxSource != "" {
 			fxSourcePtr = &fxSource
 		}
 
-		// 7) orders insert
+		// 7) orders insert (numara sayacı tx sonuna kadar kilitli kalır)
 		orderID := uuid.NewString()
+		orderNumber, err := NextOrderNumberInTx(ctx, tx, now)
+		if err != nil {
+			return err
+		}
+		var paymentMethod *string
+		if m := strings.ToLower(strings.TrimSpace(in.PaymentMethod)); m != "" {
+			paymentMethod = &m
+		}
+		var giftRecipient *string
+		if e := strings.ToLower(strings.TrimSpace(in.GiftRecipientEmail)); e != "" {
+			giftRecipient = &e
+		}
+
 		o := Order{
-			ID:                orderID,
-			UserID:            in.UserID,
-			GuestEmail:        in.GuestEmail,
-			Status:            "created",
+			ID:          orderID,
+			OrderNumber: &orderNumber,
+			UserID:      in.UserID,
+			GuestEmail:  in.GuestEmail,
+			Status:      "created",
+
+			GiftRecipientEmail: giftRecipient,
+
 			Currency:          chargeCurrency,
 			BaseCurrency:      baseCurrency,
 			DisplayCurrency:   displayCurrency,
@@ -287,10 +508,13 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 			DiscountCents:     chargeDiscount,
 			TotalCents:        chargeTotal,
 			BaseSubtotalCents: subtotal,
-			BaseTaxCents:      in.TaxCents,
+			BaseTaxCents:      taxTotal,
 			BaseShippingCents: in.ShippingCents,
-			BaseDiscountCents: in.DiscountCents,
+			BaseDiscountCents: discount,
 			BaseTotalCents:    total,
+			PromoCode:         promoCode,
+			PricesIncludeTax:  pricesIncludeTax,
+			PaymentMethod:     paymentMethod,
 
 			ShippingAddressJSON: in.ShippingAddressJSON,
 			BillingAddressJSON:  in.BillingAddressJSON,
@@ -302,19 +526,22 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 		}
 
 		if err := tx.WithContext(ctx).Create(&o).Error; err != nil {
-			// idempotency unique çakışması: mevcut order'ı dön
+			// idempotency unique çakışması: mevcut order'ı dön; tx geri alınır ki
+			// ayrılan sipariş numarası boşa gitmesin (numaralarda boşluk olmaz)
 			if isDuplicateKey(err) && in.UserID != nil && in.IdempotencyKey != nil && *in.IdempotencyKey != "" {
 				var existing Order
 				if err2 := tx.WithContext(ctx).First(&existing, "user_id = ? AND idempotency_key = ?", *in.UserID, *in.IdempotencyKey).Error; err2 == nil {
 					out = CreateFromCartResult{
 						OrderID:       existing.ID,
+						OrderNumber:   existing.Number(),
 						Status:        existing.Status,
 						Currency:      existing.Currency,
 						TotalCents:    existing.TotalCents,
 						SubtotalCents: existing.SubtotalCents,
+						GiftCardCents: existing.GiftCardCents,
 						Idempotent:    true,
 					}
-					return nil
+					return errIdempotentReplay
 				}
 			}
 			return err
@@ -328,6 +555,51 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 			return err
 		}
 
+		// 8b) stock reservation (FOR UPDATE + validate + insert)
+		if err := checkout.ReserveStockInTx(ctx, tx, orderID, lines, now.Add(s.reservationTTL)); err != nil {
+			return err // OutOfStockError buradan geçer
+		}
+
+		// 8c) promo redemption
+		if promo != nil {
+			if err := s.promotions.RedeemInTx(ctx, tx, *promo, promotions.RedeemInput{
+				OrderID:           orderID,
+				UserID:            in.UserID,
+				Email:             in.CustomerEmail,
+				DiscountCents:     chargeDiscount,
+				Currency:          chargeCurrency,
+				BaseDiscountCents: discount,
+				BaseCurrency:      baseCurrency,
+			}); err != nil {
+				return err
+			}
+		}
+
+		// 8d) gift card tender: karşılanan tutar kartlardan düşülür; kalan sağlayıcıdan tahsil edilir
+		giftCents := 0
+		if len(in.GiftCardCodes) > 0 {
+			redeemed, err := giftcards.RedeemInTx(ctx, tx, giftcards.RedeemInput{
+				Codes:       in.GiftCardCodes,
+				OrderID:     orderID,
+				UserID:      in.UserID,
+				Currency:    chargeCurrency,
+				AmountCents: chargeTotal,
+			})
+			if err != nil {
+				return err
+			}
+			for _, t := range redeemed {
+				giftCents += -t.AmountCents
+			}
+			if giftCents > 0 {
+				if err := tx.WithContext(ctx).Model(&Order{}).
+					Where("id = ?", orderID).
+					Update("gift_card_cents", giftCents).Error; err != nil {
+					return err
+				}
+			}
+		}
+
 		// 9) cart cleanup
 		if in.UserID != nil {
 			// user cart: cart kalsın, items silinsin
@@ -343,18 +615,27 @@ func (s *Service) CreateFromCart(ctx context.Context, in CreateFromCartInput) (C
 
 		out = CreateFromCartResult{
 			OrderID:       orderID,
+			OrderNumber:   orderNumber,
 			Status:        o.Status,
-			Currency:      currency,
-			TotalCents:    total,
-			SubtotalCents: subtotal,
+			Currency:      chargeCurrency,
+			TotalCents:    chargeTotal,
+			SubtotalCents: chargeSubtotal,
+			GiftCardCents: giftCents,
 			Idempotent:    false,
 		}
 		return nil
 	})
+	if errors.Is(err, errIdempotentReplay) {
+		return out, nil
+	}
 
 	return out, err
 }
 
+// errIdempotentReplay rolls back a CreateFromCart that lost the race for its
+// idempotency key; the caller gets the order that won.
+var errIdempotentReplay = errors.New("idempotent replay")
+
 func isDuplicateKey(err error) bool {
 	var me *mysql.MySQLError
 	if errors.As(err, &me) {
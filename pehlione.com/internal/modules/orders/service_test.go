@@ -0,0 +1,60 @@
+package orders
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/tax"
+)
+
+func TestPreviewTax(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	for _, q := range []string{
+		`CREATE TABLE products (id TEXT PRIMARY KEY, tax_class TEXT, kind TEXT NOT NULL DEFAULT 'standard')`,
+		`CREATE TABLE product_variants (id TEXT PRIMARY KEY, product_id TEXT)`,
+		`CREATE TABLE tax_rates (id TEXT PRIMARY KEY, country TEXT, tax_class TEXT, name TEXT, rate_bps INTEGER, created_at DATETIME, updated_at DATETIME)`,
+		`INSERT INTO products (id, tax_class, kind) VALUES ('p1', 'standard', 'standard'), ('p2', 'reduced', 'standard'), ('p3', 'standard', 'gift_card')`,
+		`INSERT INTO product_variants VALUES ('v1', 'p1'), ('v2', 'p2'), ('v3', 'p3')`,
+		`INSERT INTO tax_rates (id, country, tax_class, name, rate_bps) VALUES ('r1', 'TR', 'standard', 'KDV', 2000), ('r2', 'TR', 'reduced', 'KDV', 1000)`,
+	} {
+		require.NoError(t, db.Exec(q).Error)
+	}
+	ctx := context.Background()
+	lines := []TaxPreviewLine{{VariantID: "v2", AmountCents: 5000}, {VariantID: "v1", AmountCents: 6000}, {VariantID: "v1", AmountCents: 4000}}
+
+	svc := NewService(db, nil)
+	res, err := svc.PreviewTax(ctx, "TR", lines, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, res.TaxCents, "tax servisi yokken KDV hesaplanmaz")
+
+	// net fiyatlar: indirim satırlara dağıtılır, KDV kalan tutara eklenir
+	svc.SetTaxService(tax.NewService(db, false))
+	res, err = svc.PreviewTax(ctx, "tr", lines, 1500)
+	require.NoError(t, err)
+	require.Equal(t, []tax.LineTax{
+		{TaxClass: "standard", RateBps: 2000, TaxCents: 1800}, // v1: 10000 - 1000
+		{TaxClass: "reduced", RateBps: 1000, TaxCents: 450},   // v2: 5000 - 500
+	}, res.Lines)
+	require.Equal(t, 2250, res.TaxCents)
+	require.False(t, res.PricesIncludeTax)
+
+	// hediye kartı indirimden pay almaz (indirim yine yalnızca v1 ve v2'ye
+	// dağılır) ve ürünün vergi sınıfı ne olursa olsun KDV'siz satılır
+	res, err = svc.PreviewTax(ctx, "tr", append(lines, TaxPreviewLine{VariantID: "v3", AmountCents: 10000}), 1500)
+	require.NoError(t, err)
+	require.Equal(t, []tax.LineTax{
+		{TaxClass: "standard", RateBps: 2000, TaxCents: 1800},
+		{TaxClass: "reduced", RateBps: 1000, TaxCents: 450},
+		{TaxClass: "zero", RateBps: 0, TaxCents: 0}, // v3: hediye kartı
+	}, res.Lines)
+	require.Equal(t, 2250, res.TaxCents)
+
+	res, err = svc.PreviewTax(ctx, "DE", lines, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, res.TaxCents, "oranı tanımlı olmayan ülke")
+}
@@ -0,0 +1,243 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/checkout"
+	"pehlione.com/app/internal/modules/orders"
+)
+
+var (
+	ErrNotCapturable = errors.New("payment not capturable")
+	ErrCaptureFailed = errors.New("payment capture failed")
+	ErrVoidFailed    = errors.New("payment void failed")
+)
+
+// CaptureOrder captures the authorized payment of an order and moves the
+// order authorized -> paid. Already captured orders are a no-op.
+func (s *Service) CaptureOrder(ctx context.Context, orderID, actorUserID string) error {
+	_ = actorUserID
+
+	// Phase-1: lock order + find authorized payment
+	var pay Payment
+	done := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ord orders.Order
+		if err := tx.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&ord, "id = ?", orderID).Error; err != nil {
+			return err
+		}
+		if ord.Status == "paid" {
+			done = true
+			return nil
+		}
+		if ord.Status != "authorized" {
+			return ErrNotCapturable
+		}
+		if err := tx.WithContext(ctx).
+			Order("updated_at DESC").
+			First(&pay, "order_id = ? AND status = ?", ord.ID, StatusAuthorized).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotCapturable
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil || done {
+		return err
+	}
+
+	provider, err := s.providers.Get(pay.Provider)
+	if err != nil {
+		return err
+	}
+
+	// Phase-2: provider capture (outside tx)
+	resp, perr := provider.CapturePayment(ctx, CaptureRequest{
+		OrderID:        pay.OrderID,
+		PaymentID:      pay.ID,
+		PaymentRef:     ptrVal(pay.ProviderRef),
+		AmountCents:    pay.AmountCents,
+		Currency:       pay.Currency,
+		IdempotencyKey: "capture-" + pay.ID,
+	})
+
+	// Phase-3: finalize
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if perr != nil || resp.Status == StatusFailed {
+			msg := "capture failed"
+			if perr != nil {
+				msg = truncate(perr.Error(), 250)
+			}
+			// yetkilendirme geçerli kalır; admin tekrar deneyebilir
+			if err := tx.WithContext(ctx).Model(&Payment{}).
+				Where("id = ?", pay.ID).
+				Updates(map[string]any{"error_message": msg, "updated_at": now}).Error; err != nil {
+				return err
+			}
+			return ErrCaptureFailed
+		}
+		if resp.Status != StatusSucceeded {
+			// async: payment.captured webhook tamamlar
+			return orders.ErrCapturePending
+		}
+		return markCapturedInTx(ctx, tx, pay, now)
+	})
+}
+
+// VoidOrder releases the authorized payment of an order. Orders without an
+// authorized payment are a no-op.
+func (s *Service) VoidOrder(ctx context.Context, orderID, actorUserID string) error {
+	_ = actorUserID
+
+	var pay Payment
+	err := s.db.WithContext(ctx).
+		Order("updated_at DESC").
+		First(&pay, "order_id = ? AND status = ?", orderID, StatusAuthorized).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	provider, err := s.providers.Get(pay.Provider)
+	if err != nil {
+		return err
+	}
+	resp, perr := provider.VoidPayment(ctx, VoidRequest{
+		OrderID:        pay.OrderID,
+		PaymentID:      pay.ID,
+		PaymentRef:     ptrVal(pay.ProviderRef),
+		IdempotencyKey: "void-" + pay.ID,
+	})
+	if perr != nil || resp.Status != StatusVoided {
+		return ErrVoidFailed
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.WithContext(ctx).Model(&Payment{}).
+			Where("id = ? AND status = ?", pay.ID, StatusAuthorized).
+			Updates(map[string]any{"status": StatusVoided, "error_message": nil, "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		// ledger: payment_voided (0) — para hareketi yok
+		return ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
+			ID:          uuid.NewString(),
+			OrderID:     pay.OrderID,
+			Event:       "payment_voided",
+			AmountCents: 0,
+			Currency:    pay.Currency,
+			RefType:     "payment",
+			RefID:       pay.ID,
+			CreatedAt:   now,
+		})
+	})
+}
+
+// markAuthorizedInTx: payment -> authorized, order created -> authorized.
+// Stock is committed at authorization like at payment.
+func markAuthorizedInTx(ctx context.Context, tx *gorm.DB, p Payment, now time.Time) error {
+	if err := tx.WithContext(ctx).Model(&Payment{}).
+		Where("id = ?", p.ID).
+		Updates(map[string]any{
+			"status":        StatusAuthorized,
+			"error_message": nil,
+			"updated_at":    now,
+		}).Error; err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Model(&orders.Order{}).
+		Where("id = ? AND status = 'created'", p.OrderID).
+		Updates(map[string]any{
+			"status":     "authorized",
+			"updated_at": now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 1 {
+		// reserved stock -> permanent deduction
+		if err := checkout.ConvertReservationsInTx(ctx, tx, p.OrderID); err != nil {
+			return err
+		}
+	} else if err := flagLatePaymentInTx(ctx, tx, p, StatusAuthorized, now); err != nil {
+		return err
+	}
+
+	// ledger: payment_authorized (0) — tutar bloke, tahsil edilmedi
+	return ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
+		ID:          uuid.NewString(),
+		OrderID:     p.OrderID,
+		Event:       "payment_authorized",
+		AmountCents: 0,
+		Currency:    p.Currency,
+		RefType:     "payment",
+		RefID:       p.ID,
+		CreatedAt:   now,
+	})
+}
+
+// markCapturedInTx: payment -> succeeded, order authorized -> paid.
+func markCapturedInTx(ctx context.Context, tx *gorm.DB, p Payment, now time.Time) error {
+	if err := tx.WithContext(ctx).Model(&Payment{}).
+		Where("id = ?", p.ID).
+		Updates(map[string]any{
+			"status":        StatusSucceeded,
+			"error_message": nil,
+			"updated_at":    now,
+		}).Error; err != nil {
+		return err
+	}
+
+	paidAt := now
+	res := tx.WithContext(ctx).Model(&orders.Order{}).
+		Where("id = ? AND status = 'authorized'", p.OrderID).
+		Updates(map[string]any{
+			"status":     "paid",
+			"paid_at":    &paidAt,
+			"updated_at": now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 1 {
+		if err := orderPaidInTx(ctx, tx, p.OrderID, now); err != nil {
+			return err
+		}
+	}
+
+	// ledger: payment_captured (+)
+	return ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
+		ID:          uuid.NewString(),
+		OrderID:     p.OrderID,
+		Event:       "payment_captured",
+		AmountCents: p.AmountCents,
+		Currency:    p.Currency,
+		RefType:     "payment",
+		RefID:       p.ID,
+		CreatedAt:   now,
+	})
+}
+
+func ptrVal(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
@@ -0,0 +1,130 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+func setupCaptureDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE orders (
+		id TEXT PRIMARY KEY, user_id TEXT, guest_email TEXT, gift_recipient_email TEXT, status TEXT NOT NULL, currency TEXT NOT NULL,
+		total_cents INTEGER NOT NULL, gift_card_cents INTEGER NOT NULL DEFAULT 0, paid_at DATETIME, created_at DATETIME, updated_at DATETIME,
+		order_number TEXT, invoice_number TEXT UNIQUE, invoiced_at DATETIME)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE number_sequences (
+		name TEXT NOT NULL, year INTEGER NOT NULL, last_value INTEGER NOT NULL, updated_at DATETIME NOT NULL,
+		PRIMARY KEY (name, year))`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE payments (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, provider TEXT NOT NULL, provider_ref TEXT, status TEXT NOT NULL,
+		amount_cents INTEGER NOT NULL, currency TEXT NOT NULL, idempotency_key TEXT NOT NULL, error_message TEXT,
+		redirect_url TEXT, return_token TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE order_financial_entries (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, event TEXT NOT NULL, amount_cents INTEGER NOT NULL,
+		currency TEXT NOT NULL, ref_type TEXT NOT NULL, ref_id TEXT NOT NULL, created_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE stock_reservations (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, qty INTEGER NOT NULL, status TEXT NOT NULL)`).Error)
+	setupGiftCardTables(t, db)
+	require.NoError(t, db.Exec(`INSERT INTO orders (id, status, currency, total_cents) VALUES ('o1', 'created', 'EUR', 5000)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO payments (id, order_id, provider, provider_ref, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+		VALUES ('p1', 'o1', 'mock', 'ref1', 'initiated', 5000, 'EUR', 'k1', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`).Error)
+	return db
+}
+
+func statusOf(t *testing.T, db *gorm.DB, table, id string) string {
+	var st string
+	require.NoError(t, db.Table(table).Select("status").Where("id = ?", id).Scan(&st).Error)
+	return st
+}
+
+func ledger(t *testing.T, db *gorm.DB) map[string]int {
+	var rows []struct {
+		Event       string
+		AmountCents int
+	}
+	require.NoError(t, db.Table("order_financial_entries").Select("event, amount_cents").Scan(&rows).Error)
+	out := map[string]int{}
+	for _, r := range rows {
+		out[r.Event] += r.AmountCents
+	}
+	return out
+}
+
+func TestAuthorizeThenCapture(t *testing.T) {
+	db := setupCaptureDB(t)
+	ctx := context.Background()
+	svc := NewService(db, NewRegistry(NewMockProvider("", 0)))
+
+	var p Payment
+	require.NoError(t, db.First(&p, "id = ?", "p1").Error)
+	require.NoError(t, markAuthorizedInTx(ctx, db, p, p.CreatedAt))
+	assert.Equal(t, "authorized", statusOf(t, db, "orders", "o1"))
+	assert.Equal(t, StatusAuthorized, statusOf(t, db, "payments", "p1"))
+
+	require.NoError(t, svc.CaptureOrder(ctx, "o1", "admin"))
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+	assert.Equal(t, StatusSucceeded, statusOf(t, db, "payments", "p1"))
+
+	require.NoError(t, svc.CaptureOrder(ctx, "o1", "admin"), "capture is idempotent")
+	assert.Equal(t, map[string]int{"payment_authorized": 0, "payment_captured": 5000}, ledger(t, db))
+
+	var invoice string
+	require.NoError(t, db.Table("orders").Select("invoice_number").Where("id = ?", "o1").Scan(&invoice).Error)
+	assert.Regexp(t, `^INV-\d{4}-000001$`, invoice)
+}
+
+func TestVoidAuthorization(t *testing.T) {
+	db := setupCaptureDB(t)
+	ctx := context.Background()
+	svc := NewService(db, NewRegistry(NewMockProvider("", 0)))
+
+	var p Payment
+	require.NoError(t, db.First(&p, "id = ?", "p1").Error)
+	require.NoError(t, markAuthorizedInTx(ctx, db, p, p.CreatedAt))
+
+	require.NoError(t, svc.VoidOrder(ctx, "o1", "admin"))
+	assert.Equal(t, StatusVoided, statusOf(t, db, "payments", "p1"))
+	assert.ErrorIs(t, svc.CaptureOrder(ctx, "o1", "admin"), ErrNotCapturable)
+	assert.Equal(t, map[string]int{"payment_authorized": 0, "payment_voided": 0}, ledger(t, db))
+}
+
+func TestPayOrder_ReusesPendingPaymentAndFlagsDuplicates(t *testing.T) {
+	db := setupCaptureDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`CREATE TABLE order_events (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, actor_user_id TEXT NOT NULL, action TEXT NOT NULL,
+		from_status TEXT NOT NULL, to_status TEXT NOT NULL, note TEXT, created_at DATETIME NOT NULL)`).Error)
+	svc := NewService(db, NewRegistry(NewMockProvider("", 0)))
+
+	// ikinci sekmeden yeni anahtarla gönderim: bekleyen ödemeye devam edilir
+	res, err := svc.PayOrder(ctx, PayOrderInput{OrderID: "o1", IdempotencyKey: "k2"})
+	require.NoError(t, err)
+	assert.Equal(t, "p1", res.PaymentID)
+	assert.True(t, res.Idempotent)
+	var n int64
+	require.NoError(t, db.Model(&Payment{}).Where("order_id = ?", "o1").Count(&n).Error)
+	assert.EqualValues(t, 1, n, "no second charge is opened")
+
+	// yine de iki ödeme başarılı olursa ikincisi işaretlenir
+	require.NoError(t, db.Exec(`INSERT INTO payments (id, order_id, provider, provider_ref, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+		VALUES ('p2', 'o1', 'mock', 'ref2', 'initiated', 5000, 'EUR', 'k3', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`).Error)
+	var p1, p2 Payment
+	require.NoError(t, db.First(&p1, "id = ?", "p1").Error)
+	require.NoError(t, db.First(&p2, "id = ?", "p2").Error)
+	require.NoError(t, markSucceededInTx(ctx, db, p1, time.Now()))
+	require.NoError(t, markSucceededInTx(ctx, db, p2, time.Now()))
+
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+	var flagged []orders.OrderEvent
+	require.NoError(t, db.Where("action = ?", "duplicate_payment").Find(&flagged).Error)
+	require.Len(t, flagged, 1)
+	assert.Contains(t, *flagged[0].Note, "payment_id=p2")
+}
@@ -0,0 +1,128 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/pdf"
+)
+
+var ErrCreditNoteNotFound = errors.New("credit note not found")
+
+// assignCreditNoteInTx numbers the credit note of a succeeded refund. A refund
+// that already has a number keeps it.
+func assignCreditNoteInTx(ctx context.Context, tx *gorm.DB, refundID string, now time.Time) (string, error) {
+	var r Refund
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "credit_note_number").
+		First(&r, "id = ?", refundID).Error; err != nil {
+		return "", err
+	}
+	if n := ptrVal(r.CreditNoteNumber); n != "" {
+		return n, nil
+	}
+
+	seq, err := orders.NextNumberInTx(ctx, tx, orders.SeqCreditNote, now.Year(), now)
+	if err != nil {
+		return "", err
+	}
+	number := orders.FormatNumber(orders.CreditNotePrefix, now.Year(), seq)
+	issuedAt := now
+	if err := tx.WithContext(ctx).Model(&Refund{}).
+		Where("id = ?", refundID).
+		Updates(map[string]any{
+			"credit_note_number":    number,
+			"credit_note_issued_at": &issuedAt,
+		}).Error; err != nil {
+		return "", err
+	}
+	return number, nil
+}
+
+// CreditNote loads the credit note of a succeeded refund of the order.
+func CreditNote(ctx context.Context, db *gorm.DB, orderID, refundID string) (pdf.CreditNoteData, error) {
+	var r Refund
+	err := db.WithContext(ctx).First(&r, "id = ? AND order_id = ?", refundID, orderID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && ptrVal(r.CreditNoteNumber) == "") {
+		return pdf.CreditNoteData{}, ErrCreditNoteNotFound
+	}
+	if err != nil {
+		return pdf.CreditNoteData{}, err
+	}
+	var ord orders.Order
+	if err := db.WithContext(ctx).First(&ord, "id = ?", orderID).Error; err != nil {
+		return pdf.CreditNoteData{}, err
+	}
+	return creditNoteDataInTx(ctx, db, ord, r)
+}
+
+func creditNoteDataInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, r Refund) (pdf.CreditNoteData, error) {
+	lines, err := refundLinesInTx(ctx, tx, r.ID)
+	if err != nil {
+		return pdf.CreditNoteData{}, err
+	}
+
+	data := pdf.CreditNoteData{
+		Order:      ord,
+		Number:     ptrVal(r.CreditNoteNumber),
+		IssuedAt:   r.UpdatedAt,
+		Reason:     ptrVal(r.Reason),
+		Currency:   r.Currency,
+		TotalCents: r.AmountCents,
+	}
+	if r.CreditNoteIssuedAt != nil {
+		data.IssuedAt = *r.CreditNoteIssuedAt
+	}
+
+	// tutar bazlı iade: kalem yok, vergi payı sipariş oranında
+	if len(lines) == 0 {
+		taxShare := 0
+		if ord.TotalCents > 0 {
+			taxShare = (r.AmountCents*ord.TaxCents + ord.TotalCents/2) / ord.TotalCents
+		}
+		data.Lines = []pdf.CreditNoteLine{{
+			Label:       "Refund for order " + ord.Number(),
+			TaxCents:    taxShare,
+			AmountCents: r.AmountCents,
+		}}
+		return data, nil
+	}
+
+	var items []orders.OrderItem
+	if err := tx.WithContext(ctx).Select("id", "tax_rate_bps").Find(&items, "order_id = ?", ord.ID).Error; err != nil {
+		return pdf.CreditNoteData{}, err
+	}
+	rates := make(map[string]int, len(items))
+	for _, it := range items {
+		rates[it.ID] = it.TaxRateBps
+	}
+	for _, l := range lines {
+		cl := pdf.CreditNoteLine{Label: l.Label, TaxCents: l.TaxCents, AmountCents: l.AmountCents}
+		if l.Kind == LineItem {
+			cl.Qty = l.Qty
+			cl.TaxRateBps = rates[ptrVal(l.OrderItemID)]
+		}
+		data.Lines = append(data.Lines, cl)
+	}
+	return data, nil
+}
+
+// creditNoteAttachmentInTx renders the credit note PDF for the refund email.
+func creditNoteAttachmentInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, r Refund) (emailmod.Attachment, error) {
+	data, err := creditNoteDataInTx(ctx, tx, ord, r)
+	if err != nil {
+		return emailmod.Attachment{}, err
+	}
+	b, err := pdf.GenerateCreditNote(data)
+	if err != nil {
+		return emailmod.Attachment{}, err
+	}
+	return emailmod.Attachment{Filename: data.Filename(), ContentType: "application/pdf", Data: b}, nil
+}
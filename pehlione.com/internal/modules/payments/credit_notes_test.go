@@ -0,0 +1,51 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pehlione.com/app/internal/pdf"
+)
+
+func TestCreditNote_NumberedOnceAndRendered(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`CREATE TABLE refund_lines (
+		id TEXT PRIMARY KEY, refund_id TEXT NOT NULL, kind TEXT NOT NULL, order_item_id TEXT, label TEXT NOT NULL,
+		qty INTEGER NOT NULL DEFAULT 0, amount_cents INTEGER NOT NULL, tax_cents INTEGER NOT NULL DEFAULT 0,
+		currency TEXT NOT NULL, created_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO refunds (id, order_id, provider, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+		VALUES ('r1', 'o1', 'mock', 'succeeded', 2000, 'EUR', 'k1', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+		       ('r2', 'o1', 'mock', 'initiated', 1000, 'EUR', 'k2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`).Error)
+
+	_, err := CreditNote(ctx, db, "o1", "r1")
+	require.ErrorIs(t, err, ErrCreditNoteNotFound, "no number before the refund succeeds")
+
+	now := time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC)
+	n1, err := assignCreditNoteInTx(ctx, db, "r1", now)
+	require.NoError(t, err)
+	n2, err := assignCreditNoteInTx(ctx, db, "r1", now)
+	require.NoError(t, err)
+	assert.Equal(t, "CN-2026-000001", n1)
+	assert.Equal(t, n1, n2)
+
+	_, err = CreditNote(ctx, db, "other-order", "r1")
+	require.ErrorIs(t, err, ErrCreditNoteNotFound)
+
+	data, err := CreditNote(ctx, db, "o1", "r1")
+	require.NoError(t, err)
+	assert.Equal(t, "CN-2026-000001", data.Number)
+	assert.Equal(t, 2000, data.TotalCents)
+	require.Len(t, data.Lines, 1)
+	assert.Equal(t, 2000, data.Lines[0].AmountCents)
+	assert.Equal(t, "pehlione-credit-note-CN-2026-000001.pdf", data.Filename())
+
+	b, err := pdf.GenerateCreditNote(data)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(b, []byte("%PDF")))
+}
@@ -0,0 +1,190 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/pkg/view"
+)
+
+var ErrDisputeClosed = errors.New("dispute already closed")
+
+// applyDisputeOpened records a chargeback: the disputed amount is withdrawn
+// by the network, so it leaves the ledger immediately.
+func (s *WebhookService) applyDisputeOpened(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) error {
+	_, err := openDisputeInTx(ctx, tx, provider, ev)
+	return err
+}
+
+func openDisputeInTx(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) (Dispute, error) {
+	if ev.DisputeRef == "" {
+		return Dispute{}, errors.New("missing dispute_ref")
+	}
+
+	var d Dispute
+	err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&d, "provider = ? AND provider_ref = ?", provider, ev.DisputeRef).Error
+	if err == nil {
+		// idempotent
+		return d, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return Dispute{}, err
+	}
+
+	p, err := lockEventPaymentInTx(ctx, tx, provider, ev)
+	if err != nil {
+		return Dispute{}, err // bulunamazsa retry
+	}
+
+	amount := ev.AmountCents
+	if amount <= 0 || amount > p.AmountCents {
+		amount = p.AmountCents
+	}
+	now := time.Now()
+	d = Dispute{
+		ID:          uuid.NewString(),
+		OrderID:     p.OrderID,
+		PaymentID:   p.ID,
+		Provider:    provider,
+		ProviderRef: ev.DisputeRef,
+		Status:      DisputeOpen,
+		Reason:      nilIfEmpty(strings.TrimSpace(ev.Reason)),
+		AmountCents: amount,
+		Currency:    p.Currency,
+		OpenedAt:    now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := tx.WithContext(ctx).Create(&d).Error; err != nil {
+		return Dispute{}, err
+	}
+
+	if err := setOrderDisputeInTx(ctx, tx, d.OrderID, DisputeOpen, now); err != nil {
+		return Dispute{}, err
+	}
+
+	// ledger: dispute_opened (-) — tutar ağ tarafından geri çekildi
+	if err := ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
+		ID:          uuid.NewString(),
+		OrderID:     d.OrderID,
+		Event:       "dispute_opened",
+		AmountCents: -d.AmountCents,
+		Currency:    d.Currency,
+		RefType:     "dispute",
+		RefID:       d.ID,
+		CreatedAt:   now,
+	}); err != nil {
+		return Dispute{}, err
+	}
+
+	return d, enqueueDisputeAlertInTx(ctx, tx, d)
+}
+
+// applyDisputeClosed settles a dispute. Won reinstates the withdrawn amount;
+// lost makes the withdrawal final (0 entry for the audit trail).
+func (s *WebhookService) applyDisputeClosed(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent, outcome string) error {
+	// opened webhook'u kaçırıldıysa önce aç
+	d, err := openDisputeInTx(ctx, tx, provider, ev)
+	if err != nil {
+		return err
+	}
+	if d.Status == outcome {
+		return nil
+	}
+	if d.Status != DisputeOpen {
+		return ErrDisputeClosed
+	}
+
+	now := time.Now()
+	if err := tx.WithContext(ctx).Model(&Dispute{}).
+		Where("id = ?", d.ID).
+		Updates(map[string]any{
+			"status":     outcome,
+			"closed_at":  &now,
+			"updated_at": now,
+		}).Error; err != nil {
+		return err
+	}
+	d.Status = outcome
+
+	if err := setOrderDisputeInTx(ctx, tx, d.OrderID, outcome, now); err != nil {
+		return err
+	}
+
+	entry := orders.FinancialEntry{
+		ID:        uuid.NewString(),
+		OrderID:   d.OrderID,
+		Event:     "dispute_lost",
+		Currency:  d.Currency,
+		RefType:   "dispute",
+		RefID:     d.ID,
+		CreatedAt: now,
+	}
+	if outcome == DisputeWon {
+		// ledger: dispute_won (+) — tutar iade edildi
+		entry.Event = "dispute_won"
+		entry.AmountCents = d.AmountCents
+	}
+	if err := ensureFinancialEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return enqueueDisputeAlertInTx(ctx, tx, d)
+}
+
+func setOrderDisputeInTx(ctx context.Context, tx *gorm.DB, orderID, status string, now time.Time) error {
+	return tx.WithContext(ctx).Model(&orders.Order{}).
+		Where("id = ?", orderID).
+		Updates(map[string]any{"dispute_status": status, "updated_at": now}).Error
+}
+
+// enqueueDisputeAlertInTx queues a dispute email to every admin in the same
+// tx, so the alert is only sent if the transition commits.
+func enqueueDisputeAlertInTx(ctx context.Context, tx *gorm.DB, d Dispute) error {
+	var recipients []string
+	if err := tx.WithContext(ctx).
+		Table("users").
+		Where("role = ?", "admin").
+		Pluck("email", &recipients).Error; err != nil {
+		return err
+	}
+
+	outbox := emailmod.NewService(tx)
+	for _, to := range recipients {
+		if err := outbox.EnqueueTx(ctx, tx, emailmod.Job{
+			To:       to,
+			Template: emailmod.TemplateDisputeAlert,
+			Payload: map[string]any{
+				"OrderID":   d.OrderID,
+				"DisputeID": d.ID,
+				"Provider":  d.Provider,
+				"Status":    d.Status,
+				"Reason":    ptrVal(d.Reason),
+				"Amount":    view.MoneyFromCents(d.AmountCents, d.Currency),
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDisputes returns an order's disputes, newest first.
+func ListDisputes(ctx context.Context, db *gorm.DB, orderID string) ([]Dispute, error) {
+	var out []Dispute
+	err := db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("opened_at DESC").
+		Find(&out).Error
+	return out, err
+}
@@ -0,0 +1,131 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupDisputeDB(t *testing.T) *gorm.DB {
+	db := setupReconcileDB(t)
+	require.NoError(t, db.Exec(`ALTER TABLE orders ADD COLUMN dispute_status TEXT`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE disputes (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, payment_id TEXT NOT NULL, provider TEXT NOT NULL, provider_ref TEXT NOT NULL,
+		status TEXT NOT NULL, reason TEXT, amount_cents INTEGER NOT NULL, currency TEXT NOT NULL,
+		opened_at DATETIME NOT NULL, closed_at DATETIME, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT NOT NULL, role TEXT NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO users (id, email, role) VALUES ('u1', 'admin@example.com', 'admin'), ('u2', 'c@example.com', 'user')`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE email_outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, to_email TEXT NOT NULL, template TEXT NOT NULL, payload TEXT NOT NULL, attachments TEXT, status TEXT NOT NULL,
+		attempt_count INTEGER NOT NULL DEFAULT 0, last_error TEXT, scheduled_at DATETIME NOT NULL, locked_at DATETIME, locked_by TEXT,
+		sent_at DATETIME, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`UPDATE orders SET status = 'paid' WHERE id = 'o1'`).Error)
+	require.NoError(t, db.Exec(`UPDATE payments SET status = 'succeeded' WHERE id = 'p1'`).Error)
+	return db
+}
+
+func TestDispute_OpenedThenWon(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	svc := NewWebhookService(db)
+
+	opened := WebhookEvent{EventID: "evt_d1", Type: "dispute.opened", PaymentRef: "ref1", DisputeRef: "dp_1", Reason: "fraudulent", AmountCents: 5000, Currency: "EUR"}
+	require.NoError(t, svc.Handle(ctx, "mock", opened, []byte(`{}`)))
+
+	var ds []Dispute
+	require.NoError(t, db.Find(&ds).Error)
+	require.Len(t, ds, 1)
+	assert.Equal(t, DisputeOpen, ds[0].Status)
+	assert.Equal(t, "fraudulent", ptrVal(ds[0].Reason))
+	assert.Equal(t, DisputeOpen, disputeFlag(t, db))
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+
+	won := WebhookEvent{EventID: "evt_d2", Type: "dispute.won", PaymentRef: "ref1", DisputeRef: "dp_1", AmountCents: 5000, Currency: "EUR"}
+	require.NoError(t, svc.Handle(ctx, "mock", won, []byte(`{}`)))
+	assert.Equal(t, DisputeWon, statusOf(t, db, "disputes", ds[0].ID))
+	assert.Equal(t, DisputeWon, disputeFlag(t, db))
+	assert.Equal(t, map[string]int{"dispute_opened": -5000, "dispute_won": 5000}, ledger(t, db))
+
+	var alerts []string
+	require.NoError(t, db.Table("email_outbox").Where("template = ?", "dispute_alert").Pluck("to_email", &alerts).Error)
+	assert.Equal(t, []string{"admin@example.com", "admin@example.com"}, alerts)
+}
+
+func TestDispute_LostWithoutOpened(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	svc := NewWebhookService(db)
+
+	lost := WebhookEvent{EventID: "evt_d3", Type: "dispute.lost", PaymentRef: "ref1", DisputeRef: "dp_2", AmountCents: 2000, Currency: "EUR"}
+	require.NoError(t, svc.Handle(ctx, "mock", lost, []byte(`{}`)))
+	assert.Equal(t, DisputeLost, disputeFlag(t, db))
+	assert.Equal(t, map[string]int{"dispute_opened": -2000, "dispute_lost": 0}, ledger(t, db))
+
+	won := WebhookEvent{EventID: "evt_d4", Type: "dispute.won", PaymentRef: "ref1", DisputeRef: "dp_2"}
+	assert.ErrorIs(t, svc.Handle(ctx, "mock", won, []byte(`{}`)), ErrDisputeClosed)
+}
+
+func disputeFlag(t *testing.T, db *gorm.DB) string {
+	var st string
+	require.NoError(t, db.Table("orders").Select("dispute_status").Where("id = ?", "o1").Scan(&st).Error)
+	return st
+}
+
+func TestDispute_ReducesRefundable(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`ALTER TABLE orders ADD COLUMN refunded_cents INTEGER NOT NULL DEFAULT 0`).Error)
+	wh := NewWebhookService(db)
+	refunds := NewRefundService(db, NewRegistry(NewMockProvider("", 0)), nil, "")
+
+	opened := WebhookEvent{EventID: "evt_d5", Type: "dispute.opened", PaymentRef: "ref1", DisputeRef: "dp_3", AmountCents: 2000, Currency: "EUR"}
+	require.NoError(t, wh.Handle(ctx, "mock", opened, []byte(`{}`)))
+
+	_, err := refunds.RefundOrder(ctx, RefundOrderInput{OrderID: "o1", ActorUserID: "u1", IdempotencyKey: "r-1", AmountCents: 3500})
+	require.ErrorIs(t, err, ErrPaymentDisputed, "itiraz edilen 2000 iade edilemez")
+
+	// "kalanın tamamı" itiraz dışındaki tutarla sınırlanır
+	res, err := refunds.RefundOrder(ctx, RefundOrderInput{OrderID: "o1", ActorUserID: "u1", IdempotencyKey: "r-2"})
+	require.NoError(t, err)
+	assert.Equal(t, 3000, res.AmountCents)
+
+	lost := WebhookEvent{EventID: "evt_d6", Type: "dispute.lost", PaymentRef: "ref1", DisputeRef: "dp_3", AmountCents: 2000, Currency: "EUR"}
+	require.NoError(t, wh.Handle(ctx, "mock", lost, []byte(`{}`)))
+	require.NoError(t, db.Exec(`UPDATE orders SET refunded_cents = 3000, status = 'partially_refunded' WHERE id = 'o1'`).Error)
+	_, err = refunds.RefundOrder(ctx, RefundOrderInput{OrderID: "o1", ActorUserID: "u1", IdempotencyKey: "r-3"})
+	require.ErrorIs(t, err, ErrPaymentDisputed, "kaybedilen itiraz sonrası iade edilecek tutar kalmaz")
+}
+
+func TestDispute_FindsCheckoutPayment(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	wh := NewWebhookService(db)
+	require.NoError(t, db.Exec(`UPDATE orders SET status = 'created' WHERE id = 'o1'`).Error)
+	require.NoError(t, db.Exec(`UPDATE payments SET status = 'requires_redirect', provider_ref = 'cs_1' WHERE id = 'p1'`).Error)
+
+	// webhook'u kaçırılan checkout ödemesini reconciler ödeme niyetine anahtarlar
+	r := NewReconciler(db, NewRegistry(statusProvider{MockProvider: NewMockProvider("", 0), payment: StatusSucceeded, paymentRef: "pi_1"}), wh, 15*time.Minute, 24*time.Hour)
+	n, err := r.ReconcileOnce(ctx, time.Now().UTC().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	var p Payment
+	require.NoError(t, db.First(&p, "id = ?", "p1").Error)
+	assert.Equal(t, "pi_1", ptrVal(p.ProviderRef))
+
+	opened := WebhookEvent{EventID: "evt_d7", Type: "dispute.opened", PaymentRef: "pi_1", DisputeRef: "dp_7", AmountCents: 5000, Currency: "EUR"}
+	require.NoError(t, wh.Handle(ctx, "mock", opened, []byte(`{}`)))
+	assert.Equal(t, DisputeOpen, disputeFlag(t, db))
+
+	// checkout oturumunu da taşıyan olay, henüz anahtarlanmamış ödemeyi bulur
+	require.NoError(t, db.Exec(`INSERT INTO payments (id, order_id, provider, provider_ref, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+		VALUES ('p2', 'o1', 'mock', 'cs_2', 'succeeded', 5000, 'EUR', 'k2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`).Error)
+	opened = WebhookEvent{EventID: "evt_d8", Type: "dispute.opened", PaymentRef: "pi_2", CheckoutRef: "cs_2", DisputeRef: "dp_8", AmountCents: 1000, Currency: "EUR"}
+	require.NoError(t, wh.Handle(ctx, "mock", opened, []byte(`{}`)))
+	var d Dispute
+	require.NoError(t, db.First(&d, "provider_ref = ?", "dp_8").Error)
+	assert.Equal(t, "p2", d.PaymentID)
+}
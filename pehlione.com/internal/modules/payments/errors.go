@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrOrderNotPayable = errors.New("order not payable")
 	ErrForbidden       = errors.New("forbidden")
+	ErrInvalidReturn   = errors.New("invalid payment return")
 )
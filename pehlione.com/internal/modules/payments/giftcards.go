@@ -0,0 +1,248 @@
+package payments
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/checkout"
+	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/giftcards"
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/products"
+	"pehlione.com/app/pkg/view"
+)
+
+// orderPaidInTx runs once when an order turns paid: the invoice number is
+// assigned, the gift card part of the tender is booked next to the provider
+// payment, and purchased gift cards are issued and emailed.
+func orderPaidInTx(ctx context.Context, tx *gorm.DB, orderID string, now time.Time) error {
+	if _, err := orders.AssignInvoiceNumberInTx(ctx, tx, orderID, now); err != nil {
+		return err
+	}
+
+	redeemed, err := giftcards.OrderRedemptionsInTx(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, t := range redeemed {
+		// ledger: gift_card_redeemed (+) — kart başına ayrı satır
+		if err := ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
+			ID:          uuid.NewString(),
+			OrderID:     orderID,
+			Event:       "gift_card_redeemed",
+			AmountCents: -t.AmountCents,
+			Currency:    t.Currency,
+			RefType:     "gift_card",
+			RefID:       t.ID,
+			CreatedAt:   now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return issuePurchasedGiftCardsInTx(ctx, tx, orderID)
+}
+
+// settleGiftCardOrderInTx marks an order paid whose total is fully covered by
+// gift cards; there is no provider payment.
+func settleGiftCardOrderInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, now time.Time) error {
+	paidAt := now
+	res := tx.WithContext(ctx).Model(&orders.Order{}).
+		Where("id = ? AND status = 'created'", ord.ID).
+		Updates(map[string]any{
+			"status":     "paid",
+			"paid_at":    &paidAt,
+			"updated_at": now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil
+	}
+	if err := checkout.ConvertReservationsInTx(ctx, tx, ord.ID); err != nil {
+		return err
+	}
+	return orderPaidInTx(ctx, tx, ord.ID, now)
+}
+
+func issuePurchasedGiftCardsInTx(ctx context.Context, tx *gorm.DB, orderID string) error {
+	var items []struct {
+		ID             string
+		Quantity       int
+		UnitPriceCents int
+		Currency       string
+	}
+	if err := tx.WithContext(ctx).
+		Table("order_items oi").
+		Select("oi.id, oi.quantity, oi.unit_price_cents, oi.currency").
+		Joins("JOIN product_variants v ON v.id = oi.variant_id").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Where("oi.order_id = ? AND p.kind = ?", orderID, products.KindGiftCard).
+		Order("oi.created_at ASC, oi.id ASC").
+		Scan(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	// idempotent: kartlar bu sipariş için zaten oluşturulduysa tekrar etme
+	var issued int64
+	if err := tx.WithContext(ctx).Model(&giftcards.GiftCard{}).
+		Where("source_order_id = ? AND kind = ?", orderID, giftcards.KindGiftCard).
+		Count(&issued).Error; err != nil {
+		return err
+	}
+	if issued > 0 {
+		return nil
+	}
+
+	var ord orders.Order
+	if err := tx.WithContext(ctx).First(&ord, "id = ?", orderID).Error; err != nil {
+		return err
+	}
+	buyer, err := orderEmailInTx(ctx, tx, ord)
+	if err != nil {
+		return err
+	}
+	to := ptrVal(ord.GiftRecipientEmail)
+	if to == "" {
+		to = buyer
+	}
+
+	outbox := emailmod.NewService(tx)
+	for _, it := range items {
+		itemID := it.ID
+		for range it.Quantity {
+			card, err := giftcards.IssueInTx(ctx, tx, giftcards.IssueInput{
+				Kind:              giftcards.KindGiftCard,
+				Currency:          it.Currency,
+				AmountCents:       it.UnitPriceCents,
+				RecipientEmail:    to,
+				SourceOrderID:     &ord.ID,
+				SourceOrderItemID: &itemID,
+				RefType:           "order_item",
+				RefID:             itemID,
+			})
+			if err != nil {
+				return err
+			}
+			if to == "" {
+				continue
+			}
+			if err := outbox.EnqueueTx(ctx, tx, emailmod.Job{
+				To:       to,
+				Template: emailmod.TemplateGiftCard,
+				Payload:  giftCardPayload(card, card.InitialCents, ord.ID, buyer),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// creditStoreCreditInTx loads a refund onto the customer's store credit and
+// emails the code (new card) or the new balance.
+func creditStoreCreditInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, r Refund) (giftcards.GiftCard, error) {
+	email, err := orderEmailInTx(ctx, tx, ord)
+	if err != nil {
+		return giftcards.GiftCard{}, err
+	}
+	card, _, err := giftcards.CreditStoreCreditInTx(ctx, tx, giftcards.CreditInput{
+		UserID:      ord.UserID,
+		Email:       email,
+		Currency:    r.Currency,
+		AmountCents: r.AmountCents,
+		OrderID:     ord.ID,
+		RefundID:    r.ID,
+	})
+	if err != nil || email == "" {
+		return card, err
+	}
+	return card, emailmod.NewService(tx).EnqueueTx(ctx, tx, emailmod.Job{
+		To:       email,
+		Template: emailmod.TemplateGiftCard,
+		Payload:  giftCardPayload(card, r.AmountCents, ord.ID, ""),
+	})
+}
+
+func giftCardPayload(card giftcards.GiftCard, amountCents int, orderID, from string) map[string]any {
+	return map[string]any{
+		"Code":        card.Code,
+		"Kind":        card.Kind,
+		"StoreCredit": card.Kind == giftcards.KindStoreCredit,
+		"Amount":      view.MoneyFromCents(amountCents, card.Currency),
+		"Balance":     view.MoneyFromCents(card.BalanceCents, card.Currency),
+		"OrderID":     orderID,
+		"From":        from,
+	}
+}
+
+func orderEmailInTx(ctx context.Context, tx *gorm.DB, ord orders.Order) (string, error) {
+	if e := strings.TrimSpace(ptrVal(ord.GuestEmail)); e != "" {
+		return e, nil
+	}
+	if ord.UserID == nil {
+		return "", nil
+	}
+	var emails []string
+	if err := tx.WithContext(ctx).Table("users").Where("id = ?", *ord.UserID).Pluck("email", &emails).Error; err != nil {
+		return "", err
+	}
+	if len(emails) == 0 {
+		return "", nil
+	}
+	return emails[0], nil
+}
+
+// voidPurchasedGiftCardsInTx takes back the gift cards bought with an order
+// when the money for them is refunded: the cards of refunded gift card
+// lines, or every card on a full refund. An amount-only partial refund can't
+// be mapped to cards and must stay below the value of the cards still active.
+func voidPurchasedGiftCardsInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, lines []RefundLine, amount int, refundID string) error {
+	if lines != nil {
+		for _, l := range lines {
+			if l.Kind != LineItem || l.OrderItemID == nil {
+				continue
+			}
+			if _, err := giftcards.VoidPurchasedInTx(ctx, tx, *l.OrderItemID, l.Qty, "refund", refundID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if ord.RefundedCents+amount >= ord.TotalCents {
+		var itemIDs []string
+		if err := tx.WithContext(ctx).Model(&giftcards.GiftCard{}).
+			Distinct("source_order_item_id").
+			Where("source_order_id = ? AND kind = ? AND source_order_item_id IS NOT NULL", ord.ID, giftcards.KindGiftCard).
+			Pluck("source_order_item_id", &itemIDs).Error; err != nil {
+			return err
+		}
+		for _, id := range itemIDs {
+			if _, err := giftcards.VoidPurchasedInTx(ctx, tx, id, -1, "refund", refundID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var active int
+	if err := tx.WithContext(ctx).Model(&giftcards.GiftCard{}).
+		Select("COALESCE(SUM(initial_cents), 0)").
+		Where("source_order_id = ? AND kind = ? AND status = ?", ord.ID, giftcards.KindGiftCard, giftcards.StatusActive).
+		Scan(&active).Error; err != nil {
+		return err
+	}
+	if ord.RefundedCents+amount > ord.TotalCents-active {
+		return ErrGiftCardRefund
+	}
+	return nil
+}
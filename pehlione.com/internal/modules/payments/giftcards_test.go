@@ -0,0 +1,147 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/giftcards"
+)
+
+func setupGiftCardTables(t *testing.T, db *gorm.DB) {
+	require.NoError(t, db.Exec(`CREATE TABLE gift_cards (
+		id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, kind TEXT NOT NULL, status TEXT NOT NULL, currency TEXT NOT NULL,
+		initial_cents INTEGER NOT NULL, balance_cents INTEGER NOT NULL, user_id TEXT, recipient_email TEXT,
+		source_order_id TEXT, source_order_item_id TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE gift_card_transactions (
+		id TEXT PRIMARY KEY, gift_card_id TEXT NOT NULL, order_id TEXT, kind TEXT NOT NULL, amount_cents INTEGER NOT NULL,
+		balance_after_cents INTEGER NOT NULL, currency TEXT NOT NULL, ref_type TEXT NOT NULL, ref_id TEXT NOT NULL,
+		created_at DATETIME NOT NULL, UNIQUE (gift_card_id, kind, ref_type, ref_id))`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE products (id TEXT PRIMARY KEY, kind TEXT NOT NULL DEFAULT 'standard')`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE product_variants (id TEXT PRIMARY KEY, product_id TEXT NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE order_items (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, unit_price_cents INTEGER NOT NULL,
+		currency TEXT NOT NULL, quantity INTEGER NOT NULL, created_at DATETIME NOT NULL)`).Error)
+}
+
+// redeemCard issues a card and spends amount of it on the order, as checkout does.
+func redeemCard(t *testing.T, db *gorm.DB, orderID string, amount int) giftcards.GiftCard {
+	ctx := context.Background()
+	card, err := giftcards.IssueInTx(ctx, db, giftcards.IssueInput{Currency: "EUR", AmountCents: 10000, RefType: "order_item", RefID: "seed-" + orderID})
+	require.NoError(t, err)
+	_, err = giftcards.RedeemInTx(ctx, db, giftcards.RedeemInput{Codes: []string{card.Code}, OrderID: orderID, Currency: "EUR", AmountCents: amount})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`UPDATE orders SET gift_card_cents = ? WHERE id = ?`, amount, orderID).Error)
+	return card
+}
+
+func TestGiftCard_SplitTenderBookedSeparately(t *testing.T) {
+	db := setupCaptureDB(t)
+	ctx := context.Background()
+	redeemCard(t, db, "o1", 2000)
+	require.NoError(t, db.Exec(`UPDATE payments SET amount_cents = 3000 WHERE id = 'p1'`).Error)
+
+	var p Payment
+	require.NoError(t, db.First(&p, "id = ?", "p1").Error)
+	require.NoError(t, markSucceededInTx(ctx, db, p, time.Now()))
+	require.NoError(t, markSucceededInTx(ctx, db, p, time.Now()), "replayed success")
+
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+	assert.Equal(t, map[string]int{"payment_succeeded": 3000, "gift_card_redeemed": 2000}, ledger(t, db))
+}
+
+func TestGiftCard_FullyCoveredOrderIssuesPurchasedCards(t *testing.T) {
+	db := setupCaptureDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`CREATE TABLE email_outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, to_email TEXT NOT NULL, template TEXT NOT NULL, payload TEXT NOT NULL, attachments TEXT, status TEXT NOT NULL,
+		attempt_count INTEGER NOT NULL DEFAULT 0, last_error TEXT, scheduled_at DATETIME NOT NULL, locked_at DATETIME, locked_by TEXT,
+		sent_at DATETIME, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO orders (id, guest_email, gift_recipient_email, status, currency, total_cents)
+		VALUES ('o2', 'buyer@example.com', 'friend@example.com', 'created', 'EUR', 2500)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO products (id, kind) VALUES ('gc', 'gift_card')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO product_variants (id, product_id) VALUES ('gc-25', 'gc')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO order_items (id, order_id, variant_id, unit_price_cents, currency, quantity, created_at)
+		VALUES ('oi1', 'o2', 'gc-25', 1250, 'EUR', 2, CURRENT_TIMESTAMP)`).Error)
+	redeemCard(t, db, "o2", 2500)
+
+	svc := NewService(db, NewRegistry(NewMockProvider("", 0)))
+	res, err := svc.PayOrder(ctx, PayOrderInput{OrderID: "o2", IdempotencyKey: "k2"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, res.Status)
+	assert.Empty(t, res.PaymentID, "no provider payment")
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o2"))
+	assert.Equal(t, map[string]int{"gift_card_redeemed": 2500}, ledger(t, db))
+
+	var issued []giftcards.GiftCard
+	require.NoError(t, db.Where("source_order_id = ?", "o2").Find(&issued).Error)
+	require.Len(t, issued, 2)
+	for _, c := range issued {
+		assert.Equal(t, 1250, c.BalanceCents)
+		assert.Equal(t, "friend@example.com", *c.RecipientEmail)
+	}
+	var mails int64
+	require.NoError(t, db.Table("email_outbox").Where("to_email = ? AND template = ?", "friend@example.com", "gift_card").Count(&mails).Error)
+	assert.Equal(t, int64(2), mails)
+
+	_, err = svc.PayOrder(ctx, PayOrderInput{OrderID: "o2", IdempotencyKey: "k2"})
+	assert.ErrorIs(t, err, ErrOrderNotPayable)
+}
+
+func TestGiftCard_RefundTakesBackPurchasedCards(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		`ALTER TABLE orders ADD COLUMN refunded_cents INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN refunded_at DATETIME`,
+		`ALTER TABLE order_items ADD COLUMN product_name TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE order_items ADD COLUMN sku TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE order_items ADD COLUMN line_total_cents INTEGER NOT NULL DEFAULT 0`,
+		`CREATE TABLE refund_lines (
+			id TEXT PRIMARY KEY, refund_id TEXT NOT NULL, kind TEXT NOT NULL, order_item_id TEXT, label TEXT NOT NULL,
+			qty INTEGER NOT NULL DEFAULT 0, amount_cents INTEGER NOT NULL, tax_cents INTEGER NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL, created_at DATETIME NOT NULL)`,
+		`INSERT INTO products (id, kind) VALUES ('gc', 'gift_card'), ('pr', 'standard')`,
+		`INSERT INTO product_variants (id, product_id) VALUES ('gc-25', 'gc'), ('v1', 'pr')`,
+		`INSERT INTO order_items (id, order_id, variant_id, unit_price_cents, currency, quantity, line_total_cents, created_at) VALUES
+			('oi1', 'o1', 'gc-25', 1250, 'EUR', 2, 2500, '2026-01-01 10:00:00'),
+			('oi2', 'o1', 'v1', 2500, 'EUR', 1, 2500, '2026-01-01 10:00:01')`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+	require.NoError(t, issuePurchasedGiftCardsInTx(ctx, db, "o1"))
+	var cards []giftcards.GiftCard
+	require.NoError(t, db.Where("source_order_id = ?", "o1").Order("created_at ASC, id ASC").Find(&cards).Error)
+	require.Len(t, cards, 2)
+
+	refunds := NewRefundService(db, NewRegistry(NewMockProvider("", 0)), nil, "")
+	_, err := refunds.RefundOrder(ctx, RefundOrderInput{OrderID: "o1", ActorUserID: "u1", IdempotencyKey: "r-1", AmountCents: 3000})
+	require.ErrorIs(t, err, ErrGiftCardRefund, "only 2500 of the order is not gift cards")
+
+	_, err = giftcards.RedeemInTx(ctx, db, giftcards.RedeemInput{Codes: []string{cards[0].Code}, OrderID: "o9", Currency: "EUR", AmountCents: 100})
+	require.NoError(t, err)
+	_, err = refunds.RefundOrder(ctx, RefundOrderInput{OrderID: "o1", ActorUserID: "u1", IdempotencyKey: "r-2", Lines: []RefundLineInput{{OrderItemID: "oi1", Qty: 2}}})
+	require.ErrorIs(t, err, giftcards.ErrCardSpent)
+
+	res, err := refunds.RefundOrder(ctx, RefundOrderInput{OrderID: "o1", ActorUserID: "u1", IdempotencyKey: "r-3", Lines: []RefundLineInput{{OrderItemID: "oi1", Qty: 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, 1250, res.AmountCents)
+	assert.Equal(t, giftcards.StatusDisabled, statusOf(t, db, "gift_cards", cards[1].ID), "the unspent card is taken back")
+	assert.Equal(t, giftcards.StatusActive, statusOf(t, db, "gift_cards", cards[0].ID))
+
+	// sağlayıcı iadeyi reddederse kart yeniden açılır
+	var rf Refund
+	require.NoError(t, db.First(&rf, "id = ?", res.RefundID).Error)
+	require.NoError(t, NewWebhookService(db).Handle(ctx, "mock", WebhookEvent{EventID: "evt_rf", Type: "refund.failed", RefundRef: ptrVal(rf.ProviderRef)}, []byte(`{}`)))
+	assert.Equal(t, giftcards.StatusActive, statusOf(t, db, "gift_cards", cards[1].ID))
+	var c giftcards.GiftCard
+	require.NoError(t, db.First(&c, "id = ?", cards[1].ID).Error)
+	assert.Equal(t, 1250, c.BalanceCents)
+
+	_, err = refunds.RefundOrder(ctx, RefundOrderInput{OrderID: "o1", ActorUserID: "u1", IdempotencyKey: "r-4"})
+	require.ErrorIs(t, err, giftcards.ErrCardSpent, "a full refund can't take back a used card")
+}
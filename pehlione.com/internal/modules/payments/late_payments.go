@@ -0,0 +1,91 @@
+package payments
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+// A customer can finish paying after the reservation worker cancelled the
+// unpaid order (the hosted page was left open past the reservation TTL).
+// The payment is recorded but the order stays cancelled and its stock stays
+// released; the reconciler then gives the money back.
+
+// flagLatePaymentInTx records an order event when a payment lands on an
+// order it can no longer pay: late_payment on a cancelled order (the
+// reconciler gives it back), duplicate_payment when another payment already
+// paid the order, so admins see the second charge and refund it.
+func flagLatePaymentInTx(ctx context.Context, tx *gorm.DB, p Payment, status string, now time.Time) error {
+	var o orders.Order
+	if err := tx.WithContext(ctx).Select("id", "status").First(&o, "id = ?", p.OrderID).Error; err != nil {
+		return err
+	}
+
+	action := "late_payment"
+	note := "payment " + status + " after cancellation: payment_id=" + p.ID
+	if o.Status != "cancelled" {
+		var others int64
+		if err := tx.WithContext(ctx).Model(&Payment{}).
+			Where("order_id = ? AND id <> ? AND status IN ?", p.OrderID, p.ID, []string{StatusAuthorized, StatusSucceeded}).
+			Count(&others).Error; err != nil {
+			return err
+		}
+		if others == 0 {
+			return nil
+		}
+		action = "duplicate_payment"
+		note = "payment " + status + " on an order already paid: payment_id=" + p.ID
+	}
+	return tx.WithContext(ctx).Create(&orders.OrderEvent{
+		ID:          uuid.NewString(),
+		OrderID:     o.ID,
+		ActorUserID: orders.SystemActorID,
+		Action:      action,
+		FromStatus:  o.Status,
+		ToStatus:    o.Status,
+		Note:        &note,
+		CreatedAt:   now,
+	}).Error
+}
+
+// settleLatePayments gives back payments on cancelled orders: authorizations
+// are voided, captured payments refunded in full. A payment with a refund
+// in flight or done is left alone. Returns the number of payments settled.
+func (r *Reconciler) settleLatePayments(ctx context.Context) (int, error) {
+	var pays []Payment
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN orders ON orders.id = payments.order_id").
+		Where("orders.status = ? AND payments.status IN ?", "cancelled", []string{StatusAuthorized, StatusSucceeded}).
+		Where("NOT EXISTS (SELECT 1 FROM refunds WHERE refunds.payment_id = payments.id AND refunds.status IN ?)", []string{StatusInitiated, StatusSucceeded}).
+		Order("payments.updated_at ASC").
+		Limit(r.batchSize).
+		Find(&pays).Error; err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, p := range pays {
+		var err error
+		if p.Status == StatusAuthorized {
+			err = NewService(r.db, r.providers).VoidOrder(ctx, p.OrderID, orders.SystemActorID)
+		} else {
+			_, err = r.refunds.RefundOrder(ctx, RefundOrderInput{
+				OrderID:        p.OrderID,
+				ActorUserID:    orders.SystemActorID,
+				IdempotencyKey: "late-" + p.ID,
+				Reason:         "payment received after the order was cancelled",
+			})
+		}
+		if err != nil {
+			log.Printf("payments reconcile: late payment %s failed: %v", p.ID, err)
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
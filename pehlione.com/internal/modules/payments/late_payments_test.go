@@ -0,0 +1,81 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+func TestLatePayment_RefundedAfterAutoCancel(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		`ALTER TABLE orders ADD COLUMN refunded_cents INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN refunded_at DATETIME`,
+		`ALTER TABLE stock_reservations ADD COLUMN expires_at DATETIME`,
+		`ALTER TABLE stock_reservations ADD COLUMN converted_at DATETIME`,
+		`ALTER TABLE stock_reservations ADD COLUMN released_at DATETIME`,
+		`ALTER TABLE stock_reservations ADD COLUMN updated_at DATETIME`,
+		`CREATE TABLE order_events (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, actor_user_id TEXT NOT NULL, action TEXT NOT NULL,
+			from_status TEXT NOT NULL, to_status TEXT NOT NULL, note TEXT, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE disputes (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, payment_id TEXT NOT NULL, provider TEXT NOT NULL, provider_ref TEXT NOT NULL,
+			status TEXT NOT NULL, reason TEXT, amount_cents INTEGER NOT NULL, currency TEXT NOT NULL,
+			opened_at DATETIME NOT NULL, closed_at DATETIME, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`CREATE TABLE promotion_redemptions (
+			id TEXT PRIMARY KEY, promotion_id TEXT NOT NULL, order_id TEXT NOT NULL, user_id TEXT, email TEXT, code TEXT NOT NULL,
+			discount_cents INTEGER NOT NULL, currency TEXT NOT NULL, base_discount_cents INTEGER NOT NULL, base_currency TEXT NOT NULL,
+			created_at DATETIME NOT NULL)`,
+		`INSERT INTO stock_reservations (id, order_id, variant_id, qty, status, expires_at) VALUES ('sr1', 'o1', 'v1', 1, 'active', '2000-01-01 00:00:00')`,
+		// ikinci sipariş: yetkilendirme (manual capture) sonradan geliyor
+		`INSERT INTO orders (id, status, currency, total_cents) VALUES ('o2', 'created', 'EUR', 700)`,
+		`INSERT INTO payments (id, order_id, provider, provider_ref, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+			VALUES ('p2', 'o2', 'mock', 'ref2', 'requires_redirect', 700, 'EUR', 'k2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		`INSERT INTO stock_reservations (id, order_id, variant_id, qty, status, expires_at) VALUES ('sr2', 'o2', 'v1', 1, 'active', '2000-01-01 00:00:00')`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+
+	// müşteri ödeme sayfasındayken rezervasyon doldu, sipariş iptal edildi
+	n, err := orders.NewService(db, nil).ExpireReservations(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	wh := NewWebhookService(db)
+	require.NoError(t, wh.Handle(ctx, "mock", WebhookEvent{EventID: "evt_1", Type: "payment.succeeded", PaymentRef: "ref1"}, []byte(`{}`)))
+	require.NoError(t, wh.Handle(ctx, "mock", WebhookEvent{EventID: "evt_2", Type: "payment.authorized", PaymentRef: "ref2"}, []byte(`{}`)))
+
+	assert.Equal(t, StatusSucceeded, statusOf(t, db, "payments", "p1"))
+	assert.Equal(t, "cancelled", statusOf(t, db, "orders", "o1"), "a late payment doesn't revive the order")
+	assert.Equal(t, "cancelled", statusOf(t, db, "orders", "o2"))
+	assert.Equal(t, "released", statusOf(t, db, "stock_reservations", "sr1"), "stock stays released")
+	var flagged int64
+	require.NoError(t, db.Table("order_events").Where("action = ?", "late_payment").Count(&flagged).Error)
+	assert.EqualValues(t, 2, flagged)
+
+	// reconciler parayı geri verir: yetkilendirme iptal, tahsilat iade
+	r := NewReconciler(db, NewRegistry(NewMockProvider("", 0)), wh, 15*time.Minute, 24*time.Hour)
+	n, err = r.ReconcileOnce(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, StatusVoided, statusOf(t, db, "payments", "p2"))
+
+	var rf Refund
+	require.NoError(t, db.First(&rf, "order_id = ?", "o1").Error)
+	assert.Equal(t, "late-p1", rf.IdempotencyKey)
+	assert.Equal(t, 5000, rf.AmountCents)
+
+	n, err = r.ReconcileOnce(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "refund in flight: nothing to do")
+
+	require.NoError(t, wh.Handle(ctx, "mock", WebhookEvent{EventID: "evt_3", Type: "refund.succeeded", RefundRef: ptrVal(rf.ProviderRef)}, []byte(`{}`)))
+	assert.Equal(t, "cancelled", statusOf(t, db, "orders", "o1"))
+	assert.Equal(t, map[string]int{"payment_succeeded": 5000, "payment_authorized": 0, "payment_voided": 0, "refund_succeeded": -5000}, ledger(t, db))
+}
@@ -3,9 +3,18 @@ package payments
 import "time"
 
 const (
-	StatusInitiated = "initiated"
-	StatusSucceeded = "succeeded"
-	StatusFailed    = "failed"
+	StatusInitiated        = "initiated"
+	StatusRequiresRedirect = "requires_redirect" // müşteri sağlayıcı sayfasına (3-D Secure / hosted) yönlendirilmeli
+	StatusAuthorized       = "authorized"        // tutar bloke edildi, henüz tahsil edilmedi
+	StatusSucceeded        = "succeeded"         // tahsil edildi (capture dahil)
+	StatusFailed           = "failed"
+	StatusVoided           = "voided" // yetkilendirme iptal edildi
+)
+
+// Capture methods for CreatePaymentRequest.
+const (
+	CaptureAutomatic = "automatic"
+	CaptureManual    = "manual" // authorize now, capture on ship
 )
 
 type Payment struct {
@@ -18,6 +27,8 @@ type Payment struct {
 	Currency       string    `gorm:"type:char(3);not null"`
 	IdempotencyKey string    `gorm:"type:varchar(64);not null"`
 	ErrorMessage   *string   `gorm:"type:varchar(255)"`
+	RedirectURL    *string   `gorm:"type:varchar(1024)"`
+	ReturnToken    *string   `gorm:"type:char(32);index:ix_payments_return_token"` // ReturnURL/CancelURL doğrulaması
 	CreatedAt      time.Time `gorm:"type:datetime(3);not null"`
 	UpdatedAt      time.Time `gorm:"type:datetime(3);not null"`
 }
@@ -0,0 +1,31 @@
+package payments
+
+import "time"
+
+const (
+	DisputeOpen = "open"
+	DisputeWon  = "won"
+	DisputeLost = "lost"
+)
+
+// Dispute is a chargeback raised by the card network against a Payment.
+type Dispute struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	OrderID   string `gorm:"type:char(36);not null;index:ix_disputes_order_id"`
+	PaymentID string `gorm:"type:char(36);not null;index:ix_disputes_payment_id"`
+
+	Provider    string `gorm:"type:varchar(64);not null"`
+	ProviderRef string `gorm:"type:varchar(128);not null"`
+
+	Status      string  `gorm:"type:varchar(16);not null"` // open|won|lost
+	Reason      *string `gorm:"type:varchar(255)"`
+	AmountCents int     `gorm:"not null"`
+	Currency    string  `gorm:"type:char(3);not null"`
+
+	OpenedAt  time.Time  `gorm:"type:datetime(3);not null"`
+	ClosedAt  *time.Time `gorm:"type:datetime(3)"`
+	CreatedAt time.Time  `gorm:"type:datetime(3);not null"`
+	UpdatedAt time.Time  `gorm:"type:datetime(3);not null"`
+}
+
+func (Dispute) TableName() string { return "disputes" }
@@ -3,9 +3,9 @@ package payments
 import "time"
 
 type Refund struct {
-	ID        string `gorm:"type:char(36);primaryKey"`
-	OrderID   string `gorm:"type:char(36);not null;index:ix_refunds_order_id"`
-	PaymentID string `gorm:"type:char(36);not null;index:ix_refunds_payment_id"`
+	ID        string  `gorm:"type:char(36);primaryKey"`
+	OrderID   string  `gorm:"type:char(36);not null;index:ix_refunds_order_id"`
+	PaymentID *string `gorm:"type:char(36);index:ix_refunds_payment_id"` // store credit iadesinde boş olabilir
 
 	Provider    string  `gorm:"type:varchar(64);not null"`
 	ProviderRef *string `gorm:"type:varchar(128)"`
@@ -16,8 +16,13 @@ type Refund struct {
 	IdempotencyKey string `gorm:"type:varchar(64);not null"`
 
 	Reason       *string `gorm:"type:varchar(255)"`
+	Restock      bool    `gorm:"not null;default:false"`
 	ErrorMessage *string `gorm:"type:varchar(255)"`
 
+	// iade dekontu (credit note): iade başarılı olunca atanır
+	CreditNoteNumber   *string    `gorm:"type:varchar(32);uniqueIndex:ux_refunds_credit_note_number"`
+	CreditNoteIssuedAt *time.Time `gorm:"type:datetime(3)"`
+
 	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
 	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
 }
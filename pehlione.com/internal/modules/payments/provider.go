@@ -3,6 +3,7 @@ package payments
 import (
 	"context"
 	"net/http"
+	"net/url"
 )
 
 type CreatePaymentRequest struct {
@@ -12,14 +13,39 @@ type CreatePaymentRequest struct {
 	IdempotencyKey string
 	ReturnURL      string
 	CancelURL      string
+	CaptureMethod  string // automatic|manual
 }
 
 type CreatePaymentResponse struct {
 	ProviderRef string
-	Status      string // initiated|succeeded|failed|requires_redirect
+	Status      string // initiated|authorized|succeeded|failed|requires_redirect
 	RedirectURL string
 }
 
+type CaptureRequest struct {
+	OrderID        string
+	PaymentID      string
+	PaymentRef     string
+	AmountCents    int
+	Currency       string
+	IdempotencyKey string
+}
+
+type CaptureResponse struct {
+	Status string // initiated|succeeded|failed
+}
+
+type VoidRequest struct {
+	OrderID        string
+	PaymentID      string
+	PaymentRef     string
+	IdempotencyKey string
+}
+
+type VoidResponse struct {
+	Status string // voided|failed
+}
+
 type RefundRequest struct {
 	OrderID        string
 	PaymentID      string
@@ -35,12 +61,38 @@ type RefundResponse struct {
 	Status      string // initiated|succeeded|failed
 }
 
+// ReturnResult is the provider-verified outcome carried on a ReturnURL callback.
+type ReturnResult struct {
+	PaymentRef  string
+	CheckoutRef string // hosted checkout session, see WebhookEvent.CheckoutRef
+	Status      string // authorized|succeeded|failed|initiated (still processing)
+}
+
+// StatusResult is the provider-side state of a payment or refund.
+type StatusResult struct {
+	Status      string // initiated|requires_redirect|authorized|succeeded|failed|voided
+	AmountCents int
+	Currency    string
+	// PaymentRef: a hosted checkout's payment at the provider, once the
+	// customer paid; its later webhooks (disputes, refunds) carry this ref
+	PaymentRef string
+}
+
 type WebhookEvent struct {
 	EventID string
-	Type    string // payment.succeeded|payment.failed|refund.succeeded|refund.failed
+	Type    string // payment.authorized|payment.captured|payment.succeeded|payment.failed|refund.succeeded|refund.failed|dispute.opened|dispute.won|dispute.lost|ignored
 
 	PaymentRef string // provider_ref
 	RefundRef  string // provider_ref
+	DisputeRef string // provider_ref
+	Reason     string // dispute reason
+
+	// CheckoutRef is the hosted checkout session a payment was started with.
+	// The payment is stored under it until the provider reports the payment
+	// (PaymentRef); provider_ref is then switched to PaymentRef so later
+	// payment and dispute events find it. It is stored with the provider
+	// event so a replay matches the payment the same way.
+	CheckoutRef string
 
 	AmountCents int
 	Currency    string
@@ -51,6 +103,17 @@ type Provider interface {
 	CreatePayment(ctx context.Context, req CreatePaymentRequest) (CreatePaymentResponse, error)
 	RefundPayment(ctx context.Context, req RefundRequest) (RefundResponse, error)
 
+	// Manual capture: capture or release an authorized payment
+	CapturePayment(ctx context.Context, req CaptureRequest) (CaptureResponse, error)
+	VoidPayment(ctx context.Context, req VoidRequest) (VoidResponse, error)
+
+	// Redirect flow: verify the query the provider appended to ReturnURL
+	VerifyReturn(ctx context.Context, query url.Values) (ReturnResult, error)
+
+	// Reconciliation: look up the current state when a webhook never arrived
+	GetPayment(ctx context.Context, paymentRef string) (StatusResult, error)
+	GetRefund(ctx context.Context, refundRef string) (StatusResult, error)
+
 	// Webhook: verify signature + parse event
 	VerifyAndParseWebhook(headers http.Header, body []byte) (WebhookEvent, error)
 }
@@ -0,0 +1,113 @@
+package payments
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrEventAlreadyProcessed = errors.New("provider event already processed")
+
+// Provider event states for admin filtering.
+const (
+	EventStateFailed    = "failed"    // applied with error, not yet processed
+	EventStatePending   = "pending"   // recorded, never applied
+	EventStateProcessed = "processed" // applied
+)
+
+type EventFilter struct {
+	State    string // failed|pending|processed ("" = all)
+	Type     string
+	Provider string
+	Limit    int
+}
+
+// ListEvents returns provider events, newest first.
+func (s *WebhookService) ListEvents(ctx context.Context, f EventFilter) ([]ProviderEvent, error) {
+	q := s.db.WithContext(ctx).Model(&ProviderEvent{})
+	switch f.State {
+	case EventStateFailed:
+		q = q.Where("processed_at IS NULL AND process_error IS NOT NULL")
+	case EventStatePending:
+		q = q.Where("processed_at IS NULL AND process_error IS NULL")
+	case EventStateProcessed:
+		q = q.Where("processed_at IS NOT NULL")
+	}
+	if f.Type != "" {
+		q = q.Where("event_type = ?", f.Type)
+	}
+	if f.Provider != "" {
+		q = q.Where("provider = ?", f.Provider)
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+
+	var out []ProviderEvent
+	err := q.Order("received_at DESC").Limit(limit).Find(&out).Error
+	return out, err
+}
+
+func (s *WebhookService) GetEvent(ctx context.Context, id string) (ProviderEvent, error) {
+	var pe ProviderEvent
+	err := s.db.WithContext(ctx).First(&pe, "id = ?", id).Error
+	return pe, err
+}
+
+// Replay re-applies a recorded event that has not been processed yet
+// (failed or pending). Processed events return ErrEventAlreadyProcessed and
+// are left untouched.
+func (s *WebhookService) Replay(ctx context.Context, id string) error {
+	pe, err := s.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if pe.ProcessedAt != nil {
+		return ErrEventAlreadyProcessed
+	}
+	s.logger.InfoContext(ctx, "replaying provider event", "provider", pe.Provider, "event_id", pe.EventID, "type", pe.EventType)
+	return s.process(ctx, pe.ID, pe.Provider, pe.Event())
+}
+
+// Event rebuilds the normalized WebhookEvent stored with the row.
+func (pe ProviderEvent) Event() WebhookEvent {
+	return WebhookEvent{
+		EventID:     pe.EventID,
+		Type:        pe.EventType,
+		PaymentRef:  ptrVal(pe.PaymentRef),
+		RefundRef:   ptrVal(pe.RefundRef),
+		DisputeRef:  ptrVal(pe.DisputeRef),
+		CheckoutRef: ptrVal(pe.CheckoutRef),
+		Reason:      ptrVal(pe.Reason),
+		AmountCents: pe.AmountCents,
+		Currency:    ptrVal(pe.Currency),
+	}
+}
+
+// State is failed|pending|processed.
+func (pe ProviderEvent) State() string {
+	switch {
+	case pe.ProcessedAt != nil:
+		return EventStateProcessed
+	case pe.ProcessError != nil:
+		return EventStateFailed
+	default:
+		return EventStatePending
+	}
+}
+
+// EventTypes lists distinct recorded event types (admin filter).
+func (s *WebhookService) EventTypes(ctx context.Context) ([]string, error) {
+	var out []string
+	err := s.db.WithContext(ctx).Model(&ProviderEvent{}).
+		Distinct("event_type").Order("event_type").
+		Pluck("event_type", &out).Error
+	return out, err
+}
+
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,113 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_FailedEventIsRetried(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	svc := NewWebhookService(db)
+	ev := WebhookEvent{EventID: "evt_1", Type: "payment.succeeded", PaymentRef: "ref_late", AmountCents: 5000, Currency: "EUR"}
+	body := []byte(`{"id":"evt_1"}`)
+
+	// payment row not committed yet -> apply fails, event stays unprocessed
+	require.Error(t, svc.Handle(ctx, "mock", ev, body))
+	failed, err := svc.ListEvents(ctx, EventFilter{State: EventStateFailed})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, 1, failed[0].Attempts)
+	assert.Equal(t, "ref_late", ptrVal(failed[0].PaymentRef))
+
+	require.NoError(t, db.Exec(`UPDATE payments SET provider_ref = 'ref_late' WHERE id = 'p1'`).Error)
+
+	// provider re-delivers the same event
+	require.NoError(t, svc.Handle(ctx, "mock", ev, body))
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+
+	pe, err := svc.GetEvent(ctx, failed[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStateProcessed, pe.State())
+	assert.Equal(t, 2, pe.Attempts)
+
+	require.NoError(t, svc.Handle(ctx, "mock", ev, body), "processed events are deduplicated")
+	assert.ErrorIs(t, svc.Replay(ctx, pe.ID), ErrEventAlreadyProcessed)
+	assert.Equal(t, map[string]int{"payment_succeeded": 5000}, ledger(t, db))
+}
+
+func TestWebhook_ReplayFailedEvent(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	svc := NewWebhookService(db)
+
+	require.Error(t, svc.Handle(ctx, "mock", WebhookEvent{EventID: "evt_2", Type: "payment.failed", PaymentRef: "ref_x"}, []byte(`{}`)))
+	require.NoError(t, db.Exec(`UPDATE payments SET provider_ref = 'ref_x' WHERE id = 'p1'`).Error)
+
+	events, err := svc.ListEvents(ctx, EventFilter{Type: "payment.failed", Provider: "mock"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	require.NoError(t, svc.Replay(ctx, events[0].ID))
+	assert.Equal(t, StatusFailed, statusOf(t, db, "payments", "p1"))
+	assert.ErrorIs(t, svc.Replay(ctx, events[0].ID), ErrEventAlreadyProcessed)
+}
+
+func TestWebhook_ReplayFailedCheckoutSessionEvent(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	svc := NewWebhookService(db)
+
+	// checkout.session.expired: only the session identifies the payment
+	expired := WebhookEvent{EventID: "evt_cs", Type: "payment.failed", CheckoutRef: "cs_1"}
+	require.Error(t, svc.Handle(ctx, "mock", expired, []byte(`{}`)))
+
+	events, err := svc.ListEvents(ctx, EventFilter{State: EventStateFailed})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, expired, events[0].Event())
+
+	require.NoError(t, db.Exec(`UPDATE payments SET provider_ref = 'cs_1' WHERE id = 'p1'`).Error)
+	require.NoError(t, svc.Replay(ctx, events[0].ID))
+	assert.Equal(t, StatusFailed, statusOf(t, db, "payments", "p1"))
+}
+
+func TestWebhook_ReplayedDisputeKeepsReason(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	svc := NewWebhookService(db)
+
+	opened := WebhookEvent{EventID: "evt_d1", Type: "dispute.opened", PaymentRef: "ref_late", DisputeRef: "dp_1", Reason: "fraudulent", AmountCents: 5000, Currency: "EUR"}
+	require.Error(t, svc.Handle(ctx, "mock", opened, []byte(`{}`)))
+	require.NoError(t, db.Exec(`UPDATE payments SET provider_ref = 'ref_late' WHERE id = 'p1'`).Error)
+
+	events, err := svc.ListEvents(ctx, EventFilter{State: EventStateFailed})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.NoError(t, svc.Replay(ctx, events[0].ID))
+
+	var ds []Dispute
+	require.NoError(t, db.Find(&ds).Error)
+	require.Len(t, ds, 1)
+	assert.Equal(t, "fraudulent", ptrVal(ds[0].Reason))
+}
+
+func TestWebhook_LateFailureKeepsSettledPayment(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	svc := NewWebhookService(db)
+
+	require.NoError(t, svc.Handle(ctx, "mock", WebhookEvent{EventID: "evt_ok", Type: "payment.succeeded", PaymentRef: "ref1"}, []byte(`{}`)))
+	// ödeme başarılı olduktan sonra checkout oturumunun süresi doldu
+	require.NoError(t, svc.Handle(ctx, "mock", WebhookEvent{EventID: "evt_late", Type: "payment.failed", PaymentRef: "ref1"}, []byte(`{}`)))
+	assert.Equal(t, StatusSucceeded, statusOf(t, db, "payments", "p1"))
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+
+	require.NoError(t, db.Exec(`INSERT INTO payments (id, order_id, provider, provider_ref, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+		VALUES ('p2', 'o1', 'mock', 'ref2', 'authorized', 5000, 'EUR', 'k2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`).Error)
+	require.NoError(t, svc.Handle(ctx, "mock", WebhookEvent{EventID: "evt_late2", Type: "payment.failed", PaymentRef: "ref2"}, []byte(`{}`)))
+	assert.Equal(t, StatusAuthorized, statusOf(t, db, "payments", "p2"))
+}
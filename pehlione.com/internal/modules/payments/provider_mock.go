@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +22,10 @@ var ErrTimestampOutOfRange = errors.New("webhook timestamp out of range")
 type MockProvider struct {
 	WebhookSecret    []byte
 	ToleranceSeconds int
+
+	// HostedPageURL: set => CreatePayment returns requires_redirect to a
+	// local hosted payment page (e.g. http://localhost:8080/mock-pay)
+	HostedPageURL string
 }
 
 func NewMockProvider(secret string, tolerance int) MockProvider {
@@ -35,16 +40,80 @@ func NewMockProvider(secret string, tolerance int) MockProvider {
 
 func (MockProvider) Name() string { return "mock" }
 
-func (MockProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (CreatePaymentResponse, error) {
+func (p MockProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (CreatePaymentResponse, error) {
 	_ = ctx
-	_ = req
+	ref := uuid.NewString()
+
+	if p.HostedPageURL != "" {
+		q := url.Values{}
+		q.Set("amount_cents", strconv.Itoa(req.AmountCents))
+		q.Set("currency", req.Currency)
+		q.Set("capture", req.CaptureMethod)
+		q.Set("return_url", req.ReturnURL)
+		q.Set("cancel_url", req.CancelURL)
+		return CreatePaymentResponse{
+			ProviderRef: ref,
+			Status:      StatusRequiresRedirect,
+			RedirectURL: strings.TrimRight(p.HostedPageURL, "/") + "/" + ref + "?" + q.Encode(),
+		}, nil
+	}
+
 	// Async: return initiated; webhook later provides success/failure
 	return CreatePaymentResponse{
-		ProviderRef: uuid.NewString(),
+		ProviderRef: ref,
 		Status:      StatusInitiated,
 	}, nil
 }
 
+// HostedResultURL builds the redirect back to the merchant after the hosted
+// page decision: returnURL + payment_ref, status, t and sig (HMAC like webhooks).
+func (p MockProvider) HostedResultURL(returnURL, ref, status string) string {
+	t := time.Now().Unix()
+	q := url.Values{}
+	q.Set("payment_ref", ref)
+	q.Set("status", status)
+	q.Set("t", strconv.FormatInt(t, 10))
+	q.Set("sig", computeSigHex(p.WebhookSecret, t, []byte(ref+"."+status)))
+
+	sep := "?"
+	if strings.Contains(returnURL, "?") {
+		sep = "&"
+	}
+	return returnURL + sep + q.Encode()
+}
+
+func (p MockProvider) VerifyReturn(ctx context.Context, query url.Values) (ReturnResult, error) {
+	_ = ctx
+	ref := query.Get("payment_ref")
+	status := query.Get("status")
+	t, err := strconv.ParseInt(query.Get("t"), 10, 64)
+	if ref == "" || status == "" || err != nil {
+		return ReturnResult{}, ErrInvalidReturn
+	}
+
+	tol := p.ToleranceSeconds
+	if tol <= 0 {
+		tol = 300
+	}
+	now := time.Now().Unix()
+	if t < now-int64(tol) || t > now+int64(tol) {
+		return ReturnResult{}, ErrTimestampOutOfRange
+	}
+
+	exp, _ := hex.DecodeString(computeSigHex(p.WebhookSecret, t, []byte(ref+"."+status)))
+	got, err := hex.DecodeString(query.Get("sig"))
+	if err != nil || !hmac.Equal(exp, got) {
+		return ReturnResult{}, ErrInvalidSignature
+	}
+
+	switch status {
+	case StatusAuthorized, StatusSucceeded, StatusFailed, StatusInitiated:
+	default:
+		return ReturnResult{}, ErrInvalidReturn
+	}
+	return ReturnResult{PaymentRef: ref, Status: status}, nil
+}
+
 func (MockProvider) RefundPayment(ctx context.Context, req RefundRequest) (RefundResponse, error) {
 	_ = ctx
 	_ = req
@@ -55,12 +124,49 @@ func (MockProvider) RefundPayment(ctx context.Context, req RefundRequest) (Refun
 	}, nil
 }
 
+func (MockProvider) CapturePayment(ctx context.Context, req CaptureRequest) (CaptureResponse, error) {
+	_ = ctx
+	if req.PaymentRef == "" {
+		return CaptureResponse{}, errors.New("missing payment ref")
+	}
+	// Sync: capture of an authorization settles immediately
+	return CaptureResponse{Status: StatusSucceeded}, nil
+}
+
+func (MockProvider) VoidPayment(ctx context.Context, req VoidRequest) (VoidResponse, error) {
+	_ = ctx
+	if req.PaymentRef == "" {
+		return VoidResponse{}, errors.New("missing payment ref")
+	}
+	return VoidResponse{Status: StatusVoided}, nil
+}
+
+// GetPayment: the mock keeps no state; the outcome only arrives via webhook
+// or hosted page, so lookups always report the payment as still pending.
+func (MockProvider) GetPayment(ctx context.Context, paymentRef string) (StatusResult, error) {
+	_ = ctx
+	if paymentRef == "" {
+		return StatusResult{}, errors.New("missing payment ref")
+	}
+	return StatusResult{Status: StatusInitiated}, nil
+}
+
+func (MockProvider) GetRefund(ctx context.Context, refundRef string) (StatusResult, error) {
+	_ = ctx
+	if refundRef == "" {
+		return StatusResult{}, errors.New("missing refund ref")
+	}
+	return StatusResult{Status: StatusInitiated}, nil
+}
+
 type mockWebhookPayload struct {
 	ID   string `json:"id"`
 	Type string `json:"type"`
 	Data struct {
 		PaymentRef  string `json:"payment_ref"`
 		RefundRef   string `json:"refund_ref"`
+		DisputeRef  string `json:"dispute_ref"`
+		Reason      string `json:"reason"`
 		AmountCents int    `json:"amount_cents"`
 		Currency    string `json:"currency"`
 	} `json:"data"`
@@ -122,6 +228,8 @@ func (p MockProvider) VerifyAndParseWebhook(headers http.Header, body []byte) (W
 		Type:        pl.Type,
 		PaymentRef:  pl.Data.PaymentRef,
 		RefundRef:   pl.Data.RefundRef,
+		DisputeRef:  pl.Data.DisputeRef,
+		Reason:      pl.Data.Reason,
 		AmountCents: pl.Data.AmountCents,
 		Currency:    pl.Data.Currency,
 	}, nil
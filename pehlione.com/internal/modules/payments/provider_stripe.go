@@ -0,0 +1,535 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventIgnored is returned for provider events we receive but do not act on;
+// WebhookService records them as processed.
+const EventIgnored = "ignored"
+
+const DefaultStripeBaseURL = "https://api.stripe.com"
+
+type StripeConfig struct {
+	APIKey           string
+	WebhookSecret    string
+	BaseURL          string // default https://api.stripe.com (httptest in tests)
+	ToleranceSeconds int
+	HTTPClient       *http.Client
+}
+
+// StripeProvider talks to a Stripe-compatible REST API (hosted Checkout
+// Sessions, payment intents, refunds, signed webhooks). The card is collected
+// on Stripe's page: a payment starts as a Checkout Session (provider_ref
+// cs_...) and is re-keyed to its payment intent (pi_...) once Stripe reports
+// it, see WebhookEvent.CheckoutRef.
+type StripeProvider struct {
+	apiKey        string
+	webhookSecret []byte
+	baseURL       string
+	tolerance     int
+	http          *http.Client
+}
+
+func NewStripeProvider(cfg StripeConfig) *StripeProvider {
+	base := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if base == "" {
+		base = DefaultStripeBaseURL
+	}
+	tol := cfg.ToleranceSeconds
+	if tol <= 0 {
+		tol = 300
+	}
+	hc := cfg.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &StripeProvider{
+		apiKey:        cfg.APIKey,
+		webhookSecret: []byte(cfg.WebhookSecret),
+		baseURL:       base,
+		tolerance:     tol,
+		http:          hc,
+	}
+}
+
+func (*StripeProvider) Name() string { return "stripe" }
+
+// StripeError is a non-2xx API response.
+type StripeError struct {
+	HTTPStatus int
+	Type       string `json:"type"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *StripeError) Error() string {
+	return fmt.Sprintf("stripe: %d %s %s: %s", e.HTTPStatus, e.Type, e.Code, e.Message)
+}
+
+type stripeIntent struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Amount     int    `json:"amount"`
+	Currency   string `json:"currency"`
+	NextAction *struct {
+		RedirectToURL *struct {
+			URL string `json:"url"`
+		} `json:"redirect_to_url"`
+	} `json:"next_action"`
+}
+
+type stripeSession struct {
+	ID            string            `json:"id"`
+	URL           string            `json:"url"`
+	Status        string            `json:"status"`         // open|complete|expired
+	PaymentStatus string            `json:"payment_status"` // paid|unpaid|no_payment_required
+	AmountTotal   int               `json:"amount_total"`
+	Currency      string            `json:"currency"`
+	PaymentIntent json.RawMessage   `json:"payment_intent"` // id, or the object when expanded
+	Metadata      map[string]string `json:"metadata"`
+}
+
+// intent returns the session's payment intent; only the ID is set unless the
+// session was fetched with expand[]=payment_intent. ok is false before the
+// customer pays.
+func (cs stripeSession) intent() (pi stripeIntent, ok bool) {
+	raw := cs.PaymentIntent
+	if len(raw) == 0 || string(raw) == "null" {
+		return pi, false
+	}
+	if raw[0] == '"' {
+		err := json.Unmarshal(raw, &pi.ID)
+		return pi, err == nil && pi.ID != ""
+	}
+	err := json.Unmarshal(raw, &pi)
+	return pi, err == nil && pi.ID != ""
+}
+
+type stripeRefund struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	Amount        int    `json:"amount"`
+	Currency      string `json:"currency"`
+	PaymentIntent string `json:"payment_intent"`
+}
+
+// CreatePayment opens a hosted Checkout Session for the amount due; the
+// customer enters the card on Stripe's page and comes back on ReturnURL with
+// ?session_id=cs_...
+func (p *StripeProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (CreatePaymentResponse, error) {
+	if req.ReturnURL == "" {
+		return CreatePaymentResponse{}, fmt.Errorf("stripe: checkout needs a return url")
+	}
+	capture := stripeCaptureMethod(req.CaptureMethod)
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(req.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.Itoa(req.AmountCents))
+	form.Set("line_items[0][price_data][product_data][name]", "Order "+req.OrderID)
+	form.Set("client_reference_id", req.OrderID)
+	form.Set("metadata[order_id]", req.OrderID)
+	form.Set("metadata[capture_method]", capture)
+	form.Set("payment_intent_data[capture_method]", capture)
+	form.Set("payment_intent_data[metadata][order_id]", req.OrderID)
+	// {CHECKOUT_SESSION_ID} Stripe tarafından doldurulur, escape edilmemeli
+	sep := "?"
+	if strings.Contains(req.ReturnURL, "?") {
+		sep = "&"
+	}
+	form.Set("success_url", req.ReturnURL+sep+"session_id={CHECKOUT_SESSION_ID}")
+	if req.CancelURL != "" {
+		form.Set("cancel_url", req.CancelURL)
+	}
+
+	var cs stripeSession
+	if err := p.do(ctx, http.MethodPost, "/v1/checkout/sessions", req.IdempotencyKey, form, &cs); err != nil {
+		return CreatePaymentResponse{}, err
+	}
+	if cs.URL == "" {
+		return CreatePaymentResponse{ProviderRef: cs.ID}, fmt.Errorf("stripe: checkout session without url")
+	}
+	return CreatePaymentResponse{ProviderRef: cs.ID, Status: StatusRequiresRedirect, RedirectURL: cs.URL}, nil
+}
+
+func isCheckoutRef(ref string) bool { return strings.HasPrefix(ref, "cs_") }
+
+func (p *StripeProvider) getSession(ctx context.Context, id string) (stripeSession, error) {
+	var cs stripeSession
+	err := p.do(ctx, http.MethodGet, "/v1/checkout/sessions/"+url.PathEscape(id)+"?expand[]=payment_intent", "", nil, &cs)
+	return cs, err
+}
+
+// intentID resolves a payment's provider_ref to the payment intent that
+// capture, void and refund act on.
+func (p *StripeProvider) intentID(ctx context.Context, ref string) (string, error) {
+	if !isCheckoutRef(ref) {
+		return ref, nil
+	}
+	cs, err := p.getSession(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	pi, ok := cs.intent()
+	if !ok {
+		return "", fmt.Errorf("stripe: checkout session %s has no payment yet", ref)
+	}
+	return pi.ID, nil
+}
+
+// sessionStatus maps a Checkout Session (fetched expanded) to a payment status.
+func sessionStatus(cs stripeSession) string {
+	switch cs.Status {
+	case "expired":
+		return StatusFailed
+	case "complete":
+		if pi, ok := cs.intent(); ok && pi.Status != "" {
+			return intentStatus(pi.Status)
+		}
+		if cs.PaymentStatus == "paid" {
+			return StatusSucceeded
+		}
+		return StatusInitiated
+	default: // open: müşteri hâlâ Stripe sayfasında
+		return StatusInitiated
+	}
+}
+
+func (p *StripeProvider) RefundPayment(ctx context.Context, req RefundRequest) (RefundResponse, error) {
+	if req.PaymentRef == "" {
+		return RefundResponse{}, fmt.Errorf("stripe: missing payment intent")
+	}
+	intent, err := p.intentID(ctx, req.PaymentRef)
+	if err != nil {
+		return RefundResponse{}, err
+	}
+	form := url.Values{}
+	form.Set("payment_intent", intent)
+	form.Set("amount", strconv.Itoa(req.AmountCents))
+	form.Set("metadata[order_id]", req.OrderID)
+	form.Set("metadata[payment_id]", req.PaymentID)
+
+	var rf stripeRefund
+	if err := p.do(ctx, http.MethodPost, "/v1/refunds", req.IdempotencyKey, form, &rf); err != nil {
+		return RefundResponse{}, err
+	}
+	return RefundResponse{ProviderRef: rf.ID, Status: refundStatus(rf.Status)}, nil
+}
+
+func (p *StripeProvider) CapturePayment(ctx context.Context, req CaptureRequest) (CaptureResponse, error) {
+	if req.PaymentRef == "" {
+		return CaptureResponse{}, fmt.Errorf("stripe: missing payment intent")
+	}
+	intent, err := p.intentID(ctx, req.PaymentRef)
+	if err != nil {
+		return CaptureResponse{}, err
+	}
+	form := url.Values{}
+	form.Set("amount_to_capture", strconv.Itoa(req.AmountCents))
+
+	var pi stripeIntent
+	if err := p.do(ctx, http.MethodPost, "/v1/payment_intents/"+url.PathEscape(intent)+"/capture", req.IdempotencyKey, form, &pi); err != nil {
+		return CaptureResponse{}, err
+	}
+	switch pi.Status {
+	case "succeeded":
+		return CaptureResponse{Status: StatusSucceeded}, nil
+	case "processing":
+		return CaptureResponse{Status: StatusInitiated}, nil
+	default:
+		return CaptureResponse{Status: StatusFailed}, nil
+	}
+}
+
+func (p *StripeProvider) VoidPayment(ctx context.Context, req VoidRequest) (VoidResponse, error) {
+	if req.PaymentRef == "" {
+		return VoidResponse{}, fmt.Errorf("stripe: missing payment intent")
+	}
+	intent, err := p.intentID(ctx, req.PaymentRef)
+	if err != nil {
+		return VoidResponse{}, err
+	}
+	var pi stripeIntent
+	if err := p.do(ctx, http.MethodPost, "/v1/payment_intents/"+url.PathEscape(intent)+"/cancel", req.IdempotencyKey, url.Values{}, &pi); err != nil {
+		return VoidResponse{}, err
+	}
+	if pi.Status == "canceled" {
+		return VoidResponse{Status: StatusVoided}, nil
+	}
+	return VoidResponse{Status: StatusFailed}, nil
+}
+
+// VerifyReturn: Checkout appends ?session_id=cs_... to the success url (older
+// intent payments ?payment_intent=pi_...). The query is not signed, so the
+// session is looked up server-side.
+func (p *StripeProvider) VerifyReturn(ctx context.Context, query url.Values) (ReturnResult, error) {
+	if sid := query.Get("session_id"); sid != "" {
+		cs, err := p.getSession(ctx, sid)
+		if err != nil {
+			return ReturnResult{}, err
+		}
+		res := ReturnResult{CheckoutRef: cs.ID, Status: sessionStatus(cs)}
+		if pi, ok := cs.intent(); ok {
+			res.PaymentRef = pi.ID
+		}
+		if res.Status == StatusRequiresRedirect {
+			res.Status = StatusFailed
+		}
+		return res, nil
+	}
+
+	id := query.Get("payment_intent")
+	if id == "" {
+		return ReturnResult{}, ErrInvalidReturn
+	}
+	var pi stripeIntent
+	if err := p.do(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(id), "", nil, &pi); err != nil {
+		return ReturnResult{}, err
+	}
+	status := intentStatus(pi.Status)
+	if status == StatusRequiresRedirect {
+		// 3-D Secure yarıda bırakıldı
+		status = StatusFailed
+	}
+	return ReturnResult{PaymentRef: pi.ID, Status: status}, nil
+}
+
+func (p *StripeProvider) GetPayment(ctx context.Context, paymentRef string) (StatusResult, error) {
+	if paymentRef == "" {
+		return StatusResult{}, fmt.Errorf("stripe: missing payment intent")
+	}
+	if isCheckoutRef(paymentRef) {
+		cs, err := p.getSession(ctx, paymentRef)
+		if err != nil {
+			return StatusResult{}, err
+		}
+		res := StatusResult{Status: sessionStatus(cs), AmountCents: cs.AmountTotal, Currency: strings.ToUpper(cs.Currency)}
+		if pi, ok := cs.intent(); ok {
+			res.PaymentRef = pi.ID
+		}
+		return res, nil
+	}
+	var pi stripeIntent
+	if err := p.do(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(paymentRef), "", nil, &pi); err != nil {
+		return StatusResult{}, err
+	}
+	return StatusResult{Status: intentStatus(pi.Status), AmountCents: pi.Amount, Currency: strings.ToUpper(pi.Currency)}, nil
+}
+
+func (p *StripeProvider) GetRefund(ctx context.Context, refundRef string) (StatusResult, error) {
+	if refundRef == "" {
+		return StatusResult{}, fmt.Errorf("stripe: missing refund")
+	}
+	var rf stripeRefund
+	if err := p.do(ctx, http.MethodGet, "/v1/refunds/"+url.PathEscape(refundRef), "", nil, &rf); err != nil {
+		return StatusResult{}, err
+	}
+	return StatusResult{Status: refundStatus(rf.Status), AmountCents: rf.Amount, Currency: strings.ToUpper(rf.Currency)}, nil
+}
+
+type stripeDispute struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+	Amount        int    `json:"amount"`
+	Currency      string `json:"currency"`
+	PaymentIntent string `json:"payment_intent"`
+}
+
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Header: Stripe-Signature: t=<unix>,v1=<hex_hmac>
+// Signature: HMAC_SHA256(secret, "<t>.<raw_body>")
+func (p *StripeProvider) VerifyAndParseWebhook(headers http.Header, body []byte) (WebhookEvent, error) {
+	t, sigs, ok := parseStripeLikeSigHeader(strings.TrimSpace(headers.Get("Stripe-Signature")))
+	if !ok {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+	now := time.Now().Unix()
+	if t < now-int64(p.tolerance) || t > now+int64(p.tolerance) {
+		return WebhookEvent{}, ErrTimestampOutOfRange
+	}
+
+	expBytes, _ := hex.DecodeString(computeSigHex(p.webhookSecret, t, body))
+	matched := false
+	for _, s := range sigs {
+		got, err := hex.DecodeString(s)
+		if err == nil && hmac.Equal(expBytes, got) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+
+	var se stripeEvent
+	if err := json.Unmarshal(body, &se); err != nil {
+		return WebhookEvent{}, err
+	}
+	ev := WebhookEvent{EventID: se.ID, Type: EventIgnored}
+
+	switch se.Type {
+	case "payment_intent.succeeded", "payment_intent.amount_capturable_updated", "payment_intent.payment_failed":
+		var pi stripeIntent
+		if err := json.Unmarshal(se.Data.Object, &pi); err != nil {
+			return WebhookEvent{}, err
+		}
+		ev.PaymentRef = pi.ID
+		ev.AmountCents = pi.Amount
+		ev.Currency = strings.ToUpper(pi.Currency)
+		switch se.Type {
+		case "payment_intent.succeeded":
+			ev.Type = "payment.succeeded"
+		case "payment_intent.amount_capturable_updated":
+			ev.Type = "payment.authorized"
+		default:
+			ev.Type = "payment.failed"
+		}
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded",
+		"checkout.session.async_payment_failed", "checkout.session.expired":
+		var cs stripeSession
+		if err := json.Unmarshal(se.Data.Object, &cs); err != nil {
+			return WebhookEvent{}, err
+		}
+		ev.CheckoutRef = cs.ID
+		if pi, ok := cs.intent(); ok {
+			ev.PaymentRef = pi.ID
+		}
+		ev.AmountCents = cs.AmountTotal
+		ev.Currency = strings.ToUpper(cs.Currency)
+		switch {
+		case se.Type == "checkout.session.async_payment_succeeded":
+			ev.Type = "payment.succeeded"
+		case se.Type == "checkout.session.async_payment_failed", se.Type == "checkout.session.expired":
+			ev.Type = "payment.failed"
+		case cs.PaymentStatus == "paid":
+			ev.Type = "payment.succeeded"
+		case cs.PaymentStatus == "unpaid" && cs.Metadata["capture_method"] == "manual":
+			ev.Type = "payment.authorized"
+		}
+		// unpaid + automatic: gecikmeli ödeme yöntemi, async_payment_* ile sonuçlanır
+	case "refund.created", "refund.updated", "charge.refund.updated":
+		var rf stripeRefund
+		if err := json.Unmarshal(se.Data.Object, &rf); err != nil {
+			return WebhookEvent{}, err
+		}
+		ev.RefundRef = rf.ID
+		ev.PaymentRef = rf.PaymentIntent
+		ev.AmountCents = rf.Amount
+		ev.Currency = strings.ToUpper(rf.Currency)
+		switch refundStatus(rf.Status) {
+		case StatusSucceeded:
+			ev.Type = "refund.succeeded"
+		case StatusFailed:
+			ev.Type = "refund.failed"
+		}
+	case "charge.dispute.created", "charge.dispute.closed":
+		var dp stripeDispute
+		if err := json.Unmarshal(se.Data.Object, &dp); err != nil {
+			return WebhookEvent{}, err
+		}
+		ev.DisputeRef = dp.ID
+		ev.PaymentRef = dp.PaymentIntent
+		ev.Reason = dp.Reason
+		ev.AmountCents = dp.Amount
+		ev.Currency = strings.ToUpper(dp.Currency)
+		switch {
+		case se.Type == "charge.dispute.created":
+			ev.Type = "dispute.opened"
+		case dp.Status == "won":
+			ev.Type = "dispute.won"
+		case dp.Status == "lost":
+			ev.Type = "dispute.lost"
+		}
+	}
+	return ev, nil
+}
+
+func (p *StripeProvider) do(ctx context.Context, method, path, idemKey string, form url.Values, out any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.apiKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idemKey != "" {
+		req.Header.Set("Idempotency-Key", idemKey)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error StripeError `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &e)
+		e.Error.HTTPStatus = resp.StatusCode
+		return &e.Error
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func stripeCaptureMethod(m string) string {
+	if m == CaptureManual {
+		return "manual"
+	}
+	return "automatic"
+}
+
+func intentStatus(s string) string {
+	switch s {
+	case "succeeded":
+		return StatusSucceeded
+	case "requires_capture":
+		return StatusAuthorized
+	case "requires_action":
+		return StatusRequiresRedirect
+	case "canceled", "requires_payment_method":
+		return StatusFailed
+	default: // requires_confirmation, processing
+		return StatusInitiated
+	}
+}
+
+func refundStatus(s string) string {
+	switch s {
+	case "succeeded":
+		return StatusSucceeded
+	case "failed", "canceled":
+		return StatusFailed
+	default: // pending, requires_action
+		return StatusInitiated
+	}
+}
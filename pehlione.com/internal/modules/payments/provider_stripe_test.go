@@ -0,0 +1,345 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStripe is a minimal in-memory stand-in for the Checkout Sessions and
+// payment intents API. Like Stripe it refuses to confirm an intent without a
+// payment method, so the adapter must go through a hosted session.
+type fakeStripe struct {
+	sessions map[string]map[string]any
+	intents  map[string]map[string]any
+	idem     map[string]string
+	seq      int
+}
+
+func newFakeStripe(t *testing.T) (*fakeStripe, *httptest.Server) {
+	f := &fakeStripe{sessions: map[string]map[string]any{}, intents: map[string]map[string]any{}, idem: map[string]string{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, ok := r.BasicAuth(); !ok || user != "sk_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"bad key"}}`))
+			return
+		}
+		require.NoError(t, r.ParseForm())
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		parts := strings.Split(path, "/")
+		missing := func(what string) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"type":"invalid_request_error","code":"resource_missing","message":"no such ` + what + `"}}`))
+		}
+
+		switch {
+		case r.Method == http.MethodPost && path == "payment_intents":
+			if r.Form.Get("confirm") == "true" && r.Form.Get("payment_method") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"type":"invalid_request_error","code":"payment_intent_unexpected_state","message":"You cannot confirm this PaymentIntent because it's missing a payment method."}}`))
+				return
+			}
+			w.WriteHeader(http.StatusNotImplemented)
+		case r.Method == http.MethodPost && path == "checkout/sessions":
+			if id, ok := f.idem[r.Header.Get("Idempotency-Key")]; ok {
+				writeJSON(w, f.sessions[id])
+				return
+			}
+			require.Equal(t, "payment", r.Form.Get("mode"))
+			require.Contains(t, r.Form.Get("success_url"), "session_id={CHECKOUT_SESSION_ID}")
+			f.seq++
+			id := fmt.Sprintf("cs_%d", f.seq)
+			cs := map[string]any{
+				"id": id, "url": "https://checkout.example/pay/" + id, "status": "open", "payment_status": "unpaid",
+				"amount_total":   atoiT(t, r.Form.Get("line_items[0][price_data][unit_amount]")),
+				"currency":       r.Form.Get("line_items[0][price_data][currency]"),
+				"metadata":       map[string]any{"order_id": r.Form.Get("metadata[order_id]"), "capture_method": r.Form.Get("metadata[capture_method]")},
+				"payment_intent": nil,
+			}
+			f.sessions[id] = cs
+			f.idem[r.Header.Get("Idempotency-Key")] = id
+			writeJSON(w, cs)
+		case r.Method == http.MethodGet && parts[0] == "checkout" && len(parts) == 3:
+			cs, ok := f.sessions[parts[2]]
+			if !ok {
+				missing("checkout session")
+				return
+			}
+			require.Equal(t, "payment_intent", r.URL.Query().Get("expand[]"))
+			out := map[string]any{}
+			for k, v := range cs {
+				out[k] = v
+			}
+			if id, ok := cs["payment_intent"].(string); ok {
+				out["payment_intent"] = f.intents[id]
+			}
+			writeJSON(w, out)
+		case r.Method == http.MethodGet && parts[0] == "payment_intents" && len(parts) == 2:
+			pi, ok := f.intents[parts[1]]
+			if !ok {
+				missing("intent")
+				return
+			}
+			writeJSON(w, pi)
+		case r.Method == http.MethodPost && parts[0] == "payment_intents" && len(parts) == 3:
+			pi, ok := f.intents[parts[1]]
+			if !ok {
+				missing("intent")
+				return
+			}
+			if parts[2] == "capture" {
+				pi["status"] = "succeeded"
+			} else {
+				pi["status"] = "canceled"
+			}
+			writeJSON(w, pi)
+		case r.Method == http.MethodPost && path == "refunds":
+			if _, ok := f.intents[r.Form.Get("payment_intent")]; !ok {
+				missing("intent")
+				return
+			}
+			writeJSON(w, map[string]any{"id": "re_1", "status": "pending", "payment_intent": r.Form.Get("payment_intent")})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+// pay completes a checkout session the way the customer would on Stripe's
+// page; piStatus is the resulting payment intent status.
+func (f *fakeStripe) pay(csID, piStatus string) string {
+	cs := f.sessions[csID]
+	f.seq++
+	id := fmt.Sprintf("pi_%d", f.seq)
+	f.intents[id] = map[string]any{"id": id, "amount": cs["amount_total"], "currency": cs["currency"], "status": piStatus}
+	cs["payment_intent"] = id
+	cs["status"] = "complete"
+	if piStatus == "succeeded" {
+		cs["payment_status"] = "paid"
+	}
+	return id
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func atoiT(t *testing.T, s string) int {
+	var n int
+	_, err := fmt.Sscan(s, &n)
+	require.NoError(t, err)
+	return n
+}
+
+func TestStripeProvider_PaymentLifecycle(t *testing.T) {
+	f, srv := newFakeStripe(t)
+	p := NewStripeProvider(StripeConfig{APIKey: "sk_test", WebhookSecret: "whsec", BaseURL: srv.URL})
+	ctx := context.Background()
+	req := CreatePaymentRequest{OrderID: "o1", AmountCents: 5000, Currency: "EUR", IdempotencyKey: "k1", CaptureMethod: CaptureManual,
+		ReturnURL: "https://shop.example/orders/o1/pay/return?token=t"}
+
+	auth, err := p.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRequiresRedirect, auth.Status)
+	assert.Equal(t, "https://checkout.example/pay/"+auth.ProviderRef, auth.RedirectURL)
+
+	again, err := p.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, auth.ProviderRef, again.ProviderRef, "idempotency key is forwarded")
+
+	st, err := p.GetPayment(ctx, auth.ProviderRef)
+	require.NoError(t, err)
+	assert.Equal(t, StatusInitiated, st.Status, "customer still on the checkout page")
+	_, err = p.CapturePayment(ctx, CaptureRequest{PaymentRef: auth.ProviderRef, AmountCents: 5000})
+	require.Error(t, err, "nothing to capture before the customer pays")
+
+	f.pay(auth.ProviderRef, "requires_capture")
+	st, err = p.GetPayment(ctx, auth.ProviderRef)
+	require.NoError(t, err)
+	assert.Equal(t, StatusResult{Status: StatusAuthorized, AmountCents: 5000, Currency: "EUR", PaymentRef: "pi_2"}, st)
+
+	// session referansı da çalışır: capture/refund ödeme niyetine çözülür
+	capt, err := p.CapturePayment(ctx, CaptureRequest{PaymentRef: auth.ProviderRef, AmountCents: 5000})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, capt.Status)
+
+	rf, err := p.RefundPayment(ctx, RefundRequest{PaymentRef: auth.ProviderRef, AmountCents: 1000, IdempotencyKey: "r1"})
+	require.NoError(t, err)
+	assert.Equal(t, "re_1", rf.ProviderRef)
+	assert.Equal(t, StatusInitiated, rf.Status)
+
+	other, err := p.CreatePayment(ctx, CreatePaymentRequest{OrderID: "o2", AmountCents: 700, Currency: "EUR", IdempotencyKey: "k2", CaptureMethod: CaptureManual,
+		ReturnURL: "https://shop.example/orders/o2/pay/return"})
+	require.NoError(t, err)
+	pi := f.pay(other.ProviderRef, "requires_capture")
+	v, err := p.VoidPayment(ctx, VoidRequest{PaymentRef: pi})
+	require.NoError(t, err)
+	assert.Equal(t, StatusVoided, v.Status)
+
+	_, err = p.CreatePayment(ctx, CreatePaymentRequest{OrderID: "o3", AmountCents: 700, Currency: "EUR", IdempotencyKey: "k3"})
+	require.Error(t, err, "hosted checkout needs a return url")
+}
+
+func TestStripeProvider_RedirectAndReturn(t *testing.T) {
+	f, srv := newFakeStripe(t)
+	p := NewStripeProvider(StripeConfig{APIKey: "sk_test", WebhookSecret: "whsec", BaseURL: srv.URL})
+	ctx := context.Background()
+
+	resp, err := p.CreatePayment(ctx, CreatePaymentRequest{OrderID: "o1", AmountCents: 4242, Currency: "EUR", IdempotencyKey: "k1", ReturnURL: "https://shop.example/orders/o1/pay/return"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusRequiresRedirect, resp.Status)
+
+	res, err := p.VerifyReturn(ctx, url.Values{"session_id": {resp.ProviderRef}})
+	require.NoError(t, err)
+	assert.Equal(t, ReturnResult{CheckoutRef: resp.ProviderRef, Status: StatusInitiated}, res)
+
+	pi := f.pay(resp.ProviderRef, "succeeded")
+	res, err = p.VerifyReturn(ctx, url.Values{"session_id": {resp.ProviderRef}})
+	require.NoError(t, err)
+	assert.Equal(t, ReturnResult{PaymentRef: pi, CheckoutRef: resp.ProviderRef, Status: StatusSucceeded}, res)
+
+	// 3-D Secure yarıda bırakıldı
+	other, err := p.CreatePayment(ctx, CreatePaymentRequest{OrderID: "o2", AmountCents: 100, Currency: "EUR", IdempotencyKey: "k2", ReturnURL: "https://shop.example/r"})
+	require.NoError(t, err)
+	f.pay(other.ProviderRef, "requires_action")
+	res, err = p.VerifyReturn(ctx, url.Values{"session_id": {other.ProviderRef}})
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, res.Status)
+
+	_, err = p.VerifyReturn(ctx, url.Values{})
+	assert.ErrorIs(t, err, ErrInvalidReturn)
+
+	_, err = p.VerifyReturn(ctx, url.Values{"session_id": {"cs_missing"}})
+	var se *StripeError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, http.StatusNotFound, se.HTTPStatus)
+	assert.Equal(t, "resource_missing", se.Code)
+}
+
+func TestStripeProvider_APIError(t *testing.T) {
+	_, srv := newFakeStripe(t)
+	p := NewStripeProvider(StripeConfig{APIKey: "sk_wrong", BaseURL: srv.URL})
+
+	_, err := p.CreatePayment(context.Background(), CreatePaymentRequest{OrderID: "o1", AmountCents: 100, Currency: "EUR", ReturnURL: "https://shop.example/r"})
+	var se *StripeError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, http.StatusUnauthorized, se.HTTPStatus)
+}
+
+func TestStripeProvider_Webhook(t *testing.T) {
+	p := NewStripeProvider(StripeConfig{APIKey: "sk_test", WebhookSecret: "whsec"})
+	sign := func(body []byte, secret string, ts int64) http.Header {
+		h := http.Header{}
+		h.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%s", ts, computeSigHex([]byte(secret), ts, body)))
+		return h
+	}
+	now := time.Now().Unix()
+
+	body := []byte(`{"id":"evt_1","type":"payment_intent.amount_capturable_updated","data":{"object":{"id":"pi_1","amount":5000,"currency":"eur","status":"requires_capture"}}}`)
+	ev, err := p.VerifyAndParseWebhook(sign(body, "whsec", now), body)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEvent{EventID: "evt_1", Type: "payment.authorized", PaymentRef: "pi_1", AmountCents: 5000, Currency: "EUR"}, ev)
+
+	body = []byte(`{"id":"evt_2","type":"refund.updated","data":{"object":{"id":"re_1","payment_intent":"pi_1","amount":1000,"currency":"eur","status":"succeeded"}}}`)
+	ev, err = p.VerifyAndParseWebhook(sign(body, "whsec", now), body)
+	require.NoError(t, err)
+	assert.Equal(t, "refund.succeeded", ev.Type)
+	assert.Equal(t, "re_1", ev.RefundRef)
+
+	body = []byte(`{"id":"evt_4","type":"charge.dispute.closed","data":{"object":{"id":"dp_1","payment_intent":"pi_1","amount":5000,"currency":"eur","reason":"fraudulent","status":"lost"}}}`)
+	ev, err = p.VerifyAndParseWebhook(sign(body, "whsec", now), body)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEvent{EventID: "evt_4", Type: "dispute.lost", PaymentRef: "pi_1", DisputeRef: "dp_1", Reason: "fraudulent", AmountCents: 5000, Currency: "EUR"}, ev)
+
+	body = []byte(`{"id":"evt_5","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_intent":"pi_1","amount_total":5000,"currency":"eur","status":"complete","payment_status":"paid"}}}`)
+	ev, err = p.VerifyAndParseWebhook(sign(body, "whsec", now), body)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEvent{EventID: "evt_5", Type: "payment.succeeded", PaymentRef: "pi_1", CheckoutRef: "cs_1", AmountCents: 5000, Currency: "EUR"}, ev)
+
+	body = []byte(`{"id":"evt_6","type":"checkout.session.completed","data":{"object":{"id":"cs_2","payment_intent":"pi_2","status":"complete","payment_status":"unpaid","metadata":{"capture_method":"manual"}}}}`)
+	ev, err = p.VerifyAndParseWebhook(sign(body, "whsec", now), body)
+	require.NoError(t, err)
+	assert.Equal(t, "payment.authorized", ev.Type)
+
+	body = []byte(`{"id":"evt_7","type":"checkout.session.expired","data":{"object":{"id":"cs_3","payment_intent":null,"status":"expired","payment_status":"unpaid"}}}`)
+	ev, err = p.VerifyAndParseWebhook(sign(body, "whsec", now), body)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEvent{EventID: "evt_7", Type: "payment.failed", CheckoutRef: "cs_3"}, ev)
+
+	body = []byte(`{"id":"evt_3","type":"customer.created","data":{"object":{"id":"cus_1"}}}`)
+	ev, err = p.VerifyAndParseWebhook(sign(body, "whsec", now), body)
+	require.NoError(t, err)
+	assert.Equal(t, EventIgnored, ev.Type)
+
+	_, err = p.VerifyAndParseWebhook(sign(body, "other", now), body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = p.VerifyAndParseWebhook(sign(body, "whsec", now-3600), body)
+	assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+}
+
+func TestStripeProvider_CheckoutPaymentIsRekeyed(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`UPDATE orders SET status = 'created' WHERE id = 'o1'`).Error)
+	require.NoError(t, db.Exec(`UPDATE payments SET provider = 'stripe', provider_ref = 'cs_1', status = 'requires_redirect' WHERE id = 'p1'`).Error)
+	svc := NewWebhookService(db)
+
+	// oturum tamamlandı: ödeme artık pi_1 ile takip edilir
+	completed := WebhookEvent{EventID: "evt_1", Type: "payment.succeeded", CheckoutRef: "cs_1", PaymentRef: "pi_1", AmountCents: 5000, Currency: "EUR"}
+	require.NoError(t, svc.Handle(ctx, "stripe", completed, []byte(`{}`)))
+	assert.Equal(t, StatusSucceeded, statusOf(t, db, "payments", "p1"))
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+	var ref string
+	require.NoError(t, db.Table("payments").Select("provider_ref").Where("id = ?", "p1").Scan(&ref).Error)
+	assert.Equal(t, "pi_1", ref)
+
+	// ödeme niyeti üzerinden gelen olaylar (payment_intent.*, charge.dispute.*) eşleşir
+	require.NoError(t, svc.Handle(ctx, "stripe", WebhookEvent{EventID: "evt_2", Type: "payment.succeeded", PaymentRef: "pi_1"}, []byte(`{}`)))
+	dispute := WebhookEvent{EventID: "evt_3", Type: "dispute.opened", PaymentRef: "pi_1", DisputeRef: "dp_1", AmountCents: 5000, Currency: "EUR"}
+	require.NoError(t, svc.Handle(ctx, "stripe", dispute, []byte(`{}`)))
+	assert.Equal(t, DisputeOpen, disputeFlag(t, db))
+}
+
+func TestStripeProvider_ReplayCheckoutSessionEvent(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`UPDATE orders SET status = 'created' WHERE id = 'o1'`).Error)
+	require.NoError(t, db.Exec(`UPDATE payments SET provider = 'stripe', provider_ref = NULL, status = 'requires_redirect' WHERE id = 'p1'`).Error)
+	p := NewStripeProvider(StripeConfig{APIKey: "sk_test", WebhookSecret: "whsec"})
+	svc := NewWebhookService(db)
+
+	body := []byte(`{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_intent":"pi_1","amount_total":5000,"currency":"eur","status":"complete","payment_status":"paid"}}}`)
+	ts := time.Now().Unix()
+	h := http.Header{}
+	h.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%s", ts, computeSigHex([]byte("whsec"), ts, body)))
+	ev, err := p.VerifyAndParseWebhook(h, body)
+	require.NoError(t, err)
+
+	// oturum referansı henüz kaydedilmeden gelen olay başarısız olur
+	require.Error(t, svc.Handle(ctx, "stripe", ev, body))
+	require.NoError(t, db.Exec(`UPDATE payments SET provider_ref = 'cs_1' WHERE id = 'p1'`).Error)
+
+	events, err := svc.ListEvents(ctx, EventFilter{State: EventStateFailed, Provider: "stripe"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.NoError(t, svc.Replay(ctx, events[0].ID))
+
+	assert.Equal(t, StatusSucceeded, statusOf(t, db, "payments", "p1"))
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+	var ref string
+	require.NoError(t, db.Table("payments").Select("provider_ref").Where("id = ?", "p1").Scan(&ref).Error)
+	assert.Equal(t, "pi_1", ref)
+}
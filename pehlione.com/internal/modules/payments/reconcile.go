@@ -0,0 +1,220 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reconciler polls the provider for payments and refunds whose webhook never
+// arrived. Outcomes are fed through WebhookService.Handle as synthetic
+// provider events, so the transitions match the webhook path and every
+// action is kept in provider_events for audit.
+type Reconciler struct {
+	db          *gorm.DB
+	providers   *Registry
+	webhooks    *WebhookService
+	refunds     *RefundService
+	staleAfter  time.Duration
+	expireAfter time.Duration
+	batchSize   int
+}
+
+func NewReconciler(db *gorm.DB, providers *Registry, webhooks *WebhookService, staleAfter, expireAfter time.Duration) *Reconciler {
+	if staleAfter <= 0 {
+		staleAfter = 15 * time.Minute
+	}
+	if expireAfter <= 0 {
+		expireAfter = 24 * time.Hour
+	}
+	return &Reconciler{
+		db:          db,
+		providers:   providers,
+		webhooks:    webhooks,
+		refunds:     NewRefundService(db, providers, nil, ""),
+		staleAfter:  staleAfter,
+		expireAfter: expireAfter,
+		batchSize:   50,
+	}
+}
+
+// SetRefundService sets the service late payments are refunded through, so
+// the refund email goes out; the default sends none.
+func (r *Reconciler) SetRefundService(refunds *RefundService) {
+	r.refunds = refunds
+}
+
+// ReconcileOnce checks one batch of stale payments and refunds, and gives
+// back payments that arrived after their order was cancelled. Returns the
+// number of transitions applied.
+func (r *Reconciler) ReconcileOnce(ctx context.Context, now time.Time) (int, error) {
+	cutoff := now.Add(-r.staleAfter)
+
+	var pays []Payment
+	if err := r.db.WithContext(ctx).
+		Where("status IN ? AND created_at < ? AND provider_ref IS NOT NULL", []string{StatusInitiated, StatusRequiresRedirect}, cutoff).
+		Order("created_at ASC").
+		Limit(r.batchSize).
+		Find(&pays).Error; err != nil {
+		return 0, err
+	}
+
+	var refunds []Refund
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND created_at < ? AND provider_ref IS NOT NULL", StatusInitiated, cutoff).
+		Order("created_at ASC").
+		Limit(r.batchSize).
+		Find(&refunds).Error; err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, p := range pays {
+		ok, err := r.reconcilePayment(ctx, p, now)
+		if err != nil {
+			log.Printf("payments reconcile: payment %s failed: %v", p.ID, err)
+			continue
+		}
+		if ok {
+			applied++
+		}
+	}
+	for _, rf := range refunds {
+		ok, err := r.reconcileRefund(ctx, rf)
+		if err != nil {
+			log.Printf("payments reconcile: refund %s failed: %v", rf.ID, err)
+			continue
+		}
+		if ok {
+			applied++
+		}
+	}
+
+	n, err := r.settleLatePayments(ctx)
+	if err != nil {
+		log.Printf("payments reconcile: late payments failed: %v", err)
+	}
+	return applied + n, nil
+}
+
+func (r *Reconciler) reconcilePayment(ctx context.Context, p Payment, now time.Time) (bool, error) {
+	provider, err := r.providers.Get(p.Provider)
+	if err != nil {
+		return false, err
+	}
+	st, err := provider.GetPayment(ctx, ptrVal(p.ProviderRef))
+	if err != nil {
+		return false, err
+	}
+
+	status := st.Status
+	var evType string
+	switch status {
+	case StatusSucceeded:
+		evType = "payment.succeeded"
+	case StatusAuthorized:
+		evType = "payment.authorized"
+	case StatusFailed, StatusVoided:
+		evType = "payment.failed"
+	default:
+		// hâlâ beklemede; çok eskiyse vazgeç
+		if p.CreatedAt.After(now.Add(-r.expireAfter)) {
+			return false, nil
+		}
+		status = "expired"
+		evType = "payment.failed"
+	}
+
+	ev := WebhookEvent{
+		EventID:     "reconcile:" + p.ID + ":" + status,
+		Type:        evType,
+		PaymentRef:  ptrVal(p.ProviderRef),
+		AmountCents: p.AmountCents,
+		Currency:    p.Currency,
+	}
+	// hosted checkout ödendiyse ödeme, sağlayıcının ödeme kaydına yeniden
+	// anahtarlanır; itiraz gibi sonraki webhook'lar yalnızca onu taşır
+	if st.PaymentRef != "" && st.PaymentRef != ev.PaymentRef {
+		ev.CheckoutRef = ev.PaymentRef
+		ev.PaymentRef = st.PaymentRef
+	}
+	return true, r.webhooks.Handle(ctx, p.Provider, ev, reconcilePayload("payment", p.ID, st.Status, status))
+}
+
+func (r *Reconciler) reconcileRefund(ctx context.Context, rf Refund) (bool, error) {
+	provider, err := r.providers.Get(rf.Provider)
+	if err != nil {
+		return false, err
+	}
+	st, err := provider.GetRefund(ctx, ptrVal(rf.ProviderRef))
+	if err != nil {
+		return false, err
+	}
+
+	var evType string
+	switch st.Status {
+	case StatusSucceeded:
+		evType = "refund.succeeded"
+	case StatusFailed:
+		evType = "refund.failed"
+	default:
+		return false, nil
+	}
+
+	ev := WebhookEvent{
+		EventID:     "reconcile:" + rf.ID + ":" + st.Status,
+		Type:        evType,
+		RefundRef:   ptrVal(rf.ProviderRef),
+		AmountCents: rf.AmountCents,
+		Currency:    rf.Currency,
+	}
+	return true, r.webhooks.Handle(ctx, rf.Provider, ev, reconcilePayload("refund", rf.ID, st.Status, st.Status))
+}
+
+func reconcilePayload(kind, id, providerStatus, outcome string) []byte {
+	b, _ := json.Marshal(map[string]string{
+		"source":          "reconciliation",
+		"kind":            kind,
+		"id":              id,
+		"provider_status": providerStatus,
+		"outcome":         outcome,
+	})
+	return b
+}
+
+// ReconcileWorker periodically reconciles stale payments and refunds.
+type ReconcileWorker struct {
+	r        *Reconciler
+	interval time.Duration
+}
+
+func NewReconcileWorker(r *Reconciler, interval time.Duration) *ReconcileWorker {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &ReconcileWorker{r: r, interval: interval}
+}
+
+func (w *ReconcileWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			n, err := w.r.ReconcileOnce(ctx, time.Now())
+			if err != nil {
+				log.Printf("payments reconcile worker tick error: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("payments reconcile worker: applied %d transitions", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type statusProvider struct {
+	MockProvider
+	payment    string
+	paymentRef string
+}
+
+func (p statusProvider) GetPayment(ctx context.Context, ref string) (StatusResult, error) {
+	return StatusResult{Status: p.payment, PaymentRef: p.paymentRef}, nil
+}
+
+func setupReconcileDB(t *testing.T) *gorm.DB {
+	db := setupCaptureDB(t)
+	require.NoError(t, db.Exec(`CREATE TABLE provider_events (
+		id TEXT PRIMARY KEY, provider TEXT NOT NULL, event_id TEXT NOT NULL, event_type TEXT NOT NULL, payload_json TEXT NOT NULL,
+		payment_ref TEXT, refund_ref TEXT, dispute_ref TEXT, checkout_ref TEXT, reason TEXT, amount_cents INTEGER NOT NULL DEFAULT 0, currency TEXT,
+		received_at DATETIME NOT NULL, processed_at DATETIME, process_error TEXT, attempts INTEGER NOT NULL DEFAULT 0, UNIQUE (provider, event_id))`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE refunds (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, payment_id TEXT, provider TEXT NOT NULL, provider_ref TEXT,
+		status TEXT NOT NULL, amount_cents INTEGER NOT NULL, currency TEXT NOT NULL, idempotency_key TEXT NOT NULL,
+		reason TEXT, restock BOOLEAN NOT NULL DEFAULT 0, error_message TEXT, credit_note_number TEXT UNIQUE, credit_note_issued_at DATETIME,
+		created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	return db
+}
+
+func TestReconcile_AppliesProviderOutcome(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	r := NewReconciler(db, NewRegistry(statusProvider{MockProvider: NewMockProvider("", 0), payment: StatusSucceeded}), NewWebhookService(db), 15*time.Minute, 24*time.Hour)
+
+	n, err := r.ReconcileOnce(ctx, time.Now().UTC())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "fresh payments are left to the webhook")
+
+	n, err = r.ReconcileOnce(ctx, time.Now().UTC().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "paid", statusOf(t, db, "orders", "o1"))
+	assert.Equal(t, StatusSucceeded, statusOf(t, db, "payments", "p1"))
+	assert.Equal(t, map[string]int{"payment_succeeded": 5000}, ledger(t, db))
+
+	var ev ProviderEvent
+	require.NoError(t, db.First(&ev, "event_id = ?", "reconcile:p1:succeeded").Error)
+	assert.Equal(t, "payment.succeeded", ev.EventType)
+	assert.NotNil(t, ev.ProcessedAt)
+
+	n, err = r.ReconcileOnce(ctx, time.Now().UTC().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestReconcile_ExpiresPendingPayment(t *testing.T) {
+	db := setupReconcileDB(t)
+	ctx := context.Background()
+	r := NewReconciler(db, NewRegistry(NewMockProvider("", 0)), NewWebhookService(db), 15*time.Minute, 24*time.Hour)
+
+	n, err := r.ReconcileOnce(ctx, time.Now().UTC().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "still pending at the provider")
+
+	n, err = r.ReconcileOnce(ctx, time.Now().UTC().Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, StatusFailed, statusOf(t, db, "payments", "p1"))
+	assert.Equal(t, "created", statusOf(t, db, "orders", "o1"))
+
+	var cnt int64
+	require.NoError(t, db.Model(&ProviderEvent{}).Where("event_id = ?", "reconcile:p1:expired").Count(&cnt).Error)
+	assert.EqualValues(t, 1, cnt)
+}
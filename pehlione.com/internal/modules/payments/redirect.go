@@ -0,0 +1,149 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReturnInput is a browser callback on ReturnURL/CancelURL.
+type ReturnInput struct {
+	OrderID string
+	Token   string     // payments.return_token
+	Query   url.Values // provider parameters (ReturnURL only)
+}
+
+// CompleteReturn verifies the provider result on the ReturnURL callback and
+// applies it to the payment. A webhook may have settled the payment first;
+// then the stored status is returned unchanged.
+func (s *Service) CompleteReturn(ctx context.Context, in ReturnInput) (string, error) {
+	pay, err := s.paymentByToken(ctx, in.OrderID, in.Token)
+	if err != nil {
+		return "", err
+	}
+
+	provider, err := s.providers.Get(pay.Provider)
+	if err != nil {
+		return "", err
+	}
+	res, err := provider.VerifyReturn(ctx, in.Query)
+	if err != nil {
+		return "", ErrInvalidReturn
+	}
+	ref := ptrVal(pay.ProviderRef)
+	if ref == "" || (ref != res.PaymentRef && ref != res.CheckoutRef) {
+		return "", ErrInvalidReturn
+	}
+
+	status := pay.Status
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var p Payment
+		if err := tx.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&p, "id = ?", pay.ID).Error; err != nil {
+			return err
+		}
+		// hosted checkout: artık sağlayıcının ödeme referansıyla takip edilir
+		if res.PaymentRef != "" && ptrVal(p.ProviderRef) != res.PaymentRef {
+			if err := setProviderRefInTx(ctx, tx, &p, res.PaymentRef); err != nil {
+				return err
+			}
+		}
+		status = p.Status
+		if p.Status != StatusRequiresRedirect && p.Status != StatusInitiated {
+			return nil // webhook önce geldi
+		}
+
+		now := time.Now()
+		status = res.Status
+		switch res.Status {
+		case StatusAuthorized:
+			return markAuthorizedInTx(ctx, tx, p, now)
+		case StatusSucceeded:
+			return markSucceededInTx(ctx, tx, p, now)
+		case StatusFailed:
+			return tx.WithContext(ctx).Model(&Payment{}).
+				Where("id = ?", p.ID).
+				Updates(map[string]any{
+					"status":        StatusFailed,
+					"error_message": "provider return: failed",
+					"updated_at":    now,
+				}).Error
+		default:
+			// sağlayıcı hâlâ işliyor: webhook sonuçlandırır
+			status = StatusInitiated
+			return tx.WithContext(ctx).Model(&Payment{}).
+				Where("id = ?", p.ID).
+				Updates(map[string]any{"status": StatusInitiated, "updated_at": now}).Error
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// CancelReturn handles the CancelURL callback: the customer left the provider
+// page, so the pending payment fails and the order can be paid again.
+func (s *Service) CancelReturn(ctx context.Context, in ReturnInput) error {
+	pay, err := s.paymentByToken(ctx, in.OrderID, in.Token)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Model(&Payment{}).
+		Where("id = ? AND status = ?", pay.ID, StatusRequiresRedirect).
+		Updates(map[string]any{
+			"status":        StatusFailed,
+			"error_message": "customer cancelled at provider",
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+// LatestPayment returns the newest payment of an order, if any.
+func (s *Service) LatestPayment(ctx context.Context, orderID string) (*Payment, error) {
+	var p Payment
+	err := s.db.WithContext(ctx).Order("created_at DESC").First(&p, "order_id = ?", orderID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *Service) paymentByToken(ctx context.Context, orderID, token string) (Payment, error) {
+	var p Payment
+	if orderID == "" || len(token) != 32 {
+		return p, ErrInvalidReturn
+	}
+	err := s.db.WithContext(ctx).First(&p, "order_id = ? AND return_token = ?", orderID, token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return p, ErrInvalidReturn
+	}
+	return p, err
+}
+
+func newReturnToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func withToken(raw, token string) string {
+	if raw == "" {
+		return ""
+	}
+	sep := "?"
+	if strings.Contains(raw, "?") {
+		sep = "&"
+	}
+	return raw + sep + "token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,67 @@
+package payments
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectFlow(t *testing.T) {
+	db := setupCaptureDB(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`DELETE FROM payments`).Error)
+
+	mp := NewMockProvider("s3cret", 0)
+	mp.HostedPageURL = "http://shop.test/mock-pay"
+	svc := NewService(db, NewRegistry(mp))
+	svc.SetCaptureMethod(CaptureManual)
+
+	res, err := svc.PayOrder(ctx, PayOrderInput{
+		OrderID:        "o1",
+		IdempotencyKey: "k1",
+		ReturnURL:      "http://shop.test/orders/o1/pay/return",
+		CancelURL:      "http://shop.test/orders/o1/pay/cancel",
+	})
+	require.NoError(t, err)
+	require.Equal(t, StatusRequiresRedirect, res.Status)
+	assert.Equal(t, "created", statusOf(t, db, "orders", "o1"), "order waits for the customer")
+
+	// the hosted page carries our callback URLs (with the return token)
+	hosted, err := url.Parse(res.RedirectURL)
+	require.NoError(t, err)
+	returnURL := hosted.Query().Get("return_url")
+	require.Contains(t, returnURL, "token=")
+	ref := strings.TrimPrefix(hosted.Path, "/mock-pay/")
+
+	again, err := svc.PayOrder(ctx, PayOrderInput{OrderID: "o1", IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	assert.Equal(t, res.RedirectURL, again.RedirectURL, "retry resumes the same redirect")
+
+	back, err := url.Parse(mp.HostedResultURL(returnURL, ref, StatusAuthorized))
+	require.NoError(t, err)
+	q := back.Query()
+	in := ReturnInput{OrderID: "o1", Token: q.Get("token"), Query: q}
+
+	forged := url.Values{}
+	for k, v := range q {
+		forged[k] = v
+	}
+	forged.Set("status", StatusSucceeded)
+	_, err = svc.CompleteReturn(ctx, ReturnInput{OrderID: "o1", Token: in.Token, Query: forged})
+	assert.ErrorIs(t, err, ErrInvalidReturn, "tampered result is rejected")
+
+	status, err := svc.CompleteReturn(ctx, in)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, status)
+	assert.Equal(t, "authorized", statusOf(t, db, "orders", "o1"))
+
+	status, err = svc.CompleteReturn(ctx, in)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, status, "reloading the return page is harmless")
+
+	assert.ErrorIs(t, svc.CancelReturn(ctx, ReturnInput{OrderID: "o1", Token: strings.Repeat("0", 32)}), ErrInvalidReturn)
+}
@@ -0,0 +1,330 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/checkout"
+	"pehlione.com/app/internal/modules/orders"
+)
+
+// Refund line kinds.
+const (
+	LineItem       = "item"
+	LineShipping   = "shipping"
+	LineAdjustment = "adjustment"
+)
+
+var (
+	ErrInvalidRefundLine      = errors.New("invalid refund line")
+	ErrRefundExceedsRemaining = errors.New("refund exceeds remaining refundable amount")
+)
+
+// RefundLine records what a refund paid back: an order item quantity,
+// shipping or a free adjustment. Amounts are in the charge currency and
+// include the tax share.
+type RefundLine struct {
+	ID          string  `gorm:"type:char(36);primaryKey"`
+	RefundID    string  `gorm:"type:char(36);not null;index:ix_refund_lines_refund_id"`
+	Kind        string  `gorm:"type:varchar(16);not null"`
+	OrderItemID *string `gorm:"type:char(36);index:ix_refund_lines_order_item_id"`
+	Label       string  `gorm:"type:varchar(255);not null"`
+	Qty         int     `gorm:"not null;default:0"`
+	AmountCents int     `gorm:"not null"`
+	TaxCents    int     `gorm:"not null;default:0"`
+	Currency    string  `gorm:"type:char(3);not null"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (RefundLine) TableName() string { return "refund_lines" }
+
+// RefundLineInput selects a quantity of an order item to refund.
+type RefundLineInput struct {
+	OrderItemID string
+	Qty         int
+}
+
+// LineAmount is the refundable money for a quantity of one order item.
+type LineAmount struct {
+	AmountCents int
+	TaxCents    int
+}
+
+// ItemAmounts prices quantities of order items in the order (charge)
+// currency: the line total minus the line's share of the order discount (as
+// allocated at checkout, see orders.LineDiscounts), plus tax when prices were
+// net, pro rata to the quantity. items must be all items of the order. The
+// stored line tax is already computed on the discounted amount, so it is only
+// prorated. done holds the quantities already refunded per item (may be nil):
+// units are priced cumulatively, so the last unit takes the rounding
+// remainder and refunding every unit gives back the whole line.
+func ItemAmounts(ord orders.Order, items []orders.OrderItem, qty, done map[string]int) map[string]LineAmount {
+	discounts := orders.LineDiscounts(ord, items)
+
+	out := make(map[string]LineAmount, len(qty))
+	for i, it := range items {
+		q := qty[it.ID]
+		if q <= 0 || it.Quantity <= 0 {
+			continue
+		}
+		from := min(max(done[it.ID], 0), it.Quantity)
+		to := min(from+q, it.Quantity)
+		gross := max(it.LineTotalCents-discounts[i], 0)
+		if !ord.PricesIncludeTax {
+			gross += it.TaxCents
+		}
+		tax := max(it.TaxCents, 0)
+		out[it.ID] = LineAmount{
+			AmountCents: gross*to/it.Quantity - gross*from/it.Quantity,
+			TaxCents:    tax*to/it.Quantity - tax*from/it.Quantity,
+		}
+	}
+	return out
+}
+
+// Refundable is what is left to refund on an order.
+type Refundable struct {
+	Order             orders.Order
+	Items             []RefundableItem
+	ShippingLeftCents int
+	RemainingCents    int
+}
+
+type RefundableItem struct {
+	Item orders.OrderItem
+	Max  int
+}
+
+// Refundable lists refundable quantities per item and the shipping left.
+func (s *RefundService) Refundable(ctx context.Context, orderID string) (Refundable, error) {
+	var out Refundable
+	if err := s.db.WithContext(ctx).First(&out.Order, "id = ?", orderID).Error; err != nil {
+		return out, err
+	}
+	items, err := orderItemsInTx(ctx, s.db, orderID)
+	if err != nil {
+		return out, err
+	}
+	done, err := refundedLinesInTx(ctx, s.db, orderID)
+	if err != nil {
+		return out, err
+	}
+	for _, it := range items {
+		if left := it.Quantity - done.qty[it.ID]; left > 0 {
+			out.Items = append(out.Items, RefundableItem{Item: it, Max: left})
+		}
+	}
+	out.ShippingLeftCents = max(out.Order.ShippingCents-done.shipping, 0)
+	out.RemainingCents = max(out.Order.TotalCents-out.Order.RefundedCents, 0)
+	return out, nil
+}
+
+// buildLinesInTx validates the requested lines against what is still
+// refundable and prices them. Returns nil lines for an amount-only refund.
+func buildLinesInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, currency string, in RefundOrderInput) ([]RefundLine, error) {
+	if len(in.Lines) == 0 && in.ShippingCents == 0 && in.AdjustmentCents == 0 {
+		return nil, nil
+	}
+	if in.ShippingCents < 0 {
+		return nil, ErrInvalidRefundLine
+	}
+
+	var items []orders.OrderItem
+	if err := tx.WithContext(ctx).Order("created_at ASC").Find(&items, "order_id = ?", ord.ID).Error; err != nil {
+		return nil, err
+	}
+	done, err := refundedLinesInTx(ctx, tx, ord.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[string]int{}
+	for _, ln := range in.Lines {
+		if ln.Qty < 0 {
+			return nil, ErrInvalidRefundLine
+		}
+		if ln.Qty > 0 {
+			want[ln.OrderItemID] += ln.Qty
+		}
+	}
+
+	byID := make(map[string]orders.OrderItem, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+	for id, q := range want {
+		it, ok := byID[id]
+		if !ok || q > it.Quantity-done.qty[id] {
+			return nil, ErrInvalidRefundLine
+		}
+	}
+
+	amounts := ItemAmounts(ord, items, want, done.qty)
+	var lines []RefundLine
+	for _, it := range items {
+		q := want[it.ID]
+		if q == 0 {
+			continue
+		}
+		id := it.ID
+		lines = append(lines, RefundLine{
+			Kind:        LineItem,
+			OrderItemID: &id,
+			Label:       it.ProductName + " (" + it.SKU + ")",
+			Qty:         q,
+			AmountCents: amounts[it.ID].AmountCents,
+			TaxCents:    amounts[it.ID].TaxCents,
+			Currency:    currency,
+		})
+	}
+
+	if in.ShippingCents > 0 {
+		if in.ShippingCents > ord.ShippingCents-done.shipping {
+			return nil, ErrInvalidRefundLine
+		}
+		lines = append(lines, RefundLine{Kind: LineShipping, Label: "Shipping", AmountCents: in.ShippingCents, Currency: currency})
+	}
+	if in.AdjustmentCents != 0 {
+		// negatif düzeltme: ör. yeniden stoklama ücreti
+		lines = append(lines, RefundLine{Kind: LineAdjustment, Label: "Adjustment", AmountCents: in.AdjustmentCents, Currency: currency})
+	}
+	return lines, nil
+}
+
+func sumLines(lines []RefundLine) int {
+	total := 0
+	for _, l := range lines {
+		total += l.AmountCents
+	}
+	return total
+}
+
+type refundedTotals struct {
+	qty      map[string]int // order item -> qty
+	shipping int
+}
+
+// refundedLinesInTx sums lines of refunds that did not fail.
+func refundedLinesInTx(ctx context.Context, tx *gorm.DB, orderID string) (refundedTotals, error) {
+	var rows []RefundLine
+	if err := tx.WithContext(ctx).
+		Table("refund_lines rl").
+		Select("rl.*").
+		Joins("JOIN refunds r ON r.id = rl.refund_id").
+		Where("r.order_id = ? AND r.status <> ?", orderID, StatusFailed).
+		Scan(&rows).Error; err != nil {
+		return refundedTotals{}, err
+	}
+	out := refundedTotals{qty: map[string]int{}}
+	for _, l := range rows {
+		switch l.Kind {
+		case LineItem:
+			if l.OrderItemID != nil {
+				out.qty[*l.OrderItemID] += l.Qty
+			}
+		case LineShipping:
+			out.shipping += l.AmountCents
+		}
+	}
+	return out, nil
+}
+
+// RefundedItemQtyInTx sums the refunded quantity per order item over refunds
+// that did not fail. Refunds whose idempotency key starts with skipKeyPrefix
+// are left out, so a caller can skip the refunds it started itself.
+func RefundedItemQtyInTx(ctx context.Context, tx *gorm.DB, orderID, skipKeyPrefix string) (map[string]int, error) {
+	type row struct {
+		OrderItemID string `gorm:"column:order_item_id"`
+		Qty         int    `gorm:"column:qty"`
+	}
+	q := tx.WithContext(ctx).
+		Table("refund_lines rl").
+		Select("rl.order_item_id, SUM(rl.qty) AS qty").
+		Joins("JOIN refunds r ON r.id = rl.refund_id").
+		Where("r.order_id = ? AND r.status <> ? AND rl.kind = ?", orderID, StatusFailed, LineItem)
+	if skipKeyPrefix != "" {
+		q = q.Where("r.idempotency_key NOT LIKE ?", skipKeyPrefix+"%")
+	}
+	var rows []row
+	if err := q.Group("rl.order_item_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]int, len(rows))
+	for _, r := range rows {
+		out[r.OrderItemID] = r.Qty
+	}
+	return out, nil
+}
+
+// CheckRefundLinesInTx runs the line checks of RefundOrder (quantities still
+// refundable, amount within what is left and not under dispute) without
+// creating a refund. Callers that move stock before refunding use it so the
+// refund is not rejected afterwards.
+func CheckRefundLinesInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, lines []RefundLineInput) error {
+	built, err := buildLinesInTx(ctx, tx, ord, ord.Currency, RefundOrderInput{Lines: lines})
+	if err != nil || len(built) == 0 {
+		return err
+	}
+	disputed, err := disputedInTx(ctx, tx, ord.ID)
+	if err != nil {
+		return err
+	}
+	if sumLines(built) > ord.TotalCents-ord.RefundedCents-disputed {
+		if disputed > 0 {
+			return ErrPaymentDisputed
+		}
+		return ErrRefundExceedsRemaining
+	}
+	return nil
+}
+
+// refundStockLinesInTx maps a refund's item lines to variant quantities for restocking.
+func refundStockLinesInTx(ctx context.Context, tx *gorm.DB, refundID string) ([]checkout.StockLine, error) {
+	type row struct {
+		VariantID string `gorm:"column:variant_id"`
+		Qty       int    `gorm:"column:qty"`
+	}
+	var rows []row
+	if err := tx.WithContext(ctx).
+		Table("refund_lines rl").
+		Select("oi.variant_id, SUM(rl.qty) AS qty").
+		Joins("JOIN order_items oi ON oi.id = rl.order_item_id").
+		Where("rl.refund_id = ? AND rl.kind = ?", refundID, LineItem).
+		Group("oi.variant_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]checkout.StockLine, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, checkout.StockLine{VariantID: r.VariantID, Qty: r.Qty})
+	}
+	return out, nil
+}
+
+// ListRefunds returns an order's refunds with their lines, newest first.
+func ListRefunds(ctx context.Context, db *gorm.DB, orderID string) ([]Refund, map[string][]RefundLine, error) {
+	var refunds []Refund
+	if err := db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at DESC").Find(&refunds).Error; err != nil {
+		return nil, nil, err
+	}
+	lines := map[string][]RefundLine{}
+	if len(refunds) == 0 {
+		return refunds, lines, nil
+	}
+	ids := make([]string, 0, len(refunds))
+	for _, r := range refunds {
+		ids = append(ids, r.ID)
+	}
+	var rows []RefundLine
+	if err := db.WithContext(ctx).Where("refund_id IN ?", ids).Order("created_at ASC, kind ASC").Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, l := range rows {
+		lines[l.RefundID] = append(lines[l.RefundID], l)
+	}
+	return refunds, lines, nil
+}
@@ -0,0 +1,118 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+func TestItemAmounts(t *testing.T) {
+	items := []orders.OrderItem{
+		{ID: "a", Quantity: 2, LineTotalCents: 2000, TaxCents: 400},
+		{ID: "b", Quantity: 1, LineTotalCents: 3000, TaxCents: 600},
+	}
+
+	// tax-inclusive prices, no discount: plain pro rata
+	ord := orders.Order{PricesIncludeTax: true}
+	got := ItemAmounts(ord, items, map[string]int{"a": 1}, nil)
+	assert.Equal(t, map[string]LineAmount{"a": {AmountCents: 1000, TaxCents: 200}}, got)
+
+	// net prices: tax share is added
+	ord = orders.Order{PricesIncludeTax: false}
+	got = ItemAmounts(ord, items, map[string]int{"a": 1, "b": 1}, nil)
+	assert.Equal(t, map[string]LineAmount{"a": {AmountCents: 1200, TaxCents: 200}, "b": {AmountCents: 3600, TaxCents: 600}}, got)
+
+	// order discount is spread over the lines (10% of 5000); the stored line
+	// tax is already post-discount, so it is not reduced again
+	ord = orders.Order{PricesIncludeTax: true, DiscountCents: 500}
+	got = ItemAmounts(ord, items, map[string]int{"a": 2, "b": 5}, nil)
+	assert.Equal(t, map[string]LineAmount{"a": {AmountCents: 1800, TaxCents: 400}, "b": {AmountCents: 2700, TaxCents: 600}}, got, "qty is capped at the ordered quantity")
+
+	// net prices with a discount: discounted net + stored tax
+	ord = orders.Order{PricesIncludeTax: false, DiscountCents: 500}
+	got = ItemAmounts(ord, items, map[string]int{"b": 1}, nil)
+	assert.Equal(t, map[string]LineAmount{"b": {AmountCents: 2700 + 600, TaxCents: 600}}, got)
+}
+
+func TestItemAmounts_DiscountAsAllocatedAtCheckout(t *testing.T) {
+	ord := orders.Order{PricesIncludeTax: true, DiscountCents: 100}
+	all := map[string]int{"a": 1, "b": 1, "c": 1}
+
+	// shares stored on the items at checkout are used as they are
+	items := []orders.OrderItem{
+		{ID: "a", VariantID: "v1", Quantity: 1, LineTotalCents: 1000, DiscountCents: 34},
+		{ID: "b", VariantID: "v2", Quantity: 1, LineTotalCents: 1000, DiscountCents: 33},
+		{ID: "c", VariantID: "v3", Quantity: 1, LineTotalCents: 1000, DiscountCents: 33},
+	}
+	got := ItemAmounts(ord, items, all, nil)
+	assert.Equal(t, map[string]LineAmount{"a": {AmountCents: 966}, "b": {AmountCents: 967}, "c": {AmountCents: 967}}, got)
+
+	// older orders: allocated in variant id order like CreateFromCart, so the
+	// rounding cent lands on v3 whatever order the items are read in
+	items = []orders.OrderItem{
+		{ID: "c", VariantID: "v3", Quantity: 1, LineTotalCents: 1000},
+		{ID: "a", VariantID: "v1", Quantity: 1, LineTotalCents: 1000},
+		{ID: "b", VariantID: "v2", Quantity: 1, LineTotalCents: 1000},
+	}
+	got = ItemAmounts(ord, items, all, nil)
+	assert.Equal(t, map[string]LineAmount{"a": {AmountCents: 967}, "b": {AmountCents: 967}, "c": {AmountCents: 966}}, got)
+	sum := 0
+	for _, l := range got {
+		sum += l.AmountCents
+	}
+	assert.Equal(t, 3000-100, sum, "line refunds add up to what was charged")
+}
+
+func TestRefundLines_LastUnitTakesTheRemainder(t *testing.T) {
+	db := setupDisputeDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		`ALTER TABLE orders ADD COLUMN refunded_cents INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN refunded_at DATETIME`,
+		`ALTER TABLE orders ADD COLUMN shipping_cents INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN prices_include_tax BOOLEAN NOT NULL DEFAULT 1`,
+		`ALTER TABLE order_items ADD COLUMN product_name TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE order_items ADD COLUMN sku TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE order_items ADD COLUMN line_total_cents INTEGER NOT NULL DEFAULT 0`,
+		`CREATE TABLE refund_lines (
+			id TEXT PRIMARY KEY, refund_id TEXT NOT NULL, kind TEXT NOT NULL, order_item_id TEXT, label TEXT NOT NULL,
+			qty INTEGER NOT NULL DEFAULT 0, amount_cents INTEGER NOT NULL, tax_cents INTEGER NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL, created_at DATETIME NOT NULL)`,
+		// 40.01 üç birime tam bölünmez; kargo 9.99
+		`UPDATE orders SET total_cents = 5000, shipping_cents = 999 WHERE id = 'o1'`,
+		`INSERT INTO products (id) VALUES ('pr')`,
+		`INSERT INTO product_variants (id, product_id) VALUES ('v1', 'pr')`,
+		`INSERT INTO order_items (id, order_id, variant_id, unit_price_cents, currency, quantity, line_total_cents, created_at)
+			VALUES ('oi1', 'o1', 'v1', 1334, 'EUR', 3, 4001, CURRENT_TIMESTAMP)`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+	refunds := NewRefundService(db, NewRegistry(NewMockProvider("", 0)), nil, "")
+	wh := NewWebhookService(db)
+	refund := func(key string, in RefundOrderInput) int {
+		in.OrderID, in.ActorUserID, in.IdempotencyKey = "o1", "u1", key
+		res, err := refunds.RefundOrder(ctx, in)
+		require.NoError(t, err)
+		var rf Refund
+		require.NoError(t, db.First(&rf, "id = ?", res.RefundID).Error)
+		require.NoError(t, wh.Handle(ctx, "mock", WebhookEvent{EventID: "evt-" + key, Type: "refund.succeeded", RefundRef: ptrVal(rf.ProviderRef)}, []byte(`{}`)))
+		return res.AmountCents
+	}
+
+	var got []int
+	for _, key := range []string{"r-1", "r-2", "r-3"} {
+		got = append(got, refund(key, RefundOrderInput{Lines: []RefundLineInput{{OrderItemID: "oi1", Qty: 1}}}))
+	}
+	assert.Equal(t, []int{1333, 1334, 1334}, got, "units add up to the line total")
+	assert.Equal(t, "partially_refunded", statusOf(t, db, "orders", "o1"))
+
+	assert.Equal(t, 999, refund("r-4", RefundOrderInput{ShippingCents: 999}))
+	assert.Equal(t, "refunded", statusOf(t, db, "orders", "o1"))
+	var refunded int
+	require.NoError(t, db.Table("orders").Select("refunded_cents").Where("id = ?", "o1").Scan(&refunded).Error)
+	assert.Equal(t, 5000, refunded)
+}
@@ -11,32 +11,51 @@ import (
 	"gorm.io/gorm/clause"
 
 	"pehlione.com/app/internal/emails"
+	"pehlione.com/app/internal/modules/checkout"
 	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/giftcards"
 	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/pkg/view"
 )
 
 var (
-	ErrNoSucceededPayment = errors.New("no succeeded payment found")
-	ErrNotRefundable      = errors.New("order not refundable")
+	ErrNoSucceededPayment    = errors.New("no succeeded payment found")
+	ErrNotRefundable         = errors.New("order not refundable")
+	ErrExceedsCardRefundable = errors.New("refund exceeds the amount charged to the card")
+	ErrPaymentDisputed       = errors.New("refund exceeds the amount not under dispute")
+	ErrGiftCardRefund        = errors.New("refund reaches into purchased gift cards; refund their lines instead")
 )
 
+// ProviderStoreCredit is the Refund.Provider of refunds loaded onto store
+// credit instead of going back through the payment provider.
+const ProviderStoreCredit = "store_credit"
+
 type RefundService struct {
-	db       *gorm.DB
-	provider Provider
-	emailSvc *emailmod.OutboxService
-	baseURL  string
+	db        *gorm.DB
+	providers *Registry
+	emailSvc  *emailmod.OutboxService
+	baseURL   string
 }
 
-func NewRefundService(db *gorm.DB, p Provider, emailSvc *emailmod.OutboxService, baseURL string) *RefundService {
-	return &RefundService{db: db, provider: p, emailSvc: emailSvc, baseURL: baseURL}
+func NewRefundService(db *gorm.DB, providers *Registry, emailSvc *emailmod.OutboxService, baseURL string) *RefundService {
+	return &RefundService{db: db, providers: providers, emailSvc: emailSvc, baseURL: baseURL}
 }
 
 type RefundOrderInput struct {
 	OrderID        string
 	ActorUserID    string // admin
 	IdempotencyKey string
-	AmountCents    int // 0 => full remaining
+	AmountCents    int // 0 => full remaining; Lines/Shipping/Adjustment verilirse yok sayılır
 	Reason         string
+	Restock        bool // iade edilen kalemler (tam iadede tüm sipariş) stoğa geri eklensin mi
+	// karta değil müşterinin store credit bakiyesine iade; tamamı hediye kartıyla
+	// ödenen siparişlerde zorunlu olarak bu yol kullanılır
+	ToStoreCredit bool
+
+	// kalem bazlı iade: tutar kalemlerden (vergi payı dahil) hesaplanır
+	Lines           []RefundLineInput
+	ShippingCents   int
+	AdjustmentCents int // serbest düzeltme; negatif olabilir (ör. stoklama ücreti)
 }
 
 type RefundOrderResult struct {
@@ -55,6 +74,7 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 	var ord orders.Order
 	var pay Payment
 	var ref Refund
+	var lines []RefundLine
 	var amount int
 
 	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -65,37 +85,49 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 			return err
 		}
 
-		// refundable gate (MVP): paid/partially_refunded states only
-		if ord.Status != "paid" && ord.Status != "partially_refunded" {
+		// refundable gate: paid (also after shipping, for returns) or partially
+		// refunded; cancelled only for a card payment that arrived afterwards
+		if !isRefundableStatus(ord.Status) && ord.Status != "cancelled" {
 			return ErrNotRefundable
 		}
 
 		// find succeeded payment (latest)
+		hasPayment := true
 		if err := tx.WithContext(ctx).
 			Order("updated_at DESC").
 			First(&pay, "order_id = ? AND status = ?", ord.ID, StatusSucceeded).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			// tamamı hediye kartıyla ödenmiş: sağlayıcı ödemesi yok
+			if ord.GiftCardCents == 0 {
 				return ErrNoSucceededPayment
 			}
-			return err
+			hasPayment = false
 		}
-
-		remaining := ord.TotalCents - ord.RefundedCents
-		if remaining <= 0 {
+		toStoreCredit := in.ToStoreCredit || !hasPayment
+		if ord.Status == "cancelled" && toStoreCredit {
+			// hediye kartı payı iptalde zaten serbest bırakıldı
 			return ErrNotRefundable
 		}
 
-		amount = in.AmountCents
-		if amount <= 0 {
-			amount = remaining // full remaining
+		// açık ya da kaybedilmiş itiraz (chargeback) tutarı banka üzerinden
+		// müşteriye döner; aynı parayı bir de iade olarak ödememek için düşülür
+		disputed, err := disputedInTx(ctx, tx, ord.ID)
+		if err != nil {
+			return err
 		}
-		if amount > remaining {
-			amount = remaining
+		remaining := ord.TotalCents - ord.RefundedCents - disputed
+		if remaining <= 0 {
+			if disputed > 0 {
+				return ErrPaymentDisputed
+			}
+			return ErrNotRefundable
 		}
 
-		// idempotency: payment_id + key
+		// idempotency: order_id + key
 		var existing Refund
-		e := tx.WithContext(ctx).First(&existing, "payment_id = ? AND idempotency_key = ?", pay.ID, in.IdempotencyKey).Error
+		e := tx.WithContext(ctx).First(&existing, "order_id = ? AND idempotency_key = ?", ord.ID, in.IdempotencyKey).Error
 		if e == nil {
 			ref = existing
 			return nil
@@ -104,6 +136,70 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 			return e
 		}
 
+		// iade, ödemenin alındığı para biriminde yapılır
+		currency := pay.Currency
+		if !hasPayment || currency == "" {
+			currency = ord.Currency
+		}
+
+		if lines, err = buildLinesInTx(ctx, tx, ord, currency, in); err != nil {
+			return err
+		}
+		if lines != nil {
+			amount = sumLines(lines)
+			if amount <= 0 {
+				return ErrNotRefundable
+			}
+			if amount > remaining {
+				if disputed > 0 {
+					return ErrPaymentDisputed
+				}
+				return ErrRefundExceedsRemaining
+			}
+		} else {
+			amount = in.AmountCents
+			if amount <= 0 {
+				amount = remaining // full remaining
+			}
+			if amount > remaining && disputed > 0 {
+				return ErrPaymentDisputed
+			}
+			if amount > remaining {
+				amount = remaining
+			}
+		}
+
+		// karta en fazla karttan tahsil edilen kadar iade edilir; hediye kartı
+		// payı ancak store credit olarak geri verilebilir
+		if !toStoreCredit {
+			cardLeft, err := cardRefundableInTx(ctx, tx, pay)
+			if err != nil {
+				return err
+			}
+			if amount > cardLeft {
+				if lines != nil || in.AmountCents > 0 || cardLeft <= 0 {
+					return ErrExceedsCardRefundable
+				}
+				amount = cardLeft // "kalanın tamamı": kart payı kadar
+			}
+		}
+
+		// satın alınan hediye kartları iadeyle birlikte kapatılır; harcanmış
+		// kart iade edilemez
+		refundID := uuid.NewString()
+		if err := voidPurchasedGiftCardsInTx(ctx, tx, ord, lines, amount, refundID); err != nil {
+			return err
+		}
+
+		provider := pay.Provider // iade, ödemeyi alan sağlayıcıdan yapılır
+		var paymentID *string
+		if hasPayment {
+			paymentID = &pay.ID
+		}
+		if toStoreCredit {
+			provider = ProviderStoreCredit
+		}
+
 		now := time.Now()
 		var reasonPtr *string
 		if in.Reason != "" {
@@ -112,21 +208,33 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 		}
 
 		ref = Refund{
-			ID:             uuid.NewString(),
+			ID:             refundID,
 			OrderID:        ord.ID,
-			PaymentID:      pay.ID,
-			Provider:       s.provider.Name(),
+			PaymentID:      paymentID,
+			Provider:       provider,
 			ProviderRef:    nil,
 			Status:         StatusInitiated,
 			AmountCents:    amount,
-			Currency:       ord.Currency,
+			Currency:       currency,
 			IdempotencyKey: in.IdempotencyKey,
 			Reason:         reasonPtr,
+			Restock:        in.Restock,
 			ErrorMessage:   nil,
 			CreatedAt:      now,
 			UpdatedAt:      now,
 		}
-		return tx.WithContext(ctx).Create(&ref).Error
+		if err := tx.WithContext(ctx).Create(&ref).Error; err != nil {
+			return err
+		}
+		for i := range lines {
+			lines[i].ID = uuid.NewString()
+			lines[i].RefundID = ref.ID
+			lines[i].CreatedAt = now
+		}
+		if len(lines) > 0 {
+			return tx.WithContext(ctx).Create(&lines).Error
+		}
+		return nil
 	})
 	if err != nil {
 		return RefundOrderResult{}, err
@@ -137,20 +245,24 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 		return RefundOrderResult{RefundID: ref.ID, Status: ref.Status, AmountCents: ref.AmountCents, Idempotent: true}, nil
 	}
 
-	// Phase-2: provider refund (outside tx)
-	paymentRef := ""
-	if pay.ProviderRef != nil {
-		paymentRef = *pay.ProviderRef
+	// Phase-2: provider refund (outside tx); store credit Phase-3'te yüklenir
+	resp := RefundResponse{Status: StatusSucceeded}
+	var perr error
+	if ref.Provider != ProviderStoreCredit {
+		provider, err := s.providers.Get(ref.Provider)
+		if err != nil {
+			return RefundOrderResult{}, err
+		}
+		resp, perr = provider.RefundPayment(ctx, RefundRequest{
+			OrderID:        ord.ID,
+			PaymentID:      pay.ID,
+			PaymentRef:     ptrVal(pay.ProviderRef),
+			AmountCents:    ref.AmountCents,
+			Currency:       ref.Currency,
+			IdempotencyKey: in.IdempotencyKey,
+			Reason:         in.Reason,
+		})
 	}
-	resp, perr := s.provider.RefundPayment(ctx, RefundRequest{
-		OrderID:        ord.ID,
-		PaymentID:      pay.ID,
-		PaymentRef:     paymentRef,
-		AmountCents:    ref.AmountCents,
-		Currency:       ref.Currency,
-		IdempotencyKey: in.IdempotencyKey,
-		Reason:         in.Reason,
-	})
 
 	// Phase-3: finalize (tx)
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -190,6 +302,9 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 			if err := tx.WithContext(ctx).Model(&Refund{}).Where("id = ?", ref.ID).Updates(upd).Error; err != nil {
 				return err
 			}
+			if err := giftcards.RestoreInTx(ctx, tx, "refund", ref.ID); err != nil {
+				return err
+			}
 
 			// ledger: refund_failed (optional but good for audit)
 			fe := orders.FinancialEntry{
@@ -221,17 +336,32 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 		}
 
 		// succeeded
+		event := "refund_succeeded"
+		if ref.Provider == ProviderStoreCredit {
+			card, err := creditStoreCreditInTx(ctx, tx, ord, ref)
+			if err != nil {
+				return err
+			}
+			upd["provider_ref"] = card.ID
+			event = "refund_store_credit"
+		}
 		upd["status"] = StatusSucceeded
 		upd["error_message"] = nil
 		if err := tx.WithContext(ctx).Model(&Refund{}).Where("id = ?", ref.ID).Updates(upd).Error; err != nil {
 			return err
 		}
+		creditNote, err := assignCreditNoteInTx(ctx, tx, ref.ID, now)
+		if err != nil {
+			return err
+		}
+		ref.CreditNoteNumber = &creditNote
+		ref.CreditNoteIssuedAt = &now
 
-		// ledger: refund_succeeded (-out)
+		// ledger: refund_succeeded / refund_store_credit (-out)
 		fe := orders.FinancialEntry{
 			ID:          uuid.NewString(),
 			OrderID:     ord.ID,
-			Event:       "refund_succeeded",
+			Event:       event,
 			AmountCents: -ref.AmountCents,
 			Currency:    ord.Currency,
 			RefType:     "refund",
@@ -255,6 +385,9 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 		} else {
 			newStatus = "partially_refunded"
 		}
+		if ord.Status == "cancelled" {
+			newStatus = ord.Status // iptalden sonra gelen ödemenin iadesi
+		}
 
 		if err := tx.WithContext(ctx).Model(&orders.Order{}).
 			Where("id = ?", ord.ID).
@@ -267,6 +400,10 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 			return err
 		}
 
+		if err := restockRefundInTx(ctx, tx, ref, newStatus, in.ActorUserID); err != nil {
+			return err
+		}
+
 		// order_events (audit)
 		ev := orders.OrderEvent{
 			ID:          uuid.NewString(),
@@ -282,25 +419,7 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 			return err
 		}
 
-		var orderItems []orders.OrderItem
-		orderItems, _ = s.loadOrderItems(ctx, tx, ord.ID)
-
-		if s.emailSvc != nil {
-			if emailAddr, err := s.lookupOrderEmail(ctx, tx, ord); err == nil && emailAddr != "" {
-				statusLabel := "Refunded"
-				if newStatus == "partially_refunded" {
-					statusLabel = "Partially refunded"
-				}
-				reason := strings.TrimSpace(in.Reason)
-				payload := emails.BuildOrderPayload(s.baseURL, ord, orderItems, statusLabel, reason)
-				payload["PreviewText"] = "Refund processed - funds will post shortly."
-				_ = s.emailSvc.EnqueueTx(ctx, tx, emailmod.Job{
-					To:       emailAddr,
-					Template: emailmod.TemplateOrderRefunded,
-					Payload:  payload,
-				})
-			}
-		}
+		enqueueRefundEmailInTx(ctx, tx, s.emailSvc, s.baseURL, ord, ref, newStatus)
 
 		return nil
 	})
@@ -317,21 +436,131 @@ func (s *RefundService) RefundOrder(ctx context.Context, in RefundOrderInput) (R
 
 func ptr(s string) *string { return &s }
 
-func (s *RefundService) lookupOrderEmail(ctx context.Context, tx *gorm.DB, ord orders.Order) (string, error) {
-	if ord.GuestEmail != nil && *ord.GuestEmail != "" {
-		return *ord.GuestEmail, nil
+// cardRefundableInTx is what is left to refund on a provider payment: its
+// amount minus refunds that succeeded or are still in flight, and minus
+// open or lost disputes (the bank already pulled or holds that money).
+func cardRefundableInTx(ctx context.Context, tx *gorm.DB, pay Payment) (int, error) {
+	var refunded int
+	if err := tx.WithContext(ctx).Model(&Refund{}).
+		Select("COALESCE(SUM(amount_cents), 0)").
+		Where("payment_id = ? AND provider = ? AND status IN ?", pay.ID, pay.Provider, []string{StatusInitiated, StatusSucceeded}).
+		Scan(&refunded).Error; err != nil {
+		return 0, err
 	}
-	if ord.UserID != nil && *ord.UserID != "" {
-		var email string
-		if err := tx.WithContext(ctx).Table("users").Select("email").Where("id = ?", *ord.UserID).Take(&email).Error; err == nil {
-			return email, nil
+	var disputed int
+	if err := tx.WithContext(ctx).Model(&Dispute{}).
+		Select("COALESCE(SUM(amount_cents), 0)").
+		Where("payment_id = ? AND status IN ?", pay.ID, []string{DisputeOpen, DisputeLost}).
+		Scan(&disputed).Error; err != nil {
+		return 0, err
+	}
+	return pay.AmountCents - refunded - disputed, nil
+}
+
+// disputedInTx sums the open and lost disputes of an order. Won disputes
+// gave the money back to the shop and don't count.
+func disputedInTx(ctx context.Context, tx *gorm.DB, orderID string) (int, error) {
+	var sum int
+	err := tx.WithContext(ctx).Model(&Dispute{}).
+		Select("COALESCE(SUM(amount_cents), 0)").
+		Where("order_id = ? AND status IN ?", orderID, []string{DisputeOpen, DisputeLost}).
+		Scan(&sum).Error
+	return sum, err
+}
+
+func isRefundableStatus(status string) bool {
+	switch status {
+	case "paid", "shipped", "delivered", "partially_refunded":
+		return true
+	default:
+		return false
+	}
+}
+
+// restockRefundInTx puts stock back for a restock refund: the refunded item
+// lines, or the whole order once it is fully refunded. Amount-only partial
+// refunds can't be mapped to items and don't restock.
+func restockRefundInTx(ctx context.Context, tx *gorm.DB, r Refund, orderStatus, actorUserID string) error {
+	if !r.Restock {
+		return nil
+	}
+	in := checkout.RestockInput{
+		OrderID:     r.OrderID,
+		ActorUserID: actorUserID,
+		RefType:     "refund",
+		RefID:       r.ID,
+	}
+	if orderStatus != "refunded" {
+		lines, err := refundStockLinesInTx(ctx, tx, r.ID)
+		if err != nil || len(lines) == 0 {
+			return err
 		}
+		in.Lines = lines
 	}
-	return "", nil
+	_, err := checkout.RestockOrderInTx(ctx, tx, in)
+	return err
+}
+
+func refundLinesInTx(ctx context.Context, tx *gorm.DB, refundID string) ([]RefundLine, error) {
+	var out []RefundLine
+	err := tx.WithContext(ctx).Where("refund_id = ?", refundID).Order("created_at ASC, kind ASC").Find(&out).Error
+	return out, err
 }
 
-func (s *RefundService) loadOrderItems(ctx context.Context, tx *gorm.DB, orderID string) ([]orders.OrderItem, error) {
+func refundLinesPayload(lines []RefundLine) []map[string]any {
+	out := make([]map[string]any, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, map[string]any{
+			"Label":  l.Label,
+			"Qty":    l.Qty,
+			"Amount": view.MoneyFromCents(l.AmountCents, l.Currency),
+			"Tax":    view.MoneyFromCents(l.TaxCents, l.Currency),
+		})
+	}
+	return out
+}
+
+func orderItemsInTx(ctx context.Context, tx *gorm.DB, orderID string) ([]orders.OrderItem, error) {
 	var items []orders.OrderItem
 	err := tx.WithContext(ctx).Order("created_at ASC").Find(&items, "order_id = ?", orderID).Error
 	return items, err
 }
+
+// enqueueRefundEmailInTx queues the order_refunded email of a succeeded
+// refund with its credit note attached. Both the synchronous refund and the
+// refund.succeeded webhook (and so the reconciler) send it from here.
+// newStatus is the order status after the refund. Best effort: a failed
+// enqueue doesn't roll back the refund.
+func enqueueRefundEmailInTx(ctx context.Context, tx *gorm.DB, emailSvc *emailmod.OutboxService, baseURL string, ord orders.Order, r Refund, newStatus string) {
+	if emailSvc == nil {
+		return
+	}
+	emailAddr, err := orderEmailInTx(ctx, tx, ord)
+	if err != nil || emailAddr == "" {
+		return
+	}
+	orderItems, _ := orderItemsInTx(ctx, tx, ord.ID)
+
+	statusLabel := "Refunded"
+	if newStatus == "partially_refunded" {
+		statusLabel = "Partially refunded"
+	}
+	reason := strings.TrimSpace(ptrVal(r.Reason))
+	payload := emails.BuildOrderPayload(baseURL, ord, orderItems, statusLabel, reason)
+	payload["PreviewText"] = "Refund processed - funds will post shortly."
+	payload["RefundTotal"] = view.MoneyFromCents(r.AmountCents, r.Currency)
+	if rl, err := refundLinesInTx(ctx, tx, r.ID); err == nil && len(rl) > 0 {
+		payload["RefundLines"] = refundLinesPayload(rl)
+	}
+	job := emailmod.Job{
+		To:       emailAddr,
+		Template: emailmod.TemplateOrderRefunded,
+		Payload:  payload,
+	}
+	// dekont PDF'i üretilemezse e-posta eksiz gider; dekont sipariş sayfasında kalır
+	if att, err := creditNoteAttachmentInTx(ctx, tx, ord, r); err == nil {
+		payload["CreditNoteNumber"] = ptrVal(r.CreditNoteNumber)
+		job.Attachments = []emailmod.Attachment{att}
+	}
+	_ = emailSvc.EnqueueTx(ctx, tx, job)
+}
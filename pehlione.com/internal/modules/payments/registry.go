@@ -0,0 +1,159 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNoProvider      = errors.New("no payment provider for method/currency")
+	ErrUnknownProvider = errors.New("unknown payment provider")
+)
+
+// Route sends payments of a method and currency to a provider. Empty
+// Method/Currency (or "*") match anything.
+type Route struct {
+	Method   string
+	Currency string
+	Provider string
+}
+
+// Registry holds every configured provider. New payments are routed by
+// method and currency; captures, refunds and webhooks always go to the
+// provider recorded on the Payment.
+type Registry struct {
+	providers map[string]Provider
+	routes    []Route
+	def       string
+}
+
+// NewRegistry registers providers; the first one is the default route.
+func NewRegistry(ps ...Provider) *Registry {
+	r := &Registry{providers: map[string]Provider{}}
+	for _, p := range ps {
+		r.Register(p)
+	}
+	return r
+}
+
+func (r *Registry) Register(p Provider) {
+	name := p.Name()
+	if r.def == "" {
+		r.def = name
+	}
+	r.providers[name] = p
+}
+
+// AddRoute appends a route; routes are matched in order.
+func (r *Registry) AddRoute(rt Route) error {
+	if _, ok := r.providers[rt.Provider]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownProvider, rt.Provider)
+	}
+	rt.Method = normalizeWildcard(strings.ToLower(rt.Method))
+	rt.Currency = normalizeWildcard(strings.ToUpper(rt.Currency))
+	r.routes = append(r.routes, rt)
+	return nil
+}
+
+// Get returns a provider by name (Payment.Provider, /webhooks/:provider).
+func (r *Registry) Get(name string) (Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Route picks the provider for a new payment.
+func (r *Registry) Route(method, currency string) (Provider, error) {
+	method = strings.ToLower(strings.TrimSpace(method))
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	for _, rt := range r.routes {
+		if (rt.Method == "" || rt.Method == method) && (rt.Currency == "" || rt.Currency == currency) {
+			return r.providers[rt.Provider], nil
+		}
+	}
+	if r.def == "" {
+		return nil, ErrNoProvider
+	}
+	return r.providers[r.def], nil
+}
+
+// RegistryConfig lists the enabled providers and their settings
+// (PAYMENT_* environment, see config.PaymentConfig).
+type RegistryConfig struct {
+	Providers            []string
+	Routes               string
+	APIKey               string
+	WebhookSecret        string
+	APIBaseURL           string
+	MockWebhookSecret    string
+	MockWebhookTolerance int
+	MockHostedPageURL    string // set => the mock provider redirects to a local hosted page
+}
+
+// NewRegistryFromConfig builds the registry shared by the web app and the
+// worker: every enabled provider, then the payment routes.
+func NewRegistryFromConfig(cfg RegistryConfig) (*Registry, error) {
+	r := NewRegistry()
+	for _, name := range cfg.Providers {
+		switch name {
+		case "mock":
+			mp := NewMockProvider(cfg.MockWebhookSecret, cfg.MockWebhookTolerance)
+			mp.HostedPageURL = cfg.MockHostedPageURL
+			r.Register(mp)
+		case "stripe":
+			if cfg.APIKey == "" || cfg.WebhookSecret == "" {
+				return nil, errors.New("stripe provider requires PAYMENT_API_KEY and PAYMENT_WEBHOOK_SECRET")
+			}
+			r.Register(NewStripeProvider(StripeConfig{
+				APIKey:        cfg.APIKey,
+				WebhookSecret: cfg.WebhookSecret,
+				BaseURL:       cfg.APIBaseURL,
+			}))
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+		}
+	}
+	routes, err := ParseRoutes(cfg.Routes)
+	if err != nil {
+		return nil, err
+	}
+	for _, rt := range routes {
+		if err := r.AddRoute(rt); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// ParseRoutes parses "card:EUR=stripe,paypal:*=mock,*:*=mock".
+func ParseRoutes(s string) ([]Route, error) {
+	var out []Route
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		match, provider, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(provider) == "" {
+			return nil, fmt.Errorf("invalid payment route %q", part)
+		}
+		method, currency, _ := strings.Cut(match, ":")
+		out = append(out, Route{
+			Method:   strings.TrimSpace(method),
+			Currency: strings.TrimSpace(currency),
+			Provider: strings.ToLower(strings.TrimSpace(provider)),
+		})
+	}
+	return out, nil
+}
+
+func normalizeWildcard(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedProvider struct {
+	MockProvider
+	name string
+}
+
+func (p namedProvider) Name() string { return p.name }
+
+func TestRegistryRoute(t *testing.T) {
+	reg := NewRegistry(
+		namedProvider{name: "mock"},
+		namedProvider{name: "wallet"},
+		namedProvider{name: "cards_us"},
+	)
+
+	routes, err := ParseRoutes("paypal:*=wallet, card:usd=cards_us")
+	require.NoError(t, err)
+	for _, rt := range routes {
+		require.NoError(t, reg.AddRoute(rt))
+	}
+	assert.ErrorIs(t, reg.AddRoute(Route{Provider: "nope"}), ErrUnknownProvider)
+
+	cases := []struct{ method, currency, want string }{
+		{"paypal", "EUR", "wallet"},
+		{"card", "USD", "cards_us"},
+		{"card", "EUR", "mock"}, // default: first registered
+		{"", "TRY", "mock"},
+	}
+	for _, tc := range cases {
+		p, err := reg.Route(tc.method, tc.currency)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, p.Name(), tc.method+"/"+tc.currency)
+	}
+
+	_, err = reg.Get("stripe")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	_, err = ParseRoutes("card:EUR")
+	assert.Error(t, err)
+}
+
+func TestNewRegistryFromConfig(t *testing.T) {
+	reg, err := NewRegistryFromConfig(RegistryConfig{
+		Providers:         []string{"mock", "stripe"},
+		Routes:            "card:USD=stripe",
+		APIKey:            "sk_test",
+		WebhookSecret:     "whsec",
+		MockHostedPageURL: "http://localhost:8080/mock-pay",
+	})
+	require.NoError(t, err)
+	p, err := reg.Route("card", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "stripe", p.Name())
+	p, err = reg.Route("card", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/mock-pay", p.(MockProvider).HostedPageURL)
+
+	_, err = NewRegistryFromConfig(RegistryConfig{Providers: []string{"stripe"}, APIKey: "sk_test"})
+	assert.Error(t, err, "stripe needs its webhook secret")
+	_, err = NewRegistryFromConfig(RegistryConfig{Providers: []string{"paypal"}})
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+	_, err = NewRegistryFromConfig(RegistryConfig{Providers: []string{"mock"}, Routes: "card:USD=stripe"})
+	assert.ErrorIs(t, err, ErrUnknownProvider, "route to a disabled provider")
+}
@@ -9,31 +9,43 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"pehlione.com/app/internal/modules/checkout"
 	"pehlione.com/app/internal/modules/orders"
 )
 
 type Service struct {
-	db       *gorm.DB
-	provider Provider
+	db            *gorm.DB
+	providers     *Registry
+	captureMethod string
 }
 
-func NewService(db *gorm.DB, p Provider) *Service {
-	return &Service{db: db, provider: p}
+func NewService(db *gorm.DB, providers *Registry) *Service {
+	return &Service{db: db, providers: providers, captureMethod: CaptureAutomatic}
+}
+
+// SetCaptureMethod selects automatic capture or authorize-then-capture (manual).
+func (s *Service) SetCaptureMethod(m string) {
+	if m == CaptureManual {
+		s.captureMethod = CaptureManual
+		return
+	}
+	s.captureMethod = CaptureAutomatic
 }
 
 type PayOrderInput struct {
 	OrderID        string
 	ActorUserID    *string // order.user_id varsa match etmeli
 	IdempotencyKey string  // SSR hidden field
-	ReturnURL      string
-	CancelURL      string
+	ReturnURL      string  // absolute; "token" query param is appended
+	CancelURL      string  // absolute; "token" query param is appended
 }
 
 type PayOrderResult struct {
-	OrderID    string
-	PaymentID  string
-	Status     string
-	Idempotent bool
+	OrderID     string
+	PaymentID   string
+	Status      string
+	RedirectURL string // Status == requires_redirect
+	Idempotent  bool
 }
 
 func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResult, error) {
@@ -44,6 +56,8 @@ func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResul
 	// Phase-1: order lock + idempotency check + payment initiated create
 	var createdPayment Payment
 	var ord orders.Order
+	settled := false
+	reused := false
 
 	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Order row lock
@@ -66,28 +80,58 @@ func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResul
 			return ErrOrderNotPayable
 		}
 
+		// tamamı hediye kartıyla karşılandı: sağlayıcı çağrısı yok
+		if ord.GiftCardCents > 0 && ord.AmountDueCents() == 0 {
+			settled = true
+			return settleGiftCardOrderInTx(ctx, tx, ord, time.Now())
+		}
+
 		// Idempotency: aynı order+key payment varsa döndür
 		var existing Payment
 		e := tx.WithContext(ctx).First(&existing, "order_id = ? AND idempotency_key = ?", ord.ID, in.IdempotencyKey).Error
 		if e == nil {
 			createdPayment = existing
+			reused = true
 			return nil
 		}
 		if e != nil && !errors.Is(e, gorm.ErrRecordNotFound) {
 			return e
 		}
 
+		// bekleyen ödeme varken yenisi açılmaz (yeni anahtarla ikinci gönderim
+		// müşteriden iki kez tahsil edebilirdi): aynı ödemeye devam edilir
+		e = tx.WithContext(ctx).
+			Where("order_id = ? AND status IN ?", ord.ID, []string{StatusInitiated, StatusRequiresRedirect}).
+			Order("created_at DESC").
+			First(&existing).Error
+		if e == nil {
+			createdPayment = existing
+			reused = true
+			return nil
+		}
+		if !errors.Is(e, gorm.ErrRecordNotFound) {
+			return e
+		}
+
+		// sağlayıcı seçimi: ödeme yöntemi + para birimi
+		p, err := s.providers.Route(ptrVal(ord.PaymentMethod), ord.Currency)
+		if err != nil {
+			return err
+		}
+
 		now := time.Now()
+		token := newReturnToken()
 		createdPayment = Payment{
 			ID:             uuid.NewString(),
 			OrderID:        ord.ID,
-			Provider:       s.provider.Name(),
+			Provider:       p.Name(),
 			ProviderRef:    nil,
 			Status:         StatusInitiated,
-			AmountCents:    ord.TotalCents,
+			AmountCents:    ord.AmountDueCents(),
 			Currency:       ord.Currency,
 			IdempotencyKey: in.IdempotencyKey,
 			ErrorMessage:   nil,
+			ReturnToken:    &token,
 			CreatedAt:      now,
 			UpdatedAt:      now,
 		}
@@ -96,20 +140,38 @@ func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResul
 	if err != nil {
 		return PayOrderResult{}, err
 	}
+	if settled {
+		return PayOrderResult{OrderID: ord.ID, Status: StatusSucceeded}, nil
+	}
 
 	// Eğer idempotency ile var olan payment geldi ve succeeded ise hemen dön
-	if createdPayment.Status == StatusSucceeded {
+	if createdPayment.Status == StatusSucceeded || createdPayment.Status == StatusAuthorized {
+		return PayOrderResult{OrderID: ord.ID, PaymentID: createdPayment.ID, Status: createdPayment.Status, Idempotent: true}, nil
+	}
+	// yönlendirme bekleyen ödeme: aynı sağlayıcı sayfasına tekrar gönder
+	if createdPayment.Status == StatusRequiresRedirect && createdPayment.RedirectURL != nil {
+		return PayOrderResult{OrderID: ord.ID, PaymentID: createdPayment.ID, Status: createdPayment.Status, RedirectURL: *createdPayment.RedirectURL, Idempotent: true}, nil
+	}
+	// sağlayıcıda açılmış, sonucu webhook ile gelecek ödeme
+	if reused && createdPayment.Status == StatusInitiated && createdPayment.ProviderRef != nil {
 		return PayOrderResult{OrderID: ord.ID, PaymentID: createdPayment.ID, Status: createdPayment.Status, Idempotent: true}, nil
 	}
 
+	token := ptrVal(createdPayment.ReturnToken)
+	provider, err := s.providers.Get(createdPayment.Provider)
+	if err != nil {
+		return PayOrderResult{}, err
+	}
+
 	// Phase-2: provider çağrısı (tx dışında)
-	resp, perr := s.provider.CreatePayment(ctx, CreatePaymentRequest{
+	resp, perr := provider.CreatePayment(ctx, CreatePaymentRequest{
 		OrderID:        ord.ID,
-		AmountCents:    ord.TotalCents,
+		AmountCents:    createdPayment.AmountCents,
 		Currency:       ord.Currency,
-		IdempotencyKey: in.IdempotencyKey,
-		ReturnURL:      in.ReturnURL,
-		CancelURL:      in.CancelURL,
+		IdempotencyKey: createdPayment.IdempotencyKey,
+		ReturnURL:      withToken(in.ReturnURL, token),
+		CancelURL:      withToken(in.CancelURL, token),
+		CaptureMethod:  s.captureMethod,
 	})
 
 	// Phase-3: payment finalize + order update (tx)
@@ -146,6 +208,25 @@ func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResul
 			return nil
 		}
 
+		// 3-D Secure / hosted page: müşteri sağlayıcıya gider, dönüşte CompleteReturn
+		if resp.Status == StatusRequiresRedirect && resp.RedirectURL != "" {
+			updates["status"] = StatusRequiresRedirect
+			updates["redirect_url"] = resp.RedirectURL
+			return tx.WithContext(ctx).Model(&Payment{}).
+				Where("id = ?", createdPayment.ID).
+				Updates(updates).Error
+		}
+
+		// sync authorization (manual capture): capture on ship
+		if resp.Status == StatusAuthorized {
+			if err := tx.WithContext(ctx).Model(&Payment{}).
+				Where("id = ?", createdPayment.ID).
+				Updates(updates).Error; err != nil {
+				return err
+			}
+			return markAuthorizedInTx(ctx, tx, createdPayment, now)
+		}
+
 		// mock: succeeded
 		if resp.Status == StatusSucceeded {
 			updates["status"] = StatusSucceeded
@@ -162,7 +243,7 @@ func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResul
 				ID:          uuid.NewString(),
 				OrderID:     ord.ID,
 				Event:       "payment_succeeded",
-				AmountCents: ord.TotalCents, // +in
+				AmountCents: createdPayment.AmountCents, // +in
 				Currency:    ord.Currency,
 				RefType:     "payment",
 				RefID:       createdPayment.ID,
@@ -174,16 +255,24 @@ func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResul
 
 			// order status -> paid
 			paidAt := now
-			if err := tx.WithContext(ctx).Model(&orders.Order{}).
+			res := tx.WithContext(ctx).Model(&orders.Order{}).
 				Where("id = ? AND status = 'created'", ord.ID).
 				Updates(map[string]any{
 					"status":     "paid",
 					"paid_at":    &paidAt,
 					"updated_at": now,
-				}).Error; err != nil {
-				return err
+				})
+			if res.Error != nil {
+				return res.Error
 			}
-			return nil
+			if res.RowsAffected == 1 {
+				// reserved stock -> permanent deduction
+				if err := checkout.ConvertReservationsInTx(ctx, tx, ord.ID); err != nil {
+					return err
+				}
+				return orderPaidInTx(ctx, tx, ord.ID, now)
+			}
+			return flagLatePaymentInTx(ctx, tx, createdPayment, StatusSucceeded, now)
 		}
 
 		// default: failed
@@ -205,10 +294,19 @@ func (s *Service) PayOrder(ctx context.Context, in PayOrderInput) (PayOrderResul
 		finalStatus = StatusFailed
 	}
 
+	redirectURL := ""
+	if finalStatus == StatusRequiresRedirect {
+		if resp.RedirectURL == "" {
+			finalStatus = StatusFailed
+		}
+		redirectURL = resp.RedirectURL
+	}
+
 	return PayOrderResult{
-		OrderID:    ord.ID,
-		PaymentID:  createdPayment.ID,
-		Status:     finalStatus,
-		Idempotent: false,
+		OrderID:     ord.ID,
+		PaymentID:   createdPayment.ID,
+		Status:      finalStatus,
+		RedirectURL: redirectURL,
+		Idempotent:  false,
 	}, nil
 }
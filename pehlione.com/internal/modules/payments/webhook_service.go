@@ -13,6 +13,9 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"pehlione.com/app/internal/modules/checkout"
+	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/giftcards"
 	"pehlione.com/app/internal/modules/orders"
 )
 
@@ -21,18 +24,36 @@ type ProviderEvent struct {
 	Provider    string         `gorm:"type:varchar(64);not null;uniqueIndex:ux_provider_events_provider_event,priority:1"`
 	EventID     string         `gorm:"type:varchar(128);not null;uniqueIndex:ux_provider_events_provider_event,priority:2"`
 	EventType   string         `gorm:"type:varchar(64);not null"`
+	PaymentRef  *string        `gorm:"type:varchar(128)"`
+	RefundRef   *string        `gorm:"type:varchar(128)"`
+	DisputeRef  *string        `gorm:"type:varchar(128)"`
+	CheckoutRef *string        `gorm:"type:varchar(128)"`
+	Reason      *string        `gorm:"type:varchar(255)"`
+	AmountCents int            `gorm:"not null;default:0"`
+	Currency    *string        `gorm:"type:char(3)"`
 	PayloadJSON datatypes.JSON `gorm:"type:json;not null"`
 
 	ReceivedAt   time.Time  `gorm:"type:datetime(3);not null"`
 	ProcessedAt  *time.Time `gorm:"type:datetime(3)"`
 	ProcessError *string    `gorm:"type:varchar(255)"`
+	Attempts     int        `gorm:"not null;default:0"`
 }
 
 func (ProviderEvent) TableName() string { return "provider_events" }
 
+// RefundListener is told about refunds the provider settles asynchronously,
+// inside the webhook transaction. Implemented by returns.Service (returns
+// imports payments, not vice versa).
+type RefundListener interface {
+	RefundSucceededInTx(ctx context.Context, tx *gorm.DB, r Refund) error
+}
+
 type WebhookService struct {
-	db     *gorm.DB
-	logger *slog.Logger
+	db       *gorm.DB
+	logger   *slog.Logger
+	listener RefundListener
+	emailSvc *emailmod.OutboxService
+	baseURL  string
 }
 
 func NewWebhookService(db *gorm.DB) *WebhookService {
@@ -43,38 +64,96 @@ func (s *WebhookService) SetLogger(logger *slog.Logger) {
 	s.logger = logger
 }
 
+// SetRefundListener hooks refund.succeeded webhooks (and reconciled refunds,
+// which go through the same path) back to the flow that started the refund.
+func (s *WebhookService) SetRefundListener(l RefundListener) {
+	s.listener = l
+}
+
+// SetEmailService enables the order_refunded email (with the credit note)
+// for refunds the provider confirms asynchronously.
+func (s *WebhookService) SetEmailService(emailSvc *emailmod.OutboxService, baseURL string) {
+	s.emailSvc = emailSvc
+	s.baseURL = baseURL
+}
+
+// Handle records the event and applies it. A re-delivered event is a no-op
+// once processed; if its earlier attempt failed it is applied again.
 func (s *WebhookService) Handle(ctx context.Context, providerName string, ev WebhookEvent, rawBody []byte) error {
 	// event payload'ı persist etmek için:
 	payload, _ := json.RawMessage(rawBody).MarshalJSON()
 
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		now := time.Now()
-
-		pe := ProviderEvent{
-			ID:           uuid.NewString(),
-			Provider:     providerName,
-			EventID:      ev.EventID,
-			EventType:    ev.Type,
-			PayloadJSON:  datatypes.JSON(payload),
-			ReceivedAt:   now,
-			ProcessedAt:  nil,
-			ProcessError: nil,
+	pe, err := s.recordEvent(ctx, providerName, ev, payload)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to persist provider event", "provider", providerName, "event_id", ev.EventID, "err", err)
+		return err
+	}
+	if pe.ProcessedAt != nil {
+		// event daha önce işlendi => 200 OK dönmek için nil
+		s.logger.InfoContext(ctx, "webhook event deduplicated", "provider", providerName, "event_id", ev.EventID, "type", ev.Type)
+		return nil
+	}
+	return s.process(ctx, pe.ID, providerName, ev)
+}
+
+// recordEvent inserts the event or returns the existing row
+// (dedupe: unique(provider,event_id)).
+func (s *WebhookService) recordEvent(ctx context.Context, providerName string, ev WebhookEvent, payload []byte) (ProviderEvent, error) {
+	var pe ProviderEvent
+	err := s.db.WithContext(ctx).First(&pe, "provider = ? AND event_id = ?", providerName, ev.EventID).Error
+	if err == nil {
+		return pe, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return pe, err
+	}
+
+	pe = ProviderEvent{
+		ID:          uuid.NewString(),
+		Provider:    providerName,
+		EventID:     ev.EventID,
+		EventType:   ev.Type,
+		PaymentRef:  nilIfEmpty(ev.PaymentRef),
+		RefundRef:   nilIfEmpty(ev.RefundRef),
+		DisputeRef:  nilIfEmpty(ev.DisputeRef),
+		CheckoutRef: nilIfEmpty(ev.CheckoutRef),
+		Reason:      nilIfEmpty(ev.Reason),
+		AmountCents: ev.AmountCents,
+		Currency:    nilIfEmpty(ev.Currency),
+		PayloadJSON: datatypes.JSON(payload),
+		ReceivedAt:  time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(&pe).Error; err != nil {
+		if isDup(err) {
+			// eşzamanlı teslimat
+			err = s.db.WithContext(ctx).First(&pe, "provider = ? AND event_id = ?", providerName, ev.EventID).Error
 		}
+		return pe, err
+	}
+	return pe, nil
+}
 
-		// dedupe: unique(provider,event_id)
-		if err := tx.WithContext(ctx).Create(&pe).Error; err != nil {
-			if isDup(err) {
-				// event daha önce alındı => 200 OK dönmek için nil
-				s.logger.InfoContext(ctx, "webhook event deduplicated", "provider", providerName, "event_id", ev.EventID, "type", ev.Type)
-				return nil
-			}
-			s.logger.ErrorContext(ctx, "failed to persist provider event", "provider", providerName, "event_id", ev.EventID, "err", err)
+// process applies a recorded event under a row lock so concurrent
+// deliveries/replays apply it at most once. Failures are stored on the row.
+func (s *WebhookService) process(ctx context.Context, eventRowID, providerName string, ev WebhookEvent) error {
+	var applyErr error
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pe ProviderEvent
+		if err := tx.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&pe, "id = ?", eventRowID).Error; err != nil {
 			return err
 		}
+		if pe.ProcessedAt != nil {
+			return nil
+		}
 
 		// apply
-		var applyErr error
 		switch ev.Type {
+		case "payment.authorized":
+			applyErr = s.applyPaymentAuthorized(ctx, tx, providerName, ev)
+		case "payment.captured":
+			applyErr = s.applyPaymentCaptured(ctx, tx, providerName, ev)
 		case "payment.succeeded":
 			applyErr = s.applyPaymentSucceeded(ctx, tx, providerName, ev)
 		case "payment.failed":
@@ -83,44 +162,55 @@ func (s *WebhookService) Handle(ctx context.Context, providerName string, ev Web
 			applyErr = s.applyRefundSucceeded(ctx, tx, providerName, ev)
 		case "refund.failed":
 			applyErr = s.applyRefundFailed(ctx, tx, providerName, ev)
+		case "dispute.opened":
+			applyErr = s.applyDisputeOpened(ctx, tx, providerName, ev)
+		case "dispute.won":
+			applyErr = s.applyDisputeClosed(ctx, tx, providerName, ev, DisputeWon)
+		case "dispute.lost":
+			applyErr = s.applyDisputeClosed(ctx, tx, providerName, ev, DisputeLost)
+		case EventIgnored:
+			// kayıt altına alındı; uygulanacak geçiş yok
 		default:
 			applyErr = errors.New("unknown webhook event type")
 		}
-
 		if applyErr != nil {
-			msg := truncate(applyErr.Error(), 250)
-			if err := tx.WithContext(ctx).Model(&ProviderEvent{}).
-				Where("id = ?", pe.ID).
-				Updates(map[string]any{"process_error": msg}).Error; err != nil {
-				return err
-			}
-			// Log apply error for debugging
-			s.logger.ErrorContext(ctx, "webhook event apply failed", "provider", providerName, "event_id", ev.EventID, "type", ev.Type, "error", msg)
-			// 500 dönmek için error propagate (provider retry edebilsin)
+			// kısmi geçişleri geri al; hata aşağıda ayrı kaydedilir
 			return applyErr
 		}
 
-		processed := now
-		if err := tx.WithContext(ctx).Model(&ProviderEvent{}).
+		processed := time.Now()
+		return tx.WithContext(ctx).Model(&ProviderEvent{}).
 			Where("id = ?", pe.ID).
-			Updates(map[string]any{"processed_at": &processed, "process_error": nil}).Error; err != nil {
-			return err
+			Updates(map[string]any{
+				"processed_at":  &processed,
+				"process_error": nil,
+				"attempts":      gorm.Expr("attempts + 1"),
+			}).Error
+	})
+
+	if applyErr != nil {
+		msg := truncate(applyErr.Error(), 250)
+		if uerr := s.db.WithContext(ctx).Model(&ProviderEvent{}).
+			Where("id = ? AND processed_at IS NULL", eventRowID).
+			Updates(map[string]any{"process_error": msg, "attempts": gorm.Expr("attempts + 1")}).Error; uerr != nil {
+			s.logger.ErrorContext(ctx, "failed to record webhook apply error", "provider", providerName, "event_id", ev.EventID, "err", uerr)
 		}
+		// Log apply error for debugging
+		s.logger.ErrorContext(ctx, "webhook event apply failed", "provider", providerName, "event_id", ev.EventID, "type", ev.Type, "error", msg)
+		// 500 dönmek için error propagate (provider retry edebilsin)
+		return applyErr
+	}
+	if err != nil {
+		return err
+	}
 
-		s.logger.InfoContext(ctx, "webhook event processed successfully", "provider", providerName, "event_id", ev.EventID, "type", ev.Type)
-		return nil
-	})
+	s.logger.InfoContext(ctx, "webhook event processed successfully", "provider", providerName, "event_id", ev.EventID, "type", ev.Type)
+	return nil
 }
 
 func (s *WebhookService) applyPaymentSucceeded(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) error {
-	if ev.PaymentRef == "" {
-		return errors.New("missing payment_ref")
-	}
-
-	var p Payment
-	if err := tx.WithContext(ctx).
-		Clauses(clause.Locking{Strength: "UPDATE"}).
-		First(&p, "provider = ? AND provider_ref = ?", provider, ev.PaymentRef).Error; err != nil {
+	p, err := lockEventPaymentInTx(ctx, tx, provider, ev)
+	if err != nil {
 		return err // bulunamazsa retry
 	}
 
@@ -130,6 +220,51 @@ func (s *WebhookService) applyPaymentSucceeded(ctx context.Context, tx *gorm.DB,
 	}
 
 	now := time.Now()
+	// authorized ödemede succeeded = capture
+	if p.Status == StatusAuthorized {
+		return markCapturedInTx(ctx, tx, p, now)
+	}
+
+	return markSucceededInTx(ctx, tx, p, now)
+}
+
+// lockEventPaymentInTx finds the payment a webhook event is about. Events of
+// a hosted checkout also match the session the payment was stored under;
+// its provider_ref is then switched to the payment the provider created.
+func lockEventPaymentInTx(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) (Payment, error) {
+	var p Payment
+	if ev.PaymentRef == "" && ev.CheckoutRef == "" {
+		return p, errors.New("missing payment_ref")
+	}
+	refs := []string{ev.PaymentRef}
+	if ev.CheckoutRef != "" {
+		refs = append(refs, ev.CheckoutRef)
+	}
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&p, "provider = ? AND provider_ref IN ?", provider, refs).Error; err != nil {
+		return p, err
+	}
+	if ev.PaymentRef != "" && ptrVal(p.ProviderRef) != ev.PaymentRef {
+		if err := setProviderRefInTx(ctx, tx, &p, ev.PaymentRef); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func setProviderRefInTx(ctx context.Context, tx *gorm.DB, p *Payment, ref string) error {
+	if err := tx.WithContext(ctx).Model(&Payment{}).
+		Where("id = ?", p.ID).
+		Update("provider_ref", ref).Error; err != nil {
+		return err
+	}
+	p.ProviderRef = &ref
+	return nil
+}
+
+// markSucceededInTx: payment -> succeeded, order created -> paid (automatic capture).
+func markSucceededInTx(ctx context.Context, tx *gorm.DB, p Payment, now time.Time) error {
 	if err := tx.WithContext(ctx).Model(&Payment{}).
 		Where("id = ?", p.ID).
 		Updates(map[string]any{
@@ -142,13 +277,25 @@ func (s *WebhookService) applyPaymentSucceeded(ctx context.Context, tx *gorm.DB,
 
 	// order -> paid (created ise)
 	paidAt := now
-	if err := tx.WithContext(ctx).Model(&orders.Order{}).
+	res := tx.WithContext(ctx).Model(&orders.Order{}).
 		Where("id = ? AND status = 'created'", p.OrderID).
 		Updates(map[string]any{
 			"status":     "paid",
 			"paid_at":    &paidAt,
 			"updated_at": now,
-		}).Error; err != nil {
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 1 {
+		// reserved stock -> permanent deduction
+		if err := checkout.ConvertReservationsInTx(ctx, tx, p.OrderID); err != nil {
+			return err
+		}
+		if err := orderPaidInTx(ctx, tx, p.OrderID, now); err != nil {
+			return err
+		}
+	} else if err := flagLatePaymentInTx(ctx, tx, p, StatusSucceeded, now); err != nil {
 		return err
 	}
 
@@ -165,18 +312,50 @@ func (s *WebhookService) applyPaymentSucceeded(ctx context.Context, tx *gorm.DB,
 	})
 }
 
-func (s *WebhookService) applyPaymentFailed(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) error {
-	if ev.PaymentRef == "" {
-		return errors.New("missing payment_ref")
+func (s *WebhookService) applyPaymentAuthorized(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) error {
+	p, err := lockEventPaymentInTx(ctx, tx, provider, ev)
+	if err != nil {
+		return err
 	}
 
-	var p Payment
-	if err := tx.WithContext(ctx).
-		Clauses(clause.Locking{Strength: "UPDATE"}).
-		First(&p, "provider = ? AND provider_ref = ?", provider, ev.PaymentRef).Error; err != nil {
+	// idempotent (capture daha önce gelmiş olabilir)
+	if p.Status == StatusAuthorized || p.Status == StatusSucceeded || p.Status == StatusVoided {
+		return nil
+	}
+
+	return markAuthorizedInTx(ctx, tx, p, time.Now())
+}
+
+func (s *WebhookService) applyPaymentCaptured(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) error {
+	p, err := lockEventPaymentInTx(ctx, tx, provider, ev)
+	if err != nil {
 		return err
 	}
-	if p.Status == StatusFailed {
+	if p.Status == StatusSucceeded {
+		return nil
+	}
+	if p.Status == StatusVoided {
+		return errors.New("capture for voided payment")
+	}
+
+	now := time.Now()
+	// authorized webhook'u kaçırıldıysa önce yetkilendirme
+	if p.Status != StatusAuthorized {
+		if err := markAuthorizedInTx(ctx, tx, p, now); err != nil {
+			return err
+		}
+	}
+	return markCapturedInTx(ctx, tx, p, now)
+}
+
+func (s *WebhookService) applyPaymentFailed(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) error {
+	p, err := lockEventPaymentInTx(ctx, tx, provider, ev)
+	if err != nil {
+		return err
+	}
+	// yalnızca bekleyen ödeme başarısız olur; geç/tekrar gelen bir hata (ya da
+	// başarılı ödemeden sonra süresi dolan checkout oturumu) sonucu değiştirmez
+	if p.Status != StatusInitiated && p.Status != StatusRequiresRedirect {
 		return nil
 	}
 
@@ -215,6 +394,12 @@ func (s *WebhookService) applyRefundSucceeded(ctx context.Context, tx *gorm.DB,
 		}).Error; err != nil {
 		return err
 	}
+	creditNote, err := assignCreditNoteInTx(ctx, tx, r.ID, now)
+	if err != nil {
+		return err
+	}
+	r.CreditNoteNumber = &creditNote
+	r.CreditNoteIssuedAt = &now
 
 	// order refunded totals
 	var o orders.Order
@@ -236,6 +421,9 @@ func (s *WebhookService) applyRefundSucceeded(ctx context.Context, tx *gorm.DB,
 	} else {
 		newStatus = "partially_refunded"
 	}
+	if o.Status == "cancelled" {
+		newStatus = o.Status // iptalden sonra gelen ödemenin iadesi
+	}
 
 	if err := tx.WithContext(ctx).Model(&orders.Order{}).
 		Where("id = ?", o.ID).
@@ -248,8 +436,12 @@ func (s *WebhookService) applyRefundSucceeded(ctx context.Context, tx *gorm.DB,
 		return err
 	}
 
+	if err := restockRefundInTx(ctx, tx, r, newStatus, ""); err != nil {
+		return err
+	}
+
 	// ledger: refund_succeeded (-)
-	return ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
+	if err := ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
 		ID:          uuid.NewString(),
 		OrderID:     r.OrderID,
 		Event:       "refund_succeeded",
@@ -258,7 +450,17 @@ func (s *WebhookService) applyRefundSucceeded(ctx context.Context, tx *gorm.DB,
 		RefType:     "refund",
 		RefID:       r.ID,
 		CreatedAt:   now,
-	})
+	}); err != nil {
+		return err
+	}
+
+	r.Status = StatusSucceeded
+	enqueueRefundEmailInTx(ctx, tx, s.emailSvc, s.baseURL, o, r, newStatus)
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.RefundSucceededInTx(ctx, tx, r)
 }
 
 func (s *WebhookService) applyRefundFailed(ctx context.Context, tx *gorm.DB, provider string, ev WebhookEvent) error {
@@ -286,6 +488,9 @@ func (s *WebhookService) applyRefundFailed(ctx context.Context, tx *gorm.DB, pro
 		}).Error; err != nil {
 		return err
 	}
+	if err := giftcards.RestoreInTx(ctx, tx, "refund", r.ID); err != nil {
+		return err
+	}
 
 	// ledger: refund_failed (0)
 	_ = ensureFinancialEntry(ctx, tx, orders.FinancialEntry{
@@ -6,6 +6,21 @@ import (
 	"gorm.io/datatypes"
 )
 
+// Product kinds. Gift card products are digital: no stock reservation, and
+// paying the order issues one code per unit.
+const (
+	KindStandard = "standard"
+	KindGiftCard = "gift_card"
+)
+
+// NormalizeKind maps unknown kinds to standard.
+func NormalizeKind(kind string) string {
+	if kind == KindGiftCard {
+		return KindGiftCard
+	}
+	return KindStandard
+}
+
 type Product struct {
 	ID          string    `gorm:"type:char(36);primaryKey"`
 	Name        string    `gorm:"type:varchar(255);not null"`
@@ -13,6 +28,8 @@ type Product struct {
 	Description string    `gorm:"type:text;not null"`
 	CategoryName string   `gorm:"type:varchar(255)"`
 	CategorySlug string   `gorm:"type:varchar(255)"`
+	TaxClass    string    `gorm:"type:varchar(32);not null;default:standard"`
+	Kind        string    `gorm:"type:varchar(16);not null;default:standard"` // standard|gift_card
 	Status      string    `gorm:"type:varchar(32);not null;default:active"`
 	CreatedAt   time.Time `gorm:"type:datetime(3);not null"`
 	UpdatedAt   time.Time `gorm:"type:datetime(3);not null"`
@@ -32,8 +49,11 @@ type Variant struct {
 	CompareAtCents int            `gorm:"not null;default:0"`
 	Currency       string         `gorm:"type:char(3);not null;default:EUR"`
 	Stock          int            `gorm:"not null;default:0"`
-	CreatedAt      time.Time      `gorm:"type:datetime(3);not null"`
-	UpdatedAt      time.Time      `gorm:"type:datetime(3);not null"`
+	WeightGrams    int            `gorm:"not null;default:0"`
+	// LowStockThreshold: stok bu değere inince admin'e e-posta (0 = kapalı)
+	LowStockThreshold int       `gorm:"not null;default:0"`
+	CreatedAt         time.Time `gorm:"type:datetime(3);not null"`
+	UpdatedAt         time.Time `gorm:"type:datetime(3);not null"`
 }
 
 func (Variant) TableName() string { return "product_variants" }
@@ -3,11 +3,15 @@ package products
 import (
 	"context"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/inventory"
 )
 
 type Repo struct{ db *gorm.DB }
@@ -31,13 +35,15 @@ func (r *Repo) Get(ctx context.Context, id string) (Product, error) {
 	return p, err
 }
 
-func (r *Repo) CreateProduct(ctx context.Context, name, slug, desc, status string) (Product, error) {
+func (r *Repo) CreateProduct(ctx context.Context, name, slug, desc, status, taxClass, kind string) (Product, error) {
 	p := Product{
 		ID:          uuid.NewString(),
 		Name:        name,
 		Slug:        slug,
 		Description: desc,
 		Status:      status,
+		TaxClass:    taxClass,
+		Kind:        NormalizeKind(kind),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -47,7 +53,7 @@ func (r *Repo) CreateProduct(ctx context.Context, name, slug, desc, status strin
 	return p, nil
 }
 
-func (r *Repo) UpdateProduct(ctx context.Context, id, name, slug, desc, status string) error {
+func (r *Repo) UpdateProduct(ctx context.Context, id, name, slug, desc, status, taxClass, kind string) error {
 	return r.db.WithContext(ctx).Model(&Product{}).
 		Where("id = ?", id).
 		Updates(map[string]any{
@@ -55,6 +61,8 @@ func (r *Repo) UpdateProduct(ctx context.Context, id, name, slug, desc, status s
 			"slug":        slug,
 			"description": desc,
 			"status":      status,
+			"tax_class":   taxClass,
+			"kind":        NormalizeKind(kind),
 			"updated_at":  time.Now(),
 		}).Error
 }
@@ -63,19 +71,40 @@ func (r *Repo) DeleteProduct(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&Product{}, "id = ?", id).Error
 }
 
-func (r *Repo) AddVariant(ctx context.Context, productID, sku string, optionsJSON []byte, priceCents int, currency string, stock int) (Variant, error) {
+// AddVariant creates the variant with zero stock and books the initial
+// quantity as a manual adjustment, so the stock ledger starts from zero.
+func (r *Repo) AddVariant(ctx context.Context, productID, sku string, optionsJSON []byte, priceCents int, currency string, stock, weightGrams, lowStockThreshold int, actorUserID string) (Variant, error) {
 	v := Variant{
-		ID:         uuid.NewString(),
-		ProductID:  productID,
-		SKU:        sku,
-		Options:    optionsJSON,
-		PriceCents: priceCents,
-		Currency:   currency,
-		Stock:      stock,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:                uuid.NewString(),
+		ProductID:         productID,
+		SKU:               sku,
+		Options:           optionsJSON,
+		PriceCents:        priceCents,
+		Currency:          currency,
+		Stock:             0,
+		WeightGrams:       weightGrams,
+		LowStockThreshold: lowStockThreshold,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
 	}
-	if err := r.db.WithContext(ctx).Create(&v).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&v).Error; err != nil {
+			return err
+		}
+		if stock == 0 {
+			return nil
+		}
+		_, err := inventory.ApplyInTx(ctx, tx, inventory.Movement{
+			VariantID:   v.ID,
+			Delta:       stock,
+			Reason:      inventory.ReasonAdjust,
+			ActorUserID: inventory.Ptr(actorUserID),
+			Note:        inventory.Ptr("initial stock"),
+		})
+		v.Stock = stock
+		return err
+	})
+	if err != nil {
 		return Variant{}, err
 	}
 	return v, nil
@@ -102,16 +131,59 @@ func (r *Repo) AddImage(ctx context.Context, productID, url string, position int
 	return im, nil
 }
 
-func (r *Repo) UpdateVariant(ctx context.Context, productID, variantID string, priceCents int, currency string, stock int, optionsJSON []byte) error {
-	return r.db.WithContext(ctx).Model(&Variant{}).
-		Where("id = ? AND product_id = ?", variantID, productID).
-		Updates(map[string]any{
-			"price_cents":  priceCents,
-			"currency":     currency,
-			"stock":        stock,
-			"options_json": optionsJSON,
-			"updated_at":   time.Now(),
-		}).Error
+// UpdateVariant saves the variant fields. levels holds the new on-hand
+// quantity per location id; each change is written to the stock ledger as a
+// manual adjustment instead of overwriting the number silently.
+func (r *Repo) UpdateVariant(ctx context.Context, productID, variantID string, priceCents int, currency string, weightGrams, lowStockThreshold int, optionsJSON []byte, levels map[string]int, actorUserID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var v Variant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&v, "id = ? AND product_id = ?", variantID, productID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&Variant{}).
+			Where("id = ?", v.ID).
+			Updates(map[string]any{
+				"price_cents":         priceCents,
+				"currency":            currency,
+				"weight_grams":        weightGrams,
+				"low_stock_threshold": lowStockThreshold,
+				"options_json":        optionsJSON,
+				"updated_at":          time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+
+		current, err := inventory.LevelsForVariants(ctx, tx, []string{v.ID})
+		if err != nil {
+			return err
+		}
+
+		locIDs := make([]string, 0, len(levels))
+		for id := range levels {
+			locIDs = append(locIDs, id)
+		}
+		sort.Strings(locIDs)
+
+		for _, locID := range locIDs {
+			delta := levels[locID] - current[v.ID][locID]
+			if delta == 0 {
+				continue
+			}
+			loc := locID
+			if _, err := inventory.ApplyInTx(ctx, tx, inventory.Movement{
+				VariantID:   v.ID,
+				LocationID:  &loc,
+				Delta:       delta,
+				Reason:      inventory.ReasonAdjust,
+				ActorUserID: inventory.Ptr(actorUserID),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (r *Repo) UpdateVariantSKU(ctx context.Context, productID, variantID string, newSKU string) error {
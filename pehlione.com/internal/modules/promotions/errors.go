@@ -0,0 +1,13 @@
+package promotions
+
+import "errors"
+
+var (
+	ErrCodeNotFound         = errors.New("promotion code not found")
+	ErrCodeInactive         = errors.New("promotion code is not active")
+	ErrCodeExpired          = errors.New("promotion code is outside its validity window")
+	ErrUsageLimitReached    = errors.New("promotion usage limit reached")
+	ErrCustomerLimitReached = errors.New("promotion per-customer limit reached")
+	ErrMinSubtotalNotMet    = errors.New("order subtotal below promotion minimum")
+	ErrNotApplicable        = errors.New("promotion does not apply to these items")
+)
@@ -0,0 +1,97 @@
+package promotions
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Promotion kinds.
+const (
+	KindPercent = "percent"
+	KindFixed   = "fixed"
+)
+
+// Promotion statuses.
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+type Promotion struct {
+	ID          string  `gorm:"type:char(36);primaryKey"`
+	Code        string  `gorm:"type:varchar(64);not null;uniqueIndex:ux_promotions_code"`
+	Description *string `gorm:"type:varchar(255)"`
+
+	Kind           string  `gorm:"type:varchar(16);not null"`
+	PercentOff     int     `gorm:"not null;default:0"`
+	AmountOffCents int     `gorm:"not null;default:0"`
+	Currency       *string `gorm:"type:char(3)"` // fixed indirimler için (base currency)
+
+	MinSubtotalCents int        `gorm:"not null;default:0"`
+	StartsAt         *time.Time `gorm:"type:datetime(3)"`
+	EndsAt           *time.Time `gorm:"type:datetime(3)"`
+
+	// 0 = limitsiz
+	MaxRedemptions int `gorm:"not null;default:0"`
+	MaxPerCustomer int `gorm:"not null;default:0"`
+	RedeemedCount  int `gorm:"not null;default:0"`
+
+	ProductIDsJSON    datatypes.JSON `gorm:"column:product_ids_json;type:json"`
+	CategorySlugsJSON datatypes.JSON `gorm:"column:category_slugs_json;type:json"`
+
+	Status string `gorm:"type:varchar(16);not null"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Promotion) TableName() string { return "promotions" }
+
+// ProductIDs returns the product scope; empty means every product.
+func (p Promotion) ProductIDs() []string { return decodeList(p.ProductIDsJSON) }
+
+// CategorySlugs returns the category scope; empty means every category.
+func (p Promotion) CategorySlugs() []string { return decodeList(p.CategorySlugsJSON) }
+
+type Redemption struct {
+	ID          string  `gorm:"type:char(36);primaryKey"`
+	PromotionID string  `gorm:"type:char(36);not null;uniqueIndex:ux_promotion_redemptions_order,priority:1"`
+	OrderID     string  `gorm:"type:char(36);not null;uniqueIndex:ux_promotion_redemptions_order,priority:2"`
+	UserID      *string `gorm:"type:char(36)"`
+	Email       *string `gorm:"type:varchar(255)"`
+	Code        string  `gorm:"type:varchar(64);not null"`
+
+	DiscountCents     int    `gorm:"not null"`
+	Currency          string `gorm:"type:char(3);not null"`
+	BaseDiscountCents int    `gorm:"not null"`
+	BaseCurrency      string `gorm:"type:char(3);not null"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Redemption) TableName() string { return "promotion_redemptions" }
+
+func decodeList(raw datatypes.JSON) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var out []string
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// EncodeList marshals a scope list; empty lists are stored as NULL.
+func EncodeList(list []string) datatypes.JSON {
+	if len(list) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return nil
+	}
+	return datatypes.JSON(b)
+}
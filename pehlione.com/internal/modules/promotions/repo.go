@@ -0,0 +1,77 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type Repo struct {
+	db *gorm.DB
+}
+
+func NewRepo(db *gorm.DB) *Repo { return &Repo{db: db} }
+
+func (r *Repo) List(ctx context.Context) ([]Promotion, error) {
+	var out []Promotion
+	err := r.db.WithContext(ctx).Order("created_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *Repo) Get(ctx context.Context, id string) (Promotion, error) {
+	var p Promotion
+	err := r.db.WithContext(ctx).First(&p, "id = ?", id).Error
+	return p, err
+}
+
+func (r *Repo) Create(ctx context.Context, p *Promotion) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+// Update overwrites the editable fields; redeemed_count is left untouched.
+func (r *Repo) Update(ctx context.Context, p Promotion) error {
+	res := r.db.WithContext(ctx).
+		Model(&Promotion{}).
+		Where("id = ?", p.ID).
+		Updates(map[string]any{
+			"code":                p.Code,
+			"description":         p.Description,
+			"kind":                p.Kind,
+			"percent_off":         p.PercentOff,
+			"amount_off_cents":    p.AmountOffCents,
+			"currency":            p.Currency,
+			"min_subtotal_cents":  p.MinSubtotalCents,
+			"starts_at":           p.StartsAt,
+			"ends_at":             p.EndsAt,
+			"max_redemptions":     p.MaxRedemptions,
+			"max_per_customer":    p.MaxPerCustomer,
+			"product_ids_json":    p.ProductIDsJSON,
+			"category_slugs_json": p.CategorySlugsJSON,
+			"status":              p.Status,
+			"updated_at":          p.UpdatedAt,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListRedemptionsByOrder returns the promotions applied to an order.
+func (r *Repo) ListRedemptionsByOrder(ctx context.Context, orderID string) ([]Redemption, error) {
+	var out []Redemption
+	err := r.db.WithContext(ctx).Order("created_at ASC").Find(&out, "order_id = ?", orderID).Error
+	return out, err
+}
+
+func IsDuplicateKey(err error) bool {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return me.Number == 1062
+	}
+	return false
+}
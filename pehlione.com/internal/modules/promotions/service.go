@@ -0,0 +1,346 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/products"
+)
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Line is a cart/order line in the base currency, enriched with the scope
+// attributes a promotion can target.
+type Line struct {
+	ProductID    string
+	CategorySlug string
+	LineCents    int
+	// GiftCard lines are never discounted and do not count towards the
+	// minimum subtotal: the card is issued at face value.
+	GiftCard bool
+}
+
+// VariantLine is the unresolved form used by checkout previews.
+type VariantLine struct {
+	VariantID string
+	LineCents int
+}
+
+type ApplyInput struct {
+	Code         string
+	UserID       *string
+	Email        string
+	BaseCurrency string
+	Lines        []Line
+}
+
+type ApplyResult struct {
+	Promotion     Promotion
+	EligibleCents int
+	DiscountCents int // base currency
+}
+
+type RedeemInput struct {
+	OrderID           string
+	UserID            *string
+	Email             string
+	DiscountCents     int
+	Currency          string
+	BaseDiscountCents int
+	BaseCurrency      string
+}
+
+// NormalizeCode trims and upper-cases a customer supplied code.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Quote validates a code outside of a transaction (checkout preview).
+// The result is advisory; ApplyInTx re-validates under lock.
+func (s *Service) Quote(ctx context.Context, code string, userID *string, email, baseCurrency string, vlines []VariantLine) (ApplyResult, error) {
+	lines, err := s.resolveLines(ctx, vlines)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	return s.apply(ctx, s.db.WithContext(ctx), ApplyInput{
+		Code:         code,
+		UserID:       userID,
+		Email:        email,
+		BaseCurrency: baseCurrency,
+		Lines:        lines,
+	}, false)
+}
+
+// ApplyInTx locks the promotion row and validates window, limits and scope.
+// Call RedeemInTx in the same tx once the order exists.
+func (s *Service) ApplyInTx(ctx context.Context, tx *gorm.DB, in ApplyInput) (ApplyResult, error) {
+	return s.apply(ctx, tx.WithContext(ctx), in, true)
+}
+
+func (s *Service) apply(ctx context.Context, db *gorm.DB, in ApplyInput, lock bool) (ApplyResult, error) {
+	code := NormalizeCode(in.Code)
+	if code == "" {
+		return ApplyResult{}, ErrCodeNotFound
+	}
+
+	q := db
+	if lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var p Promotion
+	if err := q.First(&p, "code = ?", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ApplyResult{}, ErrCodeNotFound
+		}
+		return ApplyResult{}, err
+	}
+
+	if p.MaxRedemptions > 0 && p.RedeemedCount >= p.MaxRedemptions {
+		return ApplyResult{}, ErrUsageLimitReached
+	}
+
+	if p.MaxPerCustomer > 0 {
+		used, err := s.countCustomerRedemptions(ctx, db, p.ID, in.UserID, in.Email)
+		if err != nil {
+			return ApplyResult{}, err
+		}
+		if used >= int64(p.MaxPerCustomer) {
+			return ApplyResult{}, ErrCustomerLimitReached
+		}
+	}
+
+	eligible, discount, err := Evaluate(p, in.BaseCurrency, in.Lines, time.Now())
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	return ApplyResult{Promotion: p, EligibleCents: eligible, DiscountCents: discount}, nil
+}
+
+// RedeemInTx records the redemption against the order and bumps the usage counter.
+func (s *Service) RedeemInTx(ctx context.Context, tx *gorm.DB, res ApplyResult, in RedeemInput) error {
+	var email *string
+	if em := strings.ToLower(strings.TrimSpace(in.Email)); em != "" {
+		email = &em
+	}
+
+	r := Redemption{
+		ID:                uuid.NewString(),
+		PromotionID:       res.Promotion.ID,
+		OrderID:           in.OrderID,
+		UserID:            in.UserID,
+		Email:             email,
+		Code:              res.Promotion.Code,
+		DiscountCents:     in.DiscountCents,
+		Currency:          in.Currency,
+		BaseDiscountCents: in.BaseDiscountCents,
+		BaseCurrency:      in.BaseCurrency,
+		CreatedAt:         time.Now(),
+	}
+	if err := tx.WithContext(ctx).Create(&r).Error; err != nil {
+		return err
+	}
+
+	return tx.WithContext(ctx).
+		Model(&Promotion{}).
+		Where("id = ?", res.Promotion.ID).
+		Updates(map[string]any{
+			"redeemed_count": gorm.Expr("redeemed_count + 1"),
+			"updated_at":     time.Now(),
+		}).Error
+}
+
+// ReleaseOrderInTx gives back the codes used by a cancelled, unpaid order:
+// its redemptions are deleted and the usage counters decremented, so
+// single-use and limited codes can be used again. Returns the number of
+// redemptions released; calling it again releases nothing.
+func ReleaseOrderInTx(ctx context.Context, tx *gorm.DB, orderID string) (int, error) {
+	var rows []Redemption
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderID).
+		Order("promotion_id ASC").
+		Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, r := range rows {
+		if err := tx.WithContext(ctx).Delete(&Redemption{}, "id = ?", r.ID).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.WithContext(ctx).
+			Model(&Promotion{}).
+			Where("id = ? AND redeemed_count > 0", r.PromotionID).
+			Updates(map[string]any{
+				"redeemed_count": gorm.Expr("redeemed_count - 1"),
+				"updated_at":     now,
+			}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+// Evaluate checks status, validity window, scope and minimum subtotal and
+// returns the eligible subtotal and the discount, both in the base currency.
+// Gift card lines are left out of both.
+func Evaluate(p Promotion, baseCurrency string, lines []Line, now time.Time) (int, int, error) {
+	if p.Status != StatusActive {
+		return 0, 0, ErrCodeInactive
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return 0, 0, ErrCodeExpired
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return 0, 0, ErrCodeExpired
+	}
+
+	subtotal := 0
+	for _, l := range lines {
+		if !l.GiftCard {
+			subtotal += l.LineCents
+		}
+	}
+	if subtotal < p.MinSubtotalCents {
+		return 0, 0, ErrMinSubtotalNotMet
+	}
+
+	products := toSet(p.ProductIDs())
+	categories := toSet(p.CategorySlugs())
+	eligible := 0
+	for _, l := range lines {
+		if !l.GiftCard && inScope(l, products, categories) {
+			eligible += l.LineCents
+		}
+	}
+	if eligible <= 0 {
+		return 0, 0, ErrNotApplicable
+	}
+
+	discount := 0
+	switch p.Kind {
+	case KindPercent:
+		pct := p.PercentOff
+		if pct > 100 {
+			pct = 100
+		}
+		discount = int(math.Round(float64(eligible) * float64(pct) / 100))
+	case KindFixed:
+		if p.Currency != nil && *p.Currency != "" && !strings.EqualFold(*p.Currency, baseCurrency) {
+			return 0, 0, ErrNotApplicable
+		}
+		discount = p.AmountOffCents
+	default:
+		return 0, 0, ErrNotApplicable
+	}
+
+	if discount > eligible {
+		discount = eligible
+	}
+	if discount <= 0 {
+		return 0, 0, ErrNotApplicable
+	}
+	return eligible, discount, nil
+}
+
+func inScope(l Line, products, categories map[string]struct{}) bool {
+	if len(products) == 0 && len(categories) == 0 {
+		return true
+	}
+	if _, ok := products[strings.ToLower(l.ProductID)]; ok {
+		return true
+	}
+	if _, ok := categories[strings.ToLower(l.CategorySlug)]; ok && l.CategorySlug != "" {
+		return true
+	}
+	return false
+}
+
+func toSet(list []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		out[strings.ToLower(v)] = struct{}{}
+	}
+	return out
+}
+
+func (s *Service) countCustomerRedemptions(ctx context.Context, db *gorm.DB, promotionID string, userID *string, email string) (int64, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	hasUser := userID != nil && *userID != ""
+	if !hasUser && email == "" {
+		return 0, nil
+	}
+
+	q := db.WithContext(ctx).Model(&Redemption{}).Where("promotion_id = ?", promotionID)
+	switch {
+	case hasUser && email != "":
+		q = q.Where("user_id = ? OR email = ?", *userID, email)
+	case hasUser:
+		q = q.Where("user_id = ?", *userID)
+	default:
+		q = q.Where("email = ?", email)
+	}
+
+	var n int64
+	err := q.Count(&n).Error
+	return n, err
+}
+
+func (s *Service) resolveLines(ctx context.Context, vlines []VariantLine) ([]Line, error) {
+	if len(vlines) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(vlines))
+	for _, l := range vlines {
+		ids = append(ids, l.VariantID)
+	}
+
+	type row struct {
+		VariantID    string  `gorm:"column:variant_id"`
+		ProductID    string  `gorm:"column:product_id"`
+		CategorySlug *string `gorm:"column:category_slug"`
+		Kind         string  `gorm:"column:kind"`
+	}
+	var rows []row
+	if err := s.db.WithContext(ctx).
+		Table("product_variants AS v").
+		Select("v.id AS variant_id, v.product_id, p.category_slug, p.kind").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Where("v.id IN ?", ids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byVariant := make(map[string]row, len(rows))
+	for _, r := range rows {
+		byVariant[r.VariantID] = r
+	}
+
+	out := make([]Line, 0, len(vlines))
+	for _, l := range vlines {
+		r := byVariant[l.VariantID]
+		cat := ""
+		if r.CategorySlug != nil {
+			cat = *r.CategorySlug
+		}
+		out = append(out, Line{ProductID: r.ProductID, CategorySlug: cat, LineCents: l.LineCents, GiftCard: r.Kind == products.KindGiftCard})
+	}
+	return out, nil
+}
@@ -0,0 +1,136 @@
+package promotions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// sqlite only parses plain DATETIME columns back into time.Time,
+	// so the datetime(3) columns from the model tags are declared by hand.
+	require.NoError(t, db.Exec(`CREATE TABLE promotions (
+		id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, description TEXT,
+		kind TEXT NOT NULL, percent_off INTEGER NOT NULL DEFAULT 0, amount_off_cents INTEGER NOT NULL DEFAULT 0,
+		currency TEXT, min_subtotal_cents INTEGER NOT NULL DEFAULT 0,
+		starts_at DATETIME, ends_at DATETIME,
+		max_redemptions INTEGER NOT NULL DEFAULT 0, max_per_customer INTEGER NOT NULL DEFAULT 0,
+		redeemed_count INTEGER NOT NULL DEFAULT 0,
+		product_ids_json TEXT, category_slugs_json TEXT,
+		status TEXT NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE promotion_redemptions (
+		id TEXT PRIMARY KEY, promotion_id TEXT NOT NULL, order_id TEXT NOT NULL,
+		user_id TEXT, email TEXT, code TEXT NOT NULL,
+		discount_cents INTEGER NOT NULL, currency TEXT NOT NULL,
+		base_discount_cents INTEGER NOT NULL, base_currency TEXT NOT NULL,
+		created_at DATETIME NOT NULL, UNIQUE (promotion_id, order_id))`).Error)
+	return db
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	lines := []Line{
+		{ProductID: "p1", CategorySlug: "shoes", LineCents: 10000},
+		{ProductID: "p2", CategorySlug: "bags", LineCents: 5000},
+	}
+
+	t.Run("percent on whole cart", func(t *testing.T) {
+		p := Promotion{Kind: KindPercent, PercentOff: 10, Status: StatusActive}
+		eligible, discount, err := Evaluate(p, "TRY", lines, now)
+		require.NoError(t, err)
+		assert.Equal(t, 15000, eligible)
+		assert.Equal(t, 1500, discount)
+	})
+
+	t.Run("category scope limits eligible subtotal", func(t *testing.T) {
+		p := Promotion{Kind: KindPercent, PercentOff: 50, Status: StatusActive, CategorySlugsJSON: EncodeList([]string{"bags"})}
+		eligible, discount, err := Evaluate(p, "TRY", lines, now)
+		require.NoError(t, err)
+		assert.Equal(t, 5000, eligible)
+		assert.Equal(t, 2500, discount)
+	})
+
+	t.Run("fixed amount is capped and currency checked", func(t *testing.T) {
+		cur := "TRY"
+		p := Promotion{Kind: KindFixed, AmountOffCents: 20000, Currency: &cur, Status: StatusActive, ProductIDsJSON: EncodeList([]string{"p2"})}
+		_, discount, err := Evaluate(p, "TRY", lines, now)
+		require.NoError(t, err)
+		assert.Equal(t, 5000, discount)
+
+		_, _, err = Evaluate(p, "EUR", lines, now)
+		assert.ErrorIs(t, err, ErrNotApplicable)
+	})
+
+	t.Run("window and minimum subtotal", func(t *testing.T) {
+		ended := now.Add(-time.Hour)
+		p := Promotion{Kind: KindPercent, PercentOff: 10, Status: StatusActive, EndsAt: &ended}
+		_, _, err := Evaluate(p, "TRY", lines, now)
+		assert.ErrorIs(t, err, ErrCodeExpired)
+
+		p = Promotion{Kind: KindPercent, PercentOff: 10, Status: StatusActive, MinSubtotalCents: 20000}
+		_, _, err = Evaluate(p, "TRY", lines, now)
+		assert.ErrorIs(t, err, ErrMinSubtotalNotMet)
+	})
+
+	t.Run("gift cards are not discounted", func(t *testing.T) {
+		withCard := append([]Line{{ProductID: "gc", LineCents: 10000, GiftCard: true}}, lines...)
+		p := Promotion{Kind: KindPercent, PercentOff: 20, Status: StatusActive}
+		eligible, discount, err := Evaluate(p, "TRY", withCard, now)
+		require.NoError(t, err)
+		assert.Equal(t, 15000, eligible)
+		assert.Equal(t, 3000, discount)
+
+		p = Promotion{Kind: KindPercent, PercentOff: 20, Status: StatusActive, ProductIDsJSON: EncodeList([]string{"gc"})}
+		_, _, err = Evaluate(p, "TRY", withCard, now)
+		assert.ErrorIs(t, err, ErrNotApplicable)
+
+		p = Promotion{Kind: KindPercent, PercentOff: 20, Status: StatusActive, MinSubtotalCents: 20000}
+		_, _, err = Evaluate(p, "TRY", withCard, now)
+		assert.ErrorIs(t, err, ErrMinSubtotalNotMet, "gift cards do not count towards the minimum")
+	})
+}
+
+func TestService_ApplyAndRedeemLimits(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewService(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	require.NoError(t, db.Create(&Promotion{
+		ID: "promo-1", Code: "WELCOME10", Kind: KindPercent, PercentOff: 10,
+		MaxRedemptions: 2, MaxPerCustomer: 1, Status: StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}).Error)
+
+	lines := []Line{{ProductID: "p1", LineCents: 2000}}
+	redeem := func(orderID, email string) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			res, err := svc.ApplyInTx(ctx, tx, ApplyInput{Code: " welcome10 ", Email: email, BaseCurrency: "TRY", Lines: lines})
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, 200, res.DiscountCents)
+			return svc.RedeemInTx(ctx, tx, res, RedeemInput{
+				OrderID: orderID, Email: email,
+				DiscountCents: res.DiscountCents, Currency: "TRY",
+				BaseDiscountCents: res.DiscountCents, BaseCurrency: "TRY",
+			})
+		})
+	}
+
+	require.NoError(t, redeem("order-1", "a@example.com"))
+	assert.ErrorIs(t, redeem("order-2", "A@example.com"), ErrCustomerLimitReached)
+	require.NoError(t, redeem("order-3", "b@example.com"))
+	assert.ErrorIs(t, redeem("order-4", "c@example.com"), ErrUsageLimitReached)
+
+	var p Promotion
+	require.NoError(t, db.First(&p, "id = ?", "promo-1").Error)
+	assert.Equal(t, 2, p.RedeemedCount)
+}
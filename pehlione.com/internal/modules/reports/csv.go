@@ -0,0 +1,41 @@
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteSalesCSV writes sales rows with plain decimal amounts (no symbols),
+// suitable for spreadsheets.
+func WriteSalesCSV(w io.Writer, period string, rows []SalesRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		period, "currency", "orders", "gross", "refunds", "chargebacks", "net", "gift_cards",
+		"base_currency", "base_gross", "base_refunds", "base_chargebacks", "base_net", "base_gift_cards",
+	}); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := cw.Write([]string{
+			r.Bucket, r.Currency, strconv.Itoa(r.Orders),
+			Decimal(r.GrossCents), Decimal(r.RefundsCents), Decimal(r.ChargebackCents), Decimal(r.NetCents), Decimal(r.GiftCardCents),
+			r.BaseCurrency, Decimal(r.BaseGrossCents), Decimal(r.BaseRefundsCents), Decimal(r.BaseChargebackCents), Decimal(r.BaseNetCents), Decimal(r.BaseGiftCardCents),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Decimal formats cents as "-12.34".
+func Decimal(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
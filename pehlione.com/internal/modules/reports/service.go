@@ -0,0 +1,273 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Report periods.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// Ledger events by report column; anything else only counts towards net.
+// Gift card redemptions are a tender: the money was counted when the card
+// was sold, so they get their own column and stay out of gross and net.
+var (
+	grossEvents    = []string{"payment_succeeded", "payment_captured"}
+	refundEvents   = []string{"refund_succeeded", "refund_store_credit"}
+	disputeEvents  = []string{"dispute_opened", "dispute_won", "dispute_lost"}
+	giftCardEvents = []string{"gift_card_redeemed"}
+)
+
+// paidStatuses are orders whose ledger should equal TotalCents - RefundedCents.
+var paidStatuses = []string{"paid", "shipped", "delivered", "partially_refunded", "refunded"}
+
+type Params struct {
+	Period string
+	From   time.Time // inclusive
+	To     time.Time // exclusive
+}
+
+// SalesRow is one period bucket in one charge currency. Amounts are in the
+// charge currency; Base* are converted with the order's stored FX rate.
+type SalesRow struct {
+	Bucket   string
+	Currency string
+	Orders   int
+
+	GrossCents      int
+	RefundsCents    int // positive
+	ChargebackCents int // net dispute movement (negative while open/lost)
+	NetCents        int
+	GiftCardCents   int // paid with gift cards/store credit; not in gross or net
+
+	BaseCurrency        string
+	BaseGrossCents      int
+	BaseRefundsCents    int
+	BaseChargebackCents int
+	BaseNetCents        int
+	BaseGiftCardCents   int
+}
+
+// Totals are base-currency totals over all rows.
+type Totals struct {
+	BaseCurrency        string
+	BaseGrossCents      int
+	BaseRefundsCents    int
+	BaseChargebackCents int
+	BaseNetCents        int
+	BaseGiftCardCents   int
+}
+
+// Mismatch is a paid order whose ledger disagrees with its totals.
+type Mismatch struct {
+	OrderID       string
+	Status        string
+	Currency      string
+	TotalCents    int
+	RefundedCents int
+	ExpectedCents int // TotalCents - RefundedCents
+	LedgerCents   int // payment/refund entries (disputes excluded)
+	DiffCents     int // LedgerCents - ExpectedCents
+	Disputed      bool
+	CreatedAt     time.Time
+}
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+type ledgerRow struct {
+	OrderID      string
+	Event        string
+	AmountCents  int
+	Currency     string
+	CreatedAt    time.Time
+	FXRate       float64
+	BaseCurrency string
+}
+
+// Sales aggregates ledger entries into period buckets per currency.
+func (s *Service) Sales(ctx context.Context, p Params) ([]SalesRow, Totals, error) {
+	if err := validatePeriod(p.Period); err != nil {
+		return nil, Totals{}, err
+	}
+
+	var rows []ledgerRow
+	if err := s.db.WithContext(ctx).
+		Table("order_financial_entries e").
+		Select("e.order_id, e.event, e.amount_cents, e.currency, e.created_at, o.fx_rate, o.base_currency").
+		Joins("JOIN orders o ON o.id = e.order_id").
+		Where("e.created_at >= ? AND e.created_at < ?", p.From, p.To).
+		Order("e.created_at ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, Totals{}, err
+	}
+
+	type key struct{ bucket, currency string }
+	agg := map[key]*SalesRow{}
+	orderSeen := map[key]map[string]bool{}
+	var totals Totals
+
+	for _, r := range rows {
+		k := key{Bucket(r.CreatedAt, p.Period), r.Currency}
+		row := agg[k]
+		if row == nil {
+			row = &SalesRow{Bucket: k.bucket, Currency: k.currency, BaseCurrency: r.BaseCurrency}
+			agg[k] = row
+			orderSeen[k] = map[string]bool{}
+		}
+		if totals.BaseCurrency == "" {
+			totals.BaseCurrency = r.BaseCurrency
+		}
+		base := ToBase(r.AmountCents, r.FXRate)
+
+		if slices.Contains(grossEvents, r.Event) || slices.Contains(giftCardEvents, r.Event) {
+			if !orderSeen[k][r.OrderID] {
+				orderSeen[k][r.OrderID] = true
+				row.Orders++
+			}
+		}
+		switch {
+		case slices.Contains(giftCardEvents, r.Event):
+			row.GiftCardCents += r.AmountCents
+			row.BaseGiftCardCents += base
+			continue
+		case slices.Contains(grossEvents, r.Event):
+			row.GrossCents += r.AmountCents
+			row.BaseGrossCents += base
+		case slices.Contains(refundEvents, r.Event):
+			row.RefundsCents += -r.AmountCents
+			row.BaseRefundsCents += -base
+		case slices.Contains(disputeEvents, r.Event):
+			row.ChargebackCents += r.AmountCents
+			row.BaseChargebackCents += base
+		}
+		row.NetCents += r.AmountCents
+		row.BaseNetCents += base
+	}
+
+	out := make([]SalesRow, 0, len(agg))
+	for _, row := range agg {
+		out = append(out, *row)
+		totals.BaseGrossCents += row.BaseGrossCents
+		totals.BaseRefundsCents += row.BaseRefundsCents
+		totals.BaseChargebackCents += row.BaseChargebackCents
+		totals.BaseNetCents += row.BaseNetCents
+		totals.BaseGiftCardCents += row.BaseGiftCardCents
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Bucket != out[j].Bucket {
+			return out[i].Bucket < out[j].Bucket
+		}
+		return out[i].Currency < out[j].Currency
+	})
+	return out, totals, nil
+}
+
+// Reconcile lists paid orders whose payment/refund ledger sum differs from
+// TotalCents - RefundedCents. Dispute entries are excluded from the sum and
+// reported via Disputed.
+func (s *Service) Reconcile(ctx context.Context, limit int) ([]Mismatch, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+	isDispute := "e.event IN ('" + strings.Join(disputeEvents, "','") + "')"
+	ledgerSum := "COALESCE(SUM(CASE WHEN " + isDispute + " THEN 0 ELSE e.amount_cents END), 0)"
+
+	var rows []struct {
+		ID            string
+		Status        string
+		Currency      string
+		TotalCents    int
+		RefundedCents int
+		LedgerCents   int
+		Disputes      int
+		CreatedAt     time.Time
+	}
+	if err := s.db.WithContext(ctx).
+		Table("orders o").
+		Select("o.id, o.status, o.currency, o.total_cents, o.refunded_cents, o.created_at, "+
+			ledgerSum+" AS ledger_cents, "+
+			"SUM(CASE WHEN "+isDispute+" THEN 1 ELSE 0 END) AS disputes").
+		Joins("LEFT JOIN order_financial_entries e ON e.order_id = o.id").
+		Where("o.status IN ?", paidStatuses).
+		Group("o.id, o.status, o.currency, o.total_cents, o.refunded_cents, o.created_at").
+		Having(ledgerSum + " <> o.total_cents - o.refunded_cents").
+		Order("o.created_at DESC").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]Mismatch, 0, len(rows))
+	for _, r := range rows {
+		expected := r.TotalCents - r.RefundedCents
+		out = append(out, Mismatch{
+			OrderID:       r.ID,
+			Status:        r.Status,
+			Currency:      r.Currency,
+			TotalCents:    r.TotalCents,
+			RefundedCents: r.RefundedCents,
+			ExpectedCents: expected,
+			LedgerCents:   r.LedgerCents,
+			DiffCents:     r.LedgerCents - expected,
+			Disputed:      r.Disputes > 0,
+			CreatedAt:     r.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// Bucket labels t as 2026-01-08 (day), 2026-W02 (ISO week) or 2026-01 (month).
+func Bucket(t time.Time, period string) string {
+	switch period {
+	case PeriodWeek:
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	case PeriodMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// ToBase converts a charge-currency amount back to the base currency;
+// orders store charge = base * FXRate.
+func ToBase(cents int, rate float64) int {
+	if rate <= 0 || rate == 1 {
+		return cents
+	}
+	return int(math.Round(float64(cents) / rate))
+}
+
+// ParsePeriod normalizes a period query value (default day).
+func ParsePeriod(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if validatePeriod(s) != nil {
+		return PeriodDay
+	}
+	return s
+}
+
+func validatePeriod(p string) error {
+	switch p {
+	case PeriodDay, PeriodWeek, PeriodMonth:
+		return nil
+	}
+	return fmt.Errorf("invalid report period %q", p)
+}
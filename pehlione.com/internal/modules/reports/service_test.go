@@ -0,0 +1,149 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupReportsDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE orders (
+		id TEXT PRIMARY KEY, status TEXT NOT NULL, currency TEXT NOT NULL, total_cents INTEGER NOT NULL,
+		refunded_cents INTEGER NOT NULL DEFAULT 0, fx_rate REAL NOT NULL DEFAULT 1, base_currency TEXT NOT NULL,
+		created_at DATETIME NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE order_financial_entries (
+		id TEXT PRIMARY KEY, order_id TEXT NOT NULL, event TEXT NOT NULL, amount_cents INTEGER NOT NULL,
+		currency TEXT NOT NULL, ref_type TEXT, ref_id TEXT, created_at DATETIME NOT NULL)`).Error)
+	return db
+}
+
+func addOrder(t *testing.T, db *gorm.DB, id, status, currency string, total, refunded int, rate float64, at time.Time) {
+	require.NoError(t, db.Exec(`INSERT INTO orders (id, status, currency, total_cents, refunded_cents, fx_rate, base_currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'EUR', ?)`, id, status, currency, total, refunded, rate, at).Error)
+}
+
+func addEntry(t *testing.T, db *gorm.DB, id, orderID, event string, amount int, currency string, at time.Time) {
+	require.NoError(t, db.Exec(`INSERT INTO order_financial_entries (id, order_id, event, amount_cents, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, id, orderID, event, amount, currency, at).Error)
+}
+
+func TestSales_BucketsAndBaseCurrency(t *testing.T) {
+	db := setupReportsDB(t)
+	d1 := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	d2 := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	addOrder(t, db, "o1", "partially_refunded", "EUR", 10000, 2000, 1, d1)
+	addEntry(t, db, "e1", "o1", "payment_succeeded", 10000, "EUR", d1)
+	addEntry(t, db, "e2", "o1", "refund_succeeded", -2000, "EUR", d2)
+
+	// 1 EUR = 1.25 USD
+	addOrder(t, db, "o2", "paid", "USD", 5000, 0, 1.25, d1)
+	addEntry(t, db, "e3", "o2", "payment_captured", 5000, "USD", d1)
+	addEntry(t, db, "e4", "o2", "dispute_opened", -5000, "USD", d2)
+
+	svc := NewService(db)
+	p := Params{Period: PeriodDay, From: d1.Truncate(24 * time.Hour), To: d2.Truncate(24*time.Hour).AddDate(0, 0, 1)}
+	rows, totals, err := svc.Sales(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, SalesRow{Bucket: "2026-01-05", Currency: "EUR", Orders: 1, GrossCents: 10000, NetCents: 10000,
+		BaseCurrency: "EUR", BaseGrossCents: 10000, BaseNetCents: 10000}, rows[0])
+	assert.Equal(t, SalesRow{Bucket: "2026-01-05", Currency: "USD", Orders: 1, GrossCents: 5000, NetCents: 5000,
+		BaseCurrency: "EUR", BaseGrossCents: 4000, BaseNetCents: 4000}, rows[1])
+	assert.Equal(t, 2000, rows[2].RefundsCents)
+	assert.Equal(t, -5000, rows[3].ChargebackCents)
+	assert.Equal(t, -4000, rows[3].BaseChargebackCents)
+	assert.Equal(t, Totals{BaseCurrency: "EUR", BaseGrossCents: 14000, BaseRefundsCents: 2000, BaseChargebackCents: -4000, BaseNetCents: 8000}, totals)
+	for _, r := range rows {
+		assert.Equal(t, r.BaseNetCents, r.BaseGrossCents-r.BaseRefundsCents+r.BaseChargebackCents, r.Bucket)
+	}
+
+	p.Period = PeriodMonth
+	rows, _, err = svc.Sales(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "2026-01", rows[0].Bucket)
+	assert.Equal(t, 8000, rows[0].NetCents)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSalesCSV(&buf, PeriodMonth, rows[:1]))
+	assert.Equal(t, "month,currency,orders,gross,refunds,chargebacks,net,gift_cards,base_currency,base_gross,base_refunds,base_chargebacks,base_net,base_gift_cards\n"+
+		"2026-01,EUR,1,100.00,20.00,0.00,80.00,0.00,EUR,100.00,20.00,0.00,80.00,0.00\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteSalesCSV(&buf, PeriodMonth, rows[1:]))
+	assert.Contains(t, buf.String(), "\n2026-01,USD,1,50.00,0.00,-50.00,0.00,0.00,EUR,40.00,0.00,-40.00,0.00,0.00\n")
+
+	_, _, err = svc.Sales(context.Background(), Params{Period: "year"})
+	assert.Error(t, err)
+}
+
+func TestSales_GiftCardRedemptionsAreNotGross(t *testing.T) {
+	db := setupReportsDB(t)
+	at := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	// hediye kartı satışı: para burada girer
+	addOrder(t, db, "gc", "paid", "EUR", 2000, 0, 1, at)
+	addEntry(t, db, "e1", "gc", "payment_succeeded", 2000, "EUR", at)
+	// kartla kısmen ödenen sipariş
+	addOrder(t, db, "o1", "paid", "EUR", 5000, 0, 1, at)
+	addEntry(t, db, "e2", "o1", "payment_succeeded", 3000, "EUR", at)
+	addEntry(t, db, "e3", "o1", "gift_card_redeemed", 2000, "EUR", at)
+
+	p := Params{Period: PeriodDay, From: at.Truncate(24 * time.Hour), To: at.Truncate(24*time.Hour).AddDate(0, 0, 1)}
+	rows, totals, err := NewService(db).Sales(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, SalesRow{Bucket: "2026-01-05", Currency: "EUR", Orders: 2, GrossCents: 5000, NetCents: 5000, GiftCardCents: 2000,
+		BaseCurrency: "EUR", BaseGrossCents: 5000, BaseNetCents: 5000, BaseGiftCardCents: 2000}, rows[0])
+	assert.Equal(t, 2000, totals.BaseGiftCardCents)
+
+	// ledger mutabakatı kartla ödenen payı hâlâ içerir
+	items, err := NewService(db).Reconcile(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestReconcile_FlagsLedgerMismatch(t *testing.T) {
+	db := setupReportsDB(t)
+	at := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	// eşleşiyor (itiraz kayıtları hariç tutulur)
+	addOrder(t, db, "ok", "paid", "EUR", 5000, 0, 1, at)
+	addEntry(t, db, "e1", "ok", "payment_succeeded", 5000, "EUR", at)
+	addEntry(t, db, "e2", "ok", "dispute_opened", -5000, "EUR", at)
+
+	// iade ledger'a yazılmamış
+	addOrder(t, db, "bad", "partially_refunded", "EUR", 8000, 3000, 1, at.Add(time.Hour))
+	addEntry(t, db, "e3", "bad", "payment_succeeded", 8000, "EUR", at)
+
+	// ödenmemiş siparişler kontrol edilmez
+	addOrder(t, db, "pending", "pending_payment", "EUR", 1000, 0, 1, at)
+
+	items, err := NewService(db).Reconcile(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "bad", items[0].OrderID)
+	assert.Equal(t, 5000, items[0].ExpectedCents)
+	assert.Equal(t, 8000, items[0].LedgerCents)
+	assert.Equal(t, 3000, items[0].DiffCents)
+	assert.False(t, items[0].Disputed)
+}
+
+func TestBucket(t *testing.T) {
+	ts := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2026-01-01", Bucket(ts, PeriodDay))
+	assert.Equal(t, "2026-W01", Bucket(ts, PeriodWeek))
+	assert.Equal(t, "2025-W52", Bucket(time.Date(2025, 12, 28, 0, 0, 0, 0, time.UTC), PeriodWeek))
+	assert.Equal(t, "2026-01", Bucket(ts, PeriodMonth))
+	assert.Equal(t, "-1.05", Decimal(-105))
+}
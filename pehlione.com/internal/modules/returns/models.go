@@ -0,0 +1,56 @@
+package returns
+
+import "time"
+
+// Return (RMA) statuses.
+const (
+	StatusRequested = "requested"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusReceived  = "received" // goods back in stock, refund pending/failed
+	StatusRefunded  = "refunded"
+)
+
+type Return struct {
+	ID      string `gorm:"type:char(36);primaryKey"`
+	OrderID string `gorm:"type:char(36);not null;index:ix_returns_order_id"`
+	UserID  string `gorm:"type:char(36);not null;index:ix_returns_user_created,priority:1"`
+
+	Status    string  `gorm:"type:varchar(16);not null;index:ix_returns_status_created,priority:1"`
+	Reason    string  `gorm:"type:varchar(500);not null"`
+	AdminNote *string `gorm:"type:varchar(255)"`
+
+	// iade tutarı sipariş para biriminde, talep anında hesaplanır
+	RefundCents int     `gorm:"not null;default:0"`
+	Currency    string  `gorm:"type:char(3);not null"`
+	RefundID    *string `gorm:"type:char(36)"`
+
+	LabelCarrier    *string `gorm:"type:varchar(64)"`
+	LabelTrackingNo *string `gorm:"type:varchar(128)"`
+	LabelURL        *string `gorm:"type:varchar(512)"`
+
+	ApprovedAt *time.Time `gorm:"type:datetime(3)"`
+	ReceivedAt *time.Time `gorm:"type:datetime(3)"`
+	CreatedAt  time.Time  `gorm:"type:datetime(3);not null;index:ix_returns_user_created,priority:2;index:ix_returns_status_created,priority:2"`
+	UpdatedAt  time.Time  `gorm:"type:datetime(3);not null"`
+
+	Items []Item `gorm:"foreignKey:ReturnID"`
+}
+
+func (Return) TableName() string { return "returns" }
+
+type Item struct {
+	ID          string `gorm:"type:char(36);primaryKey"`
+	ReturnID    string `gorm:"type:char(36);not null;index:ix_return_items_return_id"`
+	OrderItemID string `gorm:"type:char(36);not null;index:ix_return_items_order_item_id"`
+	VariantID   string `gorm:"type:char(36);not null"`
+
+	ProductName string `gorm:"type:varchar(255);not null"`
+	SKU         string `gorm:"type:varchar(64);not null"`
+	Qty         int    `gorm:"not null"`
+	RefundCents int    `gorm:"not null;default:0"`
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Item) TableName() string { return "return_items" }
@@ -0,0 +1,765 @@
+package returns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"pehlione.com/app/internal/modules/checkout"
+	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/inventory"
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/payments"
+	"pehlione.com/app/internal/modules/products"
+	"pehlione.com/app/internal/modules/shipping"
+	"pehlione.com/app/pkg/view"
+)
+
+var (
+	ErrNotReturnable    = errors.New("order not returnable")
+	ErrNoItems          = errors.New("no items selected for return")
+	ErrInvalidQty       = errors.New("invalid return quantity")
+	ErrReasonRequired   = errors.New("return reason required")
+	ErrInvalidState     = errors.New("return not in a valid state for this action")
+	ErrCarrierRequired  = errors.New("carrier is required")
+	ErrLabelUnavailable = errors.New("return label provider unavailable")
+	ErrRefundFailed     = errors.New("return refund failed")
+)
+
+type Service struct {
+	db       *gorm.DB
+	refunds  *payments.RefundService
+	labels   shipping.Provider
+	emailSvc *emailmod.OutboxService
+	baseURL  string
+}
+
+// NewService wires the returns workflow. labels may be nil when shipping is
+// disabled; return labels are then unavailable.
+func NewService(db *gorm.DB, refunds *payments.RefundService, labels shipping.Provider, emailSvc *emailmod.OutboxService, baseURL string) *Service {
+	return &Service{db: db, refunds: refunds, labels: labels, emailSvc: emailSvc, baseURL: baseURL}
+}
+
+// LabelsAvailable reports whether return labels can be generated.
+func (s *Service) LabelsAvailable() bool { return s.labels != nil }
+
+type LineInput struct {
+	OrderItemID string
+	Qty         int
+}
+
+type CreateInput struct {
+	OrderID string
+	UserID  string
+	Reason  string
+	Lines   []LineInput
+}
+
+type ActionInput struct {
+	ReturnID    string
+	ActorUserID string
+	Note        string
+}
+
+type LabelInput struct {
+	ReturnID    string
+	ActorUserID string
+	Carrier     string
+}
+
+// EligibleItem is an order line with the quantity that can still be returned.
+type EligibleItem struct {
+	Item orders.OrderItem
+	Max  int
+}
+
+// Eligible lists the customer's returnable lines of an order.
+func (s *Service) Eligible(ctx context.Context, orderID, userID string) (orders.Order, []EligibleItem, error) {
+	var ord orders.Order
+	if err := s.db.WithContext(ctx).First(&ord, "id = ? AND user_id = ?", orderID, userID).Error; err != nil {
+		return ord, nil, err
+	}
+	ok, err := returnableInTx(ctx, s.db, ord)
+	if err != nil {
+		return ord, nil, err
+	}
+	if !ok {
+		return ord, nil, ErrNotReturnable
+	}
+
+	items, err := orderItemsInTx(ctx, s.db, ord.ID)
+	if err != nil {
+		return ord, nil, err
+	}
+	used, err := claimedQtyInTx(ctx, s.db, ord.ID)
+	if err != nil {
+		return ord, nil, err
+	}
+	giftCards, err := giftCardItemsInTx(ctx, s.db, ord.ID)
+	if err != nil {
+		return ord, nil, err
+	}
+
+	out := make([]EligibleItem, 0, len(items))
+	for _, it := range items {
+		if giftCards[it.ID] {
+			continue
+		}
+		if left := it.Quantity - used[it.ID]; left > 0 {
+			out = append(out, EligibleItem{Item: it, Max: left})
+		}
+	}
+	return ord, out, nil
+}
+
+// Create records a customer return request for a delivered order.
+func (s *Service) Create(ctx context.Context, in CreateInput) (Return, error) {
+	reason := strings.TrimSpace(in.Reason)
+	if reason == "" {
+		return Return{}, ErrReasonRequired
+	}
+
+	want := map[string]int{}
+	for _, ln := range in.Lines {
+		if ln.Qty < 0 {
+			return Return{}, ErrInvalidQty
+		}
+		if ln.Qty > 0 {
+			want[ln.OrderItemID] += ln.Qty
+		}
+	}
+	if len(want) == 0 {
+		return Return{}, ErrNoItems
+	}
+
+	var ret Return
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ord orders.Order
+		if err := tx.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&ord, "id = ? AND user_id = ?", in.OrderID, in.UserID).Error; err != nil {
+			return err
+		}
+		ok, err := returnableInTx(ctx, tx, ord)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotReturnable
+		}
+
+		items, err := orderItemsInTx(ctx, tx, ord.ID)
+		if err != nil {
+			return err
+		}
+		used, err := claimedQtyInTx(ctx, tx, ord.ID)
+		if err != nil {
+			return err
+		}
+		giftCards, err := giftCardItemsInTx(ctx, tx, ord.ID)
+		if err != nil {
+			return err
+		}
+
+		byID := make(map[string]orders.OrderItem, len(items))
+		for _, it := range items {
+			byID[it.ID] = it
+		}
+		for id, q := range want {
+			it, ok := byID[id]
+			if !ok || giftCards[id] || q > it.Quantity-used[id] {
+				return ErrInvalidQty
+			}
+		}
+
+		amounts := payments.ItemAmounts(ord, items, want, used)
+		now := time.Now()
+		ret = Return{
+			ID:        uuid.NewString(),
+			OrderID:   ord.ID,
+			UserID:    in.UserID,
+			Status:    StatusRequested,
+			Reason:    reason,
+			Currency:  ord.Currency,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		for _, it := range items {
+			q := want[it.ID]
+			if q == 0 {
+				continue
+			}
+			ret.Items = append(ret.Items, Item{
+				ID:          uuid.NewString(),
+				ReturnID:    ret.ID,
+				OrderItemID: it.ID,
+				VariantID:   it.VariantID,
+				ProductName: it.ProductName,
+				SKU:         it.SKU,
+				Qty:         q,
+				RefundCents: amounts[it.ID].AmountCents,
+				CreatedAt:   now,
+			})
+			ret.RefundCents += amounts[it.ID].AmountCents
+		}
+
+		if err := tx.WithContext(ctx).Create(&ret).Error; err != nil {
+			return err
+		}
+		if err := s.eventInTx(ctx, tx, ord, in.UserID, "return_request", ret.ID, now); err != nil {
+			return err
+		}
+		return s.notifyInTx(ctx, tx, ord, ret)
+	})
+	return ret, err
+}
+
+func (s *Service) Approve(ctx context.Context, in ActionInput) error {
+	return s.transition(ctx, in, []string{StatusRequested}, StatusApproved, "return_approve")
+}
+
+func (s *Service) Reject(ctx context.Context, in ActionInput) error {
+	return s.transition(ctx, in, []string{StatusRequested, StatusApproved}, StatusRejected, "return_reject")
+}
+
+func (s *Service) transition(ctx context.Context, in ActionInput, from []string, to, action string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ret, ord, err := lockReturnInTx(ctx, tx, in.ReturnID)
+		if err != nil {
+			return err
+		}
+		if !contains(from, ret.Status) {
+			return ErrInvalidState
+		}
+
+		now := time.Now()
+		upd := map[string]any{"status": to, "updated_at": now}
+		if to == StatusApproved {
+			upd["approved_at"] = now
+		}
+		if note := strings.TrimSpace(in.Note); note != "" {
+			upd["admin_note"] = note
+			ret.AdminNote = &note
+		}
+		if err := tx.WithContext(ctx).Model(&Return{}).Where("id = ?", ret.ID).Updates(upd).Error; err != nil {
+			return err
+		}
+		ret.Status = to
+
+		if err := s.eventInTx(ctx, tx, ord, in.ActorUserID, action, ret.ID, now); err != nil {
+			return err
+		}
+		return s.notifyInTx(ctx, tx, ord, ret)
+	})
+}
+
+// CreateLabel asks the shipping provider for a prepaid return label.
+func (s *Service) CreateLabel(ctx context.Context, in LabelInput) error {
+	if s.labels == nil {
+		return ErrLabelUnavailable
+	}
+	carrier := strings.TrimSpace(in.Carrier)
+	if carrier == "" {
+		return ErrCarrierRequired
+	}
+
+	ret, err := s.Get(ctx, in.ReturnID)
+	if err != nil {
+		return err
+	}
+	if ret.Status != StatusApproved {
+		return ErrInvalidState
+	}
+	var ord orders.Order
+	if err := s.db.WithContext(ctx).First(&ord, "id = ?", ret.OrderID).Error; err != nil {
+		return err
+	}
+
+	var sender shipping.Address
+	_ = json.Unmarshal(ord.ShippingAddressJSON, &sender)
+
+	items := make([]shipping.Item, 0, len(ret.Items))
+	for _, it := range ret.Items {
+		items = append(items, shipping.Item{Name: it.ProductName, SKU: it.SKU, Qty: it.Qty, Price: it.RefundCents})
+	}
+
+	// provider çağrısı tx dışında
+	resp, err := s.labels.CreateLabel(ctx, shipping.LabelRequest{
+		ShipmentID: ret.ID,
+		OrderID:    ord.ID,
+		Carrier:    carrier,
+		Reference:  "RMA-" + shortID(ret.ID),
+		Recipient:  sender,
+		Items:      items,
+		Note:       ret.Reason,
+		Return:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLabelUnavailable, err)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, ord, err := lockReturnInTx(ctx, tx, ret.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != StatusApproved {
+			return ErrInvalidState
+		}
+
+		now := time.Now()
+		if resp.Carrier != "" {
+			carrier = resp.Carrier
+		}
+		if err := tx.WithContext(ctx).Model(&Return{}).Where("id = ?", locked.ID).Updates(map[string]any{
+			"label_carrier":     carrier,
+			"label_tracking_no": nullable(resp.TrackingNumber),
+			"label_url":         nullable(resp.LabelURL),
+			"updated_at":        now,
+		}).Error; err != nil {
+			return err
+		}
+		locked.LabelCarrier = &carrier
+		locked.LabelTrackingNo = nullablePtr(resp.TrackingNumber)
+		locked.LabelURL = nullablePtr(resp.LabelURL)
+
+		if err := s.eventInTx(ctx, tx, ord, in.ActorUserID, "return_label", locked.ID, now); err != nil {
+			return err
+		}
+		return s.notifyInTx(ctx, tx, ord, locked)
+	})
+}
+
+// Receive marks the returned goods as received: they are restocked (reason
+// "return") and the return amount is refunded through RefundService. Calling
+// it again on a received return retries a failed refund.
+func (s *Service) Receive(ctx context.Context, in ActionInput) (Return, error) {
+	var ret Return
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, ord, err := lockReturnInTx(ctx, tx, in.ReturnID)
+		if err != nil {
+			return err
+		}
+		ret = locked
+		if ret.Status == StatusReceived {
+			return nil // yalnızca iade ödemesi tekrar denenecek
+		}
+		if ret.Status != StatusApproved {
+			return ErrInvalidState
+		}
+
+		// kalemler bu arada tek tek iade edilmiş olabilir: iade reddedilecekse
+		// stoğa ikinci kez eklemeden önce dur
+		if err := payments.CheckRefundLinesInTx(ctx, tx, ord, refundLines(ret)); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		lines := make([]checkout.StockLine, 0, len(ret.Items))
+		for _, it := range ret.Items {
+			lines = append(lines, checkout.StockLine{VariantID: it.VariantID, Qty: it.Qty})
+		}
+		if _, err := checkout.RestockOrderInTx(ctx, tx, checkout.RestockInput{
+			OrderID:     ord.ID,
+			ActorUserID: in.ActorUserID,
+			RefType:     "return",
+			RefID:       ret.ID,
+			Reason:      inventory.ReasonReturn,
+			Lines:       lines,
+		}); err != nil {
+			return err
+		}
+
+		upd := map[string]any{"status": StatusReceived, "received_at": now, "updated_at": now}
+		if note := strings.TrimSpace(in.Note); note != "" {
+			upd["admin_note"] = note
+		}
+		if err := tx.WithContext(ctx).Model(&Return{}).Where("id = ?", ret.ID).Updates(upd).Error; err != nil {
+			return err
+		}
+		ret.Status = StatusReceived
+		ret.ReceivedAt = &now
+
+		return s.eventInTx(ctx, tx, ord, in.ActorUserID, "return_receive", ret.ID, now)
+	})
+	if err != nil {
+		return ret, err
+	}
+
+	return ret, s.refund(ctx, ret, in.ActorUserID)
+}
+
+func (s *Service) refund(ctx context.Context, ret Return, actor string) error {
+	refundStatus := payments.StatusSucceeded
+	var refundID *string
+
+	// önceki deneme: asenkron (initiated) iade webhook ile sonuçlanır, tekrar gönderilmez
+	if ret.RefundID != nil {
+		var prev payments.Refund
+		if err := s.db.WithContext(ctx).First(&prev, "id = ?", *ret.RefundID).Error; err != nil {
+			return err
+		}
+		if prev.Status == payments.StatusInitiated {
+			return nil
+		}
+	}
+
+	// tutar 0 ise (ücretsiz kalemler) ödeme sağlayıcısına gidilmez
+	if ret.RefundCents > 0 {
+		if s.refunds == nil {
+			return payments.ErrNotRefundable
+		}
+		res, err := s.refunds.RefundOrder(ctx, payments.RefundOrderInput{
+			OrderID:        ret.OrderID,
+			ActorUserID:    actor,
+			IdempotencyKey: refundKeyPrefix + ret.ID,
+			Lines:          refundLines(ret),
+			Reason:         "RMA-" + shortID(ret.ID) + ": " + ret.Reason,
+		})
+		if err != nil {
+			return err
+		}
+		refundStatus = res.Status
+		refundID = &res.RefundID
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, ord, err := lockReturnInTx(ctx, tx, ret.ID)
+		if err != nil {
+			return err
+		}
+		upd := map[string]any{"updated_at": time.Now()}
+		if refundID != nil {
+			upd["refund_id"] = *refundID
+			locked.RefundID = refundID
+		}
+		if refundStatus == payments.StatusSucceeded {
+			upd["status"] = StatusRefunded
+			locked.Status = StatusRefunded
+		}
+		if err := tx.WithContext(ctx).Model(&Return{}).Where("id = ?", locked.ID).Updates(upd).Error; err != nil {
+			return err
+		}
+		if refundStatus == payments.StatusFailed {
+			return nil
+		}
+		// webhook bu satırdan önce geldiyse iade zaten tamamlandı ve bildirildi
+		if refundStatus == payments.StatusInitiated && locked.Status == StatusRefunded {
+			return nil
+		}
+		return s.notifyInTx(ctx, tx, ord, locked)
+	})
+	if err == nil && refundStatus == payments.StatusFailed {
+		return ErrRefundFailed
+	}
+	return err
+}
+
+// RefundSucceededInTx completes a received return once its asynchronous
+// refund settles (webhook or reconciliation). The refund is matched through
+// its "return-<id>" idempotency key, which is set before the provider call,
+// so a webhook that beats Receive's own bookkeeping still finds the return.
+// Refunds that don't belong to a return are ignored.
+func (s *Service) RefundSucceededInTx(ctx context.Context, tx *gorm.DB, r payments.Refund) error {
+	id, ok := strings.CutPrefix(r.IdempotencyKey, "return-")
+	if !ok || id == "" {
+		return nil
+	}
+	ret, ord, err := lockReturnInTx(ctx, tx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ret.Status != StatusReceived || ret.OrderID != r.OrderID {
+		return nil
+	}
+
+	if err := tx.WithContext(ctx).Model(&Return{}).Where("id = ?", ret.ID).Updates(map[string]any{
+		"status":     StatusRefunded,
+		"refund_id":  r.ID,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	ret.Status = StatusRefunded
+	ret.RefundID = &r.ID
+	return s.notifyInTx(ctx, tx, ord, ret)
+}
+
+func (s *Service) Get(ctx context.Context, id string) (Return, error) {
+	var ret Return
+	err := s.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, sku ASC") }).
+		First(&ret, "id = ?", id).Error
+	return ret, err
+}
+
+// ListByUser returns the customer's returns, newest first.
+func (s *Service) ListByUser(ctx context.Context, userID string, limit int) ([]Return, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var out []Return
+	err := s.db.WithContext(ctx).
+		Preload("Items").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&out).Error
+	return out, err
+}
+
+// AdminList returns returns filtered by status ("" = all), newest first.
+func (s *Service) AdminList(ctx context.Context, status string, limit int) ([]Return, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	q := s.db.WithContext(ctx).Preload("Items")
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var out []Return
+	err := q.Order("created_at DESC").Limit(limit).Find(&out).Error
+	return out, err
+}
+
+// --- helpers ---
+
+// returnableInTx: teslim edilmiş siparişler; kısmi iade sonrası durum
+// partially_refunded olduğundan teslim kaydı order_events'ten kontrol edilir.
+func returnableInTx(ctx context.Context, tx *gorm.DB, ord orders.Order) (bool, error) {
+	switch ord.Status {
+	case "delivered":
+		return true, nil
+	case "partially_refunded":
+		var n int64
+		err := tx.WithContext(ctx).Model(&orders.OrderEvent{}).
+			Where("order_id = ? AND to_status = ?", ord.ID, "delivered").
+			Count(&n).Error
+		return n > 0, err
+	default:
+		return false, nil
+	}
+}
+
+func orderItemsInTx(ctx context.Context, tx *gorm.DB, orderID string) ([]orders.OrderItem, error) {
+	var items []orders.OrderItem
+	err := tx.WithContext(ctx).Order("created_at ASC").Find(&items, "order_id = ?", orderID).Error
+	return items, err
+}
+
+// giftCardItemsInTx returns the gift card lines of an order. A gift card is
+// delivered as a code, so it can't be sent back; it is refunded by an admin.
+func giftCardItemsInTx(ctx context.Context, tx *gorm.DB, orderID string) (map[string]bool, error) {
+	var ids []string
+	if err := tx.WithContext(ctx).
+		Table("order_items oi").
+		Joins("JOIN product_variants v ON v.id = oi.variant_id").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Where("oi.order_id = ? AND p.kind = ?", orderID, products.KindGiftCard).
+		Pluck("oi.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out, nil
+}
+
+// refundKeyPrefix starts the idempotency key of a return's refund.
+const refundKeyPrefix = "return-"
+
+// claimedQtyInTx sums, per order item, the quantities claimed by non-rejected
+// returns and those already refunded line by line outside a return.
+func claimedQtyInTx(ctx context.Context, tx *gorm.DB, orderID string) (map[string]int, error) {
+	out, err := returnedQtyInTx(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	// iadelerin kendi geri ödemeleri yukarıda zaten sayıldı
+	refunded, err := payments.RefundedItemQtyInTx(ctx, tx, orderID, refundKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for id, q := range refunded {
+		out[id] += q
+	}
+	return out, nil
+}
+
+// returnedQtyInTx sums quantities already claimed by non-rejected returns, per order item.
+func returnedQtyInTx(ctx context.Context, tx *gorm.DB, orderID string) (map[string]int, error) {
+	type row struct {
+		OrderItemID string `gorm:"column:order_item_id"`
+		Qty         int    `gorm:"column:qty"`
+	}
+	var rows []row
+	if err := tx.WithContext(ctx).
+		Table("return_items ri").
+		Select("ri.order_item_id, SUM(ri.qty) AS qty").
+		Joins("JOIN returns r ON r.id = ri.return_id").
+		Where("r.order_id = ? AND r.status <> ?", orderID, StatusRejected).
+		Group("ri.order_item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]int, len(rows))
+	for _, r := range rows {
+		out[r.OrderItemID] = r.Qty
+	}
+	return out, nil
+}
+
+// refundLines maps the returned items to refund lines.
+func refundLines(ret Return) []payments.RefundLineInput {
+	out := make([]payments.RefundLineInput, 0, len(ret.Items))
+	for _, it := range ret.Items {
+		out = append(out, payments.RefundLineInput{OrderItemID: it.OrderItemID, Qty: it.Qty})
+	}
+	return out
+}
+
+func lockReturnInTx(ctx context.Context, tx *gorm.DB, id string) (Return, orders.Order, error) {
+	var ret Return
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Items").
+		First(&ret, "id = ?", id).Error; err != nil {
+		return ret, orders.Order{}, err
+	}
+	var ord orders.Order
+	err := tx.WithContext(ctx).First(&ord, "id = ?", ret.OrderID).Error
+	return ret, ord, err
+}
+
+func (s *Service) eventInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, actor, action, returnID string, now time.Time) error {
+	note := "return_id=" + returnID
+	ev := orders.OrderEvent{
+		ID:          uuid.NewString(),
+		OrderID:     ord.ID,
+		ActorUserID: actor,
+		Action:      action,
+		FromStatus:  ord.Status,
+		ToStatus:    ord.Status,
+		Note:        &note,
+		CreatedAt:   now,
+	}
+	return tx.WithContext(ctx).Create(&ev).Error
+}
+
+// notifyInTx queues the customer's return status email through the outbox.
+func (s *Service) notifyInTx(ctx context.Context, tx *gorm.DB, ord orders.Order, ret Return) error {
+	if s.emailSvc == nil {
+		return nil
+	}
+	to, err := s.lookupOrderEmail(ctx, tx, ord)
+	if err != nil || to == "" {
+		return err
+	}
+
+	items := make([]map[string]any, 0, len(ret.Items))
+	for _, it := range ret.Items {
+		items = append(items, map[string]any{
+			"Name":  it.ProductName,
+			"Qty":   it.Qty,
+			"Price": view.MoneyFromCents(it.RefundCents, ret.Currency),
+		})
+	}
+	payload := map[string]any{
+		"OrderID":     ord.ID,
+		"OrderNumber": ord.Number(),
+		"ReturnID":    ret.ID,
+		"RMA":         "RMA-" + shortID(ret.ID),
+		"Status":      ret.Status,
+		"StatusLabel": StatusLabel(ret.Status),
+		"RefundTotal": view.MoneyFromCents(ret.RefundCents, ret.Currency),
+		"Items":       items,
+		"ReturnsURL":  strings.TrimRight(s.baseURL, "/") + "/account/returns",
+	}
+	if ret.AdminNote != nil && *ret.AdminNote != "" {
+		payload["Note"] = *ret.AdminNote
+	}
+	if ret.LabelURL != nil && *ret.LabelURL != "" {
+		payload["LabelURL"] = *ret.LabelURL
+	}
+	if ret.LabelTrackingNo != nil && *ret.LabelTrackingNo != "" {
+		payload["TrackingNumber"] = *ret.LabelTrackingNo
+	}
+
+	return s.emailSvc.EnqueueTx(ctx, tx, emailmod.Job{
+		To:       to,
+		Template: emailmod.TemplateReturnUpdate,
+		Payload:  payload,
+	})
+}
+
+func (s *Service) lookupOrderEmail(ctx context.Context, tx *gorm.DB, ord orders.Order) (string, error) {
+	if ord.GuestEmail != nil && *ord.GuestEmail != "" {
+		return *ord.GuestEmail, nil
+	}
+	if ord.UserID != nil && *ord.UserID != "" {
+		var email string
+		if err := tx.WithContext(ctx).Table("users").Select("email").Where("id = ?", *ord.UserID).Take(&email).Error; err == nil {
+			return email, nil
+		}
+	}
+	return "", nil
+}
+
+// StatusLabel is the customer-facing label of a return status.
+func StatusLabel(status string) string {
+	switch status {
+	case StatusRequested:
+		return "Return requested"
+	case StatusApproved:
+		return "Return approved"
+	case StatusRejected:
+		return "Return rejected"
+	case StatusReceived:
+		return "Items received"
+	case StatusRefunded:
+		return "Refunded"
+	default:
+		return status
+	}
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return strings.ToUpper(id[:8])
+	}
+	return strings.ToUpper(id)
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func nullable(v string) any {
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	return v
+}
+
+func nullablePtr(v string) *string {
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	return &v
+}
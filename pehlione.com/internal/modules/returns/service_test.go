@@ -0,0 +1,325 @@
+package returns
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/payments"
+)
+
+// syncProvider settles refunds immediately, like a card refund that the
+// provider confirms in the API response.
+type syncProvider struct{ payments.MockProvider }
+
+func (syncProvider) RefundPayment(ctx context.Context, req payments.RefundRequest) (payments.RefundResponse, error) {
+	return payments.RefundResponse{ProviderRef: "re_" + req.IdempotencyKey, Status: payments.StatusSucceeded}, nil
+}
+
+func setupReturnsDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	for _, q := range []string{
+		`CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT NOT NULL)`,
+		`CREATE TABLE orders (
+			id TEXT PRIMARY KEY, user_id TEXT, guest_email TEXT, status TEXT NOT NULL, currency TEXT NOT NULL,
+			subtotal_cents INTEGER NOT NULL DEFAULT 0, total_cents INTEGER NOT NULL, discount_cents INTEGER NOT NULL DEFAULT 0,
+			refunded_cents INTEGER NOT NULL DEFAULT 0, gift_card_cents INTEGER NOT NULL DEFAULT 0, prices_include_tax BOOLEAN NOT NULL DEFAULT 1,
+			order_number TEXT, refunded_at DATETIME, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE order_items (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, product_name TEXT NOT NULL, sku TEXT NOT NULL,
+			quantity INTEGER NOT NULL, line_total_cents INTEGER NOT NULL, tax_cents INTEGER NOT NULL DEFAULT 0, tax_rate_bps INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME)`,
+		`CREATE TABLE order_events (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, actor_user_id TEXT NOT NULL, action TEXT NOT NULL,
+			from_status TEXT NOT NULL, to_status TEXT NOT NULL, note TEXT, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE order_financial_entries (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, event TEXT NOT NULL, amount_cents INTEGER NOT NULL,
+			currency TEXT NOT NULL, ref_type TEXT NOT NULL, ref_id TEXT NOT NULL, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE returns (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, user_id TEXT NOT NULL, status TEXT NOT NULL, reason TEXT NOT NULL,
+			admin_note TEXT, refund_cents INTEGER NOT NULL DEFAULT 0, currency TEXT NOT NULL, refund_id TEXT,
+			label_carrier TEXT, label_tracking_no TEXT, label_url TEXT, approved_at DATETIME, received_at DATETIME,
+			created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`CREATE TABLE return_items (
+			id TEXT PRIMARY KEY, return_id TEXT NOT NULL, order_item_id TEXT NOT NULL, variant_id TEXT NOT NULL,
+			product_name TEXT NOT NULL, sku TEXT NOT NULL, qty INTEGER NOT NULL, refund_cents INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL)`,
+		`CREATE TABLE payments (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, provider TEXT NOT NULL, provider_ref TEXT, status TEXT NOT NULL,
+			amount_cents INTEGER NOT NULL, currency TEXT NOT NULL, idempotency_key TEXT NOT NULL,
+			created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`CREATE TABLE refunds (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, payment_id TEXT, provider TEXT NOT NULL, provider_ref TEXT,
+			status TEXT NOT NULL, amount_cents INTEGER NOT NULL, currency TEXT NOT NULL, idempotency_key TEXT NOT NULL,
+			reason TEXT, restock BOOLEAN NOT NULL DEFAULT 0, error_message TEXT, credit_note_number TEXT UNIQUE, credit_note_issued_at DATETIME,
+			created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`CREATE TABLE refund_lines (
+			id TEXT PRIMARY KEY, refund_id TEXT NOT NULL, kind TEXT NOT NULL, order_item_id TEXT, label TEXT NOT NULL,
+			qty INTEGER NOT NULL DEFAULT 0, amount_cents INTEGER NOT NULL, tax_cents INTEGER NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL, created_at DATETIME NOT NULL)`,
+		`CREATE TABLE disputes (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, payment_id TEXT NOT NULL, provider TEXT NOT NULL, provider_ref TEXT NOT NULL,
+			status TEXT NOT NULL, reason TEXT, amount_cents INTEGER NOT NULL, currency TEXT NOT NULL,
+			opened_at DATETIME NOT NULL, closed_at DATETIME, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`CREATE TABLE number_sequences (
+			name TEXT NOT NULL, year INTEGER NOT NULL, last_value INTEGER NOT NULL, updated_at DATETIME NOT NULL,
+			PRIMARY KEY (name, year))`,
+		`CREATE TABLE provider_events (
+			id TEXT PRIMARY KEY, provider TEXT NOT NULL, event_id TEXT NOT NULL, event_type TEXT NOT NULL, payload_json TEXT NOT NULL,
+			payment_ref TEXT, refund_ref TEXT, dispute_ref TEXT, checkout_ref TEXT, reason TEXT, amount_cents INTEGER NOT NULL DEFAULT 0, currency TEXT,
+			received_at DATETIME NOT NULL, processed_at DATETIME, process_error TEXT, attempts INTEGER NOT NULL DEFAULT 0, UNIQUE (provider, event_id))`,
+		`CREATE TABLE email_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, to_email TEXT NOT NULL, template TEXT NOT NULL, payload TEXT NOT NULL, attachments TEXT, status TEXT NOT NULL,
+			attempt_count INTEGER NOT NULL DEFAULT 0, last_error TEXT, scheduled_at DATETIME NOT NULL, locked_at DATETIME, locked_by TEXT,
+			sent_at DATETIME, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`CREATE TABLE gift_cards (
+			id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, kind TEXT NOT NULL, status TEXT NOT NULL, currency TEXT NOT NULL,
+			initial_cents INTEGER NOT NULL, balance_cents INTEGER NOT NULL, user_id TEXT, recipient_email TEXT,
+			source_order_id TEXT, source_order_item_id TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`,
+		`CREATE TABLE gift_card_transactions (
+			id TEXT PRIMARY KEY, gift_card_id TEXT NOT NULL, order_id TEXT, kind TEXT NOT NULL, amount_cents INTEGER NOT NULL,
+			balance_after_cents INTEGER NOT NULL, currency TEXT NOT NULL, ref_type TEXT NOT NULL, ref_id TEXT NOT NULL,
+			created_at DATETIME NOT NULL, UNIQUE (gift_card_id, kind, ref_type, ref_id))`,
+		`CREATE TABLE products (id TEXT PRIMARY KEY, kind TEXT NOT NULL DEFAULT 'standard')`,
+		`CREATE TABLE product_variants (id TEXT PRIMARY KEY, product_id TEXT, stock INTEGER NOT NULL DEFAULT 0, low_stock_threshold INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE stock_reservations (
+			id TEXT PRIMARY KEY, order_id TEXT NOT NULL, variant_id TEXT NOT NULL, qty INTEGER NOT NULL, status TEXT NOT NULL,
+			expires_at DATETIME, converted_at DATETIME, released_at DATETIME, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE stock_movements (
+			id TEXT PRIMARY KEY, variant_id TEXT NOT NULL, location_id TEXT, delta INTEGER NOT NULL, stock_after INTEGER NOT NULL,
+			reason TEXT NOT NULL, order_id TEXT, ref_type TEXT, ref_id TEXT, actor_user_id TEXT, note TEXT,
+			created_at DATETIME NOT NULL)`,
+		`CREATE TABLE stock_locations (
+			id TEXT PRIMARY KEY, code TEXT NOT NULL UNIQUE, name TEXT NOT NULL, priority INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE stock_levels (
+			location_id TEXT NOT NULL, variant_id TEXT NOT NULL, qty INTEGER NOT NULL DEFAULT 0, updated_at DATETIME NOT NULL,
+			PRIMARY KEY (location_id, variant_id))`,
+		`CREATE TABLE order_item_allocations (
+			id TEXT PRIMARY KEY, order_item_id TEXT NOT NULL, location_id TEXT NOT NULL, qty INTEGER NOT NULL,
+			created_at DATETIME NOT NULL, UNIQUE (order_item_id, location_id))`,
+
+		`INSERT INTO users (id, email) VALUES ('u1', 'c@example.com')`,
+		`INSERT INTO orders (id, user_id, status, currency, subtotal_cents, total_cents, order_number, created_at)
+			VALUES ('o1', 'u1', 'delivered', 'EUR', 5000, 5000, 'PH-2026-000001', CURRENT_TIMESTAMP)`,
+		`INSERT INTO order_items (id, order_id, variant_id, product_name, sku, quantity, line_total_cents, tax_cents, created_at) VALUES
+			('i1', 'o1', 'v1', 'Shirt', 'SH-1', 2, 3000, 500, CURRENT_TIMESTAMP),
+			('i2', 'o1', 'v2', 'Cap', 'CP-1', 1, 2000, 333, CURRENT_TIMESTAMP)`,
+		`INSERT INTO order_events (id, order_id, actor_user_id, action, from_status, to_status, created_at)
+			VALUES ('e1', 'o1', 'admin', 'deliver', 'shipped', 'delivered', CURRENT_TIMESTAMP)`,
+		`INSERT INTO payments (id, order_id, provider, provider_ref, status, amount_cents, currency, idempotency_key, created_at, updated_at)
+			VALUES ('p1', 'o1', 'mock', 'pay_1', 'succeeded', 5000, 'EUR', 'k1', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		`INSERT INTO products (id, kind) VALUES ('pr1', 'standard')`,
+		`INSERT INTO product_variants (id, product_id, stock) VALUES ('v1', 'pr1', 0), ('v2', 'pr1', 0)`,
+		`INSERT INTO stock_locations (id, code, name, status) VALUES ('l1', 'wh1', 'Warehouse 1', 'active')`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+	return db
+}
+
+func statusOf(t *testing.T, db *gorm.DB, table, id string) string {
+	var st string
+	require.NoError(t, db.Table(table).Select("status").Where("id = ?", id).Scan(&st).Error)
+	return st
+}
+
+// returnEmails lists the statuses of the queued return update emails.
+func returnEmails(t *testing.T, db *gorm.DB) []string {
+	var rows []email.OutboxEmail
+	require.NoError(t, db.Where("template = ?", email.TemplateReturnUpdate).Order("id ASC").Find(&rows).Error)
+	out := make([]string, 0, len(rows))
+	for _, r := range rows {
+		var p struct{ Status string }
+		require.NoError(t, json.Unmarshal(r.Payload, &p))
+		out = append(out, p.Status)
+	}
+	return out
+}
+
+// refundEmails lists the credit note numbers of the queued order_refunded
+// emails, checking each one has the credit note PDF attached.
+func refundEmails(t *testing.T, db *gorm.DB) []string {
+	var rows []email.OutboxEmail
+	require.NoError(t, db.Where("template = ?", email.TemplateOrderRefunded).Order("id ASC").Find(&rows).Error)
+	out := make([]string, 0, len(rows))
+	for _, r := range rows {
+		var p struct{ CreditNoteNumber string }
+		require.NoError(t, json.Unmarshal(r.Payload, &p))
+		var atts []email.Attachment
+		require.NoError(t, json.Unmarshal(r.Attachments, &atts))
+		require.Len(t, atts, 1)
+		assert.Equal(t, "application/pdf", atts[0].ContentType)
+		out = append(out, p.CreditNoteNumber)
+	}
+	return out
+}
+
+func requestReturn(t *testing.T, svc *Service) Return {
+	ctx := context.Background()
+	_, err := svc.Create(ctx, CreateInput{OrderID: "o1", UserID: "u1", Lines: []LineInput{{OrderItemID: "i1", Qty: 1}}})
+	require.ErrorIs(t, err, ErrReasonRequired)
+	_, err = svc.Create(ctx, CreateInput{OrderID: "o1", UserID: "u1", Reason: "too small", Lines: []LineInput{{OrderItemID: "i1", Qty: 3}}})
+	require.ErrorIs(t, err, ErrInvalidQty)
+
+	ret, err := svc.Create(ctx, CreateInput{OrderID: "o1", UserID: "u1", Reason: "too small", Lines: []LineInput{{OrderItemID: "i1", Qty: 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, StatusRequested, ret.Status)
+	assert.Equal(t, 1500, ret.RefundCents)
+
+	_, err = svc.Receive(ctx, ActionInput{ReturnID: ret.ID, ActorUserID: "admin"})
+	require.ErrorIs(t, err, ErrInvalidState, "goods can't be received before approval")
+	require.NoError(t, svc.Approve(ctx, ActionInput{ReturnID: ret.ID, ActorUserID: "admin"}))
+	return ret
+}
+
+func TestReturn_SyncRefund(t *testing.T) {
+	db := setupReturnsDB(t)
+	ctx := context.Background()
+	refunds := payments.NewRefundService(db, payments.NewRegistry(syncProvider{payments.NewMockProvider("", 0)}), nil, "")
+	svc := NewService(db, refunds, nil, email.NewService(db), "https://shop.test")
+
+	ret := requestReturn(t, svc)
+	got, err := svc.Receive(ctx, ActionInput{ReturnID: ret.ID, ActorUserID: "admin"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusReceived, got.Status)
+
+	assert.Equal(t, StatusRefunded, statusOf(t, db, "returns", ret.ID))
+	assert.Equal(t, "partially_refunded", statusOf(t, db, "orders", "o1"))
+	var stock int
+	require.NoError(t, db.Table("product_variants").Select("stock").Where("id = ?", "v1").Scan(&stock).Error)
+	assert.Equal(t, 1, stock, "received goods are restocked")
+	assert.Equal(t, []string{StatusRequested, StatusApproved, StatusRefunded}, returnEmails(t, db))
+}
+
+func TestReturn_AsyncRefundCompletedByWebhook(t *testing.T) {
+	db := setupReturnsDB(t)
+	ctx := context.Background()
+	refunds := payments.NewRefundService(db, payments.NewRegistry(payments.NewMockProvider("", 0)), nil, "")
+	svc := NewService(db, refunds, nil, email.NewService(db), "https://shop.test")
+	webhooks := payments.NewWebhookService(db)
+	webhooks.SetEmailService(email.NewService(db), "https://shop.test")
+	webhooks.SetRefundListener(svc)
+
+	ret := requestReturn(t, svc)
+	_, err := svc.Receive(ctx, ActionInput{ReturnID: ret.ID, ActorUserID: "admin"})
+	require.NoError(t, err)
+
+	// mock sağlayıcı iadeyi initiated bırakır: talep received'da bekler
+	got, err := svc.Get(ctx, ret.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusReceived, got.Status)
+	require.NotNil(t, got.RefundID)
+	assert.Equal(t, []string{StatusRequested, StatusApproved, StatusReceived}, returnEmails(t, db))
+	assert.Empty(t, refundEmails(t, db), "no refund email before the provider confirms")
+
+	// tekrar Receive iadeyi yeniden göndermez
+	_, err = svc.Receive(ctx, ActionInput{ReturnID: ret.ID, ActorUserID: "admin"})
+	require.NoError(t, err)
+	var n int64
+	require.NoError(t, db.Model(&payments.Refund{}).Count(&n).Error)
+	assert.EqualValues(t, 1, n)
+
+	var rf payments.Refund
+	require.NoError(t, db.First(&rf, "id = ?", *got.RefundID).Error)
+	require.NoError(t, webhooks.Handle(ctx, "mock", payments.WebhookEvent{
+		EventID:     "evt_1",
+		Type:        "refund.succeeded",
+		RefundRef:   *rf.ProviderRef,
+		AmountCents: rf.AmountCents,
+		Currency:    rf.Currency,
+	}, []byte(`{}`)))
+
+	assert.Equal(t, StatusRefunded, statusOf(t, db, "returns", ret.ID))
+	assert.Equal(t, "partially_refunded", statusOf(t, db, "orders", "o1"))
+	assert.Equal(t, []string{StatusRequested, StatusApproved, StatusReceived, StatusRefunded}, returnEmails(t, db))
+	require.NoError(t, db.First(&rf, "id = ?", rf.ID).Error)
+	require.NotNil(t, rf.CreditNoteNumber)
+	assert.Equal(t, []string{*rf.CreditNoteNumber}, refundEmails(t, db), "order_refunded email carries the credit note")
+
+	// yeniden teslim edilen webhook ikinci e-posta göndermez
+	require.NoError(t, webhooks.Handle(ctx, "mock", payments.WebhookEvent{
+		EventID: "evt_1", Type: "refund.succeeded", RefundRef: *rf.ProviderRef,
+	}, []byte(`{}`)))
+	assert.Len(t, returnEmails(t, db), 4)
+	assert.Len(t, refundEmails(t, db), 1)
+}
+
+func TestReturn_LineRefundedItemsAreNotReturnable(t *testing.T) {
+	db := setupReturnsDB(t)
+	ctx := context.Background()
+	refunds := payments.NewRefundService(db, payments.NewRegistry(syncProvider{payments.NewMockProvider("", 0)}), nil, "")
+	svc := NewService(db, refunds, nil, email.NewService(db), "https://shop.test")
+
+	ret := requestReturn(t, svc)
+
+	// yönetici talep beklerken aynı kalemleri tek tek iade eder
+	_, err := refunds.RefundOrder(ctx, payments.RefundOrderInput{
+		OrderID: "o1", ActorUserID: "admin", IdempotencyKey: "manual-1",
+		Lines: []payments.RefundLineInput{{OrderItemID: "i1", Qty: 2}},
+	})
+	require.NoError(t, err)
+
+	_, items, err := svc.Eligible(ctx, "o1", "u1")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "i2", items[0].Item.ID)
+	_, err = svc.Create(ctx, CreateInput{OrderID: "o1", UserID: "u1", Reason: "broken", Lines: []LineInput{{OrderItemID: "i1", Qty: 1}}})
+	assert.ErrorIs(t, err, ErrInvalidQty)
+
+	// teslim alınan talep reddedilir, stoğa eklenmez
+	_, err = svc.Receive(ctx, ActionInput{ReturnID: ret.ID, ActorUserID: "admin"})
+	assert.ErrorIs(t, err, payments.ErrInvalidRefundLine)
+	assert.Equal(t, StatusApproved, statusOf(t, db, "returns", ret.ID))
+	var stock int
+	require.NoError(t, db.Table("product_variants").Select("stock").Where("id = ?", "v1").Scan(&stock).Error)
+	assert.Equal(t, 0, stock)
+}
+
+func TestReturn_RefundedReturnIsCountedOnce(t *testing.T) {
+	db := setupReturnsDB(t)
+	ctx := context.Background()
+	refunds := payments.NewRefundService(db, payments.NewRegistry(syncProvider{payments.NewMockProvider("", 0)}), nil, "")
+	svc := NewService(db, refunds, nil, email.NewService(db), "https://shop.test")
+
+	ret := requestReturn(t, svc)
+	_, err := svc.Receive(ctx, ActionInput{ReturnID: ret.ID, ActorUserID: "admin"})
+	require.NoError(t, err)
+
+	_, items, err := svc.Eligible(ctx, "o1", "u1")
+	require.NoError(t, err)
+	left := map[string]int{}
+	for _, it := range items {
+		left[it.Item.ID] = it.Max
+	}
+	assert.Equal(t, map[string]int{"i1": 1, "i2": 1}, left)
+}
+
+func TestReturn_GiftCardLinesAreNotReturnable(t *testing.T) {
+	db := setupReturnsDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		`INSERT INTO products (id, kind) VALUES ('gc', 'gift_card')`,
+		`INSERT INTO product_variants (id, product_id) VALUES ('gc-25', 'gc')`,
+		`INSERT INTO order_items (id, order_id, variant_id, product_name, sku, quantity, line_total_cents, created_at)
+			VALUES ('i3', 'o1', 'gc-25', 'Gift card', 'GC-25', 1, 2500, CURRENT_TIMESTAMP)`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+	svc := NewService(db, nil, nil, email.NewService(db), "https://shop.test")
+
+	_, items, err := svc.Eligible(ctx, "o1", "u1")
+	require.NoError(t, err)
+	for _, it := range items {
+		assert.NotEqual(t, "i3", it.Item.ID)
+	}
+	_, err = svc.Create(ctx, CreateInput{OrderID: "o1", UserID: "u1", Reason: "unwanted", Lines: []LineInput{{OrderItemID: "i3", Qty: 1}}})
+	assert.ErrorIs(t, err, ErrInvalidQty)
+}
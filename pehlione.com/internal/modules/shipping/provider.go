@@ -23,6 +23,8 @@ type Item struct {
 	SKU   string `json:"sku"`
 	Qty   int    `json:"qty"`
 	Price int    `json:"price_cents"`
+	// Location is the stock location code the line ships from (empty = not allocated).
+	Location string `json:"location,omitempty"`
 }
 
 type LabelRequest struct {
@@ -34,6 +36,8 @@ type LabelRequest struct {
 	Recipient  Address
 	Items      []Item
 	Note       string
+	// Return marks a return label: the parcel travels from Recipient back to the store.
+	Return bool
 }
 
 type LabelResponse struct {
@@ -0,0 +1,342 @@
+package shipping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Rate types for shipping methods.
+const (
+	RateFlat     = "flat"
+	RateWeight   = "weight"
+	RateSubtotal = "subtotal"
+)
+
+// Zone/method statuses.
+const (
+	RateStatusActive   = "active"
+	RateStatusInactive = "inactive"
+)
+
+// ZoneWildcard matches every country not covered by a more specific zone.
+const ZoneWildcard = "*"
+
+var ErrMethodUnavailable = errors.New("shipping method not available for destination")
+
+type Zone struct {
+	ID            string         `gorm:"type:char(36);primaryKey"`
+	Name          string         `gorm:"type:varchar(128);not null"`
+	CountriesJSON datatypes.JSON `gorm:"column:countries_json;type:json;not null"`
+	Priority      int            `gorm:"not null;default:0"`
+	Status        string         `gorm:"type:varchar(16);not null"`
+	CreatedAt     time.Time      `gorm:"type:datetime(3);not null"`
+	UpdatedAt     time.Time      `gorm:"type:datetime(3);not null"`
+
+	Methods []Method `gorm:"foreignKey:ZoneID"`
+}
+
+func (Zone) TableName() string { return "shipping_zones" }
+
+// Countries returns the upper-cased ISO codes of the zone.
+func (z Zone) Countries() []string {
+	var out []string
+	if len(z.CountriesJSON) == 0 {
+		return out
+	}
+	_ = json.Unmarshal(z.CountriesJSON, &out)
+	for i := range out {
+		out[i] = strings.ToUpper(strings.TrimSpace(out[i]))
+	}
+	return out
+}
+
+type Method struct {
+	ID            string         `gorm:"type:char(36);primaryKey"`
+	ZoneID        string         `gorm:"type:char(36);not null;uniqueIndex:ux_shipping_methods_zone_code,priority:1"`
+	Code          string         `gorm:"type:varchar(32);not null;uniqueIndex:ux_shipping_methods_zone_code,priority:2"`
+	Name          string         `gorm:"type:varchar(128);not null"`
+	RateType      string         `gorm:"type:varchar(16);not null"`
+	FlatCents     int            `gorm:"not null;default:0"`
+	TiersJSON     datatypes.JSON `gorm:"column:tiers_json;type:json"`
+	FreeOverCents int            `gorm:"not null;default:0"` // 0 = eşik yok
+	Position      int            `gorm:"not null;default:0"`
+	Status        string         `gorm:"type:varchar(16);not null"`
+	CreatedAt     time.Time      `gorm:"type:datetime(3);not null"`
+	UpdatedAt     time.Time      `gorm:"type:datetime(3);not null"`
+}
+
+func (Method) TableName() string { return "shipping_methods" }
+
+// Tier prices everything up to UpTo (grams or base cents); UpTo 0 is open-ended.
+type Tier struct {
+	UpTo  int `json:"up_to"`
+	Cents int `json:"cents"`
+}
+
+func (m Method) Tiers() []Tier {
+	var out []Tier
+	if len(m.TiersJSON) == 0 {
+		return out
+	}
+	_ = json.Unmarshal(m.TiersJSON, &out)
+	return out
+}
+
+// RateLine is a cart line used to derive the parcel weight.
+type RateLine struct {
+	VariantID string
+	Qty       int
+}
+
+type RateCart struct {
+	SubtotalCents int // base currency
+	DiscountCents int // base currency; promotions applied to the cart
+	Lines         []RateLine
+}
+
+// NetSubtotalCents is the merchandise value after discounts. The free
+// shipping threshold and subtotal tiers are checked against it, so a coupon
+// that takes the cart below the threshold also costs the free shipping.
+func (c RateCart) NetSubtotalCents() int {
+	if n := c.SubtotalCents - c.DiscountCents; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// RateQuote is a priced method; Cents is in the base currency.
+type RateQuote struct {
+	Code     string
+	Name     string
+	Cents    int
+	Free     bool
+	ZoneName string
+}
+
+type RateService struct {
+	db             *gorm.DB
+	defaultCountry string
+}
+
+func NewRateService(db *gorm.DB, defaultCountry string) *RateService {
+	return &RateService{db: db, defaultCountry: strings.ToUpper(strings.TrimSpace(defaultCountry))}
+}
+
+// DefaultCountry is used for quotes before the customer entered an address.
+func (s *RateService) DefaultCountry() string { return s.defaultCountry }
+
+// Quote prices the active methods of the zone matching the destination country.
+func (s *RateService) Quote(ctx context.Context, country string, cart RateCart) ([]RateQuote, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "" {
+		country = s.defaultCountry
+	}
+
+	var zones []Zone
+	if err := s.db.WithContext(ctx).
+		Preload("Methods", func(db *gorm.DB) *gorm.DB {
+			return db.Where("status = ?", RateStatusActive).Order("position ASC, code ASC")
+		}).
+		Where("status = ?", RateStatusActive).
+		Order("priority ASC").
+		Find(&zones).Error; err != nil {
+		return nil, err
+	}
+
+	zone, ok := MatchZone(zones, country)
+	if !ok || len(zone.Methods) == 0 {
+		return nil, nil
+	}
+
+	weight := 0
+	for _, m := range zone.Methods {
+		if m.RateType == RateWeight {
+			w, err := s.cartWeight(ctx, cart.Lines)
+			if err != nil {
+				return nil, err
+			}
+			weight = w
+			break
+		}
+	}
+
+	subtotal := cart.NetSubtotalCents()
+	out := make([]RateQuote, 0, len(zone.Methods))
+	for _, m := range zone.Methods {
+		cents, free, ok := PriceMethod(m, subtotal, weight)
+		if !ok {
+			continue
+		}
+		out = append(out, RateQuote{Code: m.Code, Name: m.Name, Cents: cents, Free: free, ZoneName: zone.Name})
+	}
+	return out, nil
+}
+
+// QuoteMethod prices a single method, returning ErrMethodUnavailable if the
+// destination zone does not offer it.
+func (s *RateService) QuoteMethod(ctx context.Context, country, code string, cart RateCart) (RateQuote, error) {
+	quotes, err := s.Quote(ctx, country, cart)
+	if err != nil {
+		return RateQuote{}, err
+	}
+	for _, q := range quotes {
+		if q.Code == code {
+			return q, nil
+		}
+	}
+	return RateQuote{}, ErrMethodUnavailable
+}
+
+// MatchZone picks the first zone (by priority) listing the country, falling
+// back to the first wildcard zone.
+func MatchZone(zones []Zone, country string) (Zone, bool) {
+	sorted := make([]Zone, len(zones))
+	copy(sorted, zones)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+
+	var fallback *Zone
+	for i := range sorted {
+		for _, c := range sorted[i].Countries() {
+			if c == country {
+				return sorted[i], true
+			}
+			if c == ZoneWildcard && fallback == nil {
+				fallback = &sorted[i]
+			}
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return Zone{}, false
+}
+
+// PriceMethod computes the base-currency price from the discounted subtotal
+// (RateCart.NetSubtotalCents). ok is false when no tier covers the cart
+// (e.g. parcel heavier than the last bounded tier).
+func PriceMethod(m Method, subtotalCents, weightGrams int) (cents int, free bool, ok bool) {
+	if m.FreeOverCents > 0 && subtotalCents >= m.FreeOverCents {
+		return 0, true, true
+	}
+
+	switch m.RateType {
+	case RateWeight:
+		cents, ok = priceTiers(m.Tiers(), weightGrams)
+	case RateSubtotal:
+		cents, ok = priceTiers(m.Tiers(), subtotalCents)
+	default:
+		cents, ok = m.FlatCents, true
+	}
+	if !ok {
+		return 0, false, false
+	}
+	return cents, cents == 0, true
+}
+
+func priceTiers(tiers []Tier, value int) (int, bool) {
+	sorted := make([]Tier, len(tiers))
+	copy(sorted, tiers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		// açık uçlu (0) tier en sona
+		if sorted[i].UpTo == 0 {
+			return false
+		}
+		if sorted[j].UpTo == 0 {
+			return true
+		}
+		return sorted[i].UpTo < sorted[j].UpTo
+	})
+	for _, t := range sorted {
+		if t.UpTo == 0 || value <= t.UpTo {
+			return t.Cents, true
+		}
+	}
+	return 0, false
+}
+
+func (s *RateService) cartWeight(ctx context.Context, lines []RateLine) (int, error) {
+	if len(lines) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, 0, len(lines))
+	for _, l := range lines {
+		ids = append(ids, l.VariantID)
+	}
+
+	type row struct {
+		ID          string `gorm:"column:id"`
+		WeightGrams int    `gorm:"column:weight_grams"`
+	}
+	var rows []row
+	if err := s.db.WithContext(ctx).
+		Table("product_variants").
+		Select("id, weight_grams").
+		Where("id IN ?", ids).
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+	weights := make(map[string]int, len(rows))
+	for _, r := range rows {
+		weights[r.ID] = r.WeightGrams
+	}
+
+	total := 0
+	for _, l := range lines {
+		total += weights[l.VariantID] * l.Qty
+	}
+	return total, nil
+}
+
+// --- admin repo ---
+
+func (s *RateService) ListZones(ctx context.Context) ([]Zone, error) {
+	var zones []Zone
+	err := s.db.WithContext(ctx).
+		Preload("Methods", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, code ASC") }).
+		Order("priority ASC, name ASC").
+		Find(&zones).Error
+	return zones, err
+}
+
+func (s *RateService) GetZone(ctx context.Context, id string) (Zone, error) {
+	var z Zone
+	err := s.db.WithContext(ctx).
+		Preload("Methods", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, code ASC") }).
+		First(&z, "id = ?", id).Error
+	return z, err
+}
+
+func (s *RateService) SaveZone(ctx context.Context, z *Zone) error {
+	now := time.Now()
+	z.UpdatedAt = now
+	if z.CreatedAt.IsZero() {
+		z.CreatedAt = now
+	}
+	return s.db.WithContext(ctx).Omit("Methods").Save(z).Error
+}
+
+func (s *RateService) SaveMethod(ctx context.Context, m *Method) error {
+	now := time.Now()
+	m.UpdatedAt = now
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	return s.db.WithContext(ctx).Save(m).Error
+}
+
+func (s *RateService) GetMethod(ctx context.Context, zoneID, id string) (Method, error) {
+	var m Method
+	err := s.db.WithContext(ctx).First(&m, "id = ? AND zone_id = ?", id, zoneID).Error
+	return m, err
+}
+
+func (s *RateService) DeleteMethod(ctx context.Context, zoneID, id string) error {
+	return s.db.WithContext(ctx).Where("id = ? AND zone_id = ?", id, zoneID).Delete(&Method{}).Error
+}
@@ -0,0 +1,89 @@
+package shipping
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMatchZone(t *testing.T) {
+	zones := []Zone{
+		{Name: "world", Priority: 100, CountriesJSON: datatypes.JSON(`["*"]`)},
+		{Name: "eu", Priority: 20, CountriesJSON: datatypes.JSON(`["DE","AT"]`)},
+		{Name: "tr", Priority: 10, CountriesJSON: datatypes.JSON(`["tr"]`)},
+	}
+
+	z, ok := MatchZone(zones, "TR")
+	assert.True(t, ok)
+	assert.Equal(t, "tr", z.Name)
+
+	z, ok = MatchZone(zones, "AT")
+	assert.True(t, ok)
+	assert.Equal(t, "eu", z.Name)
+
+	z, ok = MatchZone(zones, "US")
+	assert.True(t, ok)
+	assert.Equal(t, "world", z.Name)
+
+	_, ok = MatchZone(zones[1:], "US")
+	assert.False(t, ok)
+}
+
+func TestPriceMethod(t *testing.T) {
+	tiers := datatypes.JSON(`[{"up_to":0,"cents":2000},{"up_to":1000,"cents":900},{"up_to":5000,"cents":1400}]`)
+
+	weight := Method{RateType: RateWeight, TiersJSON: tiers}
+	cents, free, ok := PriceMethod(weight, 10000, 800)
+	assert.Equal(t, []any{900, false, true}, []any{cents, free, ok})
+	cents, _, _ = PriceMethod(weight, 10000, 3000)
+	assert.Equal(t, 1400, cents)
+	cents, _, _ = PriceMethod(weight, 10000, 9000)
+	assert.Equal(t, 2000, cents)
+
+	bounded := Method{RateType: RateWeight, TiersJSON: datatypes.JSON(`[{"up_to":1000,"cents":900}]`)}
+	_, _, ok = PriceMethod(bounded, 0, 1500)
+	assert.False(t, ok)
+
+	flat := Method{RateType: RateFlat, FlatCents: 500, FreeOverCents: 20000}
+	cents, free, ok = PriceMethod(flat, 19999, 0)
+	assert.Equal(t, []any{500, false, true}, []any{cents, free, ok})
+	cents, free, ok = PriceMethod(flat, 20000, 0)
+	assert.Equal(t, []any{0, true, true}, []any{cents, free, ok})
+}
+
+func TestQuote_FreeShippingUsesDiscountedSubtotal(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	for _, q := range []string{
+		`CREATE TABLE shipping_zones (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, countries_json TEXT NOT NULL, priority INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE shipping_methods (
+			id TEXT PRIMARY KEY, zone_id TEXT NOT NULL, code TEXT NOT NULL, name TEXT NOT NULL, rate_type TEXT NOT NULL,
+			flat_cents INTEGER NOT NULL DEFAULT 0, tiers_json TEXT, free_over_cents INTEGER NOT NULL DEFAULT 0,
+			position INTEGER NOT NULL DEFAULT 0, status TEXT NOT NULL, created_at DATETIME, updated_at DATETIME)`,
+		`INSERT INTO shipping_zones (id, name, countries_json, status) VALUES ('z1', 'tr', '["TR"]', 'active')`,
+		`INSERT INTO shipping_methods (id, zone_id, code, name, rate_type, flat_cents, free_over_cents, status)
+			VALUES ('m1', 'z1', 'std', 'Standart', 'flat', 500, 20000, 'active')`,
+	} {
+		require.NoError(t, db.Exec(q).Error, q)
+	}
+	svc := NewRateService(db, "TR")
+	ctx := context.Background()
+
+	q, err := svc.QuoteMethod(ctx, "TR", "std", RateCart{SubtotalCents: 21000})
+	require.NoError(t, err)
+	assert.True(t, q.Free)
+
+	// kupon sepeti eşiğin altına düşürürse ücretsiz kargo da gider
+	q, err = svc.QuoteMethod(ctx, "TR", "std", RateCart{SubtotalCents: 21000, DiscountCents: 2000})
+	require.NoError(t, err)
+	assert.Equal(t, []any{500, false}, []any{q.Cents, q.Free})
+
+	assert.Equal(t, 0, RateCart{SubtotalCents: 1000, DiscountCents: 1500}.NetSubtotalCents())
+}
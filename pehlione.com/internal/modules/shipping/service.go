@@ -15,6 +15,7 @@ import (
 
 	"pehlione.com/app/internal/emails"
 	emailmod "pehlione.com/app/internal/modules/email"
+	"pehlione.com/app/internal/modules/inventory"
 	"pehlione.com/app/internal/modules/orders"
 )
 
@@ -112,14 +113,18 @@ func (s *Service) QueueShipment(ctx context.Context, in QueueShipmentInput) (Shi
 			return err
 		}
 
+		itemIDs := make([]string, 0, len(orderItems))
+		for _, it := range orderItems {
+			itemIDs = append(itemIDs, it.ID)
+		}
+		locs, err := inventory.ItemLocationsInTx(ctx, tx, itemIDs)
+		if err != nil {
+			return err
+		}
+
 		items := make([]Item, 0, len(orderItems))
 		for _, it := range orderItems {
-			items = append(items, Item{
-				Name:  it.ProductName,
-				SKU:   it.SKU,
-				Qty:   it.Quantity,
-				Price: it.LineTotalCents,
-			})
+			items = append(items, splitByLocation(it, locs[it.ID])...)
 		}
 
 		now := time.Now()
@@ -593,3 +598,39 @@ func providerName(p Provider) string {
 	}
 	return p.Name()
 }
+
+// splitByLocation emits one shipment item per warehouse the order line was
+// allocated from; unallocated quantity (legacy orders) stays without a location.
+func splitByLocation(it orders.OrderItem, locs []inventory.ItemLocation) []Item {
+	out := make([]Item, 0, len(locs)+1)
+	left := it.Quantity
+	for _, l := range locs {
+		qty := l.Qty
+		if qty > left {
+			qty = left
+		}
+		if qty <= 0 {
+			continue
+		}
+		out = append(out, Item{
+			Name:     it.ProductName,
+			SKU:      it.SKU,
+			Qty:      qty,
+			Price:    it.UnitPriceCents * qty,
+			Location: l.Code,
+		})
+		left -= qty
+	}
+	if left > 0 {
+		out = append(out, Item{
+			Name:  it.ProductName,
+			SKU:   it.SKU,
+			Qty:   left,
+			Price: it.UnitPriceCents * left,
+		})
+	}
+	if len(out) == 1 {
+		out[0].Price = it.LineTotalCents
+	}
+	return out
+}
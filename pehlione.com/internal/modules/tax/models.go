@@ -0,0 +1,26 @@
+package tax
+
+import "time"
+
+// Product tax classes.
+const (
+	ClassStandard = "standard"
+	ClassReduced  = "reduced"
+	ClassZero     = "zero"
+)
+
+// Classes lists the tax classes a product can be assigned to.
+var Classes = []string{ClassStandard, ClassReduced, ClassZero}
+
+type Rate struct {
+	ID       string `gorm:"type:char(36);primaryKey"`
+	Country  string `gorm:"type:char(2);not null;uniqueIndex:ux_tax_rates_country_class,priority:1"`
+	TaxClass string `gorm:"type:varchar(32);not null;uniqueIndex:ux_tax_rates_country_class,priority:2"`
+	Name     string `gorm:"type:varchar(64);not null"`
+	RateBps  int    `gorm:"not null"` // 2000 = %20
+
+	CreatedAt time.Time `gorm:"type:datetime(3);not null"`
+	UpdatedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (Rate) TableName() string { return "tax_rates" }
@@ -0,0 +1,144 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	db               *gorm.DB
+	pricesIncludeTax bool
+}
+
+func NewService(db *gorm.DB, pricesIncludeTax bool) *Service {
+	return &Service{db: db, pricesIncludeTax: pricesIncludeTax}
+}
+
+// PricesIncludeTax reports whether catalog prices are gross (VAT included).
+func (s *Service) PricesIncludeTax() bool { return s.pricesIncludeTax }
+
+// Line is an order line in the base currency. AmountCents is the taxable
+// amount after its share of the order discount.
+type Line struct {
+	TaxClass    string
+	AmountCents int
+}
+
+type LineTax struct {
+	TaxClass string
+	RateBps  int
+	TaxCents int
+}
+
+type Result struct {
+	Country          string
+	PricesIncludeTax bool
+	Lines            []LineTax // same order as the input
+	TaxCents         int
+}
+
+// CalculateInTx resolves the rates for the shipping country and computes
+// per-line tax. Countries without configured rates are taxed at zero.
+func (s *Service) CalculateInTx(ctx context.Context, tx *gorm.DB, country string, lines []Line) (Result, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+
+	var rates []Rate
+	if country != "" {
+		if err := tx.WithContext(ctx).Where("country = ?", country).Find(&rates).Error; err != nil {
+			return Result{}, err
+		}
+	}
+	byClass := make(map[string]int, len(rates))
+	for _, r := range rates {
+		byClass[r.TaxClass] = r.RateBps
+	}
+
+	res := Compute(byClass, s.pricesIncludeTax, lines)
+	res.Country = country
+	return res, nil
+}
+
+// Compute applies class rates to the lines. Unknown classes fall back to the
+// standard rate, the zero class is always untaxed.
+func Compute(rates map[string]int, pricesIncludeTax bool, lines []Line) Result {
+	out := Result{PricesIncludeTax: pricesIncludeTax, Lines: make([]LineTax, 0, len(lines))}
+	for _, l := range lines {
+		class := NormalizeClass(l.TaxClass)
+		bps := 0
+		if class != ClassZero {
+			if r, ok := rates[class]; ok {
+				bps = r
+			} else {
+				bps = rates[ClassStandard]
+			}
+		}
+
+		taxCents := 0
+		if bps > 0 && l.AmountCents > 0 {
+			amount := float64(l.AmountCents)
+			if pricesIncludeTax {
+				taxCents = int(math.Round(amount * float64(bps) / float64(10000+bps)))
+			} else {
+				taxCents = int(math.Round(amount * float64(bps) / 10000))
+			}
+		}
+
+		out.Lines = append(out.Lines, LineTax{TaxClass: class, RateBps: bps, TaxCents: taxCents})
+		out.TaxCents += taxCents
+	}
+	return out
+}
+
+// AllocateDiscount spreads an order level discount across line amounts
+// proportionally; the last line absorbs the rounding remainder.
+func AllocateDiscount(amounts []int, discount int) []int {
+	out := make([]int, len(amounts))
+	copy(out, amounts)
+	if discount <= 0 || len(amounts) == 0 {
+		return out
+	}
+	total := 0
+	for _, a := range amounts {
+		total += a
+	}
+	if total <= 0 {
+		return out
+	}
+	if discount > total {
+		discount = total
+	}
+
+	remaining := discount
+	for i, a := range amounts {
+		share := int(math.Round(float64(discount) * float64(a) / float64(total)))
+		if i == len(amounts)-1 || share > remaining {
+			share = remaining
+		}
+		out[i] = a - share
+		if out[i] < 0 {
+			out[i] = 0
+		}
+		remaining -= share
+	}
+	return out
+}
+
+func NormalizeClass(class string) string {
+	class = strings.ToLower(strings.TrimSpace(class))
+	if class == "" {
+		return ClassStandard
+	}
+	return class
+}
+
+// FormatRate renders basis points as a percentage, e.g. 1900 -> "%19", 550 -> "%5.5".
+func FormatRate(bps int) string {
+	if bps%100 == 0 {
+		return fmt.Sprintf("%%%d", bps/100)
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%%%.2f", float64(bps)/100), "0"), ".")
+}
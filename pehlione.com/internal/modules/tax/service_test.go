@@ -0,0 +1,46 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	rates := map[string]int{ClassStandard: 2000, ClassReduced: 1000}
+	lines := []Line{
+		{TaxClass: ClassStandard, AmountCents: 12000},
+		{TaxClass: ClassReduced, AmountCents: 11000},
+		{TaxClass: ClassZero, AmountCents: 5000},
+		{TaxClass: "luxury", AmountCents: 1200},
+	}
+
+	t.Run("tax inclusive prices", func(t *testing.T) {
+		res := Compute(rates, true, lines)
+		assert.Equal(t, []LineTax{
+			{TaxClass: ClassStandard, RateBps: 2000, TaxCents: 2000},
+			{TaxClass: ClassReduced, RateBps: 1000, TaxCents: 1000},
+			{TaxClass: ClassZero, RateBps: 0, TaxCents: 0},
+			{TaxClass: "luxury", RateBps: 2000, TaxCents: 200},
+		}, res.Lines)
+		assert.Equal(t, 3200, res.TaxCents)
+	})
+
+	t.Run("tax exclusive prices", func(t *testing.T) {
+		res := Compute(rates, false, lines)
+		assert.Equal(t, 2400+1100+0+240, res.TaxCents)
+	})
+
+	t.Run("country without rates", func(t *testing.T) {
+		res := Compute(nil, true, lines)
+		assert.Equal(t, 0, res.TaxCents)
+	})
+}
+
+func TestAllocateDiscount(t *testing.T) {
+	assert.Equal(t, []int{6667, 3333}, AllocateDiscount([]int{10000, 5000}, 5000))
+	assert.Equal(t, []int{667, 667, 666}, AllocateDiscount([]int{1000, 1000, 1000}, 1000))
+	assert.Equal(t, []int{0, 0}, AllocateDiscount([]int{100, 100}, 500))
+	assert.Equal(t, "%19", FormatRate(1900))
+	assert.Equal(t, "%5.5", FormatRate(550))
+}
@@ -0,0 +1,198 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/tax"
+	"pehlione.com/app/pkg/view"
+)
+
+// CreditNoteData is a succeeded refund as the credit note shows it. Amounts
+// are in Currency and include tax.
+type CreditNoteData struct {
+	Order      orders.Order
+	Number     string
+	IssuedAt   time.Time
+	Reason     string
+	Currency   string
+	Lines      []CreditNoteLine
+	TotalCents int
+}
+
+type CreditNoteLine struct {
+	Label       string
+	Qty         int // 0: adet gösterilmez (kargo, düzeltme)
+	TaxRateBps  int
+	TaxCents    int
+	AmountCents int
+}
+
+// Filename is the download/attachment name of the credit note.
+func (d CreditNoteData) Filename() string {
+	return "pehlione-credit-note-" + d.Number + ".pdf"
+}
+
+func GenerateCreditNote(data CreditNoteData) ([]byte, error) {
+	p := fpdf.New("P", "mm", "A4", "")
+	p.SetMargins(15, 20, 15)
+	p.AddPage()
+
+	renderHeader(p)
+	p.SetFont("Helvetica", "B", 16)
+	p.CellFormat(0, 8, "Credit Note", "", 1, "L", false, 0, "")
+	p.Ln(2)
+
+	renderCreditNoteMeta(p, data)
+	renderCreditNoteLines(p, data)
+	renderCreditNoteTotals(p, data)
+
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderCreditNoteMeta(p *fpdf.Fpdf, data CreditNoteData) {
+	o := data.Order
+	row := func(label, value string) {
+		p.CellFormat(45, 6, label, "", 0, "L", false, 0, "")
+		p.CellFormat(0, 6, value, "", 1, "L", false, 0, "")
+	}
+
+	p.SetFont("Helvetica", "", 11)
+	row("Credit Note No:", data.Number)
+	row("Date:", data.IssuedAt.Local().Format("02.01.2006"))
+	if o.InvoiceNumber != nil && *o.InvoiceNumber != "" {
+		ref := *o.InvoiceNumber
+		if o.InvoicedAt != nil {
+			ref += " (" + o.InvoicedAt.Local().Format("02.01.2006") + ")"
+		}
+		row("Original Invoice:", ref)
+	}
+	row("Order No:", o.Number())
+	if data.Reason != "" {
+		row("Reason:", truncate(data.Reason, 70))
+	}
+
+	addr := o.BillingAddressJSON
+	if len(addr) == 0 {
+		addr = o.ShippingAddressJSON
+	}
+	if lines := addressLines(addr); len(lines) > 0 {
+		p.Ln(2)
+		p.SetFont("Helvetica", "B", 11)
+		p.CellFormat(0, 6, "Bill To", "", 1, "", false, 0, "")
+		p.SetFont("Helvetica", "", 11)
+		for _, line := range lines {
+			p.CellFormat(0, 6, line, "", 1, "", false, 0, "")
+		}
+	}
+
+	p.Ln(4)
+}
+
+func renderCreditNoteLines(p *fpdf.Fpdf, data CreditNoteData) {
+	p.SetFont("Helvetica", "B", 11)
+	p.SetFillColor(248, 250, 252)
+	p.CellFormat(85, 8, "Refunded", "1", 0, "L", true, 0, "")
+	p.CellFormat(20, 8, "Qty", "1", 0, "C", true, 0, "")
+	p.CellFormat(35, 8, "VAT", "1", 0, "R", true, 0, "")
+	p.CellFormat(40, 8, "Amount", "1", 1, "R", true, 0, "")
+
+	p.SetFont("Helvetica", "", 11)
+	for _, l := range data.Lines {
+		qty := ""
+		if l.Qty > 0 {
+			qty = fmt.Sprintf("%d", l.Qty)
+		}
+		vat := view.MoneyFromCents(l.TaxCents, data.Currency)
+		if l.TaxRateBps > 0 {
+			vat = tax.FormatRate(l.TaxRateBps) + " " + vat
+		}
+		p.CellFormat(85, 8, truncate(l.Label, 50), "1", 0, "L", false, 0, "")
+		p.CellFormat(20, 8, qty, "1", 0, "C", false, 0, "")
+		p.CellFormat(35, 8, vat, "1", 0, "R", false, 0, "")
+		p.CellFormat(40, 8, view.MoneyFromCents(l.AmountCents, data.Currency), "1", 1, "R", false, 0, "")
+	}
+
+	p.Ln(4)
+}
+
+func renderCreditNoteTotals(p *fpdf.Fpdf, data CreditNoteData) {
+	taxTotal := 0
+	byRate := map[int]int{}
+	for _, l := range data.Lines {
+		taxTotal += l.TaxCents
+		if l.TaxCents != 0 {
+			byRate[l.TaxRateBps] += l.TaxCents
+		}
+	}
+	rates := make([]int, 0, len(byRate))
+	for bps := range byRate {
+		rates = append(rates, bps)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rates)))
+
+	p.SetFont("Helvetica", "", 11)
+	p.CellFormat(110, 6, "", "", 0, "", false, 0, "")
+	p.CellFormat(50, 6, "Net:", "", 0, "R", false, 0, "")
+	p.CellFormat(30, 6, view.MoneyFromCents(data.TotalCents-taxTotal, data.Currency), "", 1, "R", false, 0, "")
+	for _, bps := range rates {
+		label := "VAT:"
+		if bps > 0 {
+			label = "VAT " + tax.FormatRate(bps) + ":"
+		}
+		p.CellFormat(110, 6, "", "", 0, "", false, 0, "")
+		p.CellFormat(50, 6, label, "", 0, "R", false, 0, "")
+		p.CellFormat(30, 6, view.MoneyFromCents(byRate[bps], data.Currency), "", 1, "R", false, 0, "")
+	}
+
+	p.SetFont("Helvetica", "B", 12)
+	p.CellFormat(110, 8, "", "", 0, "", false, 0, "")
+	p.CellFormat(50, 8, "Total refunded:", "", 0, "R", false, 0, "")
+	p.CellFormat(30, 8, view.MoneyFromCents(data.TotalCents, data.Currency), "", 1, "R", false, 0, "")
+
+	p.Ln(8)
+	p.SetFont("Helvetica", "", 10)
+	p.SetTextColor(120, 124, 139)
+	p.CellFormat(0, 5, "PehliONE - This document was generated digitally and does not require a signature.", "", 1, "C", false, 0, "")
+	p.CellFormat(0, 5, time.Now().Format("02.01.2006 15:04"), "", 1, "C", false, 0, "")
+}
+
+// addressLines formats an order address JSON (checkout format) for the PDF.
+func addressLines(raw []byte) []string {
+	var a struct {
+		FirstName  string `json:"first_name"`
+		LastName   string `json:"last_name"`
+		Address1   string `json:"address1"`
+		Address2   string `json:"address2"`
+		City       string `json:"city"`
+		PostalCode string `json:"postal_code"`
+		Country    string `json:"country"`
+	}
+	if len(raw) == 0 || json.Unmarshal(raw, &a) != nil {
+		return nil
+	}
+	var out []string
+	for _, s := range []string{
+		strings.TrimSpace(a.FirstName + " " + a.LastName),
+		a.Address1,
+		a.Address2,
+		strings.TrimSpace(a.PostalCode + " " + a.City),
+		a.Country,
+	} {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -3,12 +3,14 @@ package pdf
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-pdf/fpdf"
 
 	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/internal/modules/tax"
 	"pehlione.com/app/pkg/view"
 )
 
@@ -32,13 +34,50 @@ const (
 
 func GenerateInvoice(data InvoiceData) ([]byte, error) {
 	p := fpdf.New("P", "mm", "A4", "")
+	return renderInvoice(p, data)
+}
+
+// GenerateHybridInvoice renders the invoice as PDF/A-3b with the e-invoice
+// XML embedded as its machine readable alternative (ZUGFeRD-style).
+func GenerateHybridInvoice(data InvoiceData, xmlName string, xmlData []byte) ([]byte, error) {
+	p := fpdf.New("P", "mm", "A4", "")
+	useEmbeddedFonts(p)
+	raw, err := renderInvoice(p, data)
+	if err != nil {
+		return nil, err
+	}
+
+	title := "Invoice " + data.Order.Number()
+	created := data.Order.CreatedAt
+	if o := data.Order; o.InvoiceNumber != nil {
+		title = "Invoice " + *o.InvoiceNumber
+		if o.InvoicedAt != nil {
+			created = *o.InvoicedAt
+		}
+	}
+	return toPDFA3(raw, pdfaMeta{
+		Title:   title,
+		Author:  "PehliONE",
+		Subject: "Order " + data.Order.Number(),
+		Lang:    "en",
+		Created: created,
+	}, []pdfaFile{{
+		Name:         xmlName,
+		Description:  "UBL 2.1 e-invoice",
+		MIME:         "text/xml",
+		Relationship: "Alternative",
+		Data:         xmlData,
+	}})
+}
+
+func renderInvoice(p *fpdf.Fpdf, data InvoiceData) ([]byte, error) {
 	p.SetMargins(15, 20, 15)
 	p.AddPage()
 
 	renderHeader(p)
 	renderOrderMeta(p, data)
 	renderItemsTable(p, data.Items)
-	renderTotals(p, data.Order)
+	renderTotals(p, data.Order, data.Items)
 
 	var buf bytes.Buffer
 	if err := p.Output(&buf); err != nil {
@@ -61,8 +100,17 @@ func renderHeader(p *fpdf.Fpdf) {
 
 func renderOrderMeta(p *fpdf.Fpdf, data InvoiceData) {
 	p.SetFont("Helvetica", "", 11)
+	if o := data.Order; o.InvoiceNumber != nil && *o.InvoiceNumber != "" {
+		p.CellFormat(40, 6, "Invoice No:", "", 0, "L", false, 0, "")
+		p.CellFormat(0, 6, *o.InvoiceNumber, "", 1, "L", false, 0, "")
+		if o.InvoicedAt != nil {
+			p.CellFormat(40, 6, "Invoice Date:", "", 0, "L", false, 0, "")
+			p.CellFormat(0, 6, o.InvoicedAt.Local().Format("02.01.2006"), "", 1, "L", false, 0, "")
+		}
+	}
+
 	p.CellFormat(40, 6, "Order No:", "", 0, "L", false, 0, "")
-	p.CellFormat(0, 6, data.Order.ID, "", 1, "L", false, 0, "")
+	p.CellFormat(0, 6, data.Order.Number(), "", 1, "L", false, 0, "")
 
 	p.CellFormat(40, 6, "Date:", "", 0, "L", false, 0, "")
 	p.CellFormat(0, 6, data.Order.CreatedAt.Local().Format("02.01.2006 15:04"), "", 1, "L", false, 0, "")
@@ -89,21 +137,23 @@ func renderOrderMeta(p *fpdf.Fpdf, data InvoiceData) {
 func renderItemsTable(p *fpdf.Fpdf, items []orders.OrderItem) {
 	p.SetFont("Helvetica", "B", 11)
 	p.SetFillColor(248, 250, 252)
-	p.CellFormat(100, 8, "Product", "1", 0, "L", true, 0, "")
-	p.CellFormat(30, 8, "Qty", "1", 0, "C", true, 0, "")
-	p.CellFormat(50, 8, "Amount", "1", 1, "R", true, 0, "")
+	p.CellFormat(85, 8, "Product", "1", 0, "L", true, 0, "")
+	p.CellFormat(20, 8, "Qty", "1", 0, "C", true, 0, "")
+	p.CellFormat(35, 8, "VAT", "1", 0, "R", true, 0, "")
+	p.CellFormat(40, 8, "Amount", "1", 1, "R", true, 0, "")
 
 	p.SetFont("Helvetica", "", 11)
 	for _, it := range items {
-		p.CellFormat(100, 8, truncate(it.ProductName, 60), "1", 0, "L", false, 0, "")
-		p.CellFormat(30, 8, fmt.Sprintf("%d", it.Quantity), "1", 0, "C", false, 0, "")
-		p.CellFormat(50, 8, view.MoneyFromCents(it.LineTotalCents, it.Currency), "1", 1, "R", false, 0, "")
+		p.CellFormat(85, 8, truncate(it.ProductName, 50), "1", 0, "L", false, 0, "")
+		p.CellFormat(20, 8, fmt.Sprintf("%d", it.Quantity), "1", 0, "C", false, 0, "")
+		p.CellFormat(35, 8, tax.FormatRate(it.TaxRateBps)+" "+view.MoneyFromCents(it.TaxCents, it.Currency), "1", 0, "R", false, 0, "")
+		p.CellFormat(40, 8, view.MoneyFromCents(it.LineTotalCents, it.Currency), "1", 1, "R", false, 0, "")
 	}
 
 	p.Ln(4)
 }
 
-func renderTotals(p *fpdf.Fpdf, order orders.Order) {
+func renderTotals(p *fpdf.Fpdf, order orders.Order, items []orders.OrderItem) {
 	p.SetFont("Helvetica", "", 11)
 	p.CellFormat(130, 6, "", "", 0, "", false, 0, "")
 	p.CellFormat(30, 6, "Subtotal:", "", 0, "R", false, 0, "")
@@ -113,10 +163,22 @@ func renderTotals(p *fpdf.Fpdf, order orders.Order) {
 	p.CellFormat(30, 6, "Shipping:", "", 0, "R", false, 0, "")
 	p.CellFormat(30, 6, view.MoneyFromCents(order.ShippingCents, order.Currency), "", 1, "R", false, 0, "")
 
+	if order.DiscountCents > 0 {
+		label := "Discount:"
+		if order.PromoCode != nil && *order.PromoCode != "" {
+			label = "Discount (" + *order.PromoCode + "):"
+		}
+		p.CellFormat(110, 6, "", "", 0, "", false, 0, "")
+		p.CellFormat(50, 6, label, "", 0, "R", false, 0, "")
+		p.CellFormat(30, 6, "-"+view.MoneyFromCents(order.DiscountCents, order.Currency), "", 1, "R", false, 0, "")
+	}
+
 	if order.TaxCents > 0 {
-		p.CellFormat(130, 6, "", "", 0, "", false, 0, "")
-		p.CellFormat(30, 6, "Tax:", "", 0, "R", false, 0, "")
-		p.CellFormat(30, 6, view.MoneyFromCents(order.TaxCents, order.Currency), "", 1, "R", false, 0, "")
+		for _, r := range taxBreakdown(items) {
+			p.CellFormat(110, 6, "", "", 0, "", false, 0, "")
+			p.CellFormat(50, 6, view.TaxLabel(order.PricesIncludeTax)+" "+tax.FormatRate(r.rateBps)+":", "", 0, "R", false, 0, "")
+			p.CellFormat(30, 6, view.MoneyFromCents(r.cents, order.Currency), "", 1, "R", false, 0, "")
+		}
 	}
 
 	p.SetFont("Helvetica", "B", 12)
@@ -131,6 +193,28 @@ func renderTotals(p *fpdf.Fpdf, order orders.Order) {
 	p.CellFormat(0, 5, time.Now().Format("02.01.2006 15:04"), "", 1, "C", false, 0, "")
 }
 
+type taxRateTotal struct {
+	rateBps int
+	cents   int
+}
+
+// taxBreakdown groups line taxes by rate for the totals block.
+func taxBreakdown(items []orders.OrderItem) []taxRateTotal {
+	byRate := map[int]int{}
+	for _, it := range items {
+		if it.TaxCents == 0 {
+			continue
+		}
+		byRate[it.TaxRateBps] += it.TaxCents
+	}
+	out := make([]taxRateTotal, 0, len(byRate))
+	for bps, cents := range byRate {
+		out = append(out, taxRateTotal{rateBps: bps, cents: cents})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].rateBps > out[j].rateBps })
+	return out
+}
+
 func truncate(s string, max int) string {
 	if len([]rune(s)) <= max {
 		return s
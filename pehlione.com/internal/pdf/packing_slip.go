@@ -0,0 +1,151 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"pehlione.com/app/internal/modules/orders"
+	"pehlione.com/app/pkg/view"
+)
+
+// PackingSlipData is what goes into the parcel: no prices.
+type PackingSlipData struct {
+	Order orders.Order
+	Items []orders.OrderItem
+}
+
+// Filename is the download name of the packing slip.
+func (d PackingSlipData) Filename() string {
+	return "pehlione-packing-slip-" + d.Order.Number() + ".pdf"
+}
+
+func GeneratePackingSlip(data PackingSlipData) ([]byte, error) {
+	return GeneratePackingSlips([]PackingSlipData{data})
+}
+
+// GeneratePackingSlips prints several orders into one document, one order
+// per page, for batch packing.
+func GeneratePackingSlips(slips []PackingSlipData) ([]byte, error) {
+	p := fpdf.New("P", "mm", "A4", "")
+	useEmbeddedFonts(p)
+	p.SetMargins(15, 20, 15)
+
+	for _, data := range slips {
+		p.AddPage()
+		renderHeader(p)
+		p.SetFont("Helvetica", "B", 16)
+		p.CellFormat(0, 8, "Packing Slip", "", 1, "L", false, 0, "")
+		p.Ln(2)
+
+		renderPackingSlipMeta(p, data.Order)
+		renderPackingSlipItems(p, data.Items)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderPackingSlipMeta(p *fpdf.Fpdf, o orders.Order) {
+	row := func(label, value string) {
+		p.CellFormat(40, 6, label, "", 0, "L", false, 0, "")
+		p.CellFormat(0, 6, value, "", 1, "L", false, 0, "")
+	}
+
+	var ship struct {
+		Phone          string `json:"phone"`
+		ShippingMethod string `json:"shipping_method"`
+		ShippingName   string `json:"shipping_method_name"`
+	}
+	_ = json.Unmarshal(o.ShippingAddressJSON, &ship)
+	method := ship.ShippingName
+	if method == "" {
+		method = view.ShippingLabel(ship.ShippingMethod)
+	}
+
+	p.SetFont("Helvetica", "", 11)
+	row("Order No:", o.Number())
+	row("Order Date:", o.CreatedAt.Local().Format("02.01.2006"))
+	row("Shipping:", method)
+
+	if lines := addressLines(o.ShippingAddressJSON); len(lines) > 0 {
+		p.Ln(2)
+		p.SetFont("Helvetica", "B", 11)
+		p.CellFormat(0, 6, "Ship To", "", 1, "", false, 0, "")
+		p.SetFont("Helvetica", "", 11)
+		for _, line := range lines {
+			p.CellFormat(0, 6, line, "", 1, "", false, 0, "")
+		}
+		if ship.Phone != "" {
+			p.CellFormat(0, 6, "Tel: "+ship.Phone, "", 1, "", false, 0, "")
+		}
+	}
+
+	p.Ln(4)
+}
+
+func renderPackingSlipItems(p *fpdf.Fpdf, items []orders.OrderItem) {
+	p.SetFont("Helvetica", "B", 11)
+	p.SetFillColor(248, 250, 252)
+	p.CellFormat(40, 8, "SKU", "1", 0, "L", true, 0, "")
+	p.CellFormat(70, 8, "Product", "1", 0, "L", true, 0, "")
+	p.CellFormat(45, 8, "Options", "1", 0, "L", true, 0, "")
+	p.CellFormat(15, 8, "Qty", "1", 0, "C", true, 0, "")
+	p.CellFormat(10, 8, "", "1", 1, "C", true, 0, "")
+
+	p.SetFont("Helvetica", "", 10)
+	total := 0
+	for _, it := range items {
+		p.CellFormat(40, 8, truncate(it.SKU, 22), "1", 0, "L", false, 0, "")
+		p.CellFormat(70, 8, truncate(it.ProductName, 40), "1", 0, "L", false, 0, "")
+		p.CellFormat(45, 8, truncate(optionsLabel(it.OptionsJSON), 26), "1", 0, "L", false, 0, "")
+		p.CellFormat(15, 8, fmt.Sprintf("%d", it.Quantity), "1", 0, "C", false, 0, "")
+		p.CellFormat(10, 8, "", "1", 1, "C", false, 0, "")
+		total += it.Quantity
+	}
+
+	p.SetFont("Helvetica", "B", 11)
+	p.CellFormat(155, 8, "Total items:", "", 0, "R", false, 0, "")
+	p.CellFormat(15, 8, fmt.Sprintf("%d", total), "", 1, "C", false, 0, "")
+
+	p.Ln(8)
+	p.SetFont("Helvetica", "", 10)
+	p.SetTextColor(120, 124, 139)
+	p.CellFormat(0, 5, "Thank you for shopping with PehliONE. Returns: see your order page.", "", 1, "C", false, 0, "")
+	p.CellFormat(0, 5, time.Now().Format("02.01.2006 15:04"), "", 1, "C", false, 0, "")
+}
+
+// optionsLabel formats variant options JSON ({"color":"red","size":"M"}) as
+// "color: red, size: M".
+func optionsLabel(raw []byte) string {
+	var opts map[string]any
+	if len(raw) == 0 || json.Unmarshal(raw, &opts) != nil {
+		return ""
+	}
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if opts[k] == nil {
+			continue
+		}
+		v := strings.TrimSpace(fmt.Sprint(opts[k]))
+		if v == "" {
+			continue
+		}
+		parts = append(parts, k+": "+v)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,50 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"pehlione.com/app/internal/modules/inventory"
+	"pehlione.com/app/internal/modules/orders"
+)
+
+func TestOptionsLabel(t *testing.T) {
+	require.Equal(t, "color: Kırmızı, size: M", optionsLabel([]byte(`{"size":"M","color":"Kırmızı","gift":null}`)))
+	require.Empty(t, optionsLabel([]byte(`{}`)))
+	require.Empty(t, optionsLabel(nil))
+}
+
+func TestGeneratePackingSlipAndPickList(t *testing.T) {
+	num := "PH-2026-000101"
+	o := orders.Order{
+		ID:                  "8f0c3a1e-0000-4000-8000-000000000001",
+		OrderNumber:         &num,
+		ShippingAddressJSON: []byte(`{"first_name":"Ayşe","last_name":"Yılmaz","address1":"Bağdat Cad. 5","city":"İstanbul","country":"TR","shipping_method":"express"}`),
+		CreatedAt:           time.Date(2026, 3, 5, 14, 30, 0, 0, time.UTC),
+	}
+	items := []orders.OrderItem{{ProductName: "Çay bardağı", SKU: "CUP-1", OptionsJSON: []byte(`{"color":"red"}`), Quantity: 2}}
+
+	slip := PackingSlipData{Order: o, Items: items}
+	require.Equal(t, "pehlione-packing-slip-PH-2026-000101.pdf", slip.Filename())
+	out, err := GeneratePackingSlip(slip)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-")))
+
+	out, err = GeneratePackingSlips([]PackingSlipData{slip, slip})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "/Count 2")
+
+	out, err = GeneratePickList(PickListData{
+		GeneratedAt: o.CreatedAt,
+		Orders:      []string{num},
+		Lines: []inventory.PickLine{
+			{LocationCode: "wh1", LocationName: "Warehouse 1", SKU: "CUP-1", ProductName: "Çay bardağı", Qty: 2, Orders: []string{num}},
+			{SKU: "NB-1", ProductName: "Defter", Qty: 1, Orders: []string{num}},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-")))
+}
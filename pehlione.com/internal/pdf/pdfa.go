@@ -0,0 +1,411 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	_ "embed"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PDF/A forbids fonts that are not embedded; the core Helvetica used by the
+// other documents isn't.
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	fontRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	fontBold []byte
+)
+
+// useEmbeddedFonts registers the DejaVu faces under the family name the
+// renderers use, so the same render code produces an all-embedded document.
+func useEmbeddedFonts(p *fpdf.Fpdf) {
+	p.AddUTF8FontFromBytes("Helvetica", "", fontRegular)
+	p.AddUTF8FontFromBytes("Helvetica", "B", fontBold)
+}
+
+type pdfaMeta struct {
+	Title   string
+	Author  string
+	Subject string
+	Lang    string
+	Created time.Time
+}
+
+// pdfaFile is an associated file (PDF/A-3 /AF), e.g. the e-invoice XML.
+type pdfaFile struct {
+	Name         string
+	Description  string
+	MIME         string
+	Relationship string // Source|Data|Alternative|Supplement|Unspecified
+	Data         []byte
+}
+
+var (
+	reTrailerSize = regexp.MustCompile(`/Size (\d+)`)
+	reTrailerRoot = regexp.MustCompile(`/Root (\d+) 0 R`)
+)
+
+// toPDFA3 turns an fpdf document into PDF/A-3b: binary header comment, then
+// an incremental update with XMP metadata, an sRGB output intent, the
+// associated files and a catalog pointing at them.
+func toPDFA3(raw []byte, meta pdfaMeta, files []pdfaFile) ([]byte, error) {
+	if !bytes.HasPrefix(raw, []byte("%PDF-1.")) {
+		return nil, errors.New("pdfa: not a PDF")
+	}
+	nl := bytes.IndexByte(raw, '\n')
+	if nl < 0 {
+		return nil, errors.New("pdfa: missing header")
+	}
+
+	// header: PDF 1.7 + ikili yorum satırı; sonraki ofsetler kayar
+	comment := []byte("%\xE2\xE3\xCF\xD3\n")
+	var head bytes.Buffer
+	head.WriteString("%PDF-1.7")
+	head.Write(raw[len("%PDF-1.x") : nl+1])
+	head.Write(comment)
+	head.Write(raw[nl+1:])
+
+	out, prevXref, err := shiftXref(head.Bytes(), len(comment))
+	if err != nil {
+		return nil, err
+	}
+	doc := bytes.NewBuffer(out)
+
+	trailer := out[bytes.LastIndex(out, []byte("trailer")):]
+	size, root := 0, 0
+	if m := reTrailerSize.FindSubmatch(trailer); m != nil {
+		size, _ = strconv.Atoi(string(m[1]))
+	}
+	if m := reTrailerRoot.FindSubmatch(trailer); m != nil {
+		root, _ = strconv.Atoi(string(m[1]))
+	}
+	if size == 0 || root == 0 {
+		return nil, errors.New("pdfa: unreadable trailer")
+	}
+
+	created := meta.Created.Truncate(time.Second)
+	if created.IsZero() {
+		created = time.Now().Truncate(time.Second)
+	}
+
+	w := &incrementalWriter{buf: doc, next: size, offsets: map[int]int{}}
+
+	xmp := xmpPacket(meta, created)
+	metaObj := w.stream(fmt.Sprintf("/Type /Metadata /Subtype /XML /Length %d", len(xmp)), xmp)
+
+	icc := srgbProfile()
+	iccObj := w.stream(fmt.Sprintf("/N 3 /Length %d", len(icc)), icc)
+	intentObj := w.object(fmt.Sprintf("<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R >>", iccObj))
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	var af, names []string
+	for _, f := range files {
+		ef := w.stream(fmt.Sprintf("/Type /EmbeddedFile /Subtype /%s /Params << /ModDate %s /Size %d >> /Length %d",
+			pdfName(f.MIME), pdfText("D:"+pdfDate(created)), len(f.Data), len(f.Data)), f.Data)
+		rel := f.Relationship
+		if rel == "" {
+			rel = "Unspecified"
+		}
+		fs := w.object(fmt.Sprintf("<< /Type /Filespec /F %s /UF %s /Desc %s /AFRelationship /%s /EF << /F %d 0 R /UF %d 0 R >> >>",
+			pdfText(f.Name), pdfText(f.Name), pdfText(f.Description), rel, ef, ef))
+		af = append(af, fmt.Sprintf("%d 0 R", fs))
+		names = append(names, fmt.Sprintf("%s %d 0 R", pdfText(f.Name), fs))
+	}
+
+	infoObj := w.object(fmt.Sprintf("<< /Title %s /Author %s /Subject %s /Creator %s /Producer %s /CreationDate %s /ModDate %s >>",
+		pdfText(meta.Title), pdfText(meta.Author), pdfText(meta.Subject), pdfText(pdfaCreator), pdfText(pdfaCreator),
+		pdfText("D:"+pdfDate(created)), pdfText("D:"+pdfDate(created))))
+
+	catalog := fmt.Sprintf("<< /Type /Catalog /Pages 1 0 R /Metadata %d 0 R /OutputIntents [%d 0 R]", metaObj, intentObj)
+	if len(af) > 0 {
+		catalog += fmt.Sprintf(" /AF [%s] /Names << /EmbeddedFiles << /Names [%s] >> >> /PageMode /UseAttachments",
+			strings.Join(af, " "), strings.Join(names, " "))
+	}
+	if meta.Lang != "" {
+		catalog += " /Lang " + pdfText(meta.Lang)
+	}
+	w.objectAt(root, catalog+" >>")
+
+	sum := md5.Sum(doc.Bytes())
+	id := hex.EncodeToString(sum[:])
+	w.finish(fmt.Sprintf("/Size %d /Root %d 0 R /Info %d 0 R /Prev %d /ID [<%s> <%s>]", w.next, root, infoObj, prevXref, id, id))
+	return doc.Bytes(), nil
+}
+
+// pdfaCreator goes to /Creator, /Producer and their XMP twins.
+const pdfaCreator = "pehlione.com"
+
+// shiftXref moves every in-use offset of the last xref table by delta and
+// rewrites startxref; returns the document and the new xref offset.
+func shiftXref(doc []byte, delta int) ([]byte, int, error) {
+	sx := bytes.LastIndex(doc, []byte("startxref"))
+	if sx < 0 {
+		return nil, 0, errors.New("pdfa: startxref not found")
+	}
+	fields := strings.Fields(string(doc[sx+len("startxref"):]))
+	if len(fields) == 0 {
+		return nil, 0, errors.New("pdfa: bad startxref")
+	}
+	old, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, 0, err
+	}
+	xref := old + delta
+	if !bytes.HasPrefix(doc[xref:], []byte("xref")) {
+		return nil, 0, errors.New("pdfa: xref not at startxref")
+	}
+
+	pos := xref + len("xref\n")
+	for {
+		eol := bytes.IndexByte(doc[pos:], '\n')
+		if eol < 0 {
+			return nil, 0, errors.New("pdfa: truncated xref")
+		}
+		head := strings.Fields(string(doc[pos : pos+eol]))
+		if len(head) != 2 {
+			break // trailer
+		}
+		count, err := strconv.Atoi(head[1])
+		if err != nil {
+			return nil, 0, err
+		}
+		pos += eol + 1
+		for i := 0; i < count; i++ {
+			entry := doc[pos : pos+20]
+			if entry[17] == 'n' {
+				off, err := strconv.Atoi(string(entry[:10]))
+				if err != nil {
+					return nil, 0, err
+				}
+				copy(entry[:10], fmt.Sprintf("%010d", off+delta))
+			}
+			pos += 20
+		}
+	}
+
+	// startxref son satırlar; sayı bir hane uzayabilir
+	out := append(doc[:sx:sx], fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xref)...)
+	return out, xref, nil
+}
+
+type incrementalWriter struct {
+	buf     *bytes.Buffer
+	next    int
+	offsets map[int]int
+}
+
+func (w *incrementalWriter) objectAt(num int, body string) {
+	w.offsets[num] = w.buf.Len()
+	fmt.Fprintf(w.buf, "%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+func (w *incrementalWriter) object(body string) int {
+	num := w.next
+	w.next++
+	w.objectAt(num, body)
+	return num
+}
+
+func (w *incrementalWriter) stream(dict string, data []byte) int {
+	num := w.next
+	w.next++
+	w.offsets[num] = w.buf.Len()
+	fmt.Fprintf(w.buf, "%d 0 obj\n<< %s >>\nstream\n", num, dict)
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+	return num
+}
+
+// finish writes the update's xref (one subsection per run of numbers) and
+// trailer.
+func (w *incrementalWriter) finish(trailer string) {
+	nums := make([]int, 0, len(w.offsets))
+	for n := range w.offsets {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	xref := w.buf.Len()
+	w.buf.WriteString("xref\n")
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		fmt.Fprintf(w.buf, "%d %d\n", nums[i], j-i+1)
+		for _, n := range nums[i : j+1] {
+			fmt.Fprintf(w.buf, "%010d 00000 n \n", w.offsets[n])
+		}
+		i = j + 1
+	}
+	fmt.Fprintf(w.buf, "trailer\n<< %s >>\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+}
+
+func xmpPacket(meta pdfaMeta, created time.Time) []byte {
+	esc := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	date := created.Format(time.RFC3339)
+	return []byte(`<?xpacket begin="` + "\uFEFF" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+   <pdfaid:part>3</pdfaid:part>
+   <pdfaid:conformance>B</pdfaid:conformance>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <dc:format>application/pdf</dc:format>
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">` + esc(meta.Title) + `</rdf:li></rdf:Alt></dc:title>
+   <dc:creator><rdf:Seq><rdf:li>` + esc(meta.Author) + `</rdf:li></rdf:Seq></dc:creator>
+   <dc:description><rdf:Alt><rdf:li xml:lang="x-default">` + esc(meta.Subject) + `</rdf:li></rdf:Alt></dc:description>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+   <xmp:CreatorTool>` + pdfaCreator + `</xmp:CreatorTool>
+   <xmp:CreateDate>` + date + `</xmp:CreateDate>
+   <xmp:ModifyDate>` + date + `</xmp:ModifyDate>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
+   <pdf:Producer>` + pdfaCreator + `</pdf:Producer>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`)
+}
+
+// pdfDate is the PDF date format without the D: prefix (20260305143000+03'00').
+func pdfDate(t time.Time) string {
+	_, off := t.Zone()
+	sign := "+"
+	if off < 0 {
+		sign, off = "-", -off
+	}
+	return t.Format("20060102150405") + fmt.Sprintf("%s%02d'%02d'", sign, off/3600, off%3600/60)
+}
+
+// pdfText encodes a text string: literal for plain ASCII, UTF-16BE otherwise.
+func pdfText(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+		return "(" + r.Replace(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfName escapes a name object (text/xml -> text#2Fxml).
+func pdfName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || strings.IndexByte("#()<>[]{}/%", c) >= 0 {
+			fmt.Fprintf(&b, "#%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// srgbProfile builds a minimal ICC v2 display profile for sRGB (D50 adapted
+// primaries, gamma 2.2 curves); PDF/A needs one for the output intent.
+func srgbProfile() []byte {
+	s15 := func(v float64) uint32 { return uint32(int32(math.Round(v * 65536))) }
+	xyz := func(x, y, z float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		binary.BigEndian.PutUint32(b[8:], s15(x))
+		binary.BigEndian.PutUint32(b[12:], s15(y))
+		binary.BigEndian.PutUint32(b[16:], s15(z))
+		return b
+	}
+	curve := []byte{'c', 'u', 'r', 'v', 0, 0, 0, 0, 0, 0, 0, 1, 0x02, 0x33} // gamma 2.2 (u8Fixed8)
+	desc := func(s string) []byte {
+		b := make([]byte, 12, 12+len(s)+1+4+4+2+1+67)
+		copy(b, "desc")
+		binary.BigEndian.PutUint32(b[8:], uint32(len(s)+1))
+		b = append(b, s...)
+		b = append(b, 0)
+		return append(b, make([]byte, 4+4+2+1+67)...) // unicode + scriptcode kısmı boş
+	}
+	text := func(s string) []byte {
+		b := make([]byte, 8, 8+len(s)+1)
+		copy(b, "text")
+		return append(append(b, s...), 0)
+	}
+
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	tags := []tag{
+		{"desc", desc("sRGB IEC61966-2.1")},
+		{"cprt", text("No copyright, use freely")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		{"gTRC", curve},
+		{"bTRC", curve},
+	}
+
+	table := 4 + 12*len(tags)
+	body := []byte{}
+	entries := make([]byte, table)
+	binary.BigEndian.PutUint32(entries, uint32(len(tags)))
+	offset := 128 + table
+	for i, t := range tags {
+		e := entries[4+12*i:]
+		copy(e, t.sig)
+		binary.BigEndian.PutUint32(e[4:], uint32(offset+len(body)))
+		binary.BigEndian.PutUint32(e[8:], uint32(len(t.data)))
+		body = append(body, t.data...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	h := make([]byte, 128)
+	binary.BigEndian.PutUint32(h[0:], uint32(128+table+len(body)))
+	binary.BigEndian.PutUint32(h[8:], 0x02100000) // v2.1
+	copy(h[12:], "mntr")
+	copy(h[16:], "RGB ")
+	copy(h[20:], "XYZ ")
+	binary.BigEndian.PutUint16(h[24:], 2026)
+	binary.BigEndian.PutUint16(h[26:], 1)
+	binary.BigEndian.PutUint16(h[28:], 1)
+	copy(h[36:], "acsp")
+	binary.BigEndian.PutUint32(h[68:], s15(0.9642))
+	binary.BigEndian.PutUint32(h[72:], s15(1.0))
+	binary.BigEndian.PutUint32(h[76:], s15(0.8249))
+
+	return append(append(h, entries...), body...)
+}
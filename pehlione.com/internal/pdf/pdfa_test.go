@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"pehlione.com/app/internal/modules/orders"
+)
+
+func TestGenerateHybridInvoice_PDFA3(t *testing.T) {
+	inv := "INV-2026-000007"
+	at := time.Date(2026, 3, 5, 14, 30, 0, 0, time.UTC)
+	data := InvoiceData{
+		Order: orders.Order{
+			ID:            "8f0c3a1e-0000-4000-8000-000000000001",
+			InvoiceNumber: &inv,
+			InvoicedAt:    &at,
+			Currency:      "EUR",
+			SubtotalCents: 2000,
+			TotalCents:    2000,
+			CreatedAt:     at,
+		},
+		Items: []orders.OrderItem{{ProductName: "Çay bardağı", Quantity: 2, LineTotalCents: 2000, Currency: "EUR"}},
+	}
+	xmlData := []byte(`<?xml version="1.0" encoding="UTF-8"?><Invoice/>`)
+
+	out, err := GenerateHybridInvoice(data, "pehlione-invoice-INV-2026-000007.xml", xmlData)
+	require.NoError(t, err)
+
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-1.7\n%\xE2\xE3\xCF\xD3\n")))
+	require.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	require.NotContains(t, string(out), "/BaseFont /Helvetica", "core fonts are not embedded")
+	require.Contains(t, string(out), "<pdfaid:part>3</pdfaid:part>")
+	require.Contains(t, string(out), "/AFRelationship /Alternative")
+	require.Contains(t, string(out), "/Subtype /text#2Fxml")
+	require.Contains(t, string(out), string(xmlData))
+	require.Regexp(t, `/OutputIntents \[\d+ 0 R\]`, string(out))
+	require.Regexp(t, `/ID \[<[0-9a-f]{32}> <[0-9a-f]{32}>\]`, string(out))
+
+	// her xref bölümü (/Prev zinciri) nesnelerin gerçek ofsetlerini göstermeli
+	xref := lastStartXref(t, out)
+	sections := 0
+	for xref >= 0 {
+		xref = checkXrefSection(t, out, xref)
+		sections++
+	}
+	require.Equal(t, 2, sections)
+}
+
+func TestSRGBProfile_Header(t *testing.T) {
+	icc := srgbProfile()
+	require.Equal(t, uint32(len(icc)), binary.BigEndian.Uint32(icc))
+	require.Equal(t, "acsp", string(icc[36:40]))
+	require.Equal(t, "RGB XYZ ", string(icc[16:24]))
+	require.Zero(t, len(icc)%4)
+}
+
+func lastStartXref(t *testing.T, doc []byte) int {
+	t.Helper()
+	sx := bytes.LastIndex(doc, []byte("startxref\n"))
+	require.GreaterOrEqual(t, sx, 0)
+	n, err := strconv.Atoi(strings.Fields(string(doc[sx+len("startxref\n"):]))[0])
+	require.NoError(t, err)
+	return n
+}
+
+var rePrev = regexp.MustCompile(`/Prev (\d+)`)
+
+// checkXrefSection verifies the entries of the xref table at off and returns
+// its /Prev offset, or -1.
+func checkXrefSection(t *testing.T, doc []byte, off int) int {
+	t.Helper()
+	require.True(t, bytes.HasPrefix(doc[off:], []byte("xref\n")), "no xref at %d", off)
+	pos := off + len("xref\n")
+	for {
+		eol := bytes.IndexByte(doc[pos:], '\n')
+		head := strings.Fields(string(doc[pos : pos+eol]))
+		if len(head) != 2 {
+			break
+		}
+		first, _ := strconv.Atoi(head[0])
+		count, _ := strconv.Atoi(head[1])
+		pos += eol + 1
+		for i := range count {
+			entry := doc[pos : pos+20]
+			require.Len(t, strings.TrimRight(string(entry), "\n"), 19)
+			if entry[17] == 'n' {
+				at, err := strconv.Atoi(string(entry[:10]))
+				require.NoError(t, err)
+				want := fmt.Sprintf("%d 0 obj", first+i)
+				require.True(t, bytes.HasPrefix(doc[at:], []byte(want)), "object %d not at %d", first+i, at)
+			}
+			pos += 20
+		}
+	}
+	trailerEnd := bytes.Index(doc[pos:], []byte("startxref"))
+	if m := rePrev.FindSubmatch(doc[pos : pos+trailerEnd]); m != nil {
+		prev, _ := strconv.Atoi(string(m[1]))
+		return prev
+	}
+	return -1
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"pehlione.com/app/internal/modules/inventory"
+)
+
+// PickListData is a batch of paid orders to pick in one walk. Lines come
+// sorted by location (inventory.BuildPickList).
+type PickListData struct {
+	GeneratedAt time.Time
+	Orders      []string // sipariş numaraları
+	Lines       []inventory.PickLine
+}
+
+// Filename is the download name of the pick list.
+func (d PickListData) Filename() string {
+	return "pehlione-pick-list-" + d.GeneratedAt.Local().Format("20060102-1504") + ".pdf"
+}
+
+func GeneratePickList(data PickListData) ([]byte, error) {
+	p := fpdf.New("P", "mm", "A4", "")
+	useEmbeddedFonts(p)
+	p.SetMargins(15, 20, 15)
+	p.AliasNbPages("")
+	p.SetFooterFunc(func() {
+		p.SetY(-15)
+		p.SetFont("Helvetica", "", 9)
+		p.SetTextColor(120, 124, 139)
+		p.CellFormat(0, 5, fmt.Sprintf("Page %d/{nb}", p.PageNo()), "", 0, "C", false, 0, "")
+		p.SetTextColor(0, 0, 0)
+	})
+	p.AddPage()
+
+	renderHeader(p)
+	p.SetFont("Helvetica", "B", 16)
+	p.CellFormat(0, 8, "Pick List", "", 1, "L", false, 0, "")
+	p.Ln(2)
+
+	p.SetFont("Helvetica", "", 11)
+	p.CellFormat(40, 6, "Generated:", "", 0, "L", false, 0, "")
+	p.CellFormat(0, 6, data.GeneratedAt.Local().Format("02.01.2006 15:04"), "", 1, "L", false, 0, "")
+	p.CellFormat(40, 6, "Orders:", "", 0, "L", false, 0, "")
+	p.MultiCell(0, 6, fmt.Sprintf("%d - %s", len(data.Orders), strings.Join(data.Orders, ", ")), "", "L", false)
+	p.Ln(4)
+
+	renderPickLines(p, data.Lines)
+
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderPickLines(p *fpdf.Fpdf, lines []inventory.PickLine) {
+	head := func() {
+		p.SetFont("Helvetica", "B", 10)
+		p.SetFillColor(248, 250, 252)
+		p.CellFormat(35, 8, "SKU", "1", 0, "L", true, 0, "")
+		p.CellFormat(60, 8, "Product", "1", 0, "L", true, 0, "")
+		p.CellFormat(40, 8, "Options", "1", 0, "L", true, 0, "")
+		p.CellFormat(15, 8, "Qty", "1", 0, "C", true, 0, "")
+		p.CellFormat(20, 8, "Orders", "1", 0, "C", true, 0, "")
+		p.CellFormat(10, 8, "", "1", 1, "C", true, 0, "")
+	}
+
+	location := "-"
+	for _, l := range lines {
+		if l.LocationCode != location {
+			location = l.LocationCode
+			title := "Unassigned location"
+			if location != "" {
+				title = location + " - " + l.LocationName
+			}
+			p.Ln(2)
+			p.SetFont("Helvetica", "B", 12)
+			p.CellFormat(0, 8, title, "", 1, "L", false, 0, "")
+			head()
+		}
+
+		p.SetFont("Helvetica", "", 10)
+		p.CellFormat(35, 8, truncate(l.SKU, 20), "1", 0, "L", false, 0, "")
+		p.CellFormat(60, 8, truncate(l.ProductName, 34), "1", 0, "L", false, 0, "")
+		p.CellFormat(40, 8, truncate(optionsLabel([]byte(l.Options)), 22), "1", 0, "L", false, 0, "")
+		p.CellFormat(15, 8, fmt.Sprintf("%d", l.Qty), "1", 0, "C", false, 0, "")
+		p.CellFormat(20, 8, fmt.Sprintf("%d", len(l.Orders)), "1", 0, "C", false, 0, "")
+		p.CellFormat(10, 8, "", "1", 1, "C", false, 0, "")
+	}
+}
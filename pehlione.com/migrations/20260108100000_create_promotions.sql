@@ -0,0 +1,55 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS promotions (
+  id CHAR(36) NOT NULL,
+  code VARCHAR(64) NOT NULL,
+  description VARCHAR(255) NULL,
+  kind VARCHAR(16) NOT NULL,
+  percent_off INT NOT NULL DEFAULT 0,
+  amount_off_cents INT NOT NULL DEFAULT 0,
+  currency CHAR(3) NULL,
+  min_subtotal_cents INT NOT NULL DEFAULT 0,
+  starts_at DATETIME(3) NULL,
+  ends_at DATETIME(3) NULL,
+  max_redemptions INT NOT NULL DEFAULT 0,
+  max_per_customer INT NOT NULL DEFAULT 0,
+  redeemed_count INT NOT NULL DEFAULT 0,
+  product_ids_json JSON NULL,
+  category_slugs_json JSON NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'active',
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_promotions_code (code),
+  KEY idx_promotions_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+  id CHAR(36) NOT NULL,
+  promotion_id CHAR(36) NOT NULL,
+  order_id CHAR(36) NOT NULL,
+  user_id CHAR(36) NULL,
+  email VARCHAR(255) NULL,
+  code VARCHAR(64) NOT NULL,
+  discount_cents INT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  base_discount_cents INT NOT NULL,
+  base_currency CHAR(3) NOT NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_promotion_redemptions_order (promotion_id, order_id),
+  KEY idx_promotion_redemptions_user (promotion_id, user_id),
+  KEY idx_promotion_redemptions_email (promotion_id, email),
+  KEY idx_promotion_redemptions_order (order_id),
+  CONSTRAINT fk_promotion_redemptions_promotion FOREIGN KEY (promotion_id) REFERENCES promotions(id) ON DELETE RESTRICT,
+  CONSTRAINT fk_promotion_redemptions_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE orders
+  ADD COLUMN promo_code VARCHAR(64) NULL AFTER discount_cents;
+
+-- +goose Down
+ALTER TABLE orders
+  DROP COLUMN promo_code;
+
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotions;
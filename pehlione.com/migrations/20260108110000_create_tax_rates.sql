@@ -0,0 +1,58 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tax_rates (
+  id CHAR(36) NOT NULL,
+  country CHAR(2) NOT NULL,
+  tax_class VARCHAR(32) NOT NULL,
+  name VARCHAR(64) NOT NULL,
+  rate_bps INT NOT NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_tax_rates_country_class (country, tax_class)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE products
+  ADD COLUMN tax_class VARCHAR(32) NOT NULL DEFAULT 'standard' AFTER category_slug;
+
+ALTER TABLE orders
+  ADD COLUMN prices_include_tax TINYINT(1) NOT NULL DEFAULT 1 AFTER base_total_cents;
+
+ALTER TABLE order_items
+  ADD COLUMN tax_class VARCHAR(32) NOT NULL DEFAULT 'standard' AFTER base_line_total_cents,
+  ADD COLUMN tax_rate_bps INT NOT NULL DEFAULT 0 AFTER tax_class,
+  ADD COLUMN tax_cents INT NOT NULL DEFAULT 0 AFTER tax_rate_bps,
+  ADD COLUMN base_tax_cents INT NOT NULL DEFAULT 0 AFTER tax_cents;
+
+-- Standard and reduced VAT rates (basis points) for the countries we ship to.
+INSERT INTO tax_rates (id, country, tax_class, name, rate_bps) VALUES
+  (UUID(), 'TR', 'standard', 'KDV', 2000),
+  (UUID(), 'TR', 'reduced', 'KDV', 1000),
+  (UUID(), 'DE', 'standard', 'MwSt', 1900),
+  (UUID(), 'DE', 'reduced', 'MwSt', 700),
+  (UUID(), 'AT', 'standard', 'USt', 2000),
+  (UUID(), 'AT', 'reduced', 'USt', 1000),
+  (UUID(), 'NL', 'standard', 'BTW', 2100),
+  (UUID(), 'NL', 'reduced', 'BTW', 900),
+  (UUID(), 'BE', 'standard', 'BTW', 2100),
+  (UUID(), 'BE', 'reduced', 'BTW', 600),
+  (UUID(), 'FR', 'standard', 'TVA', 2000),
+  (UUID(), 'FR', 'reduced', 'TVA', 550),
+  (UUID(), 'IT', 'standard', 'IVA', 2200),
+  (UUID(), 'IT', 'reduced', 'IVA', 1000),
+  (UUID(), 'ES', 'standard', 'IVA', 2100),
+  (UUID(), 'ES', 'reduced', 'IVA', 1000);
+
+-- +goose Down
+ALTER TABLE order_items
+  DROP COLUMN base_tax_cents,
+  DROP COLUMN tax_cents,
+  DROP COLUMN tax_rate_bps,
+  DROP COLUMN tax_class;
+
+ALTER TABLE orders
+  DROP COLUMN prices_include_tax;
+
+ALTER TABLE products
+  DROP COLUMN tax_class;
+
+DROP TABLE IF EXISTS tax_rates;
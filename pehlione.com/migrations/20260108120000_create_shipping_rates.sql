@@ -0,0 +1,55 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS shipping_zones (
+  id CHAR(36) NOT NULL,
+  name VARCHAR(128) NOT NULL,
+  countries_json JSON NOT NULL,
+  priority INT NOT NULL DEFAULT 0,
+  status VARCHAR(16) NOT NULL DEFAULT 'active',
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  KEY idx_shipping_zones_status_priority (status, priority)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS shipping_methods (
+  id CHAR(36) NOT NULL,
+  zone_id CHAR(36) NOT NULL,
+  code VARCHAR(32) NOT NULL,
+  name VARCHAR(128) NOT NULL,
+  rate_type VARCHAR(16) NOT NULL,
+  flat_cents INT NOT NULL DEFAULT 0,
+  tiers_json JSON NULL,
+  free_over_cents INT NOT NULL DEFAULT 0,
+  position INT NOT NULL DEFAULT 0,
+  status VARCHAR(16) NOT NULL DEFAULT 'active',
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_shipping_methods_zone_code (zone_id, code),
+  KEY idx_shipping_methods_zone_position (zone_id, position),
+  CONSTRAINT fk_shipping_methods_zone FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE product_variants
+  ADD COLUMN weight_grams INT NOT NULL DEFAULT 0 AFTER stock;
+
+-- Defaults mirror the previous hard-coded standard (500) / express (1500) prices.
+INSERT INTO shipping_zones (id, name, countries_json, priority, status) VALUES
+  ('00000000-0000-0000-0000-00000000a001', 'Türkiye', JSON_ARRAY('TR'), 10, 'active'),
+  ('00000000-0000-0000-0000-00000000a002', 'Europe', JSON_ARRAY('DE','AT','NL','BE','FR','IT','ES'), 20, 'active'),
+  ('00000000-0000-0000-0000-00000000a003', 'Rest of world', JSON_ARRAY('*'), 100, 'active');
+
+INSERT INTO shipping_methods (id, zone_id, code, name, rate_type, flat_cents, tiers_json, free_over_cents, position, status) VALUES
+  (UUID(), '00000000-0000-0000-0000-00000000a001', 'standard', 'Standard (2-4 gün)', 'flat', 500, NULL, 0, 10, 'active'),
+  (UUID(), '00000000-0000-0000-0000-00000000a001', 'express', 'Express (1-2 gün)', 'flat', 1500, NULL, 0, 20, 'active'),
+  (UUID(), '00000000-0000-0000-0000-00000000a002', 'standard', 'Standard (4-7 gün)', 'weight', 0,
+    JSON_ARRAY(JSON_OBJECT('up_to', 2000, 'cents', 500), JSON_OBJECT('up_to', 10000, 'cents', 1000), JSON_OBJECT('up_to', 0, 'cents', 2000)), 0, 10, 'active'),
+  (UUID(), '00000000-0000-0000-0000-00000000a002', 'express', 'Express (2-3 gün)', 'flat', 1500, NULL, 0, 20, 'active'),
+  (UUID(), '00000000-0000-0000-0000-00000000a003', 'standard', 'International', 'flat', 2500, NULL, 0, 10, 'active');
+
+-- +goose Down
+ALTER TABLE product_variants
+  DROP COLUMN weight_grams;
+
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS shipping_zones;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS stock_reservations (
+  id CHAR(36) NOT NULL,
+  order_id CHAR(36) NOT NULL,
+  variant_id CHAR(36) NOT NULL,
+  qty INT NOT NULL,
+  status VARCHAR(16) NOT NULL,            -- active|converted|released
+  expires_at DATETIME(3) NOT NULL,
+  converted_at DATETIME(3) NULL,
+  released_at DATETIME(3) NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_stock_reservations_order_variant (order_id, variant_id),
+  KEY ix_stock_reservations_status_expires (status, expires_at),
+  KEY ix_stock_reservations_variant_status (variant_id, status),
+  CONSTRAINT fk_stock_reservations_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+  CONSTRAINT fk_stock_reservations_variant FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- system actor for automated order events (order_events.actor_user_id is NOT NULL)
+INSERT IGNORE INTO users (id, email, password_hash, role, created_at, updated_at)
+VALUES ('00000000-0000-0000-0000-000000000000', 'system@pehlione.local', '!', 'system', NOW(3), NOW(3));
+
+-- +goose Down
+DELETE FROM users WHERE id = '00000000-0000-0000-0000-000000000000';
+DROP TABLE IF EXISTS stock_reservations;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS stock_movements (
+  id CHAR(36) NOT NULL,
+  variant_id CHAR(36) NOT NULL,
+  delta INT NOT NULL,
+  stock_after INT NOT NULL,
+  reason VARCHAR(16) NOT NULL,            -- sale|restock
+  order_id CHAR(36) NULL,
+  ref_type VARCHAR(16) NULL,              -- order|refund|reservation
+  ref_id VARCHAR(64) NULL,
+  actor_user_id CHAR(36) NULL,
+  note VARCHAR(255) NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  KEY ix_stock_movements_variant_created (variant_id, created_at),
+  KEY ix_stock_movements_order (order_id),
+  CONSTRAINT fk_stock_movements_variant FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- refunds: admin "restock / don't restock" choice, applied when the refund completes (also via webhook)
+ALTER TABLE refunds ADD COLUMN restock TINYINT(1) NOT NULL DEFAULT 0 AFTER reason;
+
+-- +goose Down
+ALTER TABLE refunds DROP COLUMN restock;
+DROP TABLE IF EXISTS stock_movements;
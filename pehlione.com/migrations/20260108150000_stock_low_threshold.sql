@@ -0,0 +1,14 @@
+-- +goose Up
+-- stock_movements.reason: sale|restock|adjust|return|import
+ALTER TABLE product_variants ADD COLUMN low_stock_threshold INT NOT NULL DEFAULT 0 AFTER stock;
+
+-- opening balance so the ledger sums to the current stock
+INSERT INTO stock_movements (id, variant_id, delta, stock_after, reason, ref_type, note, created_at)
+SELECT UUID(), v.id, v.stock - COALESCE(m.total, 0), v.stock, 'import', 'opening', 'opening balance', NOW(3)
+FROM product_variants v
+LEFT JOIN (SELECT variant_id, SUM(delta) AS total FROM stock_movements GROUP BY variant_id) m ON m.variant_id = v.id
+WHERE v.stock - COALESCE(m.total, 0) <> 0;
+
+-- +goose Down
+DELETE FROM stock_movements WHERE ref_type = 'opening';
+ALTER TABLE product_variants DROP COLUMN low_stock_threshold;
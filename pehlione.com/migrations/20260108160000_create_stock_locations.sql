@@ -0,0 +1,62 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS stock_locations (
+  id CHAR(36) NOT NULL,
+  code VARCHAR(32) NOT NULL,
+  name VARCHAR(128) NOT NULL,
+  priority INT NOT NULL DEFAULT 0,          -- lower ships first
+  status VARCHAR(16) NOT NULL DEFAULT 'active',
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_stock_locations_code (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- product_variants.stock stays the sum of all levels (kept in sync by inventory.ApplyInTx)
+CREATE TABLE IF NOT EXISTS stock_levels (
+  location_id CHAR(36) NOT NULL,
+  variant_id CHAR(36) NOT NULL,
+  qty INT NOT NULL DEFAULT 0,
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (location_id, variant_id),
+  KEY ix_stock_levels_variant (variant_id),
+  CONSTRAINT fk_stock_levels_location FOREIGN KEY (location_id) REFERENCES stock_locations(id) ON DELETE CASCADE,
+  CONSTRAINT fk_stock_levels_variant FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS order_item_allocations (
+  id CHAR(36) NOT NULL,
+  order_item_id CHAR(36) NOT NULL,
+  location_id CHAR(36) NOT NULL,
+  qty INT NOT NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_order_item_allocations_item_location (order_item_id, location_id),
+  CONSTRAINT fk_order_item_allocations_item FOREIGN KEY (order_item_id) REFERENCES order_items(id) ON DELETE CASCADE,
+  CONSTRAINT fk_order_item_allocations_location FOREIGN KEY (location_id) REFERENCES stock_locations(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE stock_movements
+  ADD COLUMN location_id CHAR(36) NULL AFTER variant_id,
+  ADD KEY ix_stock_movements_location (location_id);
+
+INSERT INTO stock_locations (id, code, name, priority, status) VALUES
+  ('5a1e0c2e-0000-4000-8000-000000000001', 'wh1', 'Warehouse 1', 10, 'active'),
+  ('5a1e0c2e-0000-4000-8000-000000000002', 'wh2', 'Warehouse 2', 20, 'active');
+
+-- existing stock is assumed to sit in the primary warehouse
+INSERT INTO stock_levels (location_id, variant_id, qty)
+SELECT '5a1e0c2e-0000-4000-8000-000000000001', id, stock
+FROM product_variants
+WHERE stock > 0;
+
+UPDATE stock_movements
+SET location_id = '5a1e0c2e-0000-4000-8000-000000000001'
+WHERE location_id IS NULL;
+
+-- +goose Down
+ALTER TABLE stock_movements
+  DROP KEY ix_stock_movements_location,
+  DROP COLUMN location_id;
+DROP TABLE IF EXISTS order_item_allocations;
+DROP TABLE IF EXISTS stock_levels;
+DROP TABLE IF EXISTS stock_locations;
@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS returns (
+  id CHAR(36) NOT NULL,
+  order_id CHAR(36) NOT NULL,
+  user_id CHAR(36) NOT NULL,
+  status VARCHAR(16) NOT NULL,              -- requested|approved|rejected|received|refunded
+  reason VARCHAR(500) NOT NULL,
+  admin_note VARCHAR(255) NULL,
+  refund_cents INT NOT NULL DEFAULT 0,      -- order currency
+  currency CHAR(3) NOT NULL,
+  refund_id CHAR(36) NULL,
+  label_carrier VARCHAR(64) NULL,
+  label_tracking_no VARCHAR(128) NULL,
+  label_url VARCHAR(512) NULL,
+  approved_at DATETIME(3) NULL,
+  received_at DATETIME(3) NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  KEY ix_returns_order_id (order_id),
+  KEY ix_returns_user_created (user_id, created_at),
+  KEY ix_returns_status_created (status, created_at),
+  CONSTRAINT fk_returns_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+  CONSTRAINT fk_returns_user FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT fk_returns_refund FOREIGN KEY (refund_id) REFERENCES refunds(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS return_items (
+  id CHAR(36) NOT NULL,
+  return_id CHAR(36) NOT NULL,
+  order_item_id CHAR(36) NOT NULL,
+  variant_id CHAR(36) NOT NULL,
+  product_name VARCHAR(255) NOT NULL,
+  sku VARCHAR(64) NOT NULL,
+  qty INT NOT NULL,
+  refund_cents INT NOT NULL DEFAULT 0,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  KEY ix_return_items_return_id (return_id),
+  KEY ix_return_items_order_item_id (order_item_id),
+  CONSTRAINT fk_return_items_return FOREIGN KEY (return_id) REFERENCES returns(id) ON DELETE CASCADE,
+  CONSTRAINT fk_return_items_order_item FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS return_items;
+DROP TABLE IF EXISTS returns;
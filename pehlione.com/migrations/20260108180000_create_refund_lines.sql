@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS refund_lines (
+  id CHAR(36) NOT NULL,
+  refund_id CHAR(36) NOT NULL,
+  kind VARCHAR(16) NOT NULL,                -- item|shipping|adjustment
+  order_item_id CHAR(36) NULL,              -- kind=item
+  label VARCHAR(255) NOT NULL,
+  qty INT NOT NULL DEFAULT 0,
+  amount_cents INT NOT NULL,                -- charge currency, tax included
+  tax_cents INT NOT NULL DEFAULT 0,         -- tax share of amount_cents
+  currency CHAR(3) NOT NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (id),
+  KEY ix_refund_lines_refund_id (refund_id),
+  KEY ix_refund_lines_order_item_id (order_item_id),
+  CONSTRAINT fk_refund_lines_refund FOREIGN KEY (refund_id) REFERENCES refunds(id) ON DELETE CASCADE,
+  CONSTRAINT fk_refund_lines_order_item FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS refund_lines;
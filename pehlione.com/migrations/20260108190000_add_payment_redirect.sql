@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE payments
+  ADD COLUMN redirect_url VARCHAR(1024) NULL AFTER error_message,
+  ADD COLUMN return_token CHAR(32) NULL AFTER redirect_url,
+  ADD KEY ix_payments_return_token (return_token);
+
+-- +goose Down
+ALTER TABLE payments
+  DROP KEY ix_payments_return_token,
+  DROP COLUMN return_token,
+  DROP COLUMN redirect_url;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE orders
+  ADD COLUMN payment_method VARCHAR(32) NULL AFTER prices_include_tax;
+
+-- checkout bu değeri daha önce yalnızca adres JSON'una yazıyordu
+UPDATE orders
+  SET payment_method = JSON_UNQUOTE(JSON_EXTRACT(shipping_address_json, '$.payment_method'))
+  WHERE payment_method IS NULL AND shipping_address_json IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE orders
+  DROP COLUMN payment_method;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- normalized event fields so failed events can be replayed without the
+-- provider signature
+ALTER TABLE provider_events
+  ADD COLUMN payment_ref VARCHAR(128) NULL AFTER event_type,
+  ADD COLUMN refund_ref VARCHAR(128) NULL AFTER payment_ref,
+  ADD COLUMN amount_cents INT NOT NULL DEFAULT 0 AFTER refund_ref,
+  ADD COLUMN currency CHAR(3) NULL AFTER amount_cents,
+  ADD COLUMN attempts INT NOT NULL DEFAULT 0 AFTER process_error;
+
+-- +goose Down
+ALTER TABLE provider_events
+  DROP COLUMN attempts,
+  DROP COLUMN currency,
+  DROP COLUMN amount_cents,
+  DROP COLUMN refund_ref,
+  DROP COLUMN payment_ref;
@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE disputes (
+  id CHAR(36) NOT NULL,
+  order_id CHAR(36) NOT NULL,
+  payment_id CHAR(36) NOT NULL,
+
+  provider VARCHAR(64) NOT NULL,
+  provider_ref VARCHAR(128) NOT NULL,
+
+  status VARCHAR(16) NOT NULL,
+  reason VARCHAR(255) NULL,
+  amount_cents INT NOT NULL,
+  currency CHAR(3) NOT NULL,
+
+  opened_at DATETIME(3) NOT NULL,
+  closed_at DATETIME(3) NULL,
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_disputes_provider_ref (provider, provider_ref),
+  KEY ix_disputes_order_id (order_id),
+  KEY ix_disputes_payment_id (payment_id),
+  KEY ix_disputes_status_opened (status, opened_at),
+
+  CONSTRAINT fk_disputes_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+  CONSTRAINT fk_disputes_payment FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE orders
+  ADD COLUMN dispute_status VARCHAR(16) NULL AFTER refunded_at;
+
+ALTER TABLE provider_events
+  ADD COLUMN dispute_ref VARCHAR(128) NULL AFTER refund_ref;
+
+-- +goose Down
+ALTER TABLE provider_events
+  DROP COLUMN dispute_ref;
+
+ALTER TABLE orders
+  DROP COLUMN dispute_status;
+
+DROP TABLE IF EXISTS disputes;
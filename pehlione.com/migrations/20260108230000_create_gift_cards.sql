@@ -0,0 +1,71 @@
+-- +goose Up
+CREATE TABLE gift_cards (
+  id CHAR(36) NOT NULL,
+  code VARCHAR(32) NOT NULL,
+
+  kind VARCHAR(16) NOT NULL,   -- gift_card|store_credit
+  status VARCHAR(16) NOT NULL, -- active|disabled
+  currency CHAR(3) NOT NULL,
+  initial_cents INT NOT NULL,
+  balance_cents INT NOT NULL,
+
+  user_id CHAR(36) NULL,       -- store credit sahibi
+  recipient_email VARCHAR(255) NULL,
+  source_order_id CHAR(36) NULL,
+  source_order_item_id CHAR(36) NULL,
+
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_gift_cards_code (code),
+  KEY ix_gift_cards_user_kind (user_id, kind, currency),
+  KEY ix_gift_cards_source_order (source_order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE gift_card_transactions (
+  id CHAR(36) NOT NULL,
+  gift_card_id CHAR(36) NOT NULL,
+  order_id CHAR(36) NULL,
+
+  kind VARCHAR(16) NOT NULL,   -- issue|redeem|release|credit
+  amount_cents INT NOT NULL,   -- +in, -out
+  balance_after_cents INT NOT NULL,
+  currency CHAR(3) NOT NULL,
+
+  ref_type VARCHAR(16) NOT NULL, -- order|order_item|refund
+  ref_id CHAR(36) NOT NULL,
+
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+
+  PRIMARY KEY (id),
+  UNIQUE KEY ux_gift_card_tx_ref (gift_card_id, kind, ref_type, ref_id),
+  KEY ix_gift_card_tx_order (order_id, kind),
+
+  CONSTRAINT fk_gift_card_tx_card FOREIGN KEY (gift_card_id) REFERENCES gift_cards(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE products
+  ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'standard' AFTER tax_class;
+
+ALTER TABLE orders
+  ADD COLUMN gift_card_cents INT NOT NULL DEFAULT 0 AFTER total_cents,
+  ADD COLUMN gift_recipient_email VARCHAR(255) NULL AFTER guest_email;
+
+-- store credit iadelerinde sağlayıcı ödemesi olmayabilir (tamamı hediye kartıyla ödenmiş sipariş)
+ALTER TABLE refunds
+  MODIFY COLUMN payment_id CHAR(36) NULL;
+
+-- +goose Down
+ALTER TABLE refunds
+  MODIFY COLUMN payment_id CHAR(36) NOT NULL;
+
+ALTER TABLE orders
+  DROP COLUMN gift_recipient_email,
+  DROP COLUMN gift_card_cents;
+
+ALTER TABLE products
+  DROP COLUMN kind;
+
+DROP TABLE IF EXISTS gift_card_transactions;
+DROP TABLE IF EXISTS gift_cards;
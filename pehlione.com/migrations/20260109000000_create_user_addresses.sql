@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE user_addresses (
+  id CHAR(36) NOT NULL,
+  user_id CHAR(36) NOT NULL,
+
+  label VARCHAR(64) NOT NULL DEFAULT '',
+  first_name VARCHAR(100) NOT NULL,
+  last_name VARCHAR(100) NOT NULL,
+  address1 VARCHAR(255) NOT NULL,
+  address2 VARCHAR(255) NOT NULL DEFAULT '',
+  city VARCHAR(100) NOT NULL,
+  postal_code VARCHAR(32) NOT NULL,
+  country CHAR(2) NOT NULL,
+  phone VARCHAR(32) NOT NULL DEFAULT '',
+
+  is_default TINYINT(1) NOT NULL DEFAULT 0,
+
+  created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+
+  PRIMARY KEY (id),
+  KEY ix_user_addresses_user (user_id, is_default),
+
+  CONSTRAINT fk_user_addresses_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- eski serbest metin adres, düzenlenmek üzere varsayılan adres olarak taşınır
+INSERT INTO user_addresses (id, user_id, label, first_name, last_name, address1, city, postal_code, country, phone, is_default)
+SELECT UUID(), id, 'Profile', COALESCE(first_name, ''), COALESCE(last_name, ''), LEFT(address, 255), '', '', '', COALESCE(phone_e164, ''), 1
+FROM users
+WHERE address IS NOT NULL AND TRIM(address) <> '';
+
+ALTER TABLE users
+  DROP COLUMN address;
+
+-- +goose Down
+ALTER TABLE users
+  ADD COLUMN address TEXT NULL AFTER last_name;
+
+UPDATE users u
+JOIN user_addresses a ON a.user_id = u.id AND a.is_default = 1
+SET u.address = a.address1;
+
+DROP TABLE IF EXISTS user_addresses;
@@ -0,0 +1,54 @@
+-- +goose Up
+CREATE TABLE number_sequences (
+  name VARCHAR(32) NOT NULL,
+  year INT NOT NULL,
+  last_value BIGINT NOT NULL,
+  updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+
+  PRIMARY KEY (name, year)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE orders
+  ADD COLUMN order_number VARCHAR(32) NULL AFTER id,
+  ADD COLUMN invoice_number VARCHAR(32) NULL AFTER order_number,
+  ADD COLUMN invoiced_at DATETIME(3) NULL AFTER invoice_number;
+
+-- mevcut siparişler: oluşturulma sırasına göre yıllık numara
+UPDATE orders o
+  JOIN (
+    SELECT id, YEAR(created_at) AS y,
+      ROW_NUMBER() OVER (PARTITION BY YEAR(created_at) ORDER BY created_at, id) AS n
+    FROM orders
+  ) s ON s.id = o.id
+  SET o.order_number = CONCAT('PH-', s.y, '-', LPAD(s.n, 6, '0'));
+
+-- ödenmiş siparişler: ödeme sırasına göre fatura numarası
+UPDATE orders o
+  JOIN (
+    SELECT id, YEAR(paid_at) AS y,
+      ROW_NUMBER() OVER (PARTITION BY YEAR(paid_at) ORDER BY paid_at, id) AS n
+    FROM orders
+    WHERE paid_at IS NOT NULL
+  ) s ON s.id = o.id
+  SET o.invoice_number = CONCAT('INV-', s.y, '-', LPAD(s.n, 6, '0')),
+      o.invoiced_at = o.paid_at;
+
+INSERT INTO number_sequences (name, year, last_value)
+  SELECT 'order', YEAR(created_at), COUNT(*) FROM orders GROUP BY YEAR(created_at);
+
+INSERT INTO number_sequences (name, year, last_value)
+  SELECT 'invoice', YEAR(paid_at), COUNT(*) FROM orders WHERE paid_at IS NOT NULL GROUP BY YEAR(paid_at);
+
+ALTER TABLE orders
+  ADD UNIQUE KEY ux_orders_order_number (order_number),
+  ADD UNIQUE KEY ux_orders_invoice_number (invoice_number);
+
+-- +goose Down
+ALTER TABLE orders
+  DROP INDEX ux_orders_invoice_number,
+  DROP INDEX ux_orders_order_number,
+  DROP COLUMN invoiced_at,
+  DROP COLUMN invoice_number,
+  DROP COLUMN order_number;
+
+DROP TABLE IF EXISTS number_sequences;
@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE refunds
+  ADD COLUMN credit_note_number VARCHAR(32) NULL AFTER error_message,
+  ADD COLUMN credit_note_issued_at DATETIME(3) NULL AFTER credit_note_number;
+
+-- başarılı iadeler: tamamlanma sırasına göre dekont numarası
+UPDATE refunds r
+  JOIN (
+    SELECT id, YEAR(updated_at) AS y,
+      ROW_NUMBER() OVER (PARTITION BY YEAR(updated_at) ORDER BY updated_at, id) AS n
+    FROM refunds
+    WHERE status = 'succeeded'
+  ) s ON s.id = r.id
+  SET r.credit_note_number = CONCAT('CN-', s.y, '-', LPAD(s.n, 6, '0')),
+      r.credit_note_issued_at = r.updated_at;
+
+INSERT INTO number_sequences (name, year, last_value)
+  SELECT 'credit_note', YEAR(updated_at), COUNT(*) FROM refunds WHERE status = 'succeeded' GROUP BY YEAR(updated_at);
+
+ALTER TABLE refunds
+  ADD UNIQUE KEY ux_refunds_credit_note_number (credit_note_number);
+
+-- e-posta ekleri (base64, gönderimde MIME'a eklenir)
+ALTER TABLE email_outbox
+  ADD COLUMN attachments JSON NULL AFTER payload;
+
+-- +goose Down
+ALTER TABLE email_outbox
+  DROP COLUMN attachments;
+
+ALTER TABLE refunds
+  DROP INDEX ux_refunds_credit_note_number,
+  DROP COLUMN credit_note_issued_at,
+  DROP COLUMN credit_note_number;
+
+DELETE FROM number_sequences WHERE name = 'credit_note';
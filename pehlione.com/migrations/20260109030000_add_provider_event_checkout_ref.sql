@@ -0,0 +1,11 @@
+-- +goose Up
+-- hosted checkout events match the payment by session; disputes keep their
+-- reason so both survive a replay
+ALTER TABLE provider_events
+  ADD COLUMN checkout_ref VARCHAR(128) NULL AFTER dispute_ref,
+  ADD COLUMN reason VARCHAR(255) NULL AFTER checkout_ref;
+
+-- +goose Down
+ALTER TABLE provider_events
+  DROP COLUMN reason,
+  DROP COLUMN checkout_ref;
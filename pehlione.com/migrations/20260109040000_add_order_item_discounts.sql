@@ -0,0 +1,12 @@
+-- +goose Up
+-- siparişin indirim payı kalem başına saklanır: kalem bazlı iade ve e-fatura
+-- checkout'ta yapılan dağıtımı kullanır. Eski siparişler 0 kalır; payları
+-- CreateFromCart'ın sırasıyla (varyant id) yeniden hesaplanır.
+ALTER TABLE order_items
+  ADD COLUMN discount_cents INT NOT NULL DEFAULT 0 AFTER line_total_cents,
+  ADD COLUMN base_discount_cents INT NOT NULL DEFAULT 0 AFTER base_line_total_cents;
+
+-- +goose Down
+ALTER TABLE order_items
+  DROP COLUMN base_discount_cents,
+  DROP COLUMN discount_cents;
@@ -0,0 +1,30 @@
+package view
+
+type AccountAddress struct {
+	ID        string
+	Label     string
+	Name      string
+	Lines     []string
+	Phone     string
+	IsDefault bool
+}
+
+type AccountAddressesPage struct {
+	Items []AccountAddress
+	Full  bool // adres defteri dolu; yeni adres eklenemez
+}
+
+// AddressForm holds the values of the address create/edit form.
+type AddressForm struct {
+	ID         string // boşsa yeni adres
+	Label      string
+	FirstName  string
+	LastName   string
+	Address1   string
+	Address2   string
+	City       string
+	PostalCode string
+	Country    string
+	Phone      string
+	IsDefault  bool
+}
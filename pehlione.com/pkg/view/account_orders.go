@@ -13,6 +13,7 @@ type AccountOrderListItem struct {
 	Currency   string
 	ItemCount  int
 	PaidAt     *time.Time
+	Returnable bool // teslim edildi: iade talebi açılabilir
 }
 
 type AccountInfo struct {
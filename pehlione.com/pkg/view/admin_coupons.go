@@ -0,0 +1,31 @@
+package view
+
+type AdminCouponListItem struct {
+	ID          string
+	Code        string
+	Kind        string
+	Value       string
+	Window      string
+	Usage       string
+	Status      string
+	Description string
+}
+
+type AdminCoupon struct {
+	ID               string
+	Code             string
+	Description      string
+	Kind             string
+	PercentOff       string
+	AmountOffCents   string
+	Currency         string
+	MinSubtotalCents string
+	StartsAt         string // 2006-01-02T15:04
+	EndsAt           string
+	MaxRedemptions   string
+	MaxPerCustomer   string
+	ProductIDs       string // virgülle ayrılmış
+	CategorySlugs    string
+	Status           string
+	RedeemedCount    int
+}
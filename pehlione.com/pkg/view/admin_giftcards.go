@@ -0,0 +1,27 @@
+package view
+
+type AdminGiftCard struct {
+	Query    string // aranan kod
+	NotFound bool
+
+	ID            string
+	Code          string
+	Kind          string
+	Status        string
+	Initial       string
+	Balance       string
+	Recipient     string
+	UserID        string
+	SourceOrderID string
+	CreatedAt     string
+	Transactions  []AdminGiftCardTx
+}
+
+type AdminGiftCardTx struct {
+	At           string
+	Kind         string
+	Amount       string // "+10.00 EUR" / "-5.00 EUR"
+	BalanceAfter string
+	OrderID      string
+	Ref          string
+}
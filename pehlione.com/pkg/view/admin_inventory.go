@@ -0,0 +1,23 @@
+package view
+
+type AdminStockMovements struct {
+	VariantID   string // filtre; boşsa tüm hareketler
+	SKU         string
+	ProductID   string
+	ProductName string
+	Items       []AdminStockMovement
+}
+
+type AdminStockMovement struct {
+	At          string
+	VariantID   string
+	SKU         string
+	ProductName string
+	Delta       string // "+3" / "-1"
+	StockAfter  int
+	Reason      string
+	OrderID     string
+	Ref         string
+	Actor       string
+	Note        string
+}
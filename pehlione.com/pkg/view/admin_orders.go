@@ -2,11 +2,14 @@ package view
 
 type AdminOrderListItem struct {
 	ID         string
+	Number     string
+	Invoice    string // fatura numarası (ödenmemişse boş)
 	Status     string
 	Total      string
 	CreatedAt  string
 	UserID     string
 	GuestEmail string
+	Dispute    string // open|won|lost (chargeback)
 }
 
 type AdminOrdersListPage struct {
@@ -15,6 +18,24 @@ type AdminOrdersListPage struct {
 	Status     string
 	Page       int
 	TotalPages int
+
+	CSRFToken         string
+	ShippingAvailable bool // etiket kuyruğa alma (toplu işlem)
+}
+
+// AdminOrderBatchResult is one order's outcome of a batch action.
+type AdminOrderBatchResult struct {
+	ID      string
+	Number  string
+	OK      bool
+	Message string
+}
+
+type AdminOrderBatchPage struct {
+	Action    string // ship|cancel|label
+	Results   []AdminOrderBatchResult
+	Succeeded int
+	Failed    int
 }
 
 type AdminOrderItem struct {
@@ -37,25 +58,70 @@ type AdminOrderEvent struct {
 
 type AdminOrderDetail struct {
 	ID         string
+	Number     string
+	Invoice    string
+	InvoicedAt string
 	Status     string
 	Currency   string
 	UserID     string
 	GuestEmail string
 	CreatedAt  string
 
-	Subtotal string
-	Shipping string
-	Tax      string
-	Discount string
-	Total    string
+	Subtotal  string
+	Shipping  string
+	Tax       string
+	Discount  string
+	PromoCode string
+	Total     string
+	GiftCard  string // hediye kartı/store credit ile ödenen (yoksa boş)
+	AmountDue string // sağlayıcıdan tahsil edilen
 
 	Items             []AdminOrderItem
 	Events            []AdminOrderEvent
 	Shipments         []AdminShipment
 	Financial         []AdminOrderFinancialEntry
+	Refunds           []AdminOrderRefund
+	Dispute           string
+	Disputes          []AdminOrderDispute
 	ShippingAvailable bool
 }
 
+type AdminOrderDispute struct {
+	ID       string
+	Status   string
+	Amount   string
+	Reason   string
+	OpenedAt string
+	ClosedAt string
+}
+
+type AdminOrderRefund struct {
+	ID         string
+	Status     string
+	Amount     string
+	Reason     string
+	At         string
+	CreditNote string // dekont numarası (başarılı iadelerde)
+	Lines      []AdminOrderRefundLine
+}
+
+type AdminOrderRefundLine struct {
+	Kind   string
+	Label  string
+	Qty    int
+	Amount string
+	Tax    string
+}
+
+// AdminRefundItem is an order item row on the admin refund form.
+type AdminRefundItem struct {
+	OrderItemID string
+	Name        string
+	SKU         string
+	Unit        string
+	Max         int
+}
+
 type AdminOrderFinancialEntry struct {
 	Event       string
 	AmountCents int
@@ -13,7 +13,18 @@ type AdminVariant struct {
 	PriceCents int
 	Currency   string
 	Stock      int
+	Weight     int    // grams
+	LowStock   int    // alert threshold, 0 = off
 	Options    string // JSON string
+	Levels     []AdminStockLevel
+}
+
+// AdminStockLevel is the on-hand quantity of a variant at one location.
+type AdminStockLevel struct {
+	LocationID string
+	Code       string
+	Name       string
+	Qty        int
 }
 
 type AdminImage struct {
@@ -28,6 +39,8 @@ type AdminProduct struct {
 	Slug        string
 	Description string
 	Status      string
+	TaxClass    string
+	Kind        string // standard|gift_card
 	Variants    []AdminVariant
 	Images      []AdminImage
 }
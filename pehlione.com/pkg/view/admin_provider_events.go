@@ -0,0 +1,38 @@
+package view
+
+type AdminProviderEventsList struct {
+	State     string
+	Type      string
+	Provider  string
+	States    []string
+	Types     []string
+	Providers []string
+	Items     []AdminProviderEventItem
+}
+
+type AdminProviderEventItem struct {
+	ID         string
+	Provider   string
+	EventID    string
+	Type       string
+	State      string
+	Attempts   int
+	Error      string
+	ReceivedAt string
+}
+
+type AdminProviderEventDetail struct {
+	ID          string
+	Provider    string
+	EventID     string
+	Type        string
+	State       string
+	Attempts    int
+	Error       string
+	PaymentRef  string
+	RefundRef   string
+	Amount      string
+	ReceivedAt  string
+	ProcessedAt string
+	Payload     string
+}
@@ -0,0 +1,48 @@
+package view
+
+type AdminReportsPage struct {
+	Period  string
+	From    string
+	To      string
+	Periods []string
+	Rows    []AdminSalesRow
+	Totals  AdminSalesTotals
+}
+
+type AdminSalesRow struct {
+	Bucket          string
+	Currency        string
+	Orders          int
+	Gross           string
+	Refunds         string
+	Chargebacks     string
+	Net             string
+	GiftCards       string
+	BaseGross       string
+	BaseRefunds     string
+	BaseChargebacks string
+	BaseNet         string
+	BaseGiftCards   string
+}
+
+type AdminSalesTotals struct {
+	Gross       string
+	Refunds     string
+	Chargebacks string
+	Net         string
+	GiftCards   string
+}
+
+type AdminReconciliationPage struct {
+	Items []AdminReconciliationItem
+}
+
+type AdminReconciliationItem struct {
+	OrderID   string
+	Status    string
+	Expected  string
+	Ledger    string
+	Diff      string
+	Disputed  bool
+	CreatedAt string
+}
@@ -0,0 +1,31 @@
+package view
+
+type AdminShippingZoneListItem struct {
+	ID        string
+	Name      string
+	Countries string
+	Priority  int
+	Status    string
+	Methods   []string
+}
+
+type AdminShippingZone struct {
+	ID        string
+	Name      string
+	Countries string // virgülle ayrılmış ISO kodları, "*" = diğer tüm ülkeler
+	Priority  string
+	Status    string
+	Methods   []AdminShippingMethod
+}
+
+type AdminShippingMethod struct {
+	ID            string
+	Code          string
+	Name          string
+	RateType      string
+	FlatCents     string
+	Tiers         string // satır başına "up_to:cents"
+	FreeOverCents string
+	Position      string
+	Status        string
+}
@@ -30,7 +30,30 @@ type CheckoutForm struct {
 
 	ShippingMethod string
 	PaymentMethod  string
+	PromoCode      string
+	GiftCardCodes  string
+	GiftRecipient  string
 	IdemKey        string
+
+	// Address book (signed-in customers)
+	SavedAddresses []SavedAddress
+	SaveAddress    bool
+}
+
+// SavedAddress is an address book entry offered by the checkout picker.
+type SavedAddress struct {
+	ID         string
+	Label      string
+	Summary    string
+	IsDefault  bool
+	FirstName  string
+	LastName   string
+	Address1   string
+	Address2   string
+	City       string
+	PostalCode string
+	Country    string
+	Phone      string
 }
 
 type CheckoutSummary struct {
@@ -50,6 +73,24 @@ type CheckoutSummary struct {
 	DisplaySubtotalCents int
 	DisplayShippingCents int
 	DisplayTotalCents    int
+
+	PromoCode            string
+	Discount             string
+	DiscountCents        int
+	DisplayDiscountCents int
+
+	// KDV önizlemesi: sipariş oluştururken yapılan hesabın aynısı
+	PricesIncludeTax bool
+	Tax              string
+	TaxLines         []CheckoutTaxLine
+	TaxCents         int // base
+	DisplayTaxCents  int
+}
+
+// CheckoutTaxLine is the VAT of one rate in the checkout summary.
+type CheckoutTaxLine struct {
+	Label  string
+	Amount string
 }
 
 type PaymentOption struct {
@@ -7,19 +7,37 @@ type OrderItem struct {
 	Qty         int
 	PriceEach   string
 	LineTotal   string
+	TaxRate     string
+	Tax         string
 }
 
 type OrderDetail struct {
 	ID        string
+	Number    string
+	Invoice   string // fatura numarası (ödenmemişse boş)
 	Status    string
 	Currency  string
 	Subtotal  string
 	Shipping  string
 	Tax       string
+	TaxLabel  string
 	Discount  string
 	Total     string
+	GiftCard  string // hediye kartı ile ödenen (yoksa boş)
+	AmountDue string
 	Items     []OrderItem
 	Shipments []OrderShipment
+	// iade dekontları (başarılı iadeler)
+	CreditNotes []OrderCreditNote
+
+	PaymentState string // pending|confirmed|failed ("" = no payment yet)
+}
+
+type OrderCreditNote struct {
+	RefundID string
+	Number   string
+	Amount   string
+	Date     string
 }
 
 type OrderShipment struct {
@@ -28,3 +46,20 @@ type OrderShipment struct {
 	TrackingNumber string
 	TrackingURL    string
 }
+
+// TaxLabel names the order tax line depending on the pricing mode.
+func TaxLabel(pricesIncludeTax bool) string {
+	if pricesIncludeTax {
+		return "VAT (included)"
+	}
+	return "VAT"
+}
+
+// MockPayPage is the local stand-in for a provider-hosted payment page.
+type MockPayPage struct {
+	PaymentRef string
+	Amount     string
+	Capture    string
+	ReturnURL  string
+	CancelURL  string
+}
@@ -0,0 +1,85 @@
+package view
+
+// AccountReturnForm is the customer's return request form for one order.
+type AccountReturnForm struct {
+	OrderID     string
+	OrderNumber string
+	CSRFToken   string
+	Items       []AccountReturnLine
+	Reason      string
+	Error       string
+}
+
+type AccountReturnLine struct {
+	OrderItemID string
+	Name        string
+	SKU         string
+	Unit        string
+	Max         int
+}
+
+type AccountReturnsPage struct {
+	Items []AccountReturn
+}
+
+type AccountReturn struct {
+	RMA         string
+	OrderID     string
+	OrderNumber string
+	Status      string
+	StatusLabel string
+	Refund      string
+	CreatedAt   string
+	Lines       []AccountReturnItem
+	LabelURL    string
+	TrackingNo  string
+	Note        string
+}
+
+type AccountReturnItem struct {
+	Name string
+	Qty  int
+}
+
+type AdminReturnsList struct {
+	Status   string
+	Statuses []string
+	Items    []AdminReturnListItem
+}
+
+type AdminReturnListItem struct {
+	ID        string
+	RMA       string
+	OrderID   string
+	Status    string
+	Refund    string
+	ItemCount int
+	CreatedAt string
+}
+
+type AdminReturnDetail struct {
+	ID              string
+	RMA             string
+	OrderID         string
+	UserID          string
+	Status          string
+	Reason          string
+	AdminNote       string
+	Refund          string
+	RefundID        string
+	LabelCarrier    string
+	LabelTrackingNo string
+	LabelURL        string
+	CreatedAt       string
+	ApprovedAt      string
+	ReceivedAt      string
+	Items           []AdminReturnItem
+	LabelsAvailable bool
+}
+
+type AdminReturnItem struct {
+	ProductName string
+	SKU         string
+	Qty         int
+	Refund      string
+}
@@ -33,7 +33,7 @@ func AdminHeader() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<header class=\"bg-gray-800 text-white shadow\"><nav class=\"mx-auto max-w-7xl px-4 sm:px-6 lg:px-8\"><div class=\"flex h-16 items-center justify-between\"><div class=\"flex items-center\"><a href=\"/admin\" class=\"flex-shrink-0\"><h1 class=\"text-xl font-bold\">Admin Dashboard</h1></a><div class=\"hidden md:block\"><div class=\"ml-10 flex items-baseline space-x-4\"><a href=\"/admin/orders\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Orders</a> <a href=\"/admin/products\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Products</a> <a href=\"/admin/sms/failed\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Failed SMS</a></div></div></div><div class=\"hidden md:block\"><div class=\"ml-4 flex items-center md:ml-6\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<header class=\"bg-gray-800 text-white shadow\"><nav class=\"mx-auto max-w-7xl px-4 sm:px-6 lg:px-8\"><div class=\"flex h-16 items-center justify-between\"><div class=\"flex items-center\"><a href=\"/admin\" class=\"flex-shrink-0\"><h1 class=\"text-xl font-bold\">Admin Dashboard</h1></a><div class=\"hidden md:block\"><div class=\"ml-10 flex items-baseline space-x-4\"><a href=\"/admin/orders\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Orders</a> <a href=\"/admin/returns\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Returns</a> <a href=\"/admin/products\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Products</a> <a href=\"/admin/inventory/movements\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Stock</a> <a href=\"/admin/coupons\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Coupons</a> <a href=\"/admin/giftcards\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Gift cards</a> <a href=\"/admin/shipping\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Shipping</a> <a href=\"/admin/sms/failed\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Failed SMS</a> <a href=\"/admin/reports\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Reports</a> <a href=\"/admin/webhooks\" class=\"rounded-md px-3 py-2 text-sm font-medium hover:bg-gray-700\">Webhooks</a></div></div></div><div class=\"hidden md:block\"><div class=\"ml-4 flex items-center md:ml-6\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -45,7 +45,7 @@ func AdminHeader() templ.Component {
 			var templ_7745c5c3_Var2 string
 			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(h.UserEmail)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout/admin_header.templ`, Line: 27, Col: 59}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout/admin_header.templ`, Line: 34, Col: 59}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 			if templ_7745c5c3_Err != nil {
@@ -58,7 +58,7 @@ func AdminHeader() templ.Component {
 			var templ_7745c5c3_Var3 string
 			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(h.CSRFToken)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout/admin_header.templ`, Line: 29, Col: 66}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout/admin_header.templ`, Line: 36, Col: 66}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 			if templ_7745c5c3_Err != nil {
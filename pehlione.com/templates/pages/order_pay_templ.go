@@ -128,7 +128,7 @@ func OrderPayBody(csrf string, orderID string, total string, idemKey string) tem
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\"> <button class=\"rounded border px-4 py-2\" type=\"submit\">Mock Pay (Successful)</button></form><div class=\"mt-6\"><a class=\"underline\" href=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\"> <button class=\"rounded border px-4 py-2\" type=\"submit\">Pay now</button></form><div class=\"mt-6\"><a class=\"underline\" href=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}